@@ -0,0 +1,150 @@
+// Command generator simulates a fleet of hosts for frontend development: N
+// virtual hosts with distinct IDs send plausible, wandering metrics (a daily
+// CPU curve, slowly filling disks, occasional spikes) to the server.
+//
+//	go run ./cmd/generator --hosts 40 --target http://localhost:8080/api/stats
+//	go run ./cmd/generator --hosts 40 --backfill 168h --backfill-step 1m --backfill-only
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+type options struct {
+	hosts        int
+	target       string
+	interval     time.Duration
+	seed         uint64
+	backfill     time.Duration
+	backfillStep time.Duration
+	backfillOnly bool
+}
+
+func main() {
+	var opts options
+	flag.IntVar(&opts.hosts, "hosts", 20, "number of virtual hosts")
+	flag.StringVar(&opts.target, "target", "http://localhost:8080/api/stats", "ingest URL")
+	flag.DurationVar(&opts.interval, "interval", 5*time.Second, "time between live samples of each host")
+	flag.Uint64Var(&opts.seed, "seed", 1, "seed for host identities and metric curves; the same seed gives the same fleet")
+	flag.DurationVar(&opts.backfill, "backfill", 0, "first send this much history (e.g. 168h), as fast as the server accepts it")
+	flag.DurationVar(&opts.backfillStep, "backfill-step", time.Minute, "time between backfilled samples; coarser steps backfill faster")
+	flag.BoolVar(&opts.backfillOnly, "backfill-only", false, "exit after the backfill instead of continuing live")
+	flag.Parse()
+
+	if opts.hosts < 1 || opts.interval <= 0 || opts.backfill < 0 || opts.backfillStep <= 0 {
+		fmt.Fprintln(os.Stderr, "--hosts must be at least 1, --interval and --backfill-step positive, --backfill not negative")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, opts); err != nil {
+		appLogger.Error("%v", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, opts options) error {
+	now := time.Now()
+	start := now.Add(-opts.backfill)
+	hosts := make([]*virtualHost, opts.hosts)
+	for i := range hosts {
+		hosts[i] = newVirtualHost(opts.seed, i, start)
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	var sent, failed atomic.Int64
+
+	if opts.backfill > 0 {
+		appLogger.Info("Backfilling %s of history for %d hosts every %s (%d samples per host)...", opts.backfill, opts.hosts, opts.backfillStep, int64(opts.backfill/opts.backfillStep))
+		began := time.Now()
+		forEachHost(hosts, func(h *virtualHost) {
+			for at := start; at.Before(now) && ctx.Err() == nil; at = at.Add(opts.backfillStep) {
+				send(ctx, client, opts.target, h.sample(at), &sent, &failed)
+			}
+		})
+		appLogger.Info("Backfill done in %s: %d sent, %d failed.", time.Since(began).Round(time.Second), sent.Load(), failed.Load())
+		if opts.backfillOnly || ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	appLogger.Info("Sending live samples for %d hosts to %s every %s. Ctrl+C stops.", opts.hosts, opts.target, opts.interval)
+	forEachHost(hosts, func(h *virtualHost) {
+		ticker := time.NewTicker(opts.interval)
+		defer ticker.Stop()
+		for {
+			send(ctx, client, opts.target, h.sample(time.Now()), &sent, &failed)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+	appLogger.Info("Generator stopped: %d sent, %d failed.", sent.Load(), failed.Load())
+	return nil
+}
+
+// forEachHost runs fn for every host concurrently and waits for all of them.
+// Each host's samples stay in order.
+func forEachHost(hosts []*virtualHost, fn func(*virtualHost)) {
+	var wg sync.WaitGroup
+	for _, h := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(h)
+		}()
+	}
+	wg.Wait()
+}
+
+// send POSTs one payload. Failures are logged and counted, the fleet keeps going.
+func send(ctx context.Context, client *http.Client, target string, payload *models.ClientPayload, sent, failed *atomic.Int64) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		appLogger.Error("Failed to encode payload of %s: %v", payload.System.HostID, err)
+		failed.Add(1)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		appLogger.Error("Failed to create request: %v", err)
+		failed.Add(1)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			appLogger.Warn("Sending %s failed: %v", payload.System.HostID, err)
+			failed.Add(1)
+		}
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		appLogger.Warn("Server rejected %s at %s: %s %s", payload.System.HostID, payload.CollectedAt.Format(time.RFC3339), resp.Status, bytes.TrimSpace(message))
+		failed.Add(1)
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body) // reuse the connection
+	sent.Add(1)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+var tiers = []string{"web", "db", "cache", "worker"}
+
+// virtualHost is one simulated machine. All randomness comes from its own
+// generator seeded with (seed, index), so a run is reproducible host by host.
+type virtualHost struct {
+	rng      *rand.Rand
+	id       string
+	hostname string
+	tier     string
+	bootedAt time.Time
+
+	cpuBase, cpuSwing float64 // percent; the swing follows the time of day
+	memBase           float64 // percent, wanders slowly
+	diskTotalGB       float64
+	diskUsedGB        float64
+	diskFillPerDayGB  float64
+	netBase           float64 // bytes/sec at the daily low
+	spikeLeft         int     // samples of the current CPU spike still to come
+
+	sentTotal, recvTotal uint64
+	last                 time.Time
+}
+
+func newVirtualHost(seed uint64, index int, start time.Time) *virtualHost {
+	rng := rand.New(rand.NewPCG(seed, uint64(index)))
+	tier := tiers[index%len(tiers)]
+	diskTotal := []float64{100, 250, 500}[rng.IntN(3)]
+	return &virtualHost{
+		rng:              rng,
+		id:               fmt.Sprintf("sim-%04d", index),
+		hostname:         fmt.Sprintf("sim-%s-%02d", tier, index/len(tiers)+1),
+		tier:             tier,
+		bootedAt:         start.Add(-time.Duration(rng.IntN(30*24)) * time.Hour),
+		cpuBase:          5 + rng.Float64()*25,
+		cpuSwing:         10 + rng.Float64()*40,
+		memBase:          30 + rng.Float64()*40,
+		diskTotalGB:      diskTotal,
+		diskUsedGB:       diskTotal * (0.2 + rng.Float64()*0.5),
+		diskFillPerDayGB: diskTotal * (0.002 + rng.Float64()*0.02),
+		netBase:          50e3 + rng.Float64()*2e6,
+		last:             start,
+	}
+}
+
+// sample advances the host to at and returns the payload it would send.
+func (h *virtualHost) sample(at time.Time) *models.ClientPayload {
+	elapsed := at.Sub(h.last)
+	if elapsed <= 0 {
+		elapsed = time.Second
+	}
+	h.last = at
+
+	// Diurnal curve: lowest around 04:00, highest around 16:00
+	hour := float64(at.Hour()) + float64(at.Minute())/60
+	daily := (1 - math.Cos(2*math.Pi*(hour-4)/24)) / 2
+
+	cpu := h.cpuBase + h.cpuSwing*daily + h.rng.NormFloat64()*3
+	if h.spikeLeft == 0 && h.rng.Float64() < 0.002 {
+		h.spikeLeft = 3 + h.rng.IntN(10)
+	}
+	if h.spikeLeft > 0 {
+		h.spikeLeft--
+		cpu = 85 + h.rng.Float64()*15
+	}
+	h.memBase = clamp(h.memBase+h.rng.NormFloat64()*0.2, 15, 90)
+	mem := clamp(h.memBase+daily*5+h.rng.NormFloat64(), 1, 99)
+
+	// Disks fill slowly; at 95% someone cleans up to half
+	h.diskUsedGB += h.diskFillPerDayGB * elapsed.Hours() / 24
+	if h.diskUsedGB > h.diskTotalGB*0.95 {
+		h.diskUsedGB = h.diskTotalGB * 0.5
+	}
+
+	upload := h.netBase * (0.3 + daily) * (0.8 + h.rng.Float64()*0.4)
+	download := upload * (2 + h.rng.Float64())
+	sentPeriod := uint64(upload * elapsed.Seconds())
+	recvPeriod := uint64(download * elapsed.Seconds())
+	h.sentTotal += sentPeriod
+	h.recvTotal += recvPeriod
+
+	memTotal := 16.0
+	inodesTotal := uint64(h.diskTotalGB * 65536)
+	inodesUsed := uint64(float64(inodesTotal) * h.diskUsedGB / h.diskTotalGB * 0.3)
+	return &models.ClientPayload{
+		CollectedAt:  at.UTC(),
+		AgentVersion: "generator",
+		Labels:       map[string]string{"tier": h.tier, "source": "generator"},
+		System: models.SystemInfoPayload{
+			Hostname:      h.hostname,
+			HostID:        h.id,
+			OS:            "linux",
+			OSVersion:     "ubuntu 24.04",
+			Kernel:        "linux",
+			KernelVersion: "x86_64",
+			Uptime:        at.Sub(h.bootedAt).Round(time.Second).String(),
+			UptimeSeconds: uint64(at.Sub(h.bootedAt) / time.Second),
+			LoggedInUsers: h.rng.IntN(3),
+		},
+		CPU: models.CPUInfoPayload{ModelName: "Simulated CPU @ 3.00GHz", Cores: 8, Usage: clamp(cpu, 0, 100)},
+		Memory: models.MemInfoPayload{
+			TotalGB:      memTotal,
+			FreeGB:       memTotal * (1 - mem/100),
+			UsagePercent: mem,
+		},
+		Network: models.NetworkPayload{
+			InterfaceName:       "all",
+			BytesSentPeriod:     sentPeriod,
+			BytesRecvPeriod:     recvPeriod,
+			UploadBytesPerSec:   upload,
+			DownloadBytesPerSec: download,
+			CumulativeBytesSent: h.sentTotal,
+			CumulativeBytesRecv: h.recvTotal,
+		},
+		Disks: []models.DiskUsagePayload{{
+			Path:               "/",
+			TotalGB:            h.diskTotalGB,
+			UsedGB:             h.diskUsedGB,
+			FreeGB:             h.diskTotalGB - h.diskUsedGB,
+			UsagePercent:       h.diskUsedGB / h.diskTotalGB * 100,
+			InodesTotal:        inodesTotal,
+			InodesUsed:         inodesUsed,
+			InodesUsagePercent: float64(inodesUsed) / float64(inodesTotal) * 100,
+		}},
+	}
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
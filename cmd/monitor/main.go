@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,39 +18,119 @@ import (
 	"github.com/shirou/gopsutil/v3/net"
 )
 
+// version is set at build time:
+//
+//	go build -ldflags "-X main.version=1.4.0" ./cmd/monitor
+var version = "dev"
+
 type AllHostStats struct {
-	CollectedAt time.Time                   `json:"collected_at"`
-	System      clientStats.SystemInfoData  `json:"system_info"`
-	CPU         clientStats.CPUInfoData     `json:"cpu_info"`
-	Memory      clientStats.MemInfoData     `json:"memory_info"`
-	Network     clientStats.NetworkData     `json:"network_info"`
-	Processes   []clientStats.ProcessData   `json:"processes,omitempty"`
-	Disks       []clientStats.DiskUsageData `json:"disk_usage,omitempty"`
+	CollectedAt    time.Time                       `json:"collected_at"`
+	AgentVersion   string                          `json:"agent_version"`
+	Labels         map[string]string               `json:"labels,omitempty"`
+	ProcessCPU     string                          `json:"process_cpu_convention,omitempty"` // per_core or total, see GetProcessList
+	System         clientStats.SystemInfoData      `json:"system_info"`
+	CPU            clientStats.CPUInfoData         `json:"cpu_info"`
+	Memory         clientStats.MemInfoData         `json:"memory_info"`
+	Network        clientStats.NetworkData         `json:"network_info"`
+	Interfaces     []clientStats.InterfaceInfoData `json:"interface_info,omitempty"`
+	Processes      []clientStats.ProcessData       `json:"processes,omitempty"`
+	ProcessSummary *clientStats.ProcessSummaryData `json:"process_summary,omitempty"` // instead of Processes with MONITOR_PROCESS_MODE=summary
+	Disks          []clientStats.DiskUsageData     `json:"disk_usage,omitempty"`
+	DiskHealth     []clientStats.DiskHealthData    `json:"disk_health,omitempty"`
+	Throttle       *clientStats.ThrottleData       `json:"throttle,omitempty"` // ARM Linux only
+	Services       []clientStats.ServiceStatusData `json:"services,omitempty"` // MONITOR_SERVICES units, systemd hosts only
 }
 
 var (
+	collectDiskHealth = getEnvAsBool("MONITOR_SMART", false)
+
+	// CPU throttling on ARM Linux boards (Raspberry Pi), a no-op elsewhere
+	collectThrottle = getEnvAsBool("MONITOR_THROTTLE", true)
+
+	// systemd units to report, MONITOR_SERVICES=nginx.service,postgresql; empty = off
+	serviceUnits = getEnvAsUnits("MONITOR_SERVICES")
+
+	// MONITOR_REDACT fields are blanked or hashed before sending; nil = none
+	redaction = newRedactor()
+
+	// Static labels for cluster views, MONITOR_LABELS=tier=web,dc=eu-1
+	labels = getEnvAsLabels("MONITOR_LABELS")
+
+	// Processes are sent on cycles 1, N+1, 2N+1, ... (MONITOR_PROCESS_EVERY=N)
+	processEvery = getEnvAsInt("MONITOR_PROCESS_EVERY", 1)
+	cycle        int
+	processCache = clientStats.NewProcessCache() // per-process CPU and I/O baselines between cycles
+
+	// MONITOR_PROCESS_MODE=summary sends a process count and the top processes
+	// by name instead of the per-PID list
+	summarizeProcesses = processModeSummary()
+
+	// MONITOR_PROCESS_CPU_NORMALIZED=true sends process CPU as percent of all
+	// cores (max 100) instead of percent of one core (max 100 x cores)
+	normalizeProcessCPU = getEnvAsBool("MONITOR_PROCESS_CPU_NORMALIZED", false)
+
+	// Network rates above this (bytes/sec, either direction) are dropped as implausible
+	maxNetworkBytesPerSec = getEnvAsInt64("MONITOR_NET_MAX_BYTES_PER_SEC", clientStats.DefaultMaxNetworkBytesPerSec)
+
+	// MONITOR_CPU_SAMPLING=cached reads CPU usage from the previous cycle's
+	// CPU times instead of blocking a second per cycle; nil = blocking
+	cpuSampler = newCPUSampler()
+
+	interfaceCollector = clientStats.NewInterfaceCollector() // per-interface counters between cycles
+
 	previousNetCounters       net.IOCountersStat
 	previousNetCollectionTime time.Time
 	networkStatsInitialized   bool
 )
 
+// serverURL is where stats are sent; tests point it at a test server.
+var serverURL = "http://localhost:8080/api/stats"
+
 const (
-	serverURL                = "http://localhost:8080/api/stats"
 	collectionInterval       = 5 * time.Second
 	maxProcessesUsagePercent = 10.0 // Limit the usage percent for procesess memory & CPU
+	processSummaryTop        = 5    // processes named in summary mode
 )
 
 func main() {
-	fmt.Printf("Starting System Statistics Monitor Client (PID: %d)...\n", os.Getpid())
+	fmt.Printf("Starting System Statistics Monitor Client %s (PID: %d)...\n", version, os.Getpid())
 
-	// Initialize network stats baseline
-	var err error
-	previousNetCounters, err = clientStats.GetCurrentIOCounters()
+	// Exit only after run's deferred cleanup is done
+	err := run()
+	if err != nil {
+		appLogger.Error("Exiting: %v", err)
+	}
+	appLogger.CloseHooks(5 * time.Second) // deliver queued syslog/forwarded entries
 	if err != nil {
-		appLogger.Fatal("Error getting initial network counters: %v. Exiting.", err)
+		os.Exit(1)
+	}
+}
+
+// run collects and sends until SIGINT/SIGTERM, or once with MONITOR_ONESHOT.
+func run() error {
+	if redaction != nil {
+		appLogger.Info("Redacting payload fields: %s.", redaction.describe())
+	}
+
+	// MONITOR_ONESHOT=true: collect once, send, exit (for cron). No ticker and no
+	// network baseline, so the period/rate network fields are reported as zero.
+	if getEnvAsBool("MONITOR_ONESHOT", false) {
+		// The snapshot has to outlive the process to give the usage since the
+		// previous run, so oneshot always samples cached from a state file
+		cpuSampler = clientStats.NewCPUSampler(cpuStatePath())
+		appLogger.Info("Oneshot mode: collecting and sending stats once to %s.", serverURL)
+		if err := collectAndSendStats(context.Background()); err != nil {
+			return fmt.Errorf("oneshot run failed: %w", err)
+		}
+		return nil
+	}
+
+	// The network baseline is taken by the first collection, which sends no
+	// rates: it runs right away, and rates over a few milliseconds since a
+	// baseline taken here would be mostly noise
+	if _, err := clientStats.GetCurrentIOCounters(); err != nil {
+		return fmt.Errorf("error getting initial network counters: %w", err)
 	}
-	previousNetCollectionTime = time.Now()
-	networkStatsInitialized = true
 
 	// ---- Setup for periodic collection and sending -----
 	ctx, cancel := context.WithCancel(context.Background())
@@ -85,17 +169,25 @@ func main() {
 			// Allow a brief moment for any final logging or cleanup if necessary
 			time.Sleep(200 * time.Millisecond)
 			fmt.Println("Client exited.")
-			return
+			return nil
 		}
 	}
 }
 
-func collectAndSendStats(ctx context.Context) {
+// collectAndSendStats collects one snapshot and sends it. Collector errors are
+// logged and tolerated, only a failed send is returned.
+func collectAndSendStats(ctx context.Context) error {
 	appLogger.Info("Collecting stats...")
 
 	var hostStats AllHostStats
 
 	hostStats.CollectedAt = time.Now().UTC()
+	hostStats.AgentVersion = version
+	hostStats.Labels = labels
+	hostStats.ProcessCPU = clientStats.ProcessCPUPerCore
+	if normalizeProcessCPU {
+		hostStats.ProcessCPU = clientStats.ProcessCPUTotal
+	}
 
 	var err error
 	hostStats.System, err = clientStats.GetSystemInfo()
@@ -103,7 +195,7 @@ func collectAndSendStats(ctx context.Context) {
 		appLogger.Error("Error getting system info: %v", err)
 	}
 
-	hostStats.CPU, err = clientStats.GetCPUInfo()
+	hostStats.CPU, err = clientStats.GetCPUInfo(cpuSampler)
 	if err != nil {
 		appLogger.Error("Error getting CPU info: %v", err)
 	}
@@ -121,26 +213,61 @@ func collectAndSendStats(ctx context.Context) {
 		currentTime := time.Now()
 		if networkStatsInitialized {
 			duration := currentTime.Sub(previousNetCollectionTime)
-			hostStats.Network, err = clientStats.CalculateNetworkRates(currentNetCounters, previousNetCounters, duration)
+			hostStats.Network, err = clientStats.CalculateNetworkRates(currentNetCounters, previousNetCounters, duration, float64(maxNetworkBytesPerSec))
+			if err == nil && hostStats.Network.RatesSkipped {
+				appLogger.Warn("Network counters went backwards, jumped implausibly or were sampled too close together, skipping this interval's rates.")
+			}
 			if err != nil {
 
 				appLogger.Error("Error calculating network rates: %v", err)
-				// Set to a default or empty struct if calculation fails
-				hostStats.Network = clientStats.NetworkData{InterfaceName: "all"}
+				// Set to a default or empty struct if calculation fails, totals are still valid
+				hostStats.Network = clientStats.NetworkData{
+					InterfaceName:       "all",
+					CumulativeBytesSent: currentNetCounters.BytesSent,
+					CumulativeBytesRecv: currentNetCounters.BytesRecv,
+					RatesSkipped:        true,
+				}
 
 			}
 
+		} else {
+			// No baseline (first collection, oneshot mode): totals only, no rates
+			hostStats.Network = clientStats.NetworkData{
+				InterfaceName:       "all",
+				CumulativeBytesSent: currentNetCounters.BytesSent,
+				CumulativeBytesRecv: currentNetCounters.BytesRecv,
+				RatesSkipped:        true,
+			}
 		}
 		// Update for next iteration
 		previousNetCounters = currentNetCounters
 		previousNetCollectionTime = currentTime
+		networkStatsInitialized = true
 	}
 
-	// process List
-	hostStats.Processes, err = clientStats.GetProcessList(maxProcessesUsagePercent)
+	// Per-interface link speed, state and rates
+	hostStats.Interfaces, err = interfaceCollector.Collect(float64(maxNetworkBytesPerSec))
 	if err != nil {
-		appLogger.Error("Error getting process list: %v", err)
+		appLogger.Error("Error getting network interfaces: %v", err)
+	}
+
+	// process List, every processEvery-th cycle
+	if processesDue(cycle, processEvery) {
+		if summarizeProcesses {
+			summary, err := clientStats.GetProcessSummary(processSummaryTop, normalizeProcessCPU, processCache)
+			if err != nil {
+				appLogger.Error("Error getting process summary: %v", err)
+			} else {
+				hostStats.ProcessSummary = &summary
+			}
+		} else {
+			hostStats.Processes, err = clientStats.GetProcessList(maxProcessesUsagePercent, normalizeProcessCPU, processCache)
+			if err != nil {
+				appLogger.Error("Error getting process list: %v", err)
+			}
+		}
 	}
+	cycle++
 
 	// disk
 	hostStats.Disks, err = clientStats.GetDiskUsageInfo()
@@ -148,14 +275,169 @@ func collectAndSendStats(ctx context.Context) {
 		appLogger.Error("Error getting disk usage %v", err)
 	}
 
+	// disk health, MONITOR_SMART=true (needs smartctl and usually root)
+	if collectDiskHealth {
+		hostStats.DiskHealth, err = clientStats.GetDiskHealth(ctx)
+		if err != nil {
+			appLogger.Error("Error getting disk health: %v", err)
+		}
+	}
+
+	if collectThrottle {
+		hostStats.Throttle, err = clientStats.GetThrottleInfo(ctx)
+		if err != nil {
+			appLogger.Error("Error getting CPU throttling: %v", err)
+		}
+	}
+
+	// systemd unit states, MONITOR_SERVICES (skipped on hosts without systemd)
+	if len(serviceUnits) > 0 {
+		hostStats.Services, err = clientStats.GetServiceStatus(ctx, serviceUnits)
+		if err != nil {
+			appLogger.Error("Error getting service states: %v", err)
+		}
+	}
+
 	// <-------- SEND THE DATA -------->
+	redaction.apply(&hostStats)
 	err = exporter.SendStatsJSON(ctx, serverURL, hostStats) // Pass the populated hostStats struct
 	if err != nil {
+		// Stats are not kept, the next cycle sends fresh ones either way
+		var rejected *exporter.RejectedError
+		switch {
+		case exporter.Retryable(err):
+			appLogger.Error("Failed to send stats (temporary, a later send may succeed): %v", err)
+		case errors.As(err, &rejected):
+			appLogger.Error("Server rejected the stats (%s), check that agent and server versions match: %v", rejected.Status, err)
+		default:
+			appLogger.Error("Failed to send stats: %v", err)
+		}
+		return err
+	}
+	appLogger.Info("Stats dispatch initiated successfully by exporter.")
+	fmt.Println("-----------------------------------------------------")
+	return nil
+}
 
-		appLogger.Error("Failed to send stats: %v", err)
-	} else {
-		appLogger.Info("Stats dispatch initiated successfully by exporter.")
-		fmt.Println("-----------------------------------------------------")
+// newCPUSampler returns the MONITOR_CPU_SAMPLING sampler, nil for blocking.
+func newCPUSampler() *clientStats.CPUSampler {
+	switch mode := os.Getenv("MONITOR_CPU_SAMPLING"); mode {
+	case "", "blocking":
+		return nil
+	case "cached":
+		return clientStats.NewCPUSampler("") // kept in memory between cycles
+	default:
+		appLogger.Warn("Env var MONITOR_CPU_SAMPLING must be blocking or cached, got %q. Using blocking", mode)
+		return nil
+	}
+}
+
+// processModeSummary reads MONITOR_PROCESS_MODE, list (default) or summary.
+func processModeSummary() bool {
+	switch mode := os.Getenv("MONITOR_PROCESS_MODE"); mode {
+	case "", "list":
+		return false
+	case "summary":
+		return true
+	default:
+		appLogger.Warn("Env var MONITOR_PROCESS_MODE must be list or summary, got %q. Using list", mode)
+		return false
 	}
+}
+
+// processesDue reports whether processes are collected on the 0-based cycle:
+// 0, every, 2 x every, ..., so on the first cycle and every n-th after it.
+func processesDue(cycle, every int) bool {
+	return cycle%max(every, 1) == 0
+}
+
+// cpuStatePath is where oneshot runs keep the CPU times between runs:
+// MONITOR_CPU_STATE_FILE, or the user cache directory.
+func cpuStatePath() string {
+	if path := os.Getenv("MONITOR_CPU_STATE_FILE"); path != "" {
+		return path
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "system-stats-monitoring", "cpu-times.json")
+}
 
+// get an environment variable as a positive integer or return a default value.
+func getEnvAsInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		i, err := strconv.Atoi(value)
+		if err == nil && i > 0 {
+			return i
+		}
+		appLogger.Warn("Env var %s must be a positive integer, got %q. Using fallback: %d", key, value, fallback)
+	}
+	return fallback
+}
+
+// getEnvAsInt64 is getEnvAsInt for values that don't fit a 32-bit int.
+func getEnvAsInt64(key string, fallback int64) int64 {
+	if value, exists := os.LookupEnv(key); exists {
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err == nil && i > 0 {
+			return i
+		}
+		appLogger.Warn("Env var %s must be a positive integer, got %q. Using fallback: %d", key, value, fallback)
+	}
+	return fallback
+}
+
+// get an environment variable of comma-separated name=value pairs as labels.
+// Malformed entries are skipped with a warning; the server validates the rest.
+func getEnvAsLabels(key string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, labelValue, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		name, labelValue = strings.TrimSpace(name), strings.TrimSpace(labelValue)
+		if !ok || name == "" || labelValue == "" {
+			appLogger.Warn("Env var %s: ignoring %q, expected name=value", key, pair)
+			continue
+		}
+		labels[name] = labelValue
+	}
+	return labels
+}
+
+// get an environment variable of comma-separated systemd unit names. Names
+// systemctl wouldn't take as a unit are skipped with a warning.
+func getEnvAsUnits(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	var units []string
+	for _, unit := range strings.Split(value, ",") {
+		unit = strings.TrimSpace(unit)
+		if unit == "" {
+			continue
+		}
+		if !clientStats.ValidUnitName(unit) {
+			appLogger.Warn("Env var %s: ignoring %q, not a systemd unit name", key, unit)
+			continue
+		}
+		units = append(units, unit)
+	}
+	return units
+}
+
+// get an environment variable as a boolean or return a default value.
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		b, err := strconv.ParseBool(value)
+		if err == nil {
+			return b
+		}
+		appLogger.Warn("Failed to parse env var %s as bool: %v. Using fallback: %t", key, err, fallback)
+	}
+	return fallback
 }
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	clientStats "github.com/4Noyis/system-stats-monitoring/internal/stats"
+)
+
+// statsReceiver stands in for the server's stats endpoint and keeps every
+// payload the agent sends.
+type statsReceiver struct {
+	mu       sync.Mutex
+	payloads []AllHostStats
+	status   int // response status, 200 when 0
+}
+
+// newStatsReceiver starts a receiver and points serverURL at it for the test.
+func newStatsReceiver(t *testing.T) *statsReceiver {
+	t.Helper()
+	receiver := &statsReceiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload AllHostStats
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("agent sent an undecodable payload: %v", err)
+		}
+		receiver.mu.Lock()
+		receiver.payloads = append(receiver.payloads, payload)
+		status := receiver.status
+		receiver.mu.Unlock()
+		if status != 0 {
+			w.WriteHeader(status)
+		}
+		_, _ = w.Write([]byte(`{"message":"Stats received successfully"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	previous := serverURL
+	serverURL = server.URL + "/api/stats"
+	t.Cleanup(func() { serverURL = previous })
+	return receiver
+}
+
+func (r *statsReceiver) received() []AllHostStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]AllHostStats(nil), r.payloads...)
+}
+
+// runOneshot runs the agent in oneshot mode and fails the test if it doesn't
+// return.
+func runOneshot(t *testing.T) error {
+	t.Helper()
+	t.Setenv("MONITOR_ONESHOT", "true")
+	t.Setenv("MONITOR_CPU_STATE_FILE", filepath.Join(t.TempDir(), "cpu-times.json"))
+	previousSampler := cpuSampler
+	t.Cleanup(func() { cpuSampler = previousSampler })
+
+	done := make(chan error, 1)
+	go func() { done <- run() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Minute):
+		t.Fatal("run did not return in oneshot mode")
+		return nil
+	}
+}
+
+func TestOneshotSendsOnceAndExits(t *testing.T) {
+	receiver := newStatsReceiver(t)
+
+	if err := runOneshot(t); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	payloads := receiver.received()
+	if len(payloads) != 1 {
+		t.Fatalf("payloads sent = %d, want 1", len(payloads))
+	}
+	if payloads[0].CollectedAt.IsZero() || payloads[0].System.HostID == "" {
+		t.Errorf("payload lacks collected_at or host_id: %+v", payloads[0].System)
+	}
+	if !payloads[0].Network.RatesSkipped {
+		t.Error("oneshot payload has network rates, want totals only (no baseline)")
+	}
+}
+
+func TestOneshotFailsWhenTheSendFails(t *testing.T) {
+	receiver := newStatsReceiver(t)
+	receiver.status = http.StatusServiceUnavailable
+
+	if err := runOneshot(t); err == nil {
+		t.Fatal("run returned nil after a rejected send, want an error (exit status 1)")
+	}
+	if got := len(receiver.received()); got != 1 {
+		t.Errorf("payloads sent = %d, want 1 (no retry)", got)
+	}
+}
+
+func TestProcessesDueEveryNthCycle(t *testing.T) {
+	for _, every := range []int{1, 3, 12} {
+		var due []int
+		for cycle := 0; cycle < 3*every+1; cycle++ {
+			if processesDue(cycle, every) {
+				due = append(due, cycle+1) // as 1-based cycle numbers
+			}
+		}
+		want := []int{1, every + 1, 2*every + 1, 3*every + 1}
+		if fmt.Sprint(due) != fmt.Sprint(want) {
+			t.Errorf("MONITOR_PROCESS_EVERY=%d: processes on cycles %v, want %v", every, due, want)
+		}
+	}
+}
+
+func TestSummaryModeSendsNoProcessList(t *testing.T) {
+	receiver := newStatsReceiver(t)
+	previous := summarizeProcesses
+	summarizeProcesses = true // MONITOR_PROCESS_MODE=summary
+	t.Cleanup(func() { summarizeProcesses = previous })
+
+	if err := runOneshot(t); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	payloads := receiver.received()
+	if len(payloads) != 1 {
+		t.Fatalf("payloads sent = %d, want 1", len(payloads))
+	}
+	summary := payloads[0].ProcessSummary
+	if summary == nil || summary.Count == 0 || len(summary.Top) > processSummaryTop {
+		t.Fatalf("process_summary = %+v, want a count and at most %d top processes", summary, processSummaryTop)
+	}
+	if len(payloads[0].Processes) != 0 {
+		t.Errorf("summary mode sent %d processes, want none", len(payloads[0].Processes))
+	}
+}
+
+// hmacHex is the hashed form of value under salt, as the redactor computes it.
+func hmacHex(salt, value string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:hashedValueLength]
+}
+
+// setRedaction configures MONITOR_REDACT* for the rest of the test.
+func setRedaction(t *testing.T, fields, mode, salt string) *redactor {
+	t.Helper()
+	t.Setenv("MONITOR_REDACT", fields)
+	t.Setenv("MONITOR_REDACT_MODE", mode)
+	t.Setenv("MONITOR_REDACT_SALT", salt)
+	previous := redaction
+	redaction = newRedactor()
+	t.Cleanup(func() { redaction = previous })
+	return redaction
+}
+
+func TestOneshotSendsTheRedactedHostname(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		t.Skipf("os.Hostname: %q, %v", hostname, err)
+	}
+	for mode, want := range map[string]string{"blank": "", "hash": hmacHex("fleet-salt", hostname)} {
+		t.Run(mode, func(t *testing.T) {
+			setRedaction(t, "system.hostname", mode, "fleet-salt")
+			receiver := newStatsReceiver(t)
+
+			if err := runOneshot(t); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			payloads := receiver.received()
+			if len(payloads) != 1 {
+				t.Fatalf("payloads sent = %d, want 1", len(payloads))
+			}
+			if got := payloads[0].System.Hostname; got != want {
+				t.Errorf("hostname sent = %q, want %q", got, want)
+			}
+			if payloads[0].System.HostID == "" {
+				t.Error("host_id is blank, want it left alone")
+			}
+		})
+	}
+}
+
+func TestRedactedPayloadFields(t *testing.T) {
+	payload := func() *AllHostStats {
+		return &AllHostStats{
+			System: clientStats.SystemInfoData{HostID: "host-1", Hostname: "db1.internal", LoggedInUsernames: []string{"alice", "bob"}},
+			Processes: []clientStats.ProcessData{
+				{PID: 812, Name: "postgres", Username: "postgres"},
+				{PID: 901, Name: "backup.sh", Username: "alice"},
+				{PID: 902, Name: "psql", Username: "alice"},
+				{PID: 1000, Name: "unknown-user", Username: ""},
+			},
+			ProcessSummary: &clientStats.ProcessSummaryData{Top: []clientStats.ProcessSummaryEntry{{Name: "postgres"}}},
+		}
+	}
+	// what the agent sends: the JSON of the payload after apply
+	sent := func(t *testing.T, r *redactor) AllHostStats {
+		t.Helper()
+		stats := payload()
+		r.apply(stats)
+		data, err := json.Marshal(stats)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var out AllHostStats
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return out
+	}
+
+	t.Run("blank", func(t *testing.T) {
+		out := sent(t, setRedaction(t, "process.username,system.logged_in_usernames", "blank", ""))
+		for _, p := range out.Processes {
+			if p.Username != "" {
+				t.Errorf("process %d username = %q, want blank", p.PID, p.Username)
+			}
+		}
+		if len(out.System.LoggedInUsernames) != 0 {
+			t.Errorf("logged_in_usernames = %q, want none", out.System.LoggedInUsernames)
+		}
+		if out.System.Hostname != "db1.internal" || out.Processes[0].Name != "postgres" {
+			t.Errorf("hostname %q, process name %q, want fields not listed left alone", out.System.Hostname, out.Processes[0].Name)
+		}
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		const salt = "fleet-salt"
+		out := sent(t, setRedaction(t, "process.username,process.name,system.logged_in_usernames", "hash", salt))
+		for i, p := range payload().Processes {
+			want := ""
+			if p.Username != "" {
+				want = hmacHex(salt, p.Username)
+			}
+			if got := out.Processes[i].Username; got != want {
+				t.Errorf("process %d username = %q, want %q", p.PID, got, want)
+			}
+			if got := out.Processes[i].Name; got != hmacHex(salt, p.Name) {
+				t.Errorf("process %d name = %q, want the hash of %q", p.PID, got, p.Name)
+			}
+		}
+		// the same value gives the same hash, so per-user counts still work
+		if out.Processes[1].Username != out.Processes[2].Username || out.Processes[0].Username == out.Processes[1].Username {
+			t.Errorf("usernames = %q, %q, %q, want alice's two alike and postgres' different",
+				out.Processes[0].Username, out.Processes[1].Username, out.Processes[2].Username)
+		}
+		if got := out.ProcessSummary.Top[0].Name; got != hmacHex(salt, "postgres") {
+			t.Errorf("summary top name = %q, want the hash of postgres", got)
+		}
+		if len(out.System.LoggedInUsernames) != 2 {
+			t.Errorf("logged_in_usernames = %q, want two hashes", out.System.LoggedInUsernames)
+		}
+		for _, name := range out.System.LoggedInUsernames {
+			if name != hmacHex(salt, "alice") && name != hmacHex(salt, "bob") {
+				t.Errorf("logged-in username %q is not a hash of alice or bob", name)
+			}
+		}
+		// another salt, other hashes
+		other := sent(t, setRedaction(t, "process.username", "hash", "other-salt"))
+		if other.Processes[0].Username == out.Processes[0].Username {
+			t.Error("the hash does not depend on the salt")
+		}
+	})
+
+	t.Run("hash without a salt blanks", func(t *testing.T) {
+		out := sent(t, setRedaction(t, "process.username", "hash", ""))
+		if out.Processes[0].Username != "" {
+			t.Errorf("username = %q, want blank", out.Processes[0].Username)
+		}
+	})
+}
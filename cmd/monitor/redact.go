@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sort"
+	"strings"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+)
+
+// redactableFields are the payload fields MONITOR_REDACT can name, each with
+// the function that rewrites it in place.
+var redactableFields = map[string]func(stats *AllHostStats, redact func(string) string){
+	"system.hostname": func(stats *AllHostStats, redact func(string) string) {
+		stats.System.Hostname = redact(stats.System.Hostname)
+	},
+	"system.logged_in_usernames": func(stats *AllHostStats, redact func(string) string) {
+		names := stats.System.LoggedInUsernames[:0]
+		for _, name := range stats.System.LoggedInUsernames {
+			if name = redact(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		stats.System.LoggedInUsernames = names
+	},
+	"process.name": func(stats *AllHostStats, redact func(string) string) {
+		for i := range stats.Processes {
+			stats.Processes[i].Name = redact(stats.Processes[i].Name)
+		}
+		if stats.ProcessSummary != nil {
+			for i := range stats.ProcessSummary.Top {
+				stats.ProcessSummary.Top[i].Name = redact(stats.ProcessSummary.Top[i].Name)
+			}
+		}
+	},
+	"process.username": func(stats *AllHostStats, redact func(string) string) {
+		for i := range stats.Processes {
+			stats.Processes[i].Username = redact(stats.Processes[i].Username)
+		}
+	},
+}
+
+// hashedValueLength is how many hex digits of the HMAC a hashed value keeps:
+// 64 bits, enough to keep distinct values apart on one fleet.
+const hashedValueLength = 16
+
+// redactor blanks or hashes the MONITOR_REDACT fields of a payload before it
+// is sent. Hashing is HMAC-SHA256 with MONITOR_REDACT_SALT as the key, so the
+// same value always gives the same hash (process series and counts still
+// work) while short values such as usernames can't be recovered by hashing
+// guesses without the salt.
+type redactor struct {
+	fields []string // keys of redactableFields, sorted
+	salt   []byte   // nil blanks instead of hashing
+}
+
+// newRedactor returns the redactor configured by MONITOR_REDACT,
+// MONITOR_REDACT_MODE (blank or hash) and MONITOR_REDACT_SALT, nil when no
+// field is redacted. Unknown fields are skipped with a warning, and hash mode
+// without a salt falls back to blanking: unsalted hashes of usernames are
+// easily reversed.
+func newRedactor() *redactor {
+	value := os.Getenv("MONITOR_REDACT")
+	if value == "" {
+		return nil
+	}
+	r := &redactor{}
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" || seen[field] {
+			continue
+		}
+		seen[field] = true
+		if _, ok := redactableFields[field]; !ok {
+			appLogger.Warn("Env var MONITOR_REDACT: ignoring %q, known fields are %s", field, strings.Join(sortedRedactableFields(), ", "))
+			continue
+		}
+		r.fields = append(r.fields, field)
+	}
+	if len(r.fields) == 0 {
+		return nil
+	}
+	sort.Strings(r.fields)
+
+	switch mode := os.Getenv("MONITOR_REDACT_MODE"); mode {
+	case "", "blank":
+	case "hash":
+		if salt := os.Getenv("MONITOR_REDACT_SALT"); salt != "" {
+			r.salt = []byte(salt)
+		} else {
+			appLogger.Warn("MONITOR_REDACT_MODE=hash needs MONITOR_REDACT_SALT. Blanking the redacted fields instead")
+		}
+	default:
+		appLogger.Warn("Env var MONITOR_REDACT_MODE must be blank or hash, got %q. Using blank", mode)
+	}
+	return r
+}
+
+// apply redacts the fields in stats. A nil redactor leaves stats unchanged.
+func (r *redactor) apply(stats *AllHostStats) {
+	if r == nil {
+		return
+	}
+	for _, field := range r.fields {
+		redactableFields[field](stats, r.value)
+	}
+}
+
+// value is the redacted form of one value: "" when blanking, otherwise the
+// first hashedValueLength hex digits of its HMAC. Empty values stay empty.
+func (r *redactor) value(v string) string {
+	if r.salt == nil || v == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(v))
+	return hex.EncodeToString(mac.Sum(nil))[:hashedValueLength]
+}
+
+// describe names the redacted fields and the mode, for the startup log.
+func (r *redactor) describe() string {
+	mode := "blanked"
+	if r.salt != nil {
+		mode = "hashed"
+	}
+	return strings.Join(r.fields, ", ") + " " + mode
+}
+
+func sortedRedactableFields() []string {
+	fields := make([]string, 0, len(redactableFields))
+	for field := range redactableFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
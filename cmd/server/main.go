@@ -3,17 +3,25 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/alerting"
 	apiHandlers "github.com/4Noyis/system-stats-monitoring/internal/server/api"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/auth"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/database"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/hostrole"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/tracker"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -22,35 +30,103 @@ import (
 // For incoming statistics data
 
 func main() {
+	configPath := flag.String("config", os.Getenv("SERVER_CONFIG_FILE"), "YAML config file, environment variables override its values")
+	allowIncomplete := flag.Bool("allow-incomplete-config", false, "start even if settings are missing or invalid and InfluxDB is unreachable")
+	flag.Parse()
+
+	// "server config example" prints a documented config file
+	if args := flag.Args(); len(args) > 0 {
+		if len(args) == 2 && args[0] == "config" && args[1] == "example" {
+			fmt.Print(config.ExampleYAML())
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Unknown command %q (available: config example)\n", strings.Join(args, " "))
+		os.Exit(2)
+	}
+
 	// -------- load config ---------
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configPath)
+	if err != nil && (cfg == nil || !*allowIncomplete) {
+		// Use fmt here as logger might not be fully up
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n  %s\n", strings.ReplaceAll(err.Error(), "\n", "\n  "))
+		os.Exit(1)
+	}
+	if err != nil {
+		for _, problem := range strings.Split(err.Error(), "\n") {
+			appLogger.Warn("Ignoring invalid configuration (--allow-incomplete-config): %s", problem)
+		}
+	}
+	cfg.InfluxDB.AllowUnavailable = *allowIncomplete
+
+	// Everything that opens resources runs in run, so its deferred cleanup
+	// (InfluxDB clients, metadata store) happens before the process exits.
+	err = run(cfg, *configPath)
+	if err != nil {
+		appLogger.Error("Server exiting: %v", err)
+	}
+	appLogger.CloseHooks(5 * time.Second) // deliver queued syslog/forwarded entries
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err) // Use fmt here as logger might not be fully up
 		os.Exit(1)
 	}
+}
 
+// run starts the server with a loaded config and serves until SIGINT/SIGTERM.
+// Errors are returned rather than logged with Fatal so deferred closes run.
+func run(cfg *config.ServerConfig, configPath string) error {
 	// --------- initialize logger ----------
+	applyLogSettings(cfg)
 	if cfg.EnableDebugLog {
-		appLogger.SetDebug(true)
 		appLogger.Info("Debug logging enabled")
 	}
 	appLogger.Info("Server configuration loaded.")
 	appLogger.Debug("Full configuration: %+v", cfg)
 
-	// --------- initialize influxDB writer ------------
-	dbWriter, err := database.NewInfluxDBWriter(cfg.InfluxDB)
+	// --------- initialize influxDB writer and reader ------------
+	influxClient, err := database.NewClient(cfg.InfluxDB)
 	if err != nil {
-		appLogger.Fatal("Gailed to initialize InfluxDB writer: %v", err)
+		return fmt.Errorf("failed to connect to InfluxDB: %w", err)
 	}
-	defer dbWriter.Close() // ensure client is closed on exit
-	appLogger.Info("InfluxDB writer initialized.")
+	defer influxClient.Close() // one client for the writer and the reader
+	dbWriter := database.NewInfluxDBWriter(influxClient, cfg.InfluxDB)
+	dbReader := database.NewInfluxDBReader(influxClient, cfg.InfluxDB)
+	appLogger.Info("InfluxDB writer and reader initialized.")
 
-	dbReader, err := database.NewInfluxDBReader(cfg.InfluxDB) // <-- INITIALIZE READER
+	// --------- open metadata store (groups etc.) ------------
+	metaStore, err := metadata.Open(cfg.MetadataDBPath)
 	if err != nil {
-		appLogger.Fatal("Failed to initialize InfluxDB reader: %v", err)
+		return fmt.Errorf("failed to open metadata store: %w", err)
 	}
-	defer dbReader.Close() // Ensure client is closed on exit
-	appLogger.Info("InfluxDB reader initialized.")
+	defer metaStore.Close()
+
+	// Background work (host presence tracking, ...) stops when main returns
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+
+	hostTracker := tracker.NewHostTracker(metaStore, cfg.HostOfflineAfter)
+	go hostTracker.Run(bgCtx, cfg.HostTrackerInterval)
+	go dbReader.RunRollingAverages(bgCtx, cfg.InfluxDB.OverviewAverageRefresh)
+
+	// --------- alert notification targets ------------
+	alertNotifier, err := alerting.NewNotifier(cfg.Alerting)
+	if err != nil {
+		return fmt.Errorf("invalid alert notifier configuration: %w", err)
+	}
+	if alertNotifier != nil {
+		appLogger.Info("Alert notifications will be sent via %s.", alertNotifier.Name())
+	} else {
+		appLogger.Info("No alert notifiers configured (ALERT_NOTIFIERS), alerts are only logged.")
+	}
+
+	alertEngine, err := alerting.NewEngine(metaStore, dbReader, hostTracker, alertNotifier, cfg.Alerting)
+	if err != nil {
+		return fmt.Errorf("failed to initialize alerting: %w", err)
+	}
+	go alertEngine.Run(bgCtx, cfg.Alerting.EvaluationInterval)
+
+	statsAPIHandler := apiHandlers.NewStatsHandler(dbWriter, dbReader, hostTracker, hostrole.NewClassifier(cfg.HostRoleRules, metaStore), cfg.ClockDriftThreshold, cfg.HostIDIncludeHostname, cfg.Batch)
+
+	// Settings SIGHUP can change without a restart
+	live := newLiveConfig(configPath, cfg, alertEngine, dbReader, statsAPIHandler)
 
 	// ------- Initialize Gin ------------
 	if !cfg.EnableDebugLog {
@@ -62,30 +138,60 @@ func main() {
 	}
 
 	router := gin.New() // Using gin.New() for more control over middleware
+	// Route on the raw path so URL-encoded slashes (disk mountpoints like %2Fvar%2Flib) stay inside one parameter
+	router.UseRawPath = true
+	if err := configureTrustedProxies(router, cfg.TrustedProxies); err != nil {
+		return err
+	}
 
 	// Middleware
 	// Apply CORS middleware FIRST or early in the middleware chain
 	// This is a common permissive configuration for development
 	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{"http://localhost:5173"} // Your Vite frontend origin
-	// You can also use "*" to allow all origins for quick testing, but be specific for production
-	// corsConfig.AllowOrigins = []string{"*"}
+	// Your Vite frontend origin (CORS_ALLOW_ORIGINS), checked against the live list so SIGHUP can change it.
+	// "*" allows all origins for quick testing, but be specific for production
+	corsConfig.AllowOriginFunc = live.allowOrigin
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"}
+	corsConfig.ExposeHeaders = []string{"X-Request-ID"}
 	// corsConfig.AllowCredentials = true // If you need to send cookies or use auth headers that require this
 
 	router.Use(cors.New(corsConfig)) // <--- USE THE CORS MIDDLEWARE WITH YOUR CONFIG
 
-	router.Use(gin.Recovery())        // Recover from any panics and return a 500
-	router.Use(ginLoggerMiddleware()) // Your custom logger middleware
+	var inFlight atomic.Int64
+	router.Use(countInFlight(&inFlight)) // reported at shutdown
+	router.Use(apiHandlers.RequestID())  // request_id log field, X-Request-ID header
+	router.Use(gin.Recovery())           // Recover from any panics and return a 500
+	router.Use(ginLoggerMiddleware())    // Your custom logger middleware
 	appLogger.Info("Gin engine initialized with CORS, Recovery, and Logger middleware.")
 
 	// ------ Setup API Handlers and Routes -------
-	statsAPIHandler := apiHandlers.NewStatsHandler(dbWriter)
-	statsAPIHandler.RegisterRoutes(router)
+	readiness := apiHandlers.NewReadiness()
+	go readiness.Run(bgCtx, dbWriter.CheckWrite, cfg.ReadinessRetry)
+	statsAPIHandler.RegisterRoutes(router, readiness)
 
-	dashboardAPIHandler := apiHandlers.NewDashboardHandler(dbReader)
-	dashboardAPIHandler.RegisterDashboardRoutes(router)
+	// Dashboard authentication (ingest is not affected)
+	var dashboardGuards apiHandlers.RouteGuards
+	if cfg.Auth.Enabled {
+		authenticator, err := auth.NewAuthenticator(cfg.Auth)
+		if err != nil {
+			return fmt.Errorf("failed to initialize authentication: %w", err)
+		}
+		apiHandlers.NewAuthHandler(authenticator).RegisterAuthRoutes(router)
+		dashboardGuards.Read = []gin.HandlerFunc{authenticator.Middleware()}
+		dashboardGuards.Admin = []gin.HandlerFunc{auth.RequireRole(auth.RoleAdmin)}
+		appLogger.Info("Dashboard API authentication enabled (viewer: read-only, admin: read/write).")
+	} else {
+		appLogger.Warn("Dashboard API authentication is DISABLED (AUTH_ENABLED=false), anyone who can reach the server can read host data.")
+	}
+
+	dashboardAPIHandler := apiHandlers.NewDashboardHandler(dbReader, metaStore, hostTracker, cfg.HostOfflineAfter)
+	dashboardAPIHandler.RegisterDashboardRoutes(router, dashboardGuards, cfg.Timeouts)
+	if cfg.PrometheusEnabled {
+		dashboardAPIHandler.RegisterPrometheusRoute(router, dashboardGuards, cfg.Timeouts)
+		appLogger.Info("Prometheus endpoint enabled at /api/prometheus.")
+	}
+	apiHandlers.NewConfigHandler(live.config).RegisterConfigRoutes(router, dashboardGuards)
 	appLogger.Info("API and Dashboard routes registered.")
 
 	// ------- Start http Server --------
@@ -93,16 +199,25 @@ func main() {
 		Addr:    cfg.ListenAddress,
 		Handler: router,
 
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		ReadTimeout:  cfg.HTTP.Read,
+		WriteTimeout: cfg.HTTP.Write,
+		IdleTimeout:  cfg.HTTP.Idle,
 	}
 
-	// Start server in a goroutine so that it doesn't block.
+	// Start server in a goroutine so that it doesn't block. A listen error
+	// ends the run like a shutdown signal, so cleanup still happens.
+	serveErr := make(chan error, 1)
 	go func() {
-		appLogger.Info("Starting server on %s", cfg.ListenAddress)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			appLogger.Fatal("Could not listen on %s: %v\n", cfg.ListenAddress, err)
+		var err error
+		if cfg.TLS.CertFile != "" {
+			appLogger.Info("Starting server on %s (HTTPS)", cfg.ListenAddress)
+			err = srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			appLogger.Info("Starting server on %s", cfg.ListenAddress)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("could not listen on %s: %w", cfg.ListenAddress, err)
 		}
 	}()
 
@@ -112,19 +227,82 @@ func main() {
 	// kill -2 is syscall.SIGINT
 	// kill -9 is syscall.SIGKILL but can't be caught, so don't add it
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	receivedSignal := <-quit
-	appLogger.Info("Shutdown signal (%s) received. Shutting down server gracefully...", receivedSignal)
 
-	// The context is used to inform the server it has 5 seconds to finish
-	// the requests it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// kill -HUP re-reads the configuration
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			appLogger.Info("SIGHUP received, reloading configuration...")
+			live.reload()
+		}
+	}()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		appLogger.Fatal("Server forced to shutdown: %v", err)
+	select {
+	case receivedSignal := <-quit:
+		appLogger.Info("Shutdown signal (%s) received. Shutting down server gracefully...", receivedSignal)
+	case err := <-serveErr:
+		return err
 	}
 
+	// Writes to InfluxDB are blocking, so a finished ingest request is stored
+	// and there is no write buffer to flush afterwards.
+	shutdownServer(srv, cfg.HTTP.Shutdown, &inFlight)
+	bgCancel()
+
+	appLogger.Info("InfluxDB client reconnects during this run: %d", influxClient.Reconnects())
+	if n := hostTracker.Collisions(); n > 0 {
+		appLogger.Warn("Host ID collisions detected during this run: %d", n)
+	}
 	appLogger.Info("Server exiting.")
+	return nil
+}
+
+// configureTrustedProxies sets which proxies' forwarding headers ClientIP()
+// believes. Behind a load balancer list its addresses, otherwise every
+// request is attributed to the load balancer or spoofable via X-Forwarded-For.
+func configureTrustedProxies(router *gin.Engine, proxies []string) error {
+	switch {
+	case len(proxies) == 1 && proxies[0] == "none":
+		if err := router.SetTrustedProxies(nil); err != nil {
+			return fmt.Errorf("failed to disable trusted proxies: %w", err)
+		}
+		appLogger.Info("Trusted proxies: none, client IPs are the connection's remote address (X-Forwarded-For is ignored).")
+	case len(proxies) > 0:
+		if err := router.SetTrustedProxies(proxies); err != nil {
+			return fmt.Errorf("invalid SERVER_TRUSTED_PROXIES: %w", err)
+		}
+		appLogger.Info("Trusted proxies: %s, client IPs are taken from X-Forwarded-For / X-Real-IP only for requests coming from them.", strings.Join(proxies, ", "))
+	default:
+		appLogger.Warn("SERVER_TRUSTED_PROXIES is not set, X-Forwarded-For is trusted from every client. Set it to your load balancer's addresses, or \"none\".")
+	}
+	return nil
+}
+
+// shutdownServer gives srv timeout (SERVER_SHUTDOWN_TIMEOUT) to finish the
+// requests it is currently handling, then closes the connections of those
+// still in flight. It returns the Shutdown error, nil if all requests finished.
+func shutdownServer(srv *http.Server, timeout time.Duration, inFlight *atomic.Int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		appLogger.Error("Graceful shutdown did not finish within %s (%v), %d requests still in flight are aborted.", timeout, err, inFlight.Load())
+		_ = srv.Close()
+	} else {
+		appLogger.Info("HTTP server stopped, %d requests in flight.", inFlight.Load())
+	}
+	return err
+}
+
+// countInFlight keeps n at the number of requests being handled.
+func countInFlight(n *atomic.Int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n.Add(1)
+		defer n.Add(-1)
+		c.Next()
+	}
 }
 
 func ginLoggerMiddleware() gin.HandlerFunc {
@@ -140,20 +318,20 @@ func ginLoggerMiddleware() gin.HandlerFunc {
 		// userAgent := c.Request.UserAgent() // Optional
 		// errors := c.Errors.ByType(gin.ErrorTypePrivate).String() // Optional for logging Gin errors
 
-		logFunc := appLogger.Info // Default to Info
+		entry := appLogger.FromContext(c.Request.Context()).With( // after request_id, host_id
+			"status", status,
+			"latency", latency,
+			"client_ip", clientIP,
+			"method", method,
+			"path", path,
+		)
+		logFunc := entry.Info // Default to Info
 		if status >= 400 && status < 500 {
-			logFunc = appLogger.Warn
+			logFunc = entry.Warn
 		} else if status >= 500 {
-			logFunc = appLogger.Error
+			logFunc = entry.Error
 		}
-
-		logFunc("GIN | %3d | %13v | %15s | %-7s %s",
-			status,
-			latency,
-			clientIP,
-			method,
-			path,
-		)
+		logFunc("GIN request")
 		// if errors != "" {
 		//  appLogger.Error("GIN ERRORS | %s", errors)
 		// }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+func TestConfigureTrustedProxies(t *testing.T) {
+	const client, balancer, outsider = "203.0.113.7", "10.0.4.2", "198.51.100.9"
+	tests := []struct {
+		name    string
+		proxies []string
+		from    string // the connection's remote address
+		xff     string
+		want    string
+	}{
+		{"unset trusts every client", nil, outsider, client, client},
+		{"none ignores the header", []string{"none"}, balancer, client, balancer},
+		{"request through a trusted proxy", []string{"10.0.0.0/8"}, balancer, client, client},
+		{"spoofed header from an untrusted client", []string{"10.0.0.0/8"}, outsider, client, outsider},
+		{"chain through two trusted proxies", []string{"10.0.0.0/8"}, balancer, client + ", 10.0.9.9", client},
+		{"single proxy address", []string{"10.0.4.2"}, balancer, client, client},
+		{"no header behind a trusted proxy", []string{"10.0.0.0/8"}, balancer, "", balancer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			if err := configureTrustedProxies(router, tt.proxies); err != nil {
+				t.Fatalf("configureTrustedProxies(%q): %v", tt.proxies, err)
+			}
+			router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.from + ":41234"
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if err := configureTrustedProxies(gin.New(), []string{"10.0.0.0/33"}); err == nil {
+		t.Error("configureTrustedProxies accepted an invalid CIDR")
+	}
+}
+
+func TestShutdownServerHonorsTheTimeout(t *testing.T) {
+	// serve starts a server whose only handler takes handlerTime, and sends
+	// it a request; it returns once the request is being handled.
+	serve := func(t *testing.T, handlerTime time.Duration) (*http.Server, *atomic.Int64, <-chan error) {
+		t.Helper()
+		var inFlight atomic.Int64
+		started := make(chan struct{})
+		router := gin.New()
+		router.Use(countInFlight(&inFlight))
+		router.POST("/api/stats", func(c *gin.Context) {
+			close(started)
+			select {
+			case <-time.After(handlerTime):
+				c.Status(http.StatusOK)
+			case <-c.Request.Context().Done(): // the connection was closed
+			}
+		})
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		srv := &http.Server{Handler: router}
+		go srv.Serve(listener)
+		t.Cleanup(func() { srv.Close() })
+
+		response := make(chan error, 1)
+		go func() {
+			resp, err := http.Post("http://"+listener.Addr().String()+"/api/stats", "application/json", strings.NewReader("{}"))
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					err = fmt.Errorf("status %d", resp.StatusCode)
+				}
+			}
+			response <- err
+		}()
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the request never reached the handler")
+		}
+		return srv, &inFlight, response
+	}
+
+	t.Run("slow handler is aborted at the timeout", func(t *testing.T) {
+		logs := captureLog(t)
+		srv, inFlight, response := serve(t, time.Minute)
+
+		start := time.Now()
+		err := shutdownServer(srv, 200*time.Millisecond, inFlight)
+		elapsed := time.Since(start)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("shutdownServer = %v, want the deadline exceeded", err)
+		}
+		if elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+			t.Errorf("shutdown took %v, want the 200ms timeout", elapsed)
+		}
+		if err := <-response; err == nil {
+			t.Error("the aborted request got a response")
+		}
+		if !strings.Contains(logs.String(), "1 requests still in flight are aborted") {
+			t.Errorf("log lacks the aborted request count:\n%s", logs)
+		}
+	})
+
+	t.Run("handler finishing within the timeout", func(t *testing.T) {
+		logs := captureLog(t)
+		srv, inFlight, response := serve(t, 300*time.Millisecond)
+
+		if err := shutdownServer(srv, 5*time.Second, inFlight); err != nil {
+			t.Errorf("shutdownServer = %v, want the request drained", err)
+		}
+		if err := <-response; err != nil {
+			t.Errorf("drained request: %v", err)
+		}
+		if !strings.Contains(logs.String(), "HTTP server stopped, 0 requests in flight") {
+			t.Errorf("log lacks the drained shutdown:\n%s", logs)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/alerting"
+	apiHandlers "github.com/4Noyis/system-stats-monitoring/internal/server/api"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database"
+)
+
+// liveConfig applies the hot-reloadable part of the configuration on SIGHUP:
+// log level and format, CORS origins, status thresholds, batch ingest limits
+// and the alert notification targets (webhook, Slack, email). Everything else
+// is only compared and reported as requiring a restart. Alert rules are not
+// part of the config, they live in the metadata store and API changes apply
+// right away.
+type liveConfig struct {
+	configPath string
+	engine     *alerting.Engine
+	reader     *database.InfluxDBReader  // status thresholds
+	ingest     *apiHandlers.StatsHandler // batch ingest limits
+
+	mu          sync.Mutex           // serializes reloads
+	current     *config.ServerConfig // what the server is running with
+	corsOrigins atomic.Pointer[[]string]
+}
+
+func newLiveConfig(configPath string, cfg *config.ServerConfig, engine *alerting.Engine, reader *database.InfluxDBReader, ingest *apiHandlers.StatsHandler) *liveConfig {
+	live := &liveConfig{configPath: configPath, engine: engine, reader: reader, ingest: ingest, current: cfg}
+	live.corsOrigins.Store(&cfg.CORSOrigins)
+	return live
+}
+
+// config returns the configuration currently applied.
+func (l *liveConfig) config() *config.ServerConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
+
+// allowOrigin is the CORS origin check, reading the current origin list.
+func (l *liveConfig) allowOrigin(origin string) bool {
+	for _, allowed := range *l.corsOrigins.Load() {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// reload re-reads the environment and config file. Everything that can fail
+// (loading, validation, building notifiers) happens before anything is
+// applied, so a reload either applies completely or not at all.
+func (l *liveConfig) reload() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next, err := config.Load(l.configPath)
+	if err != nil && next != nil && l.current.InfluxDB.AllowUnavailable {
+		appLogger.Warn("Config reload with invalid settings (--allow-incomplete-config):\n  %s", strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	} else if err != nil {
+		appLogger.Error("Config reload failed, keeping the current configuration:\n  %s", strings.ReplaceAll(err.Error(), "\n", "\n  "))
+		return
+	}
+	next.InfluxDB.AllowUnavailable = l.current.InfluxDB.AllowUnavailable // --allow-incomplete-config, not part of the file
+	notifier, err := alerting.NewNotifier(next.Alerting)
+	if err != nil {
+		appLogger.Error("Config reload failed, keeping the current configuration: invalid alert notifier configuration: %v", err)
+		return
+	}
+
+	for _, setting := range restartRequired(l.current, next) {
+		appLogger.Warn("Config reload: %s changed, requires restart (keeping the running value).", setting)
+	}
+
+	// Nothing below can fail
+	applied := *l.current
+	applied.EnableDebugLog = next.EnableDebugLog
+	applied.LogFormat = next.LogFormat
+	applied.LogLevel = next.LogLevel
+	applied.CORSOrigins = next.CORSOrigins
+	applied.InfluxDB.Status = next.InfluxDB.Status
+	applied.Batch = next.Batch
+	notifications := next.Alerting
+	notifications.EvaluationInterval = applied.Alerting.EvaluationInterval
+	notifications.HostDown = applied.Alerting.HostDown
+	notifications.NotifyTimeout = applied.Alerting.NotifyTimeout
+	notifications.ForecastRefresh = applied.Alerting.ForecastRefresh
+	applied.Alerting = notifications
+
+	applyLogSettings(&applied)
+	l.corsOrigins.Store(&applied.CORSOrigins)
+	l.reader.SetStatusThresholds(applied.InfluxDB.Status)
+	l.ingest.SetBatchLimits(applied.Batch)
+	if !reflect.DeepEqual(applied.Alerting, l.current.Alerting) {
+		l.engine.SetNotifier(notifier) // unchanged targets keep their notifier, e.g. Slack's rate limit state
+	}
+	l.current = &applied
+
+	status := applied.InfluxDB.Status
+	if notifier != nil {
+		appLogger.Info("Config reloaded: debug log %t, CORS origins %v, usage warning/critical %g/%g%%, disk %g/%g%%, batches up to %d items, alert notifications via %s.",
+			applied.EnableDebugLog, applied.CORSOrigins, status.UsageWarning, status.UsageCritical, status.DiskWarning, status.DiskCritical, applied.Batch.MaxItems, notifier.Name())
+	} else {
+		appLogger.Info("Config reloaded: debug log %t, CORS origins %v, usage warning/critical %g/%g%%, disk %g/%g%%, batches up to %d items, no alert notifiers.",
+			applied.EnableDebugLog, applied.CORSOrigins, status.UsageWarning, status.UsageCritical, status.DiskWarning, status.DiskCritical, applied.Batch.MaxItems)
+	}
+}
+
+// applyLogSettings sets the logger's format and level from a validated config.
+func applyLogSettings(cfg *config.ServerConfig) {
+	_ = appLogger.SetFormat(cfg.LogFormat)
+	level, _ := appLogger.ParseLevel(cfg.LogLevel)
+	appLogger.SetLevel(level)
+	if cfg.EnableDebugLog {
+		appLogger.SetDebug(true)
+	}
+}
+
+// restartRequired names the settings that differ between old and next but are
+// only read at startup.
+func restartRequired(old, next *config.ServerConfig) []string {
+	// Status thresholds are part of the InfluxDB settings, but reloadable
+	storage, nextStorage := old.InfluxDB, next.InfluxDB
+	storage.Status, nextStorage.Status = config.StatusThresholds{}, config.StatusThresholds{}
+	checks := []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"listen address", old.ListenAddress, next.ListenAddress},
+		{"TLS", old.TLS, next.TLS},
+		{"trusted proxies", old.TrustedProxies, next.TrustedProxies},
+		{"InfluxDB (storage)", storage, nextStorage},
+		{"metadata store path", old.MetadataDBPath, next.MetadataDBPath},
+		{"host offline threshold", old.HostOfflineAfter, next.HostOfflineAfter},
+		{"clock drift threshold", old.ClockDriftThreshold, next.ClockDriftThreshold},
+		{"host ID mode", old.HostIDIncludeHostname, next.HostIDIncludeHostname},
+		{"host role rules", old.HostRoleRules, next.HostRoleRules},
+		{"Prometheus endpoint", old.PrometheusEnabled, next.PrometheusEnabled},
+		{"authentication", old.Auth, next.Auth},
+		{"route timeouts", old.Timeouts, next.Timeouts},
+		{"HTTP server timeouts", old.HTTP, next.HTTP},
+		{"host tracker interval", old.HostTrackerInterval, next.HostTrackerInterval},
+		{"readiness retry", old.ReadinessRetry, next.ReadinessRetry},
+		{"alert evaluation interval", old.Alerting.EvaluationInterval, next.Alerting.EvaluationInterval},
+		{"host-down alerting", old.Alerting.HostDown, next.Alerting.HostDown},
+		{"alert notification timeout", old.Alerting.NotifyTimeout, next.Alerting.NotifyTimeout},
+		{"disk forecast refresh", old.Alerting.ForecastRefresh, next.Alerting.ForecastRefresh},
+	}
+	var changed []string
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.old, c.new) {
+			changed = append(changed, c.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/alerting"
+	apiHandlers "github.com/4Noyis/system-stats-monitoring/internal/server/api"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/tracker"
+)
+
+// baseConfig is the config file the test server starts with.
+const baseConfig = `
+cors:
+  allow_origins: ["https://dash.example.com"]
+alerting:
+  notifiers: [webhook]
+  webhook_urls: ["https://hooks.example.com/alerts"]
+`
+
+// testLiveConfig is a liveConfig on a config file the test rewrites, with a
+// reader and ingest routes on a fake InfluxDB.
+type testLiveConfig struct {
+	*liveConfig
+	engine *alerting.Engine
+	path   string
+	influx *influxtest.Server
+	ingest *gin.Engine
+}
+
+func newTestLiveConfig(t *testing.T) *testLiveConfig {
+	t.Helper()
+	t.Setenv("INFLUXDB_TOKEN", "test-token")
+	t.Setenv("INFLUXDB_ORG", "test-org")
+	t.Setenv("INFLUXDB_BUCKET", "test-bucket")
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	writeFile(t, path, baseConfig)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	notifier, err := alerting.NewNotifier(cfg.Alerting)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	store, err := metadata.Open(filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatalf("metadata.Open: %v", err)
+	}
+	t.Cleanup(store.Close)
+	engine, err := alerting.NewEngine(store, nil, nil, notifier, cfg.Alerting)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	influx := influxtest.NewServer(t)
+	influxCfg := influx.Config()
+	influxCfg.Status = cfg.InfluxDB.Status
+	client, err := database.NewClient(influxCfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+	reader := database.NewInfluxDBReader(client, influxCfg)
+	ingest := apiHandlers.NewStatsHandler(database.NewInfluxDBWriter(client, influxCfg), reader, tracker.NewHostTracker(store, cfg.HostOfflineAfter), nil,
+		cfg.ClockDriftThreshold, false, cfg.Batch)
+	now := time.Now()
+	influx.Respond(`yield(name: "overview")`, influxtest.NewTable("host_id", "hostname",
+		"cpu_usage_percent:double", "mem_usage_percent:double", "uptime_seconds:long",
+		"lag_found:boolean", "ingest_lag_seconds:double",
+		"net_upload_bytes_sec:double", "net_download_bytes_sec:double",
+		"disk_found:boolean", "disk_usage_percent:double",
+		"inodes_found:boolean", "inodes_usage_percent:double", "_time:time").
+		Row("cpu-75", "web-1", 75.0, 20.0, int64(30*24*3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now).
+		Row("cpu-85", "web-2", 85.0, 20.0, int64(30*24*3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now))
+	readiness := apiHandlers.NewReadiness()
+	readiness.Run(context.Background(), func(context.Context) error { return nil }, time.Second)
+	router := gin.New()
+	ingest.RegisterRoutes(router, readiness)
+
+	return &testLiveConfig{
+		liveConfig: newLiveConfig(path, cfg, engine, reader, ingest),
+		engine:     engine,
+		path:       path,
+		influx:     influx,
+		ingest:     router,
+	}
+}
+
+// statuses are the overview statuses of the fake InfluxDB's hosts by ID:
+// cpu-75 and cpu-85, online at 75% and 85% CPU.
+func (l *testLiveConfig) statuses(t *testing.T) map[string]string {
+	t.Helper()
+	hosts, err := l.reader.GetHostOverviewList(context.Background())
+	if err != nil {
+		t.Fatalf("GetHostOverviewList: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, host := range hosts {
+		statuses[host.ID] = host.Status
+	}
+	return statuses
+}
+
+// postBatch posts n payloads to the batch route and returns the status code.
+func (l *testLiveConfig) postBatch(t *testing.T, n int) int {
+	t.Helper()
+	batch := make([]models.ClientPayload, n)
+	for i := range batch {
+		batch[i] = models.ClientPayload{
+			CollectedAt: time.Now(),
+			System:      models.SystemInfoPayload{HostID: fmt.Sprintf("host-%d", i), Hostname: "web", OS: "linux"},
+			CPU:         models.CPUInfoPayload{Cores: 4, Usage: 12.5},
+			Memory:      models.MemInfoPayload{TotalGB: 16, FreeGB: 8, UsagePercent: 50},
+		}
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	l.ingest.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/stats/batch", bytes.NewReader(body)))
+	return w.Code
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// syncBuffer collects log output.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func captureLog(t *testing.T) *syncBuffer {
+	t.Helper()
+	buf := &syncBuffer{}
+	appLogger.SetOutput(buf, true)
+	t.Cleanup(func() { appLogger.SetOutput(&bytes.Buffer{}, true) })
+	return buf
+}
+
+func TestReloadRollsBackAsAWhole(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		logged  []string
+		running func(*config.ServerConfig) string // a restart-only value that must stay, "" if none
+	}{
+		{
+			name: "config.Load fails",
+			file: `
+log_format: xml
+status:
+  usage_warning: 70
+batch:
+  max_items: 2
+cors:
+  allow_origins: ["https://new.example.com"]
+alerting:
+  notifiers: [webhook]
+  webhook_urls: ["https://hooks.example.com/other"]
+`,
+			logged: []string{"Config reload failed", "LOG_FORMAT"},
+		},
+		{
+			name: "NewNotifier fails after a valid load",
+			file: `
+status:
+  usage_warning: 70
+batch:
+  max_items: 2
+cors:
+  allow_origins: ["https://new.example.com"]
+alerting:
+  notifiers: [slack]
+`,
+			logged: []string{"Config reload failed", "slack notifier needs"},
+		},
+		{
+			name: "restart-only settings change",
+			file: `
+listen_address: ":9999"
+` + baseConfig,
+			logged: []string{"listen address changed, requires restart"},
+			running: func(cfg *config.ServerConfig) string {
+				if cfg.ListenAddress != ":8080" {
+					return "listen address changed to " + cfg.ListenAddress
+				}
+				return ""
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := newTestLiveConfig(t)
+			before := *live.config()
+			notifier := live.engine.Notifier()
+			logs := captureLog(t)
+
+			writeFile(t, live.path, tt.file)
+			live.reload()
+
+			if after := live.config(); after.String() != before.String() {
+				t.Errorf("running config changed:\nbefore %s\nafter  %s", before.String(), after.String())
+			}
+			if !live.allowOrigin("https://dash.example.com") || live.allowOrigin("https://new.example.com") {
+				t.Error("CORS origins changed, want the running https://dash.example.com only")
+			}
+			if live.engine.Notifier() != notifier {
+				t.Errorf("engine notifier replaced by %v", live.engine.Notifier())
+			}
+			if statuses := live.statuses(t); statuses["cpu-75"] != "online" || statuses["cpu-85"] != "warning" {
+				t.Errorf("statuses %v, want cpu-75 online and cpu-85 warning under the running 85/95%% thresholds", statuses)
+			}
+			if code := live.postBatch(t, 3); code == http.StatusRequestEntityTooLarge {
+				t.Error("batch of 3 refused, want the running limit of 1000 payloads")
+			}
+			if tt.running != nil {
+				if problem := tt.running(live.config()); problem != "" {
+					t.Error(problem)
+				}
+			}
+			for _, want := range tt.logged {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("log lacks %q:\n%s", want, logs)
+				}
+			}
+		})
+	}
+}
+
+func TestReloadAppliesReloadableSettings(t *testing.T) {
+	live := newTestLiveConfig(t)
+	notifier := live.engine.Notifier()
+	if statuses := live.statuses(t); statuses["cpu-75"] != "online" || statuses["cpu-85"] != "warning" {
+		t.Fatalf("statuses before the reload %v, want cpu-75 online and cpu-85 warning", statuses)
+	}
+
+	writeFile(t, live.path, `
+status:
+  usage_warning: 70
+  usage_critical: 80
+batch:
+  max_items: 2
+cors:
+  allow_origins: ["https://new.example.com"]
+alerting:
+  notifiers: [webhook]
+  webhook_urls: ["https://hooks.example.com/other"]
+`)
+	live.reload()
+
+	if !live.allowOrigin("https://new.example.com") || live.allowOrigin("https://dash.example.com") {
+		t.Error("CORS origins not replaced by https://new.example.com")
+	}
+	if live.engine.Notifier() == notifier || live.engine.Notifier() == nil {
+		t.Error("engine notifier not replaced for the new webhook URL")
+	}
+	if urls := live.config().Alerting.WebhookURLs; len(urls) != 1 || urls[0] != "https://hooks.example.com/other" {
+		t.Errorf("running webhook URLs %v, want the reloaded one", urls)
+	}
+	if statuses := live.statuses(t); statuses["cpu-75"] != "warning" || statuses["cpu-85"] != "critical" {
+		t.Errorf("statuses after the reload %v, want cpu-75 warning and cpu-85 critical under the new 70/80%% thresholds", statuses)
+	}
+	if code := live.postBatch(t, 3); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("batch of 3: status %d, want 413 over the new limit of 2 payloads", code)
+	}
+	if code := live.postBatch(t, 2); code == http.StatusRequestEntityTooLarge {
+		t.Error("batch of 2 refused, want it accepted at the new limit")
+	}
+}
+
+// Run with -race: the reload switches the format while other goroutines log.
+func TestReloadLogFormatWhileLogging(t *testing.T) {
+	live := newTestLiveConfig(t)
+	logs := &syncBuffer{}
+	appLogger.SetOutput(logs, false)
+	t.Cleanup(func() {
+		_ = appLogger.SetFormat("text")
+		appLogger.SetOutput(&bytes.Buffer{}, true)
+	})
+
+	stop := make(chan struct{})
+	var wg, started sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				appLogger.InfoKV("Still logging", "worker", i, "n", n)
+				if n == 0 {
+					started.Done()
+				}
+			}
+		}(i)
+	}
+	started.Wait()
+	writeFile(t, live.path, "log_format: json\n"+baseConfig)
+	live.reload()
+	close(stop)
+	wg.Wait()
+
+	appLogger.Info("After the reload")
+	lines := strings.Split(strings.TrimSuffix(logs.String(), "\n"), "\n")
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if !strings.HasPrefix(line, "INFO: ") && !strings.HasPrefix(line, "WARN: ") && json.Unmarshal([]byte(line), &entry) != nil {
+			t.Errorf("line neither text nor JSON: %q", line)
+		}
+	}
+	var last struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil || last.Message != "After the reload" {
+		t.Errorf("last line %q, want a JSON entry after the reload", lines[len(lines)-1])
+	}
+}
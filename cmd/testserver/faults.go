@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Fault names as they appear in the per-request log line.
+const (
+	faultNone     = "none"
+	faultError    = "error"    // 500
+	faultThrottle = "throttle" // 429 with Retry-After
+	faultDrop     = "drop"     // connection closed halfway through the response body
+)
+
+// faults configures the failures injected into ingest responses, to exercise
+// the agent's retries and backoff without a proxy like toxiproxy.
+type faults struct {
+	errorRate    float64
+	throttleRate float64
+	retryAfter   time.Duration
+	dropRate     float64
+	latency      time.Duration
+	jitter       time.Duration
+}
+
+func (f faults) validate() error {
+	for name, rate := range map[string]float64{"--error-rate": f.errorRate, "--throttle-rate": f.throttleRate, "--drop-rate": f.dropRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1, got %g", name, rate)
+		}
+	}
+	if sum := f.errorRate + f.throttleRate + f.dropRate; sum > 1 {
+		return fmt.Errorf("--error-rate, --throttle-rate and --drop-rate add up to %g, more than 1", sum)
+	}
+	if f.latency < 0 || f.jitter < 0 || f.retryAfter < 0 {
+		return fmt.Errorf("--latency, --latency-jitter and --retry-after must not be negative")
+	}
+	return nil
+}
+
+// pick draws the fault for one request; the rates are exclusive.
+func (f faults) pick() string {
+	x := rand.Float64()
+	switch {
+	case x < f.dropRate:
+		return faultDrop
+	case x < f.dropRate+f.throttleRate:
+		return faultThrottle
+	case x < f.dropRate+f.throttleRate+f.errorRate:
+		return faultError
+	}
+	return faultNone
+}
+
+// delay is latency plus a random jitter in [0, jitter).
+func (f faults) delay() time.Duration {
+	d := f.latency
+	if f.jitter > 0 {
+		d += rand.N(f.jitter)
+	}
+	return d
+}
+
+// respond writes the response for fault; faultNone is left to the caller.
+func (f faults) respond(w http.ResponseWriter, fault string) {
+	switch fault {
+	case faultError:
+		http.Error(w, `{"error":"injected failure","code":"injected_error"}`, http.StatusInternalServerError)
+	case faultThrottle:
+		w.Header().Set("Retry-After", strconv.Itoa(int(f.retryAfter.Seconds())))
+		http.Error(w, `{"error":"injected throttling","code":"injected_throttle"}`, http.StatusTooManyRequests)
+	case faultDrop:
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "connection can't be dropped", http.StatusInternalServerError)
+			return
+		}
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			return
+		}
+		// Promise a longer body than is sent, then hang up
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 67\r\n\r\n{\"status\":\"succ")
+		_ = buf.Flush()
+		_ = conn.Close()
+	}
+}
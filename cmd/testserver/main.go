@@ -0,0 +1,259 @@
+// Command testserver stands in for the server during agent development: it
+// accepts POST /api/stats and pretty-prints every payload. With --record it
+// also keeps each payload as a file, and --replay sends recorded payloads to
+// a real server, for load tests and for reproducing a payload that hit a bug.
+//
+//	go run ./cmd/testserver --listen :8080 --record ./payloads
+//	go run ./cmd/testserver --replay ./payloads --target http://localhost:8080/api/stats --rewrite-time
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+)
+
+// Recorded files are named after their receive time (UTC) so that a plain
+// sort replays them in order; the counter keeps same-instant payloads apart.
+const recordTimeFormat = "20060102T150405.000000000Z"
+
+const maxPayloadBytes = 10 << 20
+
+func main() {
+	listen := flag.String("listen", ":8080", "address to accept payloads on")
+	record := flag.String("record", "", "directory to write every received payload to")
+	replay := flag.String("replay", "", "directory of recorded payloads to send instead of listening")
+	target := flag.String("target", "http://localhost:8080/api/stats", "ingest URL that --replay sends to")
+	rewriteTime := flag.Bool("rewrite-time", false, "with --replay, set collected_at to now minus its original offset from the receive time")
+	realtime := flag.Bool("realtime", false, "with --replay, wait the original time between payloads instead of sending them back to back")
+
+	var inject faults
+	flag.Float64Var(&inject.errorRate, "error-rate", 0, "fraction of requests answered with 500, e.g. 0.2")
+	flag.Float64Var(&inject.throttleRate, "throttle-rate", 0, "fraction of requests answered with 429 and Retry-After")
+	flag.DurationVar(&inject.retryAfter, "retry-after", 5*time.Second, "Retry-After sent with injected 429s")
+	flag.Float64Var(&inject.dropRate, "drop-rate", 0, "fraction of requests whose connection is closed halfway through the response body")
+	flag.DurationVar(&inject.latency, "latency", 0, "delay before every response")
+	flag.DurationVar(&inject.jitter, "latency-jitter", 0, "random extra delay up to this, on top of --latency")
+	flag.Parse()
+	if err := inject.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	if *replay != "" {
+		err = replayDir(ctx, *replay, *target, *rewriteTime, *realtime)
+	} else {
+		err = serve(ctx, *listen, *record, inject)
+	}
+	if err != nil {
+		appLogger.Error("%v", err)
+		os.Exit(1)
+	}
+}
+
+// serve accepts payloads until ctx is done, injecting the configured faults.
+// Every request gets one log line naming its fault (none, error, throttle,
+// drop) and delay.
+func serve(ctx context.Context, listen, recordDir string, inject faults) error {
+	if recordDir != "" {
+		if err := os.MkdirAll(recordDir, 0o755); err != nil {
+			return fmt.Errorf("create record directory: %w", err)
+		}
+	}
+	var received atomic.Int64
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST only", http.StatusMethodNotAllowed)
+			return
+		}
+		receivedAt := time.Now().UTC()
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadBytes))
+		if err != nil {
+			http.Error(w, "read body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "", "  "); err != nil {
+			appLogger.Warn("Invalid JSON from %s: %v", r.RemoteAddr, err)
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		n := received.Add(1)
+		fmt.Printf("--- payload %d from %s at %s ---\n%s\n", n, r.RemoteAddr, receivedAt.Format(time.RFC3339Nano), pretty.String())
+
+		fault, delay := inject.pick(), inject.delay()
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done(): // the agent gave up waiting
+		}
+		appLogger.InfoKV("Handled payload", "payload", n, "fault", fault, "delay", delay, "client", r.RemoteAddr)
+		if fault != faultNone {
+			inject.respond(w, fault)
+			return
+		}
+
+		if recordDir != "" {
+			name := filepath.Join(recordDir, fmt.Sprintf("%s-%06d.json", receivedAt.Format(recordTimeFormat), n))
+			if err := os.WriteFile(name, body, 0o644); err != nil { // exactly as received
+				appLogger.Error("Failed to record payload %d: %v", n, err)
+			} else {
+				appLogger.Info("Recorded payload %d to %s", n, name)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","message":"Statistics received and processed"}`))
+	})
+
+	srv := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	appLogger.Info("Test server listening on %s (POST /api/stats)", listen)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("listen on %s: %w", listen, err)
+	}
+	appLogger.Info("Test server stopped after %d payloads.", received.Load())
+	return nil
+}
+
+// recording is one recorded payload file.
+type recording struct {
+	path       string
+	receivedAt time.Time
+}
+
+// replayDir POSTs every recorded payload in dir to target, oldest first.
+func replayDir(ctx context.Context, dir, target string, rewriteTime, realtime bool) error {
+	recordings, err := readRecordings(dir)
+	if err != nil {
+		return err
+	}
+	if len(recordings) == 0 {
+		return fmt.Errorf("no recorded payloads in %s", dir)
+	}
+	appLogger.Info("Replaying %d payloads from %s to %s", len(recordings), dir, target)
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	sent, failed := 0, 0
+	for i, rec := range recordings {
+		if realtime && i > 0 {
+			select {
+			case <-time.After(rec.receivedAt.Sub(recordings[i-1].receivedAt)):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		body, err := os.ReadFile(rec.path)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", rec.path, err)
+		}
+		if rewriteTime {
+			if rewritten, err := rewriteCollectedAt(body, rec.receivedAt, time.Now()); err != nil {
+				appLogger.Warn("Sending %s unchanged: %v", rec.path, err)
+			} else {
+				body = rewritten
+			}
+		}
+		if err := post(ctx, client, target, body); err != nil {
+			appLogger.Error("Replaying %s failed: %v", filepath.Base(rec.path), err)
+			failed++
+			continue
+		}
+		sent++
+	}
+	appLogger.Info("Replay finished: %d sent, %d failed, %d skipped.", sent, failed, len(recordings)-sent-failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d payloads were not accepted", failed, len(recordings))
+	}
+	return nil
+}
+
+// readRecordings lists dir's recorded payloads ordered by receive time.
+func readRecordings(dir string) ([]recording, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read replay directory: %w", err)
+	}
+	var recordings []recording
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		stamp, _, _ := strings.Cut(name, "-")
+		receivedAt, err := time.Parse(recordTimeFormat, stamp)
+		if err != nil {
+			appLogger.Warn("Skipping %s: not named like a recording (%v)", name, err)
+			continue
+		}
+		recordings = append(recordings, recording{path: filepath.Join(dir, name), receivedAt: receivedAt})
+	}
+	sort.SliceStable(recordings, func(i, j int) bool {
+		return recordings[i].receivedAt.Before(recordings[j].receivedAt)
+	})
+	return recordings, nil
+}
+
+// rewriteCollectedAt sets collected_at to now minus its original offset from
+// receivedAt, keeping the agent's clock drift and send delay. Other fields
+// are passed through untouched.
+func rewriteCollectedAt(body []byte, receivedAt, now time.Time) ([]byte, error) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %w", err)
+	}
+	var collectedAt time.Time
+	if err := json.Unmarshal(payload["collected_at"], &collectedAt); err != nil {
+		return nil, fmt.Errorf("no usable collected_at: %w", err)
+	}
+	rewritten, err := json.Marshal(now.Add(-receivedAt.Sub(collectedAt)).UTC())
+	if err != nil {
+		return nil, err
+	}
+	payload["collected_at"] = rewritten
+	return json.Marshal(payload)
+}
+
+func post(ctx context.Context, client *http.Client, target string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("server responded with %s: %s", resp.Status, strings.TrimSpace(string(responseBody)))
+	}
+	return nil
+}
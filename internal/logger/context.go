@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx that carries kv (alternating keys and
+// values) in addition to the fields ctx already has. Lines logged through
+// FromContext(ctx) include them.
+func WithFields(ctx context.Context, kv ...interface{}) context.Context {
+	existing, _ := ctx.Value(fieldsKey{}).([]interface{})
+	fields := make([]interface{}, 0, len(existing)+len(kv))
+	fields = append(append(fields, existing...), kv...)
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Entry logs with a fixed set of fields. The zero Entry has none and logs
+// like the package functions.
+type Entry struct {
+	fields []interface{}
+}
+
+// FromContext returns an Entry with the fields attached to ctx by WithFields,
+// e.g. request_id and host_id on the ingest path.
+func FromContext(ctx context.Context) Entry {
+	if ctx == nil {
+		return Entry{}
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	return Entry{fields: fields}
+}
+
+// With returns an Entry with kv added to e's fields.
+func (e Entry) With(kv ...interface{}) Entry {
+	fields := make([]interface{}, 0, len(e.fields)+len(kv))
+	return Entry{fields: append(append(fields, e.fields...), kv...)}
+}
+
+func (e Entry) Info(format string, v ...interface{}) {
+	if enabled(LevelInfo) {
+		output(infoLog, LevelInfo, getCallerInfo(2), fmt.Sprintf(format, v...), e.fields)
+	}
+}
+
+func (e Entry) Warn(format string, v ...interface{}) {
+	if enabled(LevelWarn) {
+		output(warnLog, LevelWarn, getCallerInfo(2), fmt.Sprintf(format, v...), e.fields)
+	}
+}
+
+func (e Entry) Error(format string, v ...interface{}) {
+	if enabled(LevelError) {
+		output(errorLog, LevelError, getCallerInfo(2), fmt.Sprintf(format, v...), e.fields)
+	}
+}
+
+func (e Entry) Debug(format string, v ...interface{}) {
+	if enabled(LevelDebug) {
+		output(debugLog, LevelDebug, getCallerInfo(2), fmt.Sprintf(format, v...), e.fields)
+	}
+}
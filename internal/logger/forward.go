@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// forwardFailureReport limits how often a failing HTTP hook complains.
+const forwardFailureReport = time.Minute
+
+// HTTPHook returns a hook POSTing every entry as one JSON object to url:
+//
+//	{"level", "timestamp", "caller", "message", "hostname", "program", "fields"}
+//
+// token, if set, is sent as a bearer token. Failures can't be logged through
+// the logger (that would feed the hook), they go to stderr at most once a minute.
+func HTTPHook(url, token string, timeout time.Duration) Hook {
+	client := &http.Client{Timeout: timeout}
+	hostname, _ := os.Hostname()
+	program := filepath.Base(os.Args[0])
+
+	var mu sync.Mutex
+	var lastReport time.Time
+	failed := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(lastReport) >= forwardFailureReport {
+			lastReport = time.Now()
+			fmt.Fprintf(os.Stderr, "log forwarding to %s failed: %v\n", url, err)
+		}
+	}
+
+	return func(level Level, caller, message string, fields map[string]interface{}) {
+		body, err := json.Marshal(map[string]interface{}{
+			"level":     level.String(),
+			"timestamp": time.Now().Format(time.RFC3339Nano),
+			"caller":    caller,
+			"message":   message,
+			"hostname":  hostname,
+			"program":   program,
+			"fields":    fields,
+		})
+		if err != nil {
+			failed(err)
+			return
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			failed(err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			failed(err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			failed(fmt.Errorf("status %s", resp.Status))
+		}
+	}
+}
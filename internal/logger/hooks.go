@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Hook receives every entry at or above the level it was added with, e.g. to
+// forward it to syslog or a central log system. fields holds the entry's
+// key/value fields (nil if it has none) and must not be modified.
+type Hook func(level Level, caller, message string, fields map[string]interface{})
+
+// hookQueueSize bounds the entries waiting per hook. When a hook falls behind
+// further entries are dropped for it, logging itself never waits.
+const hookQueueSize = 1024
+
+type hookEntry struct {
+	level   Level
+	caller  string
+	message string
+	fields  map[string]interface{}
+}
+
+type hookRunner struct {
+	min     Level
+	hook    Hook
+	queue   chan hookEntry
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []*hookRunner
+)
+
+// AddHook runs hook for every logged entry at min or above. Each hook has its
+// own goroutine and a bounded queue, so a slow hook (a remote endpoint timing
+// out) drops entries instead of blocking the caller.
+func AddHook(min Level, hook Hook) {
+	runner := &hookRunner{min: min, hook: hook, queue: make(chan hookEntry, hookQueueSize), done: make(chan struct{})}
+	go runner.run()
+	hooksMu.Lock()
+	hooks = append(hooks, runner)
+	hooksMu.Unlock()
+}
+
+func (h *hookRunner) run() {
+	defer close(h.done)
+	for entry := range h.queue {
+		h.hook(entry.level, entry.caller, entry.message, entry.fields)
+	}
+}
+
+// runHooks queues an entry for every hook that wants its level.
+func runHooks(level Level, caller, message string, kv []interface{}) {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+	var fields map[string]interface{}
+	if len(kv) > 0 {
+		fields = make(map[string]interface{}, len(kv)/2)
+		for i := 0; i < len(kv); i += 2 {
+			key, value := fieldAt(kv, i)
+			fields[key] = value
+		}
+	}
+	entry := hookEntry{level: level, caller: caller, message: message, fields: fields}
+	for _, h := range hooks {
+		if level < h.min {
+			continue
+		}
+		select {
+		case h.queue <- entry:
+		default:
+			h.dropped.Add(1)
+		}
+	}
+}
+
+// CloseHooks stops accepting entries and waits up to timeout for the hooks to
+// handle what is queued. Call it before the process exits; entries logged
+// afterwards only go to the regular output.
+func CloseHooks(timeout time.Duration) {
+	hooksMu.Lock()
+	closing := hooks
+	hooks = nil
+	hooksMu.Unlock()
+
+	deadline := time.After(timeout)
+	for _, h := range closing {
+		close(h.queue)
+	}
+	for _, h := range closing {
+		select {
+		case <-h.done:
+		case <-deadline:
+			fmt.Fprintf(os.Stderr, "log hooks did not finish within %s, queued entries are lost\n", timeout)
+			return
+		}
+		if n := h.dropped.Load(); n > 0 {
+			fmt.Fprintf(os.Stderr, "a log hook dropped %d entries because its queue was full\n", n)
+		}
+	}
+}
+
+// String returns the level's name as ParseLevel accepts it.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case levelFatal:
+		return "fatal"
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+// mapFields formats hook fields as " key=value" in key order.
+func mapFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, fields[key])
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBlockedHookDoesntStallLogging(t *testing.T) {
+	SetOutput(io.Discard, false)
+	t.Cleanup(func() { SetOutput(io.Discard, true) })
+
+	first := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var got []string
+	AddHook(LevelInfo, func(level Level, caller, message string, fields map[string]interface{}) {
+		mu.Lock()
+		got = append(got, message)
+		n := len(got)
+		mu.Unlock()
+		if n == 1 {
+			close(first)
+			<-release // a forwarding endpoint that stopped answering
+		}
+	})
+	hooksMu.RLock()
+	runner := hooks[len(hooks)-1]
+	hooksMu.RUnlock()
+	t.Cleanup(func() { CloseHooks(time.Second) })
+
+	Info("entry 0")
+	<-first // the hook is now stuck on entry 0, its queue is empty
+
+	const overflow = 100
+	start := time.Now()
+	for i := 1; i <= hookQueueSize+overflow; i++ {
+		Info("entry %d", i)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("logging %d entries took %s with the hook blocked", hookQueueSize+overflow, elapsed)
+	}
+	if dropped := runner.dropped.Load(); dropped != overflow {
+		t.Errorf("dropped %d entries, want the %d that didn't fit the queue", dropped, overflow)
+	}
+
+	close(release)
+	CloseHooks(5 * time.Second)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1+hookQueueSize {
+		t.Fatalf("hook got %d entries, want entry 0 and the %d queued", len(got), hookQueueSize)
+	}
+	if last := fmt.Sprintf("entry %d", hookQueueSize); got[1] != "entry 1" || got[len(got)-1] != last {
+		t.Errorf("hook got %q to %q, want entry 1 to %s in order", got[1], got[len(got)-1], last)
+	}
+}
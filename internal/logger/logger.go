@@ -1,11 +1,31 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the minimum severity that is logged.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+
+	levelFatal // only for Fatal's entries, not a configurable level
 )
 
 var (
@@ -14,7 +34,10 @@ var (
 	errorLog *log.Logger
 	debugLog *log.Logger
 
-	debugEnabled = false // set ture for enable debug logging
+	minLevel   atomic.Int32 // a Level, checked before anything is formatted
+	jsonFormat atomic.Bool  // LOG_FORMAT=json, one JSON object per line
+
+	jsonMu sync.Mutex // serializes JSON lines on the shared writers
 )
 
 // initializes the loggers. Automatically called when the package is imported
@@ -29,6 +52,141 @@ func init() {
 	warnLog = log.New(os.Stdout, "WARN: ", baseFlags) // os.Stdout for warnings
 	errorLog = log.New(os.Stderr, "ERROR: ", baseFlags)
 	debugLog = log.New(os.Stdout, "DEBUG: ", baseFlags)
+
+	minLevel.Store(int32(LevelInfo))
+
+	// Read here rather than from the server/agent config so that the very
+	// first lines of a run are already in the right format and level.
+	if format, ok := os.LookupEnv("LOG_FORMAT"); ok {
+		if err := SetFormat(format); err != nil {
+			Warn("%v, logging as text", err)
+		}
+	}
+	if name, ok := os.LookupEnv("LOG_LEVEL"); ok {
+		level, err := ParseLevel(name)
+		if err != nil {
+			Warn("%v, logging at info", err)
+		}
+		SetLevel(level)
+	}
+	if path := os.Getenv("LOG_FILE"); path != "" {
+		if err := outputFromEnv(path); err != nil {
+			Error("Logging to stdout/stderr only: %v", err)
+		}
+	}
+	hooksFromEnv()
+}
+
+// hooksFromEnv adds the syslog (LOG_SYSLOG=true) and HTTP forwarding
+// (LOG_FORWARD_URL, warn and error by default) hooks.
+func hooksFromEnv() {
+	if os.Getenv("LOG_SYSLOG") == "true" {
+		hook, err := SyslogHook(filepath.Base(os.Args[0]))
+		if err != nil {
+			Error("Not logging to syslog: %v", err)
+		} else {
+			AddHook(LevelDebug, hook)
+		}
+	}
+	if url := os.Getenv("LOG_FORWARD_URL"); url != "" {
+		min := LevelWarn
+		if name, ok := os.LookupEnv("LOG_FORWARD_LEVEL"); ok {
+			level, err := ParseLevel(name)
+			if err != nil {
+				Warn("LOG_FORWARD_LEVEL: %v, forwarding warn and error", err)
+				level = LevelWarn
+			}
+			min = level
+		}
+		AddHook(min, HTTPHook(url, os.Getenv("LOG_FORWARD_TOKEN"), 5*time.Second))
+	}
+}
+
+// SetOutput sends all log output to w, in addition to stdout/stderr when
+// keepStd is set. The default is stdout (info, warn, debug) and stderr (errors).
+func SetOutput(w io.Writer, keepStd bool) {
+	out, errOut := w, w
+	if keepStd {
+		out, errOut = io.MultiWriter(os.Stdout, w), io.MultiWriter(os.Stderr, w)
+	}
+	infoLog.SetOutput(out)
+	warnLog.SetOutput(out)
+	debugLog.SetOutput(out)
+	errorLog.SetOutput(errOut)
+}
+
+// outputFromEnv opens the LOG_FILE_* configured rotating file for SetOutput.
+func outputFromEnv(path string) error {
+	maxSizeMB, err := envInt("LOG_FILE_MAX_SIZE_MB", 100)
+	if err != nil {
+		return err
+	}
+	maxBackups, err := envInt("LOG_FILE_MAX_BACKUPS", 5)
+	if err != nil {
+		return err
+	}
+	maxAge := time.Duration(0)
+	if value := os.Getenv("LOG_FILE_MAX_AGE"); value != "" {
+		if maxAge, err = time.ParseDuration(value); err != nil || maxAge < 0 {
+			return fmt.Errorf("LOG_FILE_MAX_AGE %q is not a duration (e.g. 168h)", value)
+		}
+	}
+	file, err := OpenRotatingFile(path, int64(maxSizeMB)<<20, maxBackups, maxAge)
+	if err != nil {
+		return err
+	}
+	SetOutput(file, os.Getenv("LOG_FILE_ONLY") != "true")
+	return nil
+}
+
+func envInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s %q must be a non-negative integer", key, value)
+	}
+	return n, nil
+}
+
+// ParseLevel parses debug, info, warn (or warning) and error. Unknown names
+// return an error and LevelInfo.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown LOG_LEVEL %q (debug, info, warn or error)", name)
+}
+
+// SetLevel sets the minimum level that is logged. Fatal is always logged.
+func SetLevel(level Level) {
+	minLevel.Store(int32(level))
+}
+
+func enabled(level Level) bool {
+	return Level(minLevel.Load()) <= level
+}
+
+// SetFormat switches between "text" (the default) and "json" output.
+func SetFormat(format string) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		jsonFormat.Store(false)
+	case "json":
+		jsonFormat.Store(true)
+	default:
+		return fmt.Errorf("unknown LOG_FORMAT %q (text or json)", format)
+	}
+	return nil
 }
 
 // return file and line number of the caller
@@ -44,44 +202,149 @@ func getCallerInfo(skip int) string {
 	return fmt.Sprintf("%s:%d", fileName, line)
 }
 
+// output writes one entry. kv are alternating keys and values; in text mode
+// they are appended as key=value, in JSON mode they become fields of the entry.
+func output(textLog *log.Logger, level Level, caller, message string, kv []interface{}) {
+	runHooks(level, caller, message, kv)
+	if !jsonFormat.Load() {
+		textLog.Printf("%s: %s%s", caller, message, textFields(kv))
+		return
+	}
+
+	var line bytes.Buffer
+	line.WriteString(`{"level":`)
+	writeJSON(&line, level.String())
+	line.WriteString(`,"timestamp":`)
+	writeJSON(&line, time.Now().Format(time.RFC3339Nano))
+	line.WriteString(`,"caller":`)
+	writeJSON(&line, caller)
+	line.WriteString(`,"message":`)
+	writeJSON(&line, message)
+	for i := 0; i < len(kv); i += 2 {
+		key, value := fieldAt(kv, i)
+		switch key {
+		case "level", "timestamp", "caller", "message":
+			key = "field_" + key // don't shadow the entry's own keys
+		}
+		line.WriteByte(',')
+		writeJSON(&line, key)
+		line.WriteByte(':')
+		writeJSON(&line, value)
+	}
+	line.WriteString("}\n")
+
+	jsonMu.Lock()
+	defer jsonMu.Unlock()
+	_, _ = textLog.Writer().Write(line.Bytes())
+}
+
+// fieldAt returns the key/value pair starting at kv[i]. A missing value is
+// reported rather than dropped so the broken call site is visible.
+func fieldAt(kv []interface{}, i int) (string, interface{}) {
+	key := fmt.Sprint(kv[i])
+	if i+1 >= len(kv) {
+		return key, "(missing value)"
+	}
+	value := kv[i+1]
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case json.Marshaler:
+	case fmt.Stringer: // durations, IPs, ...
+		value = v.String()
+	}
+	return key, value
+}
+
+func textFields(kv []interface{}) string {
+	var b strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		key, value := fieldAt(kv, i)
+		fmt.Fprintf(&b, " %s=%v", key, value)
+	}
+	return b.String()
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(data)
+}
+
 // Info Logs
 func Info(format string, v ...interface{}) {
-	caller := getCallerInfo(2)
-	message := fmt.Sprintf(format, v...)
-	infoLog.Printf("%s: %s", caller, message)
+	if enabled(LevelInfo) {
+		output(infoLog, LevelInfo, getCallerInfo(2), fmt.Sprintf(format, v...), nil)
+	}
 }
 
 // Warning Logs
 func Warn(format string, v ...interface{}) {
-	caller := getCallerInfo(2)
-	message := fmt.Sprintf(format, v...)
-	warnLog.Printf("%s: %s", caller, message)
+	if enabled(LevelWarn) {
+		output(warnLog, LevelWarn, getCallerInfo(2), fmt.Sprintf(format, v...), nil)
+	}
 }
 
 // Error logs
 func Error(format string, v ...interface{}) {
-	caller := getCallerInfo(2)
-	message := fmt.Sprintf(format, v...)
-	errorLog.Printf("%s: %s", caller, message)
+	if enabled(LevelError) {
+		output(errorLog, LevelError, getCallerInfo(2), fmt.Sprintf(format, v...), nil)
+	}
 }
 
 // If debug enabled
 func Debug(format string, v ...interface{}) {
-	if debugEnabled {
-		caller := getCallerInfo(2)
-		message := fmt.Sprintf(format, v...)
-		debugLog.Printf("%s: %s", caller, message)
+	if enabled(LevelDebug) {
+		output(debugLog, LevelDebug, getCallerInfo(2), fmt.Sprintf(format, v...), nil)
 	}
 }
 
-// Fatal Logs calls os.Exit(1)
+// Fatal logs and calls os.Exit(1) right away: deferred functions do not run,
+// so nothing is closed. The commands return errors up to main instead and
+// exit there; only use Fatal where nothing has been opened yet.
 func Fatal(format string, v ...interface{}) {
-	caller := getCallerInfo(2)
-	message := fmt.Sprintf(format, v...)
-	errorLog.Printf("%s: %s", caller, message)
+	output(errorLog, levelFatal, getCallerInfo(2), fmt.Sprintf(format, v...), nil)
+	CloseHooks(2 * time.Second) // so a forwarded fatal error isn't lost
 	os.Exit(1)
 }
 
+// InfoKV logs message with key/value fields, e.g.
+// InfoKV("stats stored", "host_id", id, "points", n).
+func InfoKV(message string, kv ...interface{}) {
+	if enabled(LevelInfo) {
+		output(infoLog, LevelInfo, getCallerInfo(2), message, kv)
+	}
+}
+
+// WarnKV is InfoKV at warning level.
+func WarnKV(message string, kv ...interface{}) {
+	if enabled(LevelWarn) {
+		output(warnLog, LevelWarn, getCallerInfo(2), message, kv)
+	}
+}
+
+// ErrorKV is InfoKV at error level.
+func ErrorKV(message string, kv ...interface{}) {
+	if enabled(LevelError) {
+		output(errorLog, LevelError, getCallerInfo(2), message, kv)
+	}
+}
+
+// DebugKV is InfoKV at debug level.
+func DebugKV(message string, kv ...interface{}) {
+	if enabled(LevelDebug) {
+		output(debugLog, LevelDebug, getCallerInfo(2), message, kv)
+	}
+}
+
+// SetDebug(true) is SetLevel(LevelDebug). SetDebug(false) goes back to info
+// from debug but leaves a higher level (LOG_LEVEL=warn) alone.
 func SetDebug(enable bool) {
-	debugEnabled = enable
+	if enable {
+		SetLevel(LevelDebug)
+	} else {
+		minLevel.CompareAndSwap(int32(LevelDebug), int32(LevelInfo))
+	}
 }
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer collects log output from several goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureJSON sends JSON entries at debug level and above to the returned
+// buffer until the test ends.
+func captureJSON(t *testing.T) *syncBuffer {
+	t.Helper()
+	buf := &syncBuffer{}
+	SetOutput(buf, false)
+	if err := SetFormat("json"); err != nil {
+		t.Fatal(err)
+	}
+	SetLevel(LevelDebug)
+	t.Cleanup(func() {
+		SetLevel(LevelInfo)
+		_ = SetFormat("text")
+		SetOutput(io.Discard, true)
+	})
+	return buf
+}
+
+// jsonLines decodes every line of out, which must be one object per line.
+func jsonLines(t *testing.T, out string) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line is not a JSON object: %v\n%s", err, line)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// keys returns the keys of a JSON object line in the order they appear.
+func keys(t *testing.T, line string) []string {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(line))
+	if _, err := dec.Token(); err != nil { // {
+		t.Fatal(err)
+	}
+	var order []string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		order = append(order, key.(string))
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return order
+}
+
+func TestJSONEntryShape(t *testing.T) {
+	out := captureJSON(t)
+	before := time.Now()
+
+	ctx := WithFields(context.Background(), "request_id", "req-1", "host_id", "web-1")
+	FromContext(ctx).With("attempt", 2).Warn("Write failed for %s", "web-1")
+
+	entries := jsonLines(t, out.String())
+	if len(entries) != 1 {
+		t.Fatalf("%d entries, want 1:\n%s", len(entries), out)
+	}
+	entry := entries[0]
+	if entry["level"] != "warn" || entry["message"] != "Write failed for web-1" {
+		t.Errorf("level %v, message %v, want warn and the formatted message", entry["level"], entry["message"])
+	}
+	if caller, _ := entry["caller"].(string); !strings.HasPrefix(caller, "logger_test.go:") {
+		t.Errorf("caller %v, want this file", entry["caller"])
+	}
+	at, err := time.Parse(time.RFC3339Nano, entry["timestamp"].(string))
+	if err != nil || at.Before(before.Add(-time.Second)) || at.After(time.Now().Add(time.Second)) {
+		t.Errorf("timestamp %v (%v), want about now in RFC 3339", entry["timestamp"], err)
+	}
+	if entry["request_id"] != "req-1" || entry["host_id"] != "web-1" || entry["attempt"] != 2.0 {
+		t.Errorf("fields %v, want request_id and host_id from the context and attempt from With", entry)
+	}
+
+	// the entry's own keys first, then the fields in the order they were added
+	want := []string{"level", "timestamp", "caller", "message", "request_id", "host_id", "attempt"}
+	if got := keys(t, strings.TrimSpace(out.String())); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("keys %v, want %v", got, want)
+	}
+}
+
+func TestJSONEntryEscaping(t *testing.T) {
+	out := captureJSON(t)
+	message := "quote \" backslash \\ newline \n tab \t <b>&</b> ünïcode \x01"
+	InfoKV(message, "path", `C:\logs "new"`, "err", errors.New("line 1\nline 2"), "elapsed", 1500*time.Millisecond)
+
+	if lines := strings.Count(out.String(), "\n"); lines != 1 {
+		t.Fatalf("%d lines, want the entry on one line:\n%s", lines, out)
+	}
+	entry := jsonLines(t, out.String())[0]
+	if entry["message"] != message {
+		t.Errorf("message %q, want %q", entry["message"], message)
+	}
+	if entry["path"] != `C:\logs "new"` || entry["err"] != "line 1\nline 2" || entry["elapsed"] != "1.5s" {
+		t.Errorf("fields %v, want the path as is, the error's text and the duration's", entry)
+	}
+}
+
+func TestJSONFieldsDontShadowTheEntry(t *testing.T) {
+	out := captureJSON(t)
+	DebugKV("Real message", "message", "spoofed", "level", "error", "orphan")
+
+	entry := jsonLines(t, out.String())[0]
+	if entry["message"] != "Real message" || entry["level"] != "debug" {
+		t.Errorf("message %v, level %v, want the entry's own", entry["message"], entry["level"])
+	}
+	if entry["field_message"] != "spoofed" || entry["field_level"] != "error" || entry["orphan"] != "(missing value)" {
+		t.Errorf("fields %v, want the clashing keys prefixed and the orphan key reported", entry)
+	}
+}
+
+func TestJSONRespectsTheLevel(t *testing.T) {
+	out := captureJSON(t)
+	SetLevel(LevelWarn)
+	Info("not logged")
+	Debug("not logged")
+	Error("logged")
+
+	entries := jsonLines(t, out.String())
+	if len(entries) != 1 || entries[0]["level"] != "error" {
+		t.Errorf("entries %v, want only the error", entries)
+	}
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102-150405.000"
+
+// RotatingFile is an io.Writer appending to a file that is rotated once it
+// would grow beyond MaxSize: the file is renamed to <path>.<timestamp> and a
+// new one is started. Backups beyond MaxBackups or older than MaxAge are
+// deleted. It is safe for concurrent use, all loggers may share one.
+type RotatingFile struct {
+	path       string
+	maxSize    int64         // bytes, 0 = never rotate
+	maxBackups int           // 0 = keep all
+	maxAge     time.Duration // 0 = keep all
+
+	mu         sync.Mutex
+	file       *os.File
+	size       int64
+	lastBackup time.Time // time in the newest backup's name
+}
+
+// OpenRotatingFile opens (or creates) path for appending.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int, maxAge time.Duration) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	r := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups, maxAge: maxAge}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p would take the file past maxSize.
+// A log line is never split across two files.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil { // reopening after a failed rotation
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "log rotation of %s failed: %v\n", r.path, err)
+			if r.file == nil {
+				return 0, err
+			}
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	renameErr := os.Rename(r.path, r.backupName())
+	if err := r.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+	r.removeOldBackups()
+	return nil
+}
+
+// backupName returns a name for the next backup. Names only have
+// milliseconds, so a rotation within the same millisecond as the previous one
+// (or onto a name left from an earlier run) moves a millisecond on rather
+// than replacing that backup; names stay in rotation order.
+func (r *RotatingFile) backupName() string {
+	at := time.Now().Truncate(time.Millisecond)
+	for {
+		if at.After(r.lastBackup) {
+			name := r.path + "." + at.Format(backupTimeFormat)
+			if _, err := os.Lstat(name); err != nil { // not there, or Rename reports why
+				r.lastBackup = at
+				return name
+			}
+		}
+		at = at.Add(time.Millisecond)
+	}
+}
+
+// removeOldBackups deletes backups beyond maxBackups (oldest first) and
+// those older than maxAge, by the time in their names.
+func (r *RotatingFile) removeOldBackups() {
+	if r.maxBackups <= 0 && r.maxAge <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return
+	}
+	prefix := r.path + "."
+	type backup struct {
+		name string
+		at   time.Time
+	}
+	var found []backup
+	for _, name := range backups {
+		if at, err := time.ParseInLocation(backupTimeFormat, strings.TrimPrefix(name, prefix), time.Local); err == nil {
+			found = append(found, backup{name, at})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].at.After(found[j].at) }) // newest first
+	for i, b := range found {
+		if (r.maxBackups > 0 && i >= r.maxBackups) || (r.maxAge > 0 && time.Since(b.at) > r.maxAge) {
+			os.Remove(b.name)
+		}
+	}
+}
+
+// Close closes the current file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Run with -race: every writer crosses the size limit many times.
+func TestRotatingFileConcurrentWriters(t *testing.T) {
+	const (
+		writers   = 8
+		perWriter = 500
+		lineLen   = 32
+		perFile   = 16 // lines that fit in maxSize
+		maxSize   = perFile * lineLen
+	)
+	path := filepath.Join(t.TempDir(), "server.log")
+	file, err := OpenRotatingFile(path, maxSize, 0, 0)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; n < perWriter; n++ {
+				line := fmt.Sprintf("writer %d line %04d", w, n)
+				line += strings.Repeat(".", lineLen-len(line)-1) + "\n"
+				if _, err := file.Write([]byte(line)); err != nil {
+					t.Errorf("Write: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	files, err := filepath.Glob(path + "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := writers * perWriter
+	if want := (total+perFile-1)/perFile - 1; len(files)-1 != want {
+		t.Errorf("%d rotated files, want %d", len(files)-1, want)
+	}
+
+	linePattern := regexp.MustCompile(`^writer (\d) line (\d{4})\.+$`)
+	seen := map[string]bool{}
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > maxSize {
+			t.Errorf("%s has %d bytes, over the %d limit", filepath.Base(name), len(data), maxSize)
+		}
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			t.Errorf("%s ends in the middle of a line", filepath.Base(name))
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if len(line) != lineLen-1 || !linePattern.MatchString(line) {
+				t.Errorf("%s: split or mixed line %q", filepath.Base(name), line)
+				continue
+			}
+			if seen[line] {
+				t.Errorf("line %q written twice", line)
+			}
+			seen[line] = true
+		}
+	}
+	if len(seen) != total {
+		t.Errorf("%d lines across the files, want %d", len(seen), total)
+	}
+}
+
+func TestRotatingFileKeepsMaxBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	file, err := OpenRotatingFile(path, 10, 3, 0)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile: %v", err)
+	}
+	defer file.Close()
+	for n := 0; n < 20; n++ {
+		if _, err := fmt.Fprintf(file, "line %03d\n", n); err != nil { // 9 bytes, one per file
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("backups %v, want 3", backups)
+	}
+	// the newest three: lines 16 to 18, line 19 is the current file
+	for i, name := range backups {
+		data, _ := os.ReadFile(name)
+		if want := fmt.Sprintf("line %03d\n", 16+i); string(data) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), data, want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// SyslogHook returns a hook writing entries to the local syslog daemon under
+// tag, at the syslog severity matching the entry's level.
+func SyslogHook(tag string) (Hook, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("connect to syslog: %w", err)
+	}
+	return func(level Level, caller, message string, fields map[string]interface{}) {
+		line := caller + ": " + message + mapFields(fields)
+		switch level {
+		case LevelDebug:
+			_ = w.Debug(line)
+		case LevelInfo:
+			_ = w.Info(line)
+		case LevelWarn:
+			_ = w.Warning(line)
+		case LevelError:
+			_ = w.Err(line)
+		default:
+			_ = w.Crit(line)
+		}
+	}, nil
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package logger
+
+import "errors"
+
+// SyslogHook is not available on this platform, there is no local syslog.
+func SyslogHook(tag string) (Hook, error) {
+	return nil, errors.New("syslog is not available on this platform")
+}
@@ -0,0 +1,70 @@
+package alerting
+
+import (
+	"fmt"
+	"time"
+)
+
+// State of an alert instance (one rule on one host).
+type State string
+
+const (
+	StatePending  State = "pending" // condition true, waiting for the rule's "for" duration
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// Alert is what notifiers receive.
+type Alert struct {
+	RuleID    string    `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	HostID    string    `json:"host_id"`
+	Hostname  string    `json:"hostname"`
+	Metric    string    `json:"metric"`
+	Operator  string    `json:"operator"`
+	Threshold float64   `json:"threshold"`
+	Value     float64   `json:"value"`
+	State     State     `json:"status"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at,omitempty"`   // set once resolved
+	LastSeen  time.Time `json:"last_seen,omitempty"` // host-down alerts only
+	// disk_full_days alerts: projected time the root disk is full
+	ProjectedFullAt time.Time `json:"projected_full_at,omitempty"`
+
+	RecentValues []ValuePoint `json:"recent_values,omitempty"` // last evaluated values, oldest first
+
+	// Rule routing, not part of the payload
+	WebhookURLs  []string `json:"-"` // replaces the webhook notifier's default URLs
+	SlackChannel string   `json:"-"` // ALERT_SLACK_CHANNELS name, empty = default channel
+	EmailTo      []string `json:"-"` // replaces ALERT_SMTP_TO
+}
+
+// ValuePoint is one evaluated metric value.
+type ValuePoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Summary is a one-line human readable description, used by chat and email notifiers.
+func (a *Alert) Summary() string {
+	host := a.Hostname
+	if host == "" {
+		host = a.HostID
+	}
+	if a.Metric == MetricHostDown {
+		if a.State == StateResolved {
+			return fmt.Sprintf("[RESOLVED] %s is reporting again", host)
+		}
+		return fmt.Sprintf("[FIRING] %s stopped reporting, last seen %s", host, a.LastSeen.UTC().Format(time.RFC3339))
+	}
+	if a.Metric == MetricDiskFullDays {
+		if a.State == StateResolved {
+			return fmt.Sprintf("[RESOLVED] %s on %s: root disk no longer projected to fill within %.0f days", a.RuleName, host, a.Threshold)
+		}
+		return fmt.Sprintf("[FIRING] %s on %s: root disk projected full in %.1f days (%s)", a.RuleName, host, a.Value, a.ProjectedFullAt.UTC().Format("2006-01-02"))
+	}
+	if a.State == StateResolved {
+		return fmt.Sprintf("[RESOLVED] %s on %s: %s is %.2f (threshold %s %.2f)", a.RuleName, host, a.Metric, a.Value, a.Operator, a.Threshold)
+	}
+	return fmt.Sprintf("[FIRING] %s on %s: %s is %.2f (threshold %s %.2f)", a.RuleName, host, a.Metric, a.Value, a.Operator, a.Threshold)
+}
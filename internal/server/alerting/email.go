@@ -0,0 +1,339 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	texttemplate "text/template"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+)
+
+// SMTP transport security modes (ALERT_SMTP_TLS)
+const (
+	SMTPStartTLS = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	SMTPSSL      = "ssl"      // implicit TLS, usually port 465
+	SMTPNone     = "none"     // unencrypted, only for local relays
+)
+
+const (
+	smtpTimeout     = 30 * time.Second
+	smtpBaseBackoff = 2 * time.Second
+)
+
+// EmailNotifier sends alerts over SMTP as multipart plain text + HTML mails.
+type EmailNotifier struct {
+	cfg config.SMTPConfig
+
+	sent    atomic.Int64
+	failed  atomic.Int64
+	retries atomic.Int64
+}
+
+// EmailStats are the notifier's delivery counters since start.
+type EmailStats struct {
+	Sent    int64 `json:"sent"`
+	Failed  int64 `json:"failed"`
+	Retries int64 `json:"retries"`
+}
+
+func NewEmailNotifier(cfg config.SMTPConfig) *EmailNotifier {
+	return &EmailNotifier{cfg: cfg}
+}
+
+func (e *EmailNotifier) Name() string { return "email" }
+
+// Stats returns the delivery counters.
+func (e *EmailNotifier) Stats() EmailStats {
+	return EmailStats{Sent: e.sent.Load(), Failed: e.failed.Load(), Retries: e.retries.Load()}
+}
+
+func (e *EmailNotifier) Send(ctx context.Context, alert Alert) error {
+	return e.SendBatch(ctx, []Alert{alert})
+}
+
+// SendBatch mails one host's alerts together, one message per recipient list
+// (rules can override the recipients).
+func (e *EmailNotifier) SendBatch(ctx context.Context, alerts []Alert) error {
+	var order []string
+	recipients := make(map[string][]string)
+	byRecipients := make(map[string][]Alert)
+	for _, alert := range alerts {
+		to := e.cfg.To
+		if len(alert.EmailTo) > 0 {
+			to = alert.EmailTo
+		}
+		if len(to) == 0 {
+			continue // no default recipients and no rule override
+		}
+		key := strings.Join(to, ",")
+		if _, ok := byRecipients[key]; !ok {
+			order = append(order, key)
+			recipients[key] = to
+		}
+		byRecipients[key] = append(byRecipients[key], alert)
+	}
+
+	var errs []error
+	for _, key := range order {
+		msg, err := buildEmailMessage(e.cfg.From, recipients[key], byRecipients[key], time.Now())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := e.sendWithRetry(ctx, recipients[key], msg, byRecipients[key][0]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendWithRetry delivers msg, retrying temporary failures with backoff. first
+// is only used for logging.
+func (e *EmailNotifier) sendWithRetry(ctx context.Context, to []string, msg []byte, first Alert) error {
+	backoff := smtpBaseBackoff
+	for attempt := 0; ; attempt++ {
+		err := e.deliver(ctx, to, msg)
+		if err == nil {
+			e.sent.Add(1)
+			return nil
+		}
+		if attempt >= e.cfg.MaxRetries || !smtpRetryable(err) {
+			e.failed.Add(1)
+			stats := e.Stats()
+			appLogger.Error("Email notification for %s on %s failed (sent %d, failed %d, retries %d so far)", first.RuleName, first.HostID, stats.Sent, stats.Failed, stats.Retries)
+			return err
+		}
+		e.retries.Add(1)
+		appLogger.Warn("SMTP delivery failed (attempt %d/%d), retrying in %s: %v", attempt+1, e.cfg.MaxRetries+1, backoff, err)
+		select {
+		case <-ctx.Done():
+			e.failed.Add(1)
+			return fmt.Errorf("send mail: %w (last error: %v)", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// deliver runs one SMTP session.
+func (e *EmailNotifier) deliver(ctx context.Context, to []string, msg []byte) error {
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: e.cfg.Host}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if e.cfg.TLSMode == SMTPSSL {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake with %s: %w", addr, err)
+	}
+	defer c.Close()
+
+	if e.cfg.TLSMode == SMTPStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS (set ALERT_SMTP_TLS=none to send unencrypted)", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls with %s: %w", addr, err)
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := c.Mail(e.cfg.From); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finish message: %w", err)
+	}
+	return c.Quit()
+}
+
+// smtpRetryable: connection problems and 4xx (temporary) replies. 5xx replies
+// (bad recipient, auth rejected, ...) won't change on retry.
+func smtpRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code >= 400 && tpErr.Code < 500
+	}
+	var netErr net.Error
+	var opErr *net.OpError
+	return errors.As(err, &netErr) || errors.As(err, &opErr) || errors.Is(err, net.ErrClosed)
+}
+
+// emailView is what the templates render.
+type emailView struct {
+	Alert
+	Status string
+	Host   string
+}
+
+// Both templates render a list of views, one section per alert.
+var emailTextTemplate = texttemplate.Must(texttemplate.New("text").Parse(`{{range $i, $a := .}}{{with $a}}
+{{- if $i}}
+----------------------------------------
+
+{{end}}{{.Status}}: {{.RuleName}} on {{.Host}}
+
+Rule:      {{.RuleName}}
+Host:      {{.Host}} ({{.HostID}})
+Metric:    {{.Metric}}
+Value:     {{printf "%.2f" .Value}}
+Threshold: {{.Operator}} {{printf "%.2f" .Threshold}}
+Since:     {{.StartsAt.UTC.Format "2006-01-02 15:04:05 UTC"}}
+{{- if not .LastSeen.IsZero}}
+Last seen: {{.LastSeen.UTC.Format "2006-01-02 15:04:05 UTC"}}
+{{- end}}
+{{- if not .ProjectedFullAt.IsZero}}
+Projected full: {{.ProjectedFullAt.UTC.Format "2006-01-02 15:04 UTC"}}
+{{- end}}
+{{- if not .EndsAt.IsZero}}
+Resolved:  {{.EndsAt.UTC.Format "2006-01-02 15:04:05 UTC"}}
+{{- end}}
+{{- if .RecentValues}}
+
+Recent values:
+{{- range .RecentValues}}
+  {{.Time.UTC.Format "15:04:05"}}  {{printf "%.2f" .Value}}
+{{- end}}
+{{- end}}
+{{end}}{{end}}`))
+
+var emailHTMLTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<html><body style="font-family: sans-serif">
+{{- range .}}
+<h2 style="color: {{if eq .Status "RESOLVED"}}#12b76a{{else}}#d92d20{{end}}">{{.Status}}: {{.RuleName}}</h2>
+<table cellpadding="4">
+<tr><td><b>Host</b></td><td>{{.Host}} ({{.HostID}})</td></tr>
+<tr><td><b>Metric</b></td><td>{{.Metric}}</td></tr>
+<tr><td><b>Value</b></td><td>{{printf "%.2f" .Value}}</td></tr>
+<tr><td><b>Threshold</b></td><td>{{.Operator}} {{printf "%.2f" .Threshold}}</td></tr>
+<tr><td><b>Since</b></td><td>{{.StartsAt.UTC.Format "2006-01-02 15:04:05 UTC"}}</td></tr>
+{{- if not .LastSeen.IsZero}}
+<tr><td><b>Last seen</b></td><td>{{.LastSeen.UTC.Format "2006-01-02 15:04:05 UTC"}}</td></tr>
+{{- end}}
+{{- if not .ProjectedFullAt.IsZero}}
+<tr><td><b>Projected full</b></td><td>{{.ProjectedFullAt.UTC.Format "2006-01-02 15:04 UTC"}}</td></tr>
+{{- end}}
+{{- if not .EndsAt.IsZero}}
+<tr><td><b>Resolved</b></td><td>{{.EndsAt.UTC.Format "2006-01-02 15:04:05 UTC"}}</td></tr>
+{{- end}}
+</table>
+{{- if .RecentValues}}
+<h3>Recent values</h3>
+<table cellpadding="4" border="1" style="border-collapse: collapse">
+<tr><th>Time (UTC)</th><th>{{.Metric}}</th></tr>
+{{- range .RecentValues}}
+<tr><td>{{.Time.UTC.Format "15:04:05"}}</td><td>{{printf "%.2f" .Value}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</body></html>
+`))
+
+// buildEmailMessage renders a multipart/alternative (plain text + HTML) RFC 5322
+// message covering one or more alerts of the same host.
+func buildEmailMessage(from string, to []string, alerts []Alert, now time.Time) ([]byte, error) {
+	views := make([]emailView, len(alerts))
+	ruleNames := make([]string, len(alerts))
+	for i, alert := range alerts {
+		views[i] = emailView{Alert: alert, Status: "FIRING", Host: alert.Hostname}
+		if alert.State == StateResolved {
+			views[i].Status = "RESOLVED"
+		}
+		if views[i].Host == "" {
+			views[i].Host = alert.HostID
+		}
+		ruleNames[i] = alert.RuleName
+	}
+	subject := alerts[0].Summary()
+	if len(alerts) > 1 {
+		subject = fmt.Sprintf("[%d alerts] %s: %s", len(alerts), views[0].Host, strings.Join(ruleNames, ", "))
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		render      func(*bytes.Buffer) error
+	}{
+		{"text/plain; charset=UTF-8", func(b *bytes.Buffer) error { return emailTextTemplate.Execute(b, views) }},
+		{"text/html; charset=UTF-8", func(b *bytes.Buffer) error { return emailHTMLTemplate.Execute(b, views) }},
+	}
+	for _, part := range parts {
+		var rendered bytes.Buffer
+		if err := part.render(&rendered); err != nil {
+			return nil, fmt.Errorf("render email: %w", err)
+		}
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create email part: %w", err)
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write(rendered.Bytes()); err != nil {
+			return nil, fmt.Errorf("encode email part: %w", err)
+		}
+		qp.Close()
+	}
+	mw.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n", mw.Boundary())
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
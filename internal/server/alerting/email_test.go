@@ -0,0 +1,258 @@
+package alerting
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+)
+
+// parseEmail returns the headers and the decoded parts of msg by content type.
+func parseEmail(t *testing.T, msg []byte) (mail.Header, map[string]string) {
+	t.Helper()
+	m, err := mail.ReadMessage(strings.NewReader(string(msg)))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type %q, want multipart/alternative", m.Header.Get("Content-Type"))
+	}
+	parts := make(map[string]string)
+	reader := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextRawPart: %v", err)
+		}
+		if enc := part.Header.Get("Content-Transfer-Encoding"); enc != "quoted-printable" {
+			t.Errorf("part encoding %q, want quoted-printable", enc)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatalf("decode part: %v", err)
+		}
+		parts[part.Header.Get("Content-Type")] = string(body)
+	}
+	return m.Header, parts
+}
+
+func TestBuildEmailMessage(t *testing.T) {
+	alert := testAlert(StateFiring)
+	alert.RecentValues = []ValuePoint{{Time: testStart.Add(-time.Minute), Value: 95.5}, {Time: testStart, Value: 97.456}}
+	now := time.Date(2026, 3, 4, 5, 7, 0, 0, time.UTC)
+
+	msg, err := buildEmailMessage("alerts@example.com", []string{"ops@example.com", "oncall@example.com"}, []Alert{alert}, now)
+	if err != nil {
+		t.Fatalf("buildEmailMessage: %v", err)
+	}
+	header, parts := parseEmail(t, msg)
+	for name, want := range map[string]string{
+		"From":         "alerts@example.com",
+		"To":           "ops@example.com, oncall@example.com",
+		"Subject":      "[FIRING] High CPU on web-1: cpu_usage is 97.46 (threshold > 90.00)",
+		"Date":         "Wed, 04 Mar 2026 05:07:00 +0000",
+		"Mime-Version": "1.0",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	text := parts["text/plain; charset=UTF-8"]
+	for _, want := range []string{
+		"FIRING: High CPU on web-1",
+		"Host:      web-1 (host 1)",
+		"Value:     97.46",
+		"Threshold: > 90.00",
+		"Since:     2026-03-04 05:06:07 UTC",
+		"  05:05:07  95.50",
+		"  05:06:07  97.46",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Resolved:") {
+		t.Errorf("text part of a firing alert has a resolved time:\n%s", text)
+	}
+	html := parts["text/html; charset=UTF-8"]
+	for _, want := range []string{`<h2 style="color: #d92d20">FIRING: High CPU</h2>`, "<td>web-1 (host 1)</td>", "<h3>Recent values</h3>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html part lacks %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestBuildEmailMessageBatch(t *testing.T) {
+	memory := testAlert(StateResolved)
+	memory.RuleName, memory.Metric = "High memory", MetricMemUsage
+	memory.Hostname = "<web-1>" // escaped in the HTML part
+
+	msg, err := buildEmailMessage("alerts@example.com", []string{"ops@example.com"}, []Alert{testAlert(StateFiring), memory}, testStart)
+	if err != nil {
+		t.Fatalf("buildEmailMessage: %v", err)
+	}
+	header, parts := parseEmail(t, msg)
+	if got, want := header.Get("Subject"), "[2 alerts] web-1: High CPU, High memory"; got != want {
+		t.Errorf("Subject = %q, want %q", got, want)
+	}
+	text := parts["text/plain; charset=UTF-8"]
+	if !strings.Contains(text, "FIRING: High CPU on web-1") || !strings.Contains(text, "RESOLVED: High memory on <web-1>") ||
+		!strings.Contains(text, "Resolved:  2026-03-04 05:16:07 UTC") {
+		t.Errorf("text part lacks one of the alerts:\n%s", text)
+	}
+	html := parts["text/html; charset=UTF-8"]
+	if !strings.Contains(html, "&lt;web-1&gt;") || strings.Contains(html, "<web-1>") {
+		t.Errorf("html part doesn't escape the hostname:\n%s", html)
+	}
+}
+
+// smtpServer is a plain SMTP server (no TLS, no auth) keeping the sessions
+// it receives.
+type smtpServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	rcpts    []string
+	data     []string
+	reject   int // RCPT reply code, 250 when 0
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.session(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) session(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP test")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.mu.Lock()
+			code := s.reject
+			s.rcpts = append(s.rcpts, strings.TrimSpace(line[len("RCPT TO:"):]))
+			s.mu.Unlock()
+			if code != 0 {
+				reply(strconv.Itoa(code) + " mailbox unavailable")
+				continue
+			}
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, ".")) // undo dot-stuffing
+			}
+			s.mu.Lock()
+			s.data = append(s.data, data.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) config() config.SMTPConfig {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return config.SMTPConfig{
+		Host:    "127.0.0.1",
+		Port:    addr.Port,
+		TLSMode: SMTPNone,
+		From:    "alerts@example.com",
+		To:      []string{"ops@example.com"},
+	}
+}
+
+func TestEmailNotifierDelivers(t *testing.T) {
+	server := newSMTPServer(t)
+	email := NewEmailNotifier(server.config())
+
+	routed := testAlert(StateFiring)
+	routed.RuleName, routed.EmailTo = "Routed", []string{"db-team@example.com"}
+	if err := email.SendBatch(context.Background(), []Alert{testAlert(StateFiring), routed}); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if got := strings.Join(server.rcpts, " "); got != "<ops@example.com> <db-team@example.com>" {
+		t.Errorf("recipients %s, want the default and the rule's in separate mails", got)
+	}
+	if len(server.data) != 2 {
+		t.Fatalf("mails = %d, want 2", len(server.data))
+	}
+	header, _ := parseEmail(t, []byte(server.data[1]))
+	if got := header.Get("To"); got != "db-team@example.com" {
+		t.Errorf("second mail To = %q, want the rule's recipients", got)
+	}
+	if stats := email.Stats(); stats.Sent != 2 || stats.Failed != 0 {
+		t.Errorf("stats = %+v, want 2 sent", stats)
+	}
+}
+
+func TestEmailNotifierDoesNotRetryPermanentFailures(t *testing.T) {
+	server := newSMTPServer(t)
+	server.reject = 550
+	cfg := server.config()
+	cfg.MaxRetries = 3
+	email := NewEmailNotifier(cfg)
+
+	if err := email.Send(context.Background(), testAlert(StateFiring)); err == nil {
+		t.Fatal("Send succeeded with the recipient rejected")
+	}
+	if stats := email.Stats(); stats.Failed != 1 || stats.Retries != 0 {
+		t.Errorf("stats = %+v, want 1 failed and no retries for a 5xx reply", stats)
+	}
+}
@@ -0,0 +1,501 @@
+package alerting
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/tracker"
+)
+
+const recentValuesKept = 5 // per open alert, shown in notifications
+
+// Built-in host-down rule. Its alerts use this rule ID, it isn't stored with the other rules.
+const (
+	HostDownRuleID = "host-down"
+	MetricHostDown = "host_down" // seconds since the last report
+)
+
+// MetricSource provides the latest metrics of every active host.
+type MetricSource interface {
+	GetHostOverviewList(ctx context.Context) ([]models.HostOverviewData, error)
+	GetRootDiskUsageSeries(ctx context.Context, lookback, every time.Duration) ([]models.DiskUsageSeries, error)
+}
+
+// PresenceSource provides when each host last reported.
+type PresenceSource interface {
+	Snapshot() []tracker.HostStatus
+}
+
+// Engine evaluates alert rules on a timer and drives each (rule, host) pair
+// through pending -> firing -> resolved. Open alerts are kept in memory and
+// mirrored to the metadata store so they survive restarts.
+type Engine struct {
+	store    *metadata.Store
+	metrics  MetricSource
+	presence PresenceSource
+	hostDown config.HostDownConfig
+
+	notifyTimeout   time.Duration // covers webhook/SMTP retries with backoff
+	forecastRefresh time.Duration // how often a lookback's disk series is re-queried
+
+	notifierMu sync.RWMutex
+	notifier   Notifier // nil = log only, swapped by SetNotifier on config reload
+
+	// only touched from the evaluation goroutine
+	open    map[string]*metadata.AlertEvent // ruleID + "/" + hostID
+	recent  map[string][]ValuePoint         // last values of open alerts, same keys
+	outbox  []Alert                         // notifications of the current round
+	history []metadata.AlertTransition      // transitions of the current round
+
+	// Cooldown bookkeeping, in memory only: a restart forgets it.
+	lastNotified map[string]time.Time // last firing notification per key
+	muted        map[string]bool      // fired inside the cooldown, resolve is not sent either
+
+	forecasts map[time.Duration]forecastCache // disk series per rule lookback
+}
+
+// NewEngine creates an engine and loads open alerts from the store. Of cfg
+// only the host-down rule and the timings are used, notifiers are built by
+// the caller.
+func NewEngine(store *metadata.Store, metrics MetricSource, presence PresenceSource, notifier Notifier, cfg config.AlertingConfig) (*Engine, error) {
+	events, err := store.OpenAlertEvents()
+	if err != nil {
+		return nil, err
+	}
+	e := &Engine{
+		store:           store,
+		metrics:         metrics,
+		presence:        presence,
+		notifier:        notifier,
+		hostDown:        cfg.HostDown,
+		notifyTimeout:   cfg.NotifyTimeout,
+		forecastRefresh: cfg.ForecastRefresh,
+		open:            make(map[string]*metadata.AlertEvent, len(events)),
+		recent:          make(map[string][]ValuePoint),
+		lastNotified:    make(map[string]time.Time),
+		muted:           make(map[string]bool),
+		forecasts:       make(map[time.Duration]forecastCache),
+	}
+	for i := range events {
+		e.open[alertKey(events[i].RuleID, events[i].HostID)] = &events[i]
+	}
+	if len(events) > 0 {
+		appLogger.Info("Alerting: restored %d open alerts", len(events))
+	}
+	return e, nil
+}
+
+// Run evaluates all rules every interval until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.evaluate(ctx, now)
+		}
+	}
+}
+
+// sample is one host's value for one rule in this evaluation round.
+type sample struct {
+	hostID, hostname string
+	value            float64
+	lastSeen         time.Time // only set for presence based samples
+	projectedFull    time.Time // only set for disk forecasts
+}
+
+func (e *Engine) evaluate(ctx context.Context, now time.Time) {
+	rules, err := e.store.ListAlertRules()
+	if err != nil {
+		appLogger.Error("Alerting: failed to load rules: %v", err)
+		return
+	}
+	groupsByHost, err := e.store.GroupsByHost()
+	if err != nil {
+		appLogger.Error("Alerting: failed to load host groups: %v", err)
+		return
+	}
+
+	var overview []models.HostOverviewData
+	if needsMetrics(rules) {
+		overview, err = e.metrics.GetHostOverviewList(ctx)
+		if err != nil {
+			// don't resolve anything on a failed query, try again next round
+			appLogger.Error("Alerting: failed to query host metrics: %v", err)
+			return
+		}
+	}
+	presence := e.presence.Snapshot()
+	decommissioned, err := e.store.DecommissionedHosts()
+	if err != nil {
+		// only host-down uses it, a round treating every host as active is harmless
+		appLogger.Error("Alerting: failed to load decommissioned hosts: %v", err)
+	}
+
+	defer e.flush(ctx)
+
+	seen := make(map[string]bool)
+	for i := range rules {
+		rule := &rules[i]
+		samples := samplesFor(rule, overview, presence, now)
+		if rule.Metric == MetricDiskFullDays {
+			if samples, err = e.forecastSamples(ctx, rule, now); err != nil {
+				// open forecast alerts stay as they are until the next successful query
+				appLogger.Error("Alerting: failed to query disk usage for %s: %v", rule.Name, err)
+			}
+		}
+		for _, s := range samples {
+			resolved := ResolveRule(rule, s.hostID, groupsByHost[s.hostID])
+			if !resolved.Applies {
+				continue
+			}
+			key := alertKey(rule.ID, s.hostID)
+			seen[key] = true
+			if resolved.Disabled {
+				if event, ok := e.open[key]; ok {
+					e.clear(key, event, &resolved.AlertRule, now)
+				}
+				continue
+			}
+			e.step(&resolved.AlertRule, s, now)
+		}
+	}
+
+	if e.hostDown.Enabled {
+		for _, h := range presence {
+			if _, ok := decommissioned[h.HostID]; ok {
+				continue // retired on purpose, its open alert is resolved below
+			}
+			rule := e.hostDownRule(h)
+			s := sample{hostID: h.HostID, hostname: h.Hostname, value: now.Sub(h.LastSeen).Seconds(), lastSeen: h.LastSeen}
+			seen[alertKey(rule.ID, h.HostID)] = true
+			e.step(&rule, s, now)
+		}
+	}
+
+	// Open alerts without a sample: rule deleted or no data for the host.
+	for key, event := range e.open {
+		if seen[key] {
+			continue
+		}
+		if _, retired := decommissioned[event.HostID]; event.RuleID == HostDownRuleID && e.hostDown.Enabled && !retired {
+			continue // host not seen since restart, resolves once it reports
+		}
+		if rule := findRule(rules, event.RuleID); rule == nil || event.State == metadata.AlertPending {
+			e.clear(key, event, rule, now)
+		}
+		// firing alerts for hosts without data stay firing until data says otherwise
+	}
+}
+
+// step applies one observation to the state machine.
+func (e *Engine) step(rule *metadata.AlertRule, s sample, now time.Time) {
+	key := alertKey(rule.ID, s.hostID)
+	event, isOpen := e.open[key]
+	met := conditionMet(rule, s.value, isOpen && event.State == metadata.AlertFiring)
+
+	if isOpen || met {
+		e.recordValue(key, now, s.value)
+	}
+	if !met {
+		if isOpen && e.holdFiring(rule, event, now) {
+			event.Value = s.value
+			if err := e.store.UpdateAlertEvent(event); err != nil {
+				appLogger.Error("Alerting: failed to update alert %d: %v", event.ID, err)
+			}
+			return
+		}
+		if isOpen {
+			event.Value = s.value
+			e.clear(key, event, rule, now)
+		}
+		return
+	}
+
+	if !isOpen {
+		created, err := e.store.AddAlertEvent(metadata.AlertEvent{
+			RuleID:    rule.ID,
+			RuleName:  rule.Name,
+			HostID:    s.hostID,
+			Hostname:  s.hostname,
+			Metric:    rule.Metric,
+			Operator:  rule.Operator,
+			Threshold: rule.Threshold,
+			Value:     s.value,
+			State:     metadata.AlertPending,
+			StartsAt:  now,
+		})
+		if err != nil {
+			appLogger.Error("Alerting: failed to store alert %s for host %s: %v", rule.Name, s.hostID, err)
+			return
+		}
+		event = created
+		e.open[key] = event
+	}
+
+	event.Value = s.value
+	if !s.lastSeen.IsZero() {
+		event.LastSeen = s.lastSeen.UTC()
+	}
+	if rule.Metric == MetricDiskFullDays {
+		event.ProjectedFullAt = s.projectedFull
+	}
+	if event.State == metadata.AlertPending && now.Sub(event.StartsAt) >= rule.For {
+		event.State = metadata.AlertFiring
+		event.FiredAt = now.UTC()
+		appLogger.Warn("Alert firing: %s on %s (%s = %.2f, threshold %s %.2f)", rule.Name, s.hostID, rule.Metric, s.value, rule.Operator, rule.Threshold)
+		e.notify(event, rule)
+	}
+	if err := e.store.UpdateAlertEvent(event); err != nil {
+		appLogger.Error("Alerting: failed to update alert %d: %v", event.ID, err)
+	}
+}
+
+// clear resolves a firing alert, or drops a pending one that never fired.
+// rule is nil when the rule was deleted.
+func (e *Engine) clear(key string, event *metadata.AlertEvent, rule *metadata.AlertRule, now time.Time) {
+	delete(e.open, key)
+	defer delete(e.recent, key) // after notify has copied them
+	if event.State == metadata.AlertPending {
+		if err := e.store.DeleteAlertEvent(event); err != nil {
+			appLogger.Error("Alerting: failed to drop pending alert %d: %v", event.ID, err)
+		}
+		return
+	}
+	event.State = metadata.AlertResolved
+	event.EndsAt = now.UTC()
+	appLogger.Info("Alert resolved: %s on %s", event.RuleName, event.HostID)
+	if err := e.store.UpdateAlertEvent(event); err != nil {
+		appLogger.Error("Alerting: failed to update alert %d: %v", event.ID, err)
+	}
+	e.notify(event, rule)
+}
+
+// hostDownRule builds the built-in rule for one host: down once silent for
+// IntervalMultiplier x its reporting interval.
+func (e *Engine) hostDownRule(h tracker.HostStatus) metadata.AlertRule {
+	interval := h.ExpectedInterval
+	if interval <= 0 {
+		interval = e.hostDown.DefaultInterval
+	}
+	return metadata.AlertRule{
+		ID:        HostDownRuleID,
+		Name:      "Host down",
+		Metric:    MetricHostDown,
+		Operator:  ">",
+		Threshold: (time.Duration(e.hostDown.IntervalMultiplier) * interval).Seconds(),
+	}
+}
+
+// holdFiring keeps a firing host-down alert open for at least MinFiring, so a
+// flapping host doesn't produce a fire/resolve pair on every blip.
+func (e *Engine) holdFiring(rule *metadata.AlertRule, event *metadata.AlertEvent, now time.Time) bool {
+	return rule.ID == HostDownRuleID && event.State == metadata.AlertFiring && now.Sub(event.FiredAt) < e.hostDown.MinFiring
+}
+
+// recordValue keeps the last recentValuesKept values of an alert.
+func (e *Engine) recordValue(key string, at time.Time, value float64) {
+	values := append(e.recent[key], ValuePoint{Time: at.UTC(), Value: value})
+	if len(values) > recentValuesKept {
+		values = values[len(values)-recentValuesKept:]
+	}
+	e.recent[key] = values
+}
+
+// inCooldown reports whether the notification for this transition is skipped
+// because the rule's cooldown hasn't passed since the host's last one. A
+// resolve is skipped exactly when its firing notification was.
+func (e *Engine) inCooldown(event *metadata.AlertEvent, rule *metadata.AlertRule, at time.Time) bool {
+	key := alertKey(event.RuleID, event.HostID)
+	if event.State == metadata.AlertResolved {
+		muted := e.muted[key]
+		delete(e.muted, key)
+		return muted
+	}
+	if rule != nil && rule.Cooldown > 0 {
+		if last, ok := e.lastNotified[key]; ok && at.Sub(last) < rule.Cooldown {
+			appLogger.Info("Alerting: %s on %s fired again within its %s cooldown, not notifying", event.RuleName, event.HostID, rule.Cooldown)
+			e.muted[key] = true
+			return true
+		}
+	}
+	e.lastNotified[key] = at
+	return false
+}
+
+// notify queues a notification, sent by flush at the end of the round.
+func (e *Engine) notify(event *metadata.AlertEvent, rule *metadata.AlertRule) {
+	at := event.FiredAt
+	if event.State == metadata.AlertResolved {
+		at = event.EndsAt
+	}
+	e.history = append(e.history, metadata.AlertTransition{
+		EventID:   event.ID,
+		RuleID:    event.RuleID,
+		RuleName:  event.RuleName,
+		HostID:    event.HostID,
+		Hostname:  event.Hostname,
+		Metric:    event.Metric,
+		Operator:  event.Operator,
+		Threshold: event.Threshold,
+		Value:     event.Value,
+		State:     event.State,
+		Time:      at,
+	})
+	if e.Notifier() == nil || e.inCooldown(event, rule, at) {
+		return
+	}
+	alert := toAlert(event)
+	alert.RecentValues = append([]ValuePoint(nil), e.recent[alertKey(event.RuleID, event.HostID)]...)
+	if rule != nil {
+		alert.WebhookURLs = rule.WebhookURLs
+		alert.SlackChannel = rule.SlackChannel
+		alert.EmailTo = rule.EmailTo
+	}
+	e.outbox = append(e.outbox, alert)
+}
+
+// flush sends the round's notifications in the background so a slow target
+// can't stall evaluation. Sending them together lets notifiers batch per host.
+// Transitions are written to the alert history first, marked if the host was silenced.
+func (e *Engine) flush(ctx context.Context) {
+	if len(e.outbox) == 0 && len(e.history) == 0 {
+		return
+	}
+	silences, err := e.store.ActiveSilences(time.Now())
+	if err != nil {
+		appLogger.Error("Alerting: failed to load silences, notifying anyway: %v", err)
+	}
+
+	for _, transition := range e.history {
+		_, transition.Silenced = silences[transition.HostID]
+		if err := e.store.AddAlertTransition(transition); err != nil {
+			appLogger.Error("Alerting: failed to record %s transition of alert %d: %v", transition.State, transition.EventID, err)
+		}
+	}
+	e.history = nil
+
+	alerts := unsilenced(e.outbox, silences)
+	e.outbox = nil
+	notifier := e.Notifier()
+	if len(alerts) == 0 || notifier == nil {
+		return
+	}
+	go func() {
+		sendCtx, cancel := context.WithTimeout(ctx, e.notifyTimeout)
+		defer cancel()
+		if err := SendAll(sendCtx, notifier, alerts); err != nil {
+			appLogger.Error("Alerting: failed to send %d notifications via %s: %v", len(alerts), notifier.Name(), err)
+		}
+	}()
+}
+
+// SetNotifier replaces the notification targets (nil = log only). Notifications
+// already being sent finish with the old ones.
+func (e *Engine) SetNotifier(n Notifier) {
+	e.notifierMu.Lock()
+	defer e.notifierMu.Unlock()
+	e.notifier = n
+}
+
+// Notifier returns the current notification targets, nil when alerts are only logged.
+func (e *Engine) Notifier() Notifier {
+	e.notifierMu.RLock()
+	defer e.notifierMu.RUnlock()
+	return e.notifier
+}
+
+// unsilenced drops notifications for silenced hosts. The alerts are still
+// tracked and listed, only the notification is skipped.
+func unsilenced(alerts []Alert, silences map[string]metadata.Silence) []Alert {
+	kept := alerts[:0]
+	for _, alert := range alerts {
+		if silence, ok := silences[alert.HostID]; ok {
+			appLogger.Info("Alerting: %s notification for %s on %s suppressed, host silenced until %s", alert.State, alert.RuleName, alert.HostID, silence.Until.Format(time.RFC3339))
+			continue
+		}
+		kept = append(kept, alert)
+	}
+	return kept
+}
+
+// samplesFor extracts the rule's metric for every host that has a value.
+func samplesFor(rule *metadata.AlertRule, overview []models.HostOverviewData, presence []tracker.HostStatus, now time.Time) []sample {
+	var samples []sample
+	if rule.Metric == MetricHostOffline {
+		for _, h := range presence {
+			samples = append(samples, sample{hostID: h.HostID, hostname: h.Hostname, value: now.Sub(h.LastSeen).Seconds(), lastSeen: h.LastSeen})
+		}
+		return samples
+	}
+	for _, h := range overview {
+		if h.Status == "offline" {
+			continue // last-known values, not a new observation
+		}
+		var value float64
+		switch rule.Metric {
+		case MetricCPUUsage:
+			value = h.CPUUsage
+		case MetricMemUsage:
+			value = h.RAMUsage
+		case MetricDiskUsage:
+			if h.DiskUsage == nil {
+				continue // no root disk reported, not the same as 0%
+			}
+			value = *h.DiskUsage
+		default:
+			continue
+		}
+		samples = append(samples, sample{hostID: h.ID, hostname: h.Hostname, value: value})
+	}
+	return samples
+}
+
+func needsMetrics(rules []metadata.AlertRule) bool {
+	for _, r := range rules {
+		if r.Metric != MetricHostOffline && r.Metric != MetricDiskFullDays {
+			return true
+		}
+	}
+	return false
+}
+
+func findRule(rules []metadata.AlertRule, id string) *metadata.AlertRule {
+	for i := range rules {
+		if rules[i].ID == id {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func alertKey(ruleID, hostID string) string {
+	return ruleID + "/" + hostID
+}
+
+func toAlert(event *metadata.AlertEvent) Alert {
+	return Alert{
+		RuleID:    event.RuleID,
+		RuleName:  event.RuleName,
+		HostID:    event.HostID,
+		Hostname:  event.Hostname,
+		Metric:    event.Metric,
+		Operator:  event.Operator,
+		Threshold: event.Threshold,
+		Value:     event.Value,
+		State:     State(event.State),
+		StartsAt:  event.StartsAt,
+		EndsAt:    event.EndsAt,
+		LastSeen:  event.LastSeen,
+
+		ProjectedFullAt: event.ProjectedFullAt,
+	}
+}
@@ -0,0 +1,256 @@
+package alerting
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/tracker"
+)
+
+// fakeMetrics serves the overview a test sets.
+type fakeMetrics struct {
+	mu       sync.Mutex
+	overview []models.HostOverviewData
+}
+
+func (m *fakeMetrics) GetHostOverviewList(ctx context.Context) ([]models.HostOverviewData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.HostOverviewData(nil), m.overview...), nil
+}
+
+func (m *fakeMetrics) GetRootDiskUsageSeries(ctx context.Context, lookback, every time.Duration) ([]models.DiskUsageSeries, error) {
+	return nil, nil
+}
+
+type noPresence struct{}
+
+func (noPresence) Snapshot() []tracker.HostStatus { return nil }
+
+// batchRecorder receives the engine's notifications, one batch per host.
+type batchRecorder struct{ batches chan []Alert }
+
+func (r *batchRecorder) Name() string { return "recorder" }
+
+func (r *batchRecorder) Send(ctx context.Context, alert Alert) error {
+	return r.SendBatch(ctx, []Alert{alert})
+}
+
+func (r *batchRecorder) SendBatch(ctx context.Context, alerts []Alert) error {
+	r.batches <- append([]Alert(nil), alerts...)
+	return nil
+}
+
+// testEngine evaluates rules against a fake overview at times the test picks.
+type testEngine struct {
+	*Engine
+	metrics *fakeMetrics
+	sent    *batchRecorder
+	start   time.Time
+}
+
+func newTestEngine(t *testing.T, rules ...metadata.AlertRule) *testEngine {
+	t.Helper()
+	store, err := metadata.Open(filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatalf("metadata.Open: %v", err)
+	}
+	t.Cleanup(store.Close)
+	for _, rule := range rules {
+		if _, err := store.CreateAlertRule(rule); err != nil {
+			t.Fatalf("CreateAlertRule: %v", err)
+		}
+	}
+	metrics := &fakeMetrics{}
+	sent := &batchRecorder{batches: make(chan []Alert, 16)}
+	engine, err := NewEngine(store, metrics, noPresence{}, sent, config.AlertingConfig{NotifyTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return &testEngine{Engine: engine, metrics: metrics, sent: sent, start: time.Now()}
+}
+
+// round evaluates the rules at start+offset with hosts as the overview and
+// returns the notifications sent, one batch per host.
+func (e *testEngine) round(offset time.Duration, hosts ...models.HostOverviewData) [][]Alert {
+	e.metrics.mu.Lock()
+	e.metrics.overview = hosts
+	e.metrics.mu.Unlock()
+	e.evaluate(context.Background(), e.start.Add(offset))
+
+	// flush sends in the background, a round's batches follow each other closely
+	var batches [][]Alert
+	for {
+		select {
+		case batch := <-e.sent.batches:
+			batches = append(batches, batch)
+		case <-time.After(100 * time.Millisecond):
+			return batches
+		}
+	}
+}
+
+func onlineHost(id string, cpu, mem float64) models.HostOverviewData {
+	return models.HostOverviewData{ID: id, Hostname: id, Status: "online", CPUUsage: cpu, RAMUsage: mem}
+}
+
+// states lists the alert states of batches in order.
+func states(batches [][]Alert) []State {
+	var states []State
+	for _, batch := range batches {
+		for _, alert := range batch {
+			states = append(states, alert.State)
+		}
+	}
+	return states
+}
+
+func TestHysteresisResolvesAtTheClearThreshold(t *testing.T) {
+	clear := 80.0
+	e := newTestEngine(t, metadata.AlertRule{Name: "cpu", Metric: MetricCPUUsage, Operator: ">", Threshold: 85, ClearThreshold: &clear})
+
+	steps := []struct {
+		cpu  float64
+		want []State
+	}{
+		{84, nil},                    // below the threshold, nothing opens
+		{87, []State{StateFiring}},   // over it fires
+		{83, nil},                    // between clear and fire: still firing, no new message
+		{86, nil},                    // over again: the open alert is not re-sent
+		{80.5, nil},                  // still above clear
+		{80, []State{StateResolved}}, // "> 80" no longer holds at 80
+		{84, nil},                    // resolved alerts need the fire threshold again
+		{85.5, []State{StateFiring}},
+	}
+	for i, step := range steps {
+		got := states(e.round(time.Duration(i)*time.Minute, onlineHost("host-1", step.cpu, 10)))
+		if len(got) != len(step.want) || (len(got) > 0 && got[0] != step.want[0]) {
+			t.Errorf("step %d, cpu %v: notifications %v, want %v", i, step.cpu, got, step.want)
+		}
+	}
+}
+
+func TestPendingAlertFiresAfterItsForDuration(t *testing.T) {
+	e := newTestEngine(t, metadata.AlertRule{Name: "cpu", Metric: MetricCPUUsage, Operator: ">", Threshold: 90, For: 2 * time.Minute})
+
+	// a spike shorter than For is dropped without any message
+	if got := states(e.round(0, onlineHost("host-1", 95, 10))); got != nil {
+		t.Errorf("first value over: %v, want pending only", got)
+	}
+	if got := states(e.round(time.Minute, onlineHost("host-1", 50, 10))); got != nil {
+		t.Errorf("dropped while pending: %v, want no resolve message", got)
+	}
+
+	for _, offset := range []time.Duration{2 * time.Minute, 3 * time.Minute} {
+		if got := states(e.round(offset, onlineHost("host-1", 95, 10))); got != nil {
+			t.Errorf("%v: %v, want still pending", offset, got)
+		}
+	}
+	// exactly For after it started
+	if got := states(e.round(4*time.Minute, onlineHost("host-1", 95, 10))); len(got) != 1 || got[0] != StateFiring {
+		t.Errorf("For elapsed: %v, want firing", got)
+	}
+}
+
+func TestCooldownMutesRefiringAndItsResolve(t *testing.T) {
+	e := newTestEngine(t, metadata.AlertRule{Name: "cpu", Metric: MetricCPUUsage, Operator: ">", Threshold: 85, Cooldown: 10 * time.Minute})
+
+	steps := []struct {
+		at   time.Duration
+		cpu  float64
+		want []State
+	}{
+		{0, 90, []State{StateFiring}},
+		{1 * time.Minute, 50, []State{StateResolved}}, // the first resolve is always sent
+		{2 * time.Minute, 90, nil},                    // fires again inside the cooldown
+		{3 * time.Minute, 50, nil},                    // and its resolve is muted with it
+		{9 * time.Minute, 90, nil},
+		{9*time.Minute + 30*time.Second, 50, nil},
+		{10 * time.Minute, 90, []State{StateFiring}}, // cooldown over, counted from the last sent firing
+		{11 * time.Minute, 50, []State{StateResolved}},
+	}
+	for _, step := range steps {
+		got := states(e.round(step.at, onlineHost("host-1", step.cpu, 10)))
+		if len(got) != len(step.want) || (len(got) > 0 && got[0] != step.want[0]) {
+			t.Errorf("%v, cpu %v: notifications %v, want %v", step.at, step.cpu, got, step.want)
+		}
+	}
+}
+
+func TestRulesFiringOnOneHostAreGrouped(t *testing.T) {
+	e := newTestEngine(t,
+		metadata.AlertRule{Name: "cpu", Metric: MetricCPUUsage, Operator: ">", Threshold: 85},
+		metadata.AlertRule{Name: "mem", Metric: MetricMemUsage, Operator: ">", Threshold: 85},
+	)
+
+	batches := e.round(0, onlineHost("host-1", 95, 95), onlineHost("host-2", 95, 10))
+	byHost := make(map[string][]Alert)
+	for _, batch := range batches {
+		for _, alert := range batch[1:] {
+			if alert.HostID != batch[0].HostID {
+				t.Errorf("batch mixes hosts %s and %s", batch[0].HostID, alert.HostID)
+			}
+		}
+		if _, dup := byHost[batch[0].HostID]; dup {
+			t.Errorf("%s notified in more than one batch", batch[0].HostID)
+		}
+		byHost[batch[0].HostID] = batch
+	}
+	if len(batches) != 2 || len(byHost["host-1"]) != 2 || len(byHost["host-2"]) != 1 {
+		t.Fatalf("batches %d, host-1 %d alerts, host-2 %d alerts; want 2 batches of 2 and 1",
+			len(batches), len(byHost["host-1"]), len(byHost["host-2"]))
+	}
+
+	// one rule resolving on its own is sent alone
+	batches = e.round(time.Minute, onlineHost("host-1", 50, 95), onlineHost("host-2", 95, 10))
+	if got := states(batches); len(got) != 1 || got[0] != StateResolved || batches[0][0].RuleName != "cpu" {
+		t.Errorf("cpu recovered on host-1: %v, want one resolved cpu alert", got)
+	}
+}
+
+func TestSilencedHostIsNotNotified(t *testing.T) {
+	e := newTestEngine(t, metadata.AlertRule{Name: "cpu", Metric: MetricCPUUsage, Operator: ">", Threshold: 85})
+	until := time.Now().Add(400 * time.Millisecond)
+	if _, err := e.store.SetSilence(metadata.Silence{HostID: "host-1", Until: until}); err != nil {
+		t.Fatalf("SetSilence: %v", err)
+	}
+
+	batches := e.round(0, onlineHost("host-1", 95, 10), onlineHost("host-2", 95, 10))
+	if len(batches) != 1 || len(batches[0]) != 1 || batches[0][0].HostID != "host-2" {
+		t.Fatalf("notifications %v, want only host-2's", batches)
+	}
+	if open, err := e.store.OpenAlertEvents(); err != nil || len(open) != 2 {
+		t.Errorf("open alerts %d (%v), want 2: silencing skips the message, not the alert", len(open), err)
+	}
+
+	// once the silence runs out, the silenced alert's resolve is sent
+	time.Sleep(time.Until(until) + 50*time.Millisecond)
+	batches = e.round(time.Minute, onlineHost("host-1", 50, 10), onlineHost("host-2", 95, 10))
+	if got := states(batches); len(got) != 1 || got[0] != StateResolved || batches[0][0].HostID != "host-1" {
+		t.Errorf("after the silence expired: %v, want host-1 resolved", batches)
+	}
+
+	history, _, err := e.store.AlertHistory(metadata.AlertHistoryQuery{Start: e.start.Add(-time.Hour), End: e.start.Add(time.Hour), Limit: 10})
+	if err != nil {
+		t.Fatalf("AlertHistory: %v", err)
+	}
+	silenced := map[string]bool{}
+	for _, transition := range history {
+		silenced[transition.HostID+" "+transition.State] = transition.Silenced
+	}
+	want := map[string]bool{"host-1 firing": true, "host-2 firing": false, "host-1 resolved": false}
+	if len(silenced) != len(want) {
+		t.Errorf("history %v, want %v", silenced, want)
+	}
+	for key, wantSilenced := range want {
+		if got, ok := silenced[key]; !ok || got != wantSilenced {
+			t.Errorf("history %s silenced = %v (recorded %v), want %v", key, got, ok, wantSilenced)
+		}
+	}
+}
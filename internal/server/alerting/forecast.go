@@ -0,0 +1,121 @@
+package alerting
+
+import (
+	"context"
+	"math"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+const (
+	forecastWindows   = 96 // mean windows per lookback (24h -> 15m)
+	forecastMinPoints = 6
+
+	// Reported when there is no usable upward trend, so the alert can resolve
+	// without putting +Inf into the stored event.
+	maxForecastDays = 3650
+)
+
+// Defaults for disk_full_days rules.
+const (
+	DefaultForecastLookback = 24 * time.Hour
+	DefaultForecastMinR2    = 0.8
+	DefaultForecastDays     = 7
+)
+
+type forecastCache struct {
+	fetched time.Time
+	series  []models.DiskUsageSeries
+}
+
+// forecastSamples projects when each host's root disk fills up, using the
+// rule's lookback. value is days until full, maxForecastDays when the trend is
+// flat, shrinking or too noisy (R² below the rule's minimum).
+func (e *Engine) forecastSamples(ctx context.Context, rule *metadata.AlertRule, now time.Time) ([]sample, error) {
+	lookback := rule.Lookback
+	if lookback <= 0 {
+		lookback = DefaultForecastLookback
+	}
+	cached, ok := e.forecasts[lookback]
+	if !ok || now.Sub(cached.fetched) >= e.forecastRefresh {
+		every := (lookback / forecastWindows).Truncate(time.Minute)
+		if every < time.Minute {
+			every = time.Minute
+		}
+		series, err := e.metrics.GetRootDiskUsageSeries(ctx, lookback, every)
+		if err != nil {
+			return nil, err
+		}
+		cached = forecastCache{fetched: now, series: series}
+		e.forecasts[lookback] = cached
+	}
+
+	samples := make([]sample, 0, len(cached.series))
+	for _, series := range cached.series {
+		days, fullAt, ok := forecastDiskFull(series, rule.MinR2, now)
+		if !ok {
+			days, fullAt = maxForecastDays, time.Time{}
+		}
+		samples = append(samples, sample{hostID: series.HostID, hostname: series.Hostname, value: days, projectedFull: fullAt})
+	}
+	return samples, nil
+}
+
+// forecastDiskFull fits used_gb = a + b*t by least squares and extrapolates to
+// total_gb. ok is false without enough points, an upward trend or a fit of at
+// least minR2 (log rotation sawtooth patterns fit poorly and are ignored).
+func forecastDiskFull(series models.DiskUsageSeries, minR2 float64, now time.Time) (days float64, fullAt time.Time, ok bool) {
+	n := len(series.Points)
+	if n < forecastMinPoints || series.TotalGB <= 0 {
+		return 0, time.Time{}, false
+	}
+	origin := series.Points[0].Time
+	xs, ys := make([]float64, n), make([]float64, n)
+	for i, p := range series.Points {
+		xs[i] = p.Time.Sub(origin).Seconds()
+		ys[i] = p.UsedGB
+	}
+	slope, intercept, r2 := linearFit(xs, ys)
+	if slope <= 0 || r2 < minR2 {
+		return 0, time.Time{}, false
+	}
+
+	secondsToFull := (series.TotalGB-intercept)/slope - now.Sub(origin).Seconds()
+	days = math.Max(secondsToFull/86400, 0)
+	if days > maxForecastDays {
+		return 0, time.Time{}, false
+	}
+	appLogger.Debug("Alerting: %s root disk grows %.3f GB/day (R² %.2f), full in %.1f days", series.HostID, slope*86400, r2, days)
+	return days, now.Add(time.Duration(secondsToFull * float64(time.Second))).UTC(), true
+}
+
+// linearFit returns the least squares line y = intercept + slope*x and its R².
+func linearFit(xs, ys []float64) (slope, intercept, r2 float64) {
+	n := float64(len(xs))
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return 0, meanY, 0
+	}
+	slope = sxy / sxx
+	intercept = meanY - slope*meanX
+	if syy == 0 {
+		return slope, intercept, 1 // perfectly flat
+	}
+	return slope, intercept, (sxy * sxy) / (sxx * syy)
+}
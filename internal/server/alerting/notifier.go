@@ -0,0 +1,123 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+)
+
+// Notifier delivers an alert state change somewhere.
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, alert Alert) error
+}
+
+// BatchNotifier is implemented by notifiers that can combine several alerts
+// for the same host into one message.
+type BatchNotifier interface {
+	Notifier
+	SendBatch(ctx context.Context, alerts []Alert) error // all alerts share one HostID
+}
+
+// SendAll delivers alerts through n, grouped per host for BatchNotifiers.
+func SendAll(ctx context.Context, n Notifier, alerts []Alert) error {
+	batcher, ok := n.(BatchNotifier)
+	if !ok {
+		var errs []error
+		for _, alert := range alerts {
+			if err := n.Send(ctx, alert); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	var hostOrder []string
+	byHost := make(map[string][]Alert)
+	for _, alert := range alerts {
+		if _, ok := byHost[alert.HostID]; !ok {
+			hostOrder = append(hostOrder, alert.HostID)
+		}
+		byHost[alert.HostID] = append(byHost[alert.HostID], alert)
+	}
+	var errs []error
+	for _, hostID := range hostOrder {
+		if err := batcher.SendBatch(ctx, byHost[hostID]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewNotifier builds the notifiers listed in cfg.Notifiers ("webhook", "slack",
+// "email"). Several can be combined; an empty list returns nil (log only).
+// With no list but webhook URLs configured, the webhook notifier is used.
+func NewNotifier(cfg config.AlertingConfig) (Notifier, error) {
+	names := cfg.Notifiers
+	if len(names) == 0 && len(cfg.WebhookURLs) > 0 {
+		names = []string{"webhook"}
+	}
+	var notifiers []Notifier
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "webhook":
+			// no default URLs is fine: only rules with their own webhook_urls are posted
+			notifiers = append(notifiers, NewWebhookNotifier(cfg.WebhookURLs, cfg.WebhookSecret, cfg.WebhookMaxRetries))
+		case "slack":
+			if cfg.SlackWebhookURL == "" && len(cfg.SlackChannels) == 0 {
+				return nil, fmt.Errorf("slack notifier needs ALERT_SLACK_WEBHOOK_URL or ALERT_SLACK_CHANNELS")
+			}
+			notifiers = append(notifiers, NewSlackNotifier(cfg.SlackWebhookURL, cfg.SlackChannels, cfg.DashboardURL))
+		case "email":
+			if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+				return nil, fmt.Errorf("email notifier needs ALERT_SMTP_HOST and ALERT_SMTP_FROM")
+			}
+			switch cfg.SMTP.TLSMode {
+			case SMTPStartTLS, SMTPSSL, SMTPNone:
+			default:
+				return nil, fmt.Errorf("unknown ALERT_SMTP_TLS mode %q (expected starttls, ssl or none)", cfg.SMTP.TLSMode)
+			}
+			notifiers = append(notifiers, NewEmailNotifier(cfg.SMTP))
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown notifier %q", name)
+		}
+	}
+
+	switch len(notifiers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return notifiers[0], nil
+	}
+	return multiNotifier(notifiers), nil
+}
+
+// multiNotifier fans out to every configured notifier and joins their errors.
+type multiNotifier []Notifier
+
+func (m multiNotifier) Name() string {
+	names := make([]string, len(m))
+	for i, n := range m {
+		names[i] = n.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+func (m multiNotifier) Send(ctx context.Context, alert Alert) error {
+	return m.SendBatch(ctx, []Alert{alert})
+}
+
+// SendBatch lets batching notifiers in the list batch, the others get one call per alert.
+func (m multiNotifier) SendBatch(ctx context.Context, alerts []Alert) error {
+	var errs []error
+	for _, n := range m {
+		if err := SendAll(ctx, n, alerts); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
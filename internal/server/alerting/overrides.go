@@ -0,0 +1,109 @@
+package alerting
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+)
+
+// Override sources, in the order they are applied.
+const (
+	SourceRule  = "rule"
+	SourceGroup = "group:" // + group name
+	SourceHost  = "host"
+)
+
+// ResolvedRule is a rule as it applies to one host.
+type ResolvedRule struct {
+	metadata.AlertRule
+	Applies  bool     `json:"applies"`  // the rule's host_ids/group filter matches the host
+	Disabled bool     `json:"disabled"` // an override turned the rule off for the host
+	Sources  []string `json:"sources"`  // what shaped the result: "rule", "group:<name>", "host"
+}
+
+// ResolveRule applies the rule's overrides for hostID: group overrides in
+// group name order, then the host override, so the result doesn't depend on
+// how the overrides are listed.
+func ResolveRule(rule *metadata.AlertRule, hostID string, hostGroups []string) ResolvedRule {
+	resolved := ResolvedRule{
+		AlertRule: *rule,
+		Applies:   appliesTo(rule, hostID, hostGroups),
+		Sources:   []string{SourceRule},
+	}
+	resolved.Overrides = nil
+
+	var groupOverrides []metadata.RuleOverride
+	var hostOverride *metadata.RuleOverride
+	for i, o := range rule.Overrides {
+		switch {
+		case o.HostID != "" && o.HostID == hostID:
+			hostOverride = &rule.Overrides[i]
+		case o.Group != "" && contains(hostGroups, o.Group):
+			groupOverrides = append(groupOverrides, o)
+		}
+	}
+	sort.SliceStable(groupOverrides, func(i, j int) bool { return groupOverrides[i].Group < groupOverrides[j].Group })
+
+	for _, o := range groupOverrides {
+		applyOverride(&resolved, &o)
+		resolved.Sources = append(resolved.Sources, SourceGroup+o.Group)
+	}
+	if hostOverride != nil {
+		applyOverride(&resolved, hostOverride)
+		resolved.Sources = append(resolved.Sources, SourceHost)
+	}
+	return resolved
+}
+
+func applyOverride(resolved *ResolvedRule, o *metadata.RuleOverride) {
+	resolved.Disabled = o.Disabled
+	if o.Threshold != nil {
+		resolved.Threshold = *o.Threshold
+	}
+	if o.ClearThreshold != nil {
+		resolved.ClearThreshold = o.ClearThreshold
+	}
+	if len(o.WebhookURLs) > 0 {
+		resolved.WebhookURLs = o.WebhookURLs
+	}
+	if o.SlackChannel != "" {
+		resolved.SlackChannel = o.SlackChannel
+	}
+	if len(o.EmailTo) > 0 {
+		resolved.EmailTo = o.EmailTo
+	}
+}
+
+// validateOverrides checks each override on its own against the rule.
+func validateOverrides(rule *metadata.AlertRule) error {
+	seen := make(map[string]bool)
+	for _, o := range rule.Overrides {
+		if (o.HostID == "") == (o.Group == "") {
+			return fmt.Errorf("each override needs exactly one of host_id and group")
+		}
+		target := "host " + o.HostID
+		if o.Group != "" {
+			target = "group " + o.Group
+		}
+		if seen[target] {
+			return fmt.Errorf("more than one override for %s", target)
+		}
+		seen[target] = true
+
+		threshold, clear := rule.Threshold, rule.ClearThreshold
+		if o.Threshold != nil {
+			threshold = *o.Threshold
+		}
+		if o.ClearThreshold != nil {
+			clear = o.ClearThreshold
+		}
+		if err := validateClearThreshold(rule.Operator, threshold, clear); err != nil {
+			return fmt.Errorf("override for %s: %w", target, err)
+		}
+		if err := validateRouting(o.EmailTo, o.WebhookURLs); err != nil {
+			return fmt.Errorf("override for %s: %w", target, err)
+		}
+	}
+	return nil
+}
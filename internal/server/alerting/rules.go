@@ -0,0 +1,158 @@
+package alerting
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+)
+
+// Metrics a rule can watch.
+const (
+	MetricCPUUsage    = "cpu_usage"    // percent
+	MetricMemUsage    = "mem_usage"    // percent
+	MetricDiskUsage   = "disk_usage"   // root disk, percent
+	MetricHostOffline = "host_offline" // seconds since the host last reported
+	// Days until the root disk is full, projected from its used_gb trend
+	MetricDiskFullDays = "disk_full_days"
+)
+
+var validMetrics = map[string]bool{
+	MetricCPUUsage:     true,
+	MetricMemUsage:     true,
+	MetricDiskUsage:    true,
+	MetricHostOffline:  true,
+	MetricDiskFullDays: true,
+}
+
+// DefaultCooldown is used for new rules that don't set one.
+const DefaultCooldown = 5 * time.Minute
+
+var validOperators = map[string]bool{">": true, ">=": true, "<": true, "<=": true}
+
+// ValidateRule checks a rule before it is stored and fills defaults.
+// host_offline rules default to "> offlineAfter" when no threshold is given,
+// disk_full_days rules to "< 7" days over a 24h lookback with R² >= 0.8.
+func ValidateRule(rule *metadata.AlertRule, offlineAfter time.Duration) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	if !validMetrics[rule.Metric] {
+		return fmt.Errorf("unknown metric %q (expected cpu_usage, mem_usage, disk_usage, host_offline or disk_full_days)", rule.Metric)
+	}
+	if rule.Metric == MetricDiskFullDays {
+		if rule.Operator == "" {
+			rule.Operator = "<"
+		}
+		if rule.Threshold == 0 {
+			rule.Threshold = DefaultForecastDays
+		}
+		if rule.Lookback == 0 {
+			rule.Lookback = DefaultForecastLookback
+		}
+		if rule.Lookback < time.Hour {
+			return fmt.Errorf("lookback must be at least 1h")
+		}
+		if rule.MinR2 == 0 {
+			rule.MinR2 = DefaultForecastMinR2
+		}
+		if rule.MinR2 < 0 || rule.MinR2 > 1 {
+			return fmt.Errorf("min_r2 must be between 0 and 1")
+		}
+	}
+	if rule.Metric == MetricHostOffline {
+		if rule.Operator == "" {
+			rule.Operator = ">"
+		}
+		if rule.Threshold == 0 {
+			rule.Threshold = offlineAfter.Seconds()
+		}
+	}
+	if !validOperators[rule.Operator] {
+		return fmt.Errorf("unknown operator %q (expected >, >=, < or <=)", rule.Operator)
+	}
+	if rule.For < 0 {
+		return fmt.Errorf("for duration must not be negative")
+	}
+	if rule.Cooldown < 0 {
+		return fmt.Errorf("cooldown must not be negative")
+	}
+	if err := validateClearThreshold(rule.Operator, rule.Threshold, rule.ClearThreshold); err != nil {
+		return err
+	}
+	if err := validateRouting(rule.EmailTo, rule.WebhookURLs); err != nil {
+		return err
+	}
+	return validateOverrides(rule)
+}
+
+func validateClearThreshold(operator string, threshold float64, clear *float64) error {
+	if clear == nil {
+		return nil
+	}
+	above := operator == ">" || operator == ">="
+	if (above && *clear > threshold) || (!above && *clear < threshold) {
+		return fmt.Errorf("clear threshold %.2f must be on the non-alerting side of threshold %s %.2f", *clear, operator, threshold)
+	}
+	return nil
+}
+
+func validateRouting(emailTo, webhookURLs []string) error {
+	for _, addr := range emailTo {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid email address %q", addr)
+		}
+	}
+	for _, raw := range webhookURLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL %q", raw)
+		}
+	}
+	return nil
+}
+
+// conditionMet compares value against the rule's threshold. For an alert that
+// is already firing the clear threshold is used instead when the rule has one,
+// so e.g. "> 85, clear 80" keeps firing until the value drops to 80.
+func conditionMet(rule *metadata.AlertRule, value float64, firing bool) bool {
+	threshold := rule.Threshold
+	if firing && rule.ClearThreshold != nil {
+		threshold = *rule.ClearThreshold
+	}
+	switch rule.Operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	}
+	return false
+}
+
+// appliesTo reports whether the rule's host filter matches hostID.
+func appliesTo(rule *metadata.AlertRule, hostID string, hostGroups []string) bool {
+	if len(rule.HostIDs) > 0 && !contains(rule.HostIDs, hostID) {
+		return false
+	}
+	if rule.Group != "" && !contains(hostGroups, rule.Group) {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+)
+
+const (
+	slackColorFiring   = "#d92d20"
+	slackColorResolved = "#12b76a"
+
+	// Slack allows about one message per second per incoming webhook.
+	slackMinInterval   = 1 * time.Second
+	slackMaxRetryAfter = 30 * time.Second
+)
+
+// SlackNotifier posts to Slack incoming webhooks. Alerts for the same host
+// sent together (one evaluation round) become a single message per channel.
+type SlackNotifier struct {
+	defaultURL   string
+	channels     map[string]string // name -> webhook URL
+	dashboardURL string            // base for "view host" links, no links when empty
+	client       *http.Client
+
+	mu       sync.Mutex
+	nextSend map[string]time.Time // webhook URL -> earliest time of the next post
+}
+
+func NewSlackNotifier(defaultURL string, channels map[string]string, dashboardURL string) *SlackNotifier {
+	return &SlackNotifier{
+		defaultURL:   defaultURL,
+		channels:     channels,
+		dashboardURL: strings.TrimRight(dashboardURL, "/"),
+		client:       &http.Client{Timeout: 10 * time.Second},
+		nextSend:     make(map[string]time.Time),
+	}
+}
+
+func (s *SlackNotifier) Name() string { return "slack" }
+
+func (s *SlackNotifier) Send(ctx context.Context, alert Alert) error {
+	return s.SendBatch(ctx, []Alert{alert})
+}
+
+// SendBatch posts one message per destination channel for alerts of one host.
+func (s *SlackNotifier) SendBatch(ctx context.Context, alerts []Alert) error {
+	var order []string
+	byURL := make(map[string][]Alert)
+	for _, alert := range alerts {
+		webhookURL := s.webhookFor(alert)
+		if webhookURL == "" {
+			continue
+		}
+		if _, ok := byURL[webhookURL]; !ok {
+			order = append(order, webhookURL)
+		}
+		byURL[webhookURL] = append(byURL[webhookURL], alert)
+	}
+
+	var errs []error
+	for _, webhookURL := range order {
+		body, err := json.Marshal(s.message(byURL[webhookURL]))
+		if err != nil {
+			return fmt.Errorf("marshal slack message: %w", err)
+		}
+		if err := s.post(ctx, webhookURL, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// webhookFor resolves the rule's channel, falling back to the default one.
+func (s *SlackNotifier) webhookFor(alert Alert) string {
+	if alert.SlackChannel != "" {
+		if webhookURL, ok := s.channels[alert.SlackChannel]; ok {
+			return webhookURL
+		}
+		appLogger.Warn("Slack channel %q of rule %s is not configured, using the default channel", alert.SlackChannel, alert.RuleName)
+	}
+	return s.defaultURL
+}
+
+// post waits for the webhook's rate limit slot and retries once on 429.
+func (s *SlackNotifier) post(ctx context.Context, webhookURL string, body []byte) error {
+	for attempt := 0; ; attempt++ {
+		if err := s.waitTurn(ctx, webhookURL); err != nil {
+			return err
+		}
+		err := postJSON(ctx, s.client, webhookURL, body, nil)
+		var se *statusError
+		if attempt > 0 || !errors.As(err, &se) || se.code != http.StatusTooManyRequests {
+			return err
+		}
+		wait := min(max(se.retryAfter, slackMinInterval), slackMaxRetryAfter)
+		appLogger.Warn("Slack rate limited the alert webhook, retrying in %s", wait)
+		s.mu.Lock()
+		s.nextSend[webhookURL] = time.Now().Add(wait)
+		s.mu.Unlock()
+	}
+}
+
+// waitTurn blocks until webhookURL may be posted to again and reserves the slot.
+func (s *SlackNotifier) waitTurn(ctx context.Context, webhookURL string) error {
+	s.mu.Lock()
+	now := time.Now()
+	at := s.nextSend[webhookURL]
+	if at.Before(now) {
+		at = now
+	}
+	s.nextSend[webhookURL] = at.Add(slackMinInterval)
+	s.mu.Unlock()
+
+	if wait := time.Until(at); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return nil
+}
+
+type slackPayload struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color     string       `json:"color"`
+	Title     string       `json:"title"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Fields    []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// message formats alerts of one host as a Slack message, one colored attachment per alert.
+func (s *SlackNotifier) message(alerts []Alert) slackPayload {
+	host := alerts[0].Hostname
+	if host == "" {
+		host = alerts[0].HostID
+	}
+	payload := slackPayload{Text: alerts[0].Summary()}
+	if len(alerts) > 1 {
+		payload.Text = fmt.Sprintf("%d alerts changed on %s", len(alerts), host)
+	}
+
+	link := ""
+	if s.dashboardURL != "" {
+		link = s.dashboardURL + "/host/" + url.PathEscape(alerts[0].HostID)
+	}
+	for _, alert := range alerts {
+		color, status := slackColorFiring, "FIRING"
+		if alert.State == StateResolved {
+			color, status = slackColorResolved, "RESOLVED"
+		}
+		since := alert.StartsAt.UTC().Format(time.RFC3339)
+		if !alert.EndsAt.IsZero() {
+			since += " - " + alert.EndsAt.UTC().Format(time.RFC3339)
+		}
+		payload.Attachments = append(payload.Attachments, slackAttachment{
+			Color:     color,
+			Title:     fmt.Sprintf("[%s] %s", status, alert.RuleName),
+			TitleLink: link,
+			Fields: []slackField{
+				{Title: "Host", Value: host, Short: true},
+				{Title: "Metric", Value: alert.Metric, Short: true},
+				{Title: "Value", Value: fmt.Sprintf("%.2f", alert.Value), Short: true},
+				{Title: "Threshold", Value: fmt.Sprintf("%s %.2f", alert.Operator, alert.Threshold), Short: true},
+				{Title: "Time", Value: since, Short: false},
+			},
+		})
+		if !alert.LastSeen.IsZero() {
+			attachment := &payload.Attachments[len(payload.Attachments)-1]
+			attachment.Fields = append(attachment.Fields, slackField{Title: "Last seen", Value: alert.LastSeen.UTC().Format(time.RFC3339), Short: false})
+		}
+		if !alert.ProjectedFullAt.IsZero() {
+			attachment := &payload.Attachments[len(payload.Attachments)-1]
+			attachment.Fields = append(attachment.Fields, slackField{Title: "Projected full", Value: alert.ProjectedFullAt.UTC().Format("2006-01-02 15:04 UTC"), Short: false})
+		}
+	}
+	return payload
+}
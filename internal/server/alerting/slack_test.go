@@ -0,0 +1,159 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slackReceiver is a Slack incoming webhook keeping the messages it gets.
+type slackReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	messages []slackPayload
+}
+
+func newSlackReceiver(t *testing.T) *slackReceiver {
+	t.Helper()
+	r := &slackReceiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var msg slackPayload
+		if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+			t.Errorf("undecodable slack message: %v", err)
+		}
+		r.mu.Lock()
+		r.messages = append(r.messages, msg)
+		r.mu.Unlock()
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *slackReceiver) received() []slackPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]slackPayload(nil), r.messages...)
+}
+
+var testStart = time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+func testAlert(state State) Alert {
+	alert := Alert{
+		RuleID:    "rule-1",
+		RuleName:  "High CPU",
+		HostID:    "host 1",
+		Hostname:  "web-1",
+		Metric:    MetricCPUUsage,
+		Operator:  ">",
+		Threshold: 90,
+		Value:     97.456,
+		State:     state,
+		StartsAt:  testStart,
+	}
+	if state == StateResolved {
+		alert.Value = 40
+		alert.EndsAt = testStart.Add(10 * time.Minute)
+	}
+	return alert
+}
+
+func fieldValues(a slackAttachment) map[string]string {
+	values := make(map[string]string)
+	for _, f := range a.Fields {
+		values[f.Title] = f.Value
+	}
+	return values
+}
+
+func TestSlackMessageFormatting(t *testing.T) {
+	receiver := newSlackReceiver(t)
+	slack := NewSlackNotifier(receiver.URL, nil, "https://dash.example.com/")
+
+	if err := slack.Send(context.Background(), testAlert(StateFiring)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	messages := receiver.received()
+	if len(messages) != 1 {
+		t.Fatalf("messages = %d, want 1", len(messages))
+	}
+	msg := messages[0]
+	if want := "[FIRING] High CPU on web-1: cpu_usage is 97.46 (threshold > 90.00)"; msg.Text != want {
+		t.Errorf("text = %q, want %q", msg.Text, want)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("attachments = %d, want 1", len(msg.Attachments))
+	}
+	attachment := msg.Attachments[0]
+	if attachment.Color != slackColorFiring || attachment.Title != "[FIRING] High CPU" {
+		t.Errorf("attachment color %s title %q, want firing red and [FIRING] High CPU", attachment.Color, attachment.Title)
+	}
+	if attachment.TitleLink != "https://dash.example.com/host/host%201" {
+		t.Errorf("title link = %q, want the escaped host details link", attachment.TitleLink)
+	}
+	want := map[string]string{
+		"Host":      "web-1",
+		"Metric":    "cpu_usage",
+		"Value":     "97.46",
+		"Threshold": "> 90.00",
+		"Time":      "2026-03-04T05:06:07Z",
+	}
+	if got := fieldValues(attachment); len(got) != len(want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	} else {
+		for title, value := range want {
+			if got[title] != value {
+				t.Errorf("field %s = %q, want %q", title, got[title], value)
+			}
+		}
+	}
+}
+
+func TestSlackBatchesAlertsOfOneHost(t *testing.T) {
+	receiver := newSlackReceiver(t)
+	slack := NewSlackNotifier(receiver.URL, nil, "")
+
+	memory := testAlert(StateResolved)
+	memory.RuleName, memory.Metric = "High memory", MetricMemUsage
+	if err := SendAll(context.Background(), slack, []Alert{testAlert(StateFiring), memory}); err != nil {
+		t.Fatalf("SendAll: %v", err)
+	}
+	messages := receiver.received()
+	if len(messages) != 1 {
+		t.Fatalf("messages = %d, want one for both alerts of the host", len(messages))
+	}
+	msg := messages[0]
+	if msg.Text != "2 alerts changed on web-1" || len(msg.Attachments) != 2 {
+		t.Fatalf("text %q with %d attachments, want a summary of 2", msg.Text, len(msg.Attachments))
+	}
+	resolved := msg.Attachments[1]
+	if resolved.Color != slackColorResolved || resolved.Title != "[RESOLVED] High memory" || resolved.TitleLink != "" {
+		t.Errorf("resolved attachment = %+v, want green, [RESOLVED] and no link without a dashboard URL", resolved)
+	}
+	if got := fieldValues(resolved)["Time"]; got != "2026-03-04T05:06:07Z - 2026-03-04T05:16:07Z" {
+		t.Errorf("resolved time = %q, want the firing period", got)
+	}
+}
+
+func TestSlackRoutesRuleChannels(t *testing.T) {
+	fallback, ops := newSlackReceiver(t), newSlackReceiver(t)
+	slack := NewSlackNotifier(fallback.URL, map[string]string{"ops": ops.URL}, "")
+
+	routed := testAlert(StateFiring)
+	routed.SlackChannel = "ops"
+	unknown := testAlert(StateFiring)
+	unknown.RuleName, unknown.SlackChannel = "Unknown channel", "nope"
+	if err := slack.SendBatch(context.Background(), []Alert{routed, unknown}); err != nil {
+		t.Fatalf("SendBatch: %v", err)
+	}
+	if got := len(ops.received()); got != 1 {
+		t.Errorf("ops channel messages = %d, want 1", got)
+	}
+	if got := fallback.received(); len(got) != 1 || got[0].Attachments[0].Title != "[FIRING] Unknown channel" {
+		t.Errorf("default channel messages = %+v, want the alert of the unknown channel", got)
+	}
+}
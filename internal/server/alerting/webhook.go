@@ -0,0 +1,161 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+)
+
+const (
+	// SignatureHeader carries "sha256=<hex HMAC-SHA256 of the body>" when a secret is set.
+	SignatureHeader = "X-Signature-256"
+
+	webhookBaseBackoff = 1 * time.Second
+	// webhookMaxRetryAfter caps the wait a receiver's Retry-After can ask for.
+	webhookMaxRetryAfter = 1 * time.Minute
+)
+
+// WebhookNotifier POSTs the alert as JSON to one or more URLs, retrying
+// transient failures with exponential backoff.
+type WebhookNotifier struct {
+	urls       []string // default destinations, a rule's webhook_urls replace them
+	secret     []byte
+	maxRetries int
+	client     *http.Client
+
+	baseBackoff time.Duration // first retry delay, doubled after each attempt
+}
+
+func NewWebhookNotifier(urls []string, secret string, maxRetries int) *WebhookNotifier {
+	return &WebhookNotifier{
+		urls:       urls,
+		secret:     []byte(secret),
+		maxRetries: maxRetries,
+		client:     &http.Client{Timeout: 10 * time.Second},
+
+		baseBackoff: webhookBaseBackoff,
+	}
+}
+
+func (w *WebhookNotifier) Name() string { return "webhook" }
+
+func (w *WebhookNotifier) Send(ctx context.Context, alert Alert) error {
+	urls := w.urls
+	if len(alert.WebhookURLs) > 0 {
+		urls = alert.WebhookURLs
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("marshal alert: %w", err)
+	}
+	headers := map[string]string{}
+	if len(w.secret) > 0 {
+		headers[SignatureHeader] = Sign(w.secret, body)
+	}
+
+	var errs []error
+	for _, url := range urls {
+		if err := w.postWithRetry(ctx, url, body, headers); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sign returns the signature header value for body, receivers recompute it
+// with the shared secret and compare with hmac.Equal.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWithRetry posts body to url, retrying with exponential backoff. A 429
+// waits at least as long as its Retry-After asks, up to webhookMaxRetryAfter.
+func (w *WebhookNotifier) postWithRetry(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	backoff := w.baseBackoff
+	for attempt := 0; ; attempt++ {
+		err := postJSON(ctx, w.client, url, body, headers)
+		if err == nil || attempt >= w.maxRetries || !retryable(err) {
+			return err
+		}
+		wait := backoff
+		var se *statusError
+		if errors.As(err, &se) && se.code == http.StatusTooManyRequests {
+			wait = max(wait, min(se.retryAfter, webhookMaxRetryAfter))
+		}
+		appLogger.Warn("Webhook %s failed (attempt %d/%d), retrying in %s: %v", url, attempt+1, w.maxRetries+1, wait, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("post to %s: %w (last error: %v)", url, ctx.Err(), err)
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// statusError is a non-2xx response.
+type statusError struct {
+	url        string
+	code       int
+	status     string
+	body       []byte
+	retryAfter time.Duration // from the Retry-After header, 0 if absent
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("post to %s: status %s: %s", e.url, e.status, e.body)
+}
+
+// retryable: network errors, 429 and 5xx. Other 4xx won't get better by retrying.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	return true
+}
+
+// postJSON sends body and treats any non-2xx response as an error.
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request to %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		se := &statusError{url: url, code: resp.StatusCode, status: resp.Status, body: snippet}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			se.retryAfter = time.Duration(seconds) * time.Second
+		}
+		return se
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package alerting
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver answers POSTs with the scripted statuses in turn (200 once
+// they run out) and keeps every request it gets.
+type webhookReceiver struct {
+	*httptest.Server
+	mu         sync.Mutex
+	statuses   []int
+	retryAfter string // Retry-After header sent with a 429
+	requests   []webhookRequest
+}
+
+type webhookRequest struct {
+	at        time.Time
+	body      []byte
+	signature string
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	t.Helper()
+	r := &webhookReceiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, webhookRequest{at: time.Now(), body: body, signature: req.Header.Get(SignatureHeader)})
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.mu.Unlock()
+		if status == http.StatusTooManyRequests && r.retryAfter != "" {
+			w.Header().Set("Retry-After", r.retryAfter)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *webhookReceiver) received() []webhookRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]webhookRequest(nil), r.requests...)
+}
+
+// newTestWebhook is a webhook notifier with millisecond backoff.
+func newTestWebhook(urls []string, secret string, maxRetries int) *WebhookNotifier {
+	w := NewWebhookNotifier(urls, secret, maxRetries)
+	w.baseBackoff = 10 * time.Millisecond
+	return w
+}
+
+func TestWebhookSignature(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	if err := newTestWebhook([]string{receiver.URL}, "s3cret", 0).Send(context.Background(), testAlert(StateFiring)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("requests %d, want 1", len(requests))
+	}
+
+	// what a receiver does: recompute the HMAC over the raw body
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(requests[0].body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(requests[0].signature), []byte(want)) {
+		t.Errorf("signature %q, want %q", requests[0].signature, want)
+	}
+	var alert Alert
+	if err := json.Unmarshal(requests[0].body, &alert); err != nil || alert.RuleName != "High CPU" || alert.HostID != "host 1" {
+		t.Errorf("body %s (%v), want the alert as JSON", requests[0].body, err)
+	}
+
+	// without a secret there is no header to forge
+	receiver = newWebhookReceiver(t)
+	if err := newTestWebhook([]string{receiver.URL}, "", 0).Send(context.Background(), testAlert(StateFiring)); err != nil {
+		t.Fatalf("Send without a secret: %v", err)
+	}
+	if got := receiver.received(); len(got) != 1 || got[0].signature != "" {
+		t.Errorf("without a secret: signature %q, want none", got[0].signature)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+		attempts int
+	}{
+		{"5xx then success", []int{500, 503}, false, 3},
+		{"429 then success", []int{429}, false, 2},
+		{"5xx until the retries run out", []int{502, 502, 502, 502, 502}, true, 4},
+		{"400 is not retried", []int{400}, true, 1},
+		{"404 is not retried", []int{404}, true, 1},
+		{"401 is not retried", []int{401}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := newWebhookReceiver(t, tt.statuses...)
+			err := newTestWebhook([]string{receiver.URL}, "", 3).Send(context.Background(), testAlert(StateFiring))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send error %v, want error %v", err, tt.wantErr)
+			}
+			requests := receiver.received()
+			if len(requests) != tt.attempts {
+				t.Fatalf("attempts %d, want %d", len(requests), tt.attempts)
+			}
+			// the backoff doubles: 10ms, 20ms, 40ms
+			for i := 1; i < len(requests); i++ {
+				want := 10 * time.Millisecond << (i - 1)
+				if gap := requests[i].at.Sub(requests[i-1].at); gap < want {
+					t.Errorf("gap before attempt %d = %s, want at least %s", i+1, gap, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookHonorsRetryAfter(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusTooManyRequests)
+	receiver.retryAfter = "1"
+	if err := newTestWebhook([]string{receiver.URL}, "", 3).Send(context.Background(), testAlert(StateFiring)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	requests := receiver.received()
+	if len(requests) != 2 {
+		t.Fatalf("attempts %d, want 2", len(requests))
+	}
+	if gap := requests[1].at.Sub(requests[0].at); gap < time.Second {
+		t.Errorf("retried after %s, want at least the Retry-After of 1s", gap)
+	}
+
+	// the context still bounds the wait
+	receiver = newWebhookReceiver(t, http.StatusTooManyRequests)
+	receiver.retryAfter = "30"
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := newTestWebhook([]string{receiver.URL}, "", 3).Send(ctx, testAlert(StateFiring)); err == nil {
+		t.Error("Send succeeded, want the context's deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Send took %s, want it to give up with the context", elapsed)
+	}
+}
+
+func TestWebhookRuleURLsReplaceTheDefaults(t *testing.T) {
+	defaults := newWebhookReceiver(t)
+	ruleA, ruleB := newWebhookReceiver(t), newWebhookReceiver(t)
+	webhook := newTestWebhook([]string{defaults.URL}, "", 0)
+
+	alert := testAlert(StateFiring)
+	alert.WebhookURLs = []string{ruleA.URL, ruleB.URL}
+	if err := webhook.Send(context.Background(), alert); err != nil {
+		t.Fatalf("Send with rule URLs: %v", err)
+	}
+	if len(defaults.received()) != 0 || len(ruleA.received()) != 1 || len(ruleB.received()) != 1 {
+		t.Errorf("default %d, rule A %d, rule B %d requests; want only the rule's URLs, once each",
+			len(defaults.received()), len(ruleA.received()), len(ruleB.received()))
+	}
+
+	if err := webhook.Send(context.Background(), testAlert(StateResolved)); err != nil {
+		t.Fatalf("Send without rule URLs: %v", err)
+	}
+	if len(defaults.received()) != 1 {
+		t.Errorf("default requests %d, want 1 for an alert without rule URLs", len(defaults.received()))
+	}
+
+	// one failing URL doesn't keep the alert from the others
+	failing := newWebhookReceiver(t, http.StatusBadRequest)
+	alert.WebhookURLs = []string{failing.URL, ruleA.URL}
+	if err := webhook.Send(context.Background(), alert); err == nil {
+		t.Error("Send with a failing URL: no error")
+	}
+	if len(ruleA.received()) != 2 {
+		t.Errorf("rule A requests %d, want 2", len(ruleA.received()))
+	}
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/alerting"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createAlertRuleRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Metric    string   `json:"metric" binding:"required"`
+	Operator  string   `json:"operator"`
+	Threshold float64  `json:"threshold"`
+	For       string   `json:"for_duration"` // e.g. "5m", empty = fire on the first match
+	HostIDs   []string `json:"host_ids"`
+	Group     string   `json:"group"`
+
+	// Firing alerts resolve only past this value, e.g. fire > 85, clear at 80
+	ClearThreshold *float64 `json:"clear_threshold"`
+	Cooldown       string   `json:"cooldown"` // min time between notifications per host, empty = alerting.DefaultCooldown, "0s" = off
+
+	// disk_full_days rules only, empty/0 = defaults
+	Lookback string  `json:"lookback"` // e.g. "24h"
+	MinR2    float64 `json:"min_r2"`
+
+	WebhookURLs  []string `json:"webhook_urls"`  // overrides ALERT_WEBHOOK_URLS for this rule
+	SlackChannel string   `json:"slack_channel"` // ALERT_SLACK_CHANNELS name, empty = default
+	EmailTo      []string `json:"email_to"`      // overrides ALERT_SMTP_TO for this rule
+
+	Overrides []metadata.RuleOverride `json:"overrides"` // per-host/per-group exceptions
+}
+
+// ListAlerts handles GET /api/dashboard/alerts?range=24h
+// Returns open (pending/firing) alerts and alerts resolved within the range.
+func (h *DashboardHandler) ListAlerts(c *gin.Context) {
+	start, _, err := parseTimeRange(c, "24h")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := h.metaStore.ListAlertEvents(start)
+	if err != nil {
+		appLogger.Error("Failed to list alerts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alerts"})
+		return
+	}
+	active, recent := []metadata.AlertEvent{}, []metadata.AlertEvent{}
+	for _, e := range events {
+		if e.State == metadata.AlertResolved {
+			recent = append(recent, e)
+		} else {
+			active = append(active, e)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"active": active, "recent": recent})
+}
+
+const (
+	defaultHistoryPageSize = 100
+	maxHistoryPageSize     = 1000
+)
+
+// ListAlertHistory handles GET /api/dashboard/alerts/history?host=<id>&state=firing&range=30d&limit=100&offset=0
+// Returns firing/resolved transitions newest first, plus the total number of
+// matches so clients can page and count ("how often did this host page us").
+func (h *DashboardHandler) ListAlertHistory(c *gin.Context) {
+	start, end, err := parseTimeRange(c, "7d")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	state := c.Query("state")
+	if state != "" && state != metadata.AlertFiring && state != metadata.AlertResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state must be firing or resolved"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultHistoryPageSize)))
+	if err != nil || limit < 1 || limit > maxHistoryPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxHistoryPageSize)})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+		return
+	}
+
+	transitions, total, err := h.metaStore.AlertHistory(metadata.AlertHistoryQuery{
+		Start:  start,
+		End:    end,
+		HostID: c.Query("host"),
+		State:  state,
+		Offset: offset,
+		Limit:  limit,
+	})
+	if err != nil {
+		appLogger.Error("Failed to read alert history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"transitions": transitions,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// ListAlertRules handles GET /api/dashboard/alert-rules
+func (h *DashboardHandler) ListAlertRules(c *gin.Context) {
+	rules, err := h.metaStore.ListAlertRules()
+	if err != nil {
+		appLogger.Error("Failed to list alert rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert rules"})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateAlertRule handles POST /api/dashboard/alert-rules
+func (h *DashboardHandler) CreateAlertRule(c *gin.Context) {
+	var req createAlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule payload", "details": err.Error()})
+		return
+	}
+
+	rule := metadata.AlertRule{
+		Name:      req.Name,
+		Metric:    req.Metric,
+		Operator:  req.Operator,
+		Threshold: req.Threshold,
+		HostIDs:   req.HostIDs,
+
+		ClearThreshold: req.ClearThreshold,
+		Cooldown:       alerting.DefaultCooldown,
+		MinR2:          req.MinR2,
+		Group:          req.Group,
+
+		WebhookURLs:  req.WebhookURLs,
+		SlackChannel: req.SlackChannel,
+		EmailTo:      req.EmailTo,
+		Overrides:    req.Overrides,
+	}
+	if req.For != "" {
+		forDuration, err := time.ParseDuration(req.For)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid for_duration format"})
+			return
+		}
+		rule.For = forDuration
+	}
+	if req.Cooldown != "" {
+		cooldown, err := time.ParseDuration(req.Cooldown)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cooldown format"})
+			return
+		}
+		rule.Cooldown = cooldown
+	}
+	if req.Lookback != "" {
+		lookback, err := parseRangeDuration(req.Lookback)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lookback format"})
+			return
+		}
+		rule.Lookback = lookback
+	}
+	if err := alerting.ValidateRule(&rule, h.hostOfflineAfter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.metaStore.CreateAlertRule(rule)
+	if err != nil {
+		appLogger.Error("Failed to create alert rule %q: %v", rule.Name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert rule"})
+		return
+	}
+	appLogger.Info("Created alert rule %s (%s %s %.2f for %s)", created.Name, created.Metric, created.Operator, created.Threshold, created.For)
+	c.JSON(http.StatusCreated, created)
+}
+
+// SetAlertRuleOverrides handles PUT /api/dashboard/alert-rules/:ruleID/overrides
+// The body is the complete list of overrides, [] removes them all.
+func (h *DashboardHandler) SetAlertRuleOverrides(c *gin.Context) {
+	ruleID := c.Param("ruleID")
+	var overrides []metadata.RuleOverride
+	if err := c.ShouldBindJSON(&overrides); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid overrides payload, expected a JSON array", "details": err.Error()})
+		return
+	}
+
+	rule, err := h.metaStore.GetAlertRule(ruleID)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+			return
+		}
+		appLogger.Error("Failed to load alert rule %s: %v", ruleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert rule"})
+		return
+	}
+	rule.Overrides = overrides
+	if err := alerting.ValidateRule(rule, h.hostOfflineAfter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.metaStore.SetAlertRuleOverrides(ruleID, overrides)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+			return
+		}
+		appLogger.Error("Failed to update overrides of alert rule %s: %v", ruleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert rule"})
+		return
+	}
+	appLogger.Info("Set %d overrides on alert rule %s", len(overrides), ruleID)
+	c.JSON(http.StatusOK, updated)
+}
+
+// GetHostAlertRules handles GET /api/dashboard/host/:hostID/alert-rules
+// Returns every rule as it applies to the host after overrides, with the
+// sources that shaped it, so operators can see why a host did or didn't alert.
+func (h *DashboardHandler) GetHostAlertRules(c *gin.Context) {
+	hostID := c.Param("hostID")
+	rules, err := h.metaStore.ListAlertRules()
+	if err != nil {
+		appLogger.Error("Failed to list alert rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert rules"})
+		return
+	}
+	groupsByHost, err := h.metaStore.GroupsByHost()
+	if err != nil {
+		appLogger.Error("Failed to load host groups: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert rules"})
+		return
+	}
+
+	resolved := make([]alerting.ResolvedRule, 0, len(rules))
+	for i := range rules {
+		resolved = append(resolved, alerting.ResolveRule(&rules[i], hostID, groupsByHost[hostID]))
+	}
+	c.JSON(http.StatusOK, resolved)
+}
+
+// DeleteAlertRule handles DELETE /api/dashboard/alert-rules/:ruleID
+// Open alerts of the rule are resolved on the next evaluation.
+func (h *DashboardHandler) DeleteAlertRule(c *gin.Context) {
+	ruleID := c.Param("ruleID")
+	if err := h.metaStore.DeleteAlertRule(ruleID); err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+			return
+		}
+		appLogger.Error("Failed to delete alert rule %s: %v", ruleID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert rule"})
+		return
+	}
+	appLogger.Info("Deleted alert rule %s", ruleID)
+	c.Status(http.StatusNoContent)
+}
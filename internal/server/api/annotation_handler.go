@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createAnnotationRequest struct {
+	Time   time.Time `json:"time"` // optional, defaults to now
+	HostID string    `json:"host_id"`
+	Title  string    `json:"title" binding:"required"`
+	Text   string    `json:"text"`
+	Tags   []string  `json:"tags"`
+}
+
+// CreateAnnotation handles POST /api/dashboard/annotations
+func (h *DashboardHandler) CreateAnnotation(c *gin.Context) {
+	var req createAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid annotation payload", "details": err.Error()})
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Annotation title is required"})
+		return
+	}
+
+	annotation, err := h.metaStore.AddAnnotation(metadata.Annotation{
+		Time:   req.Time,
+		HostID: req.HostID,
+		Title:  req.Title,
+		Text:   req.Text,
+		Tags:   req.Tags,
+	})
+	if err != nil {
+		appLogger.Error("Failed to store annotation %q: %v", req.Title, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store annotation"})
+		return
+	}
+	c.JSON(http.StatusCreated, annotation)
+}
+
+// ListAnnotations handles GET /api/dashboard/annotations?range=24h&host_id=...
+// (or ?start=...&end=...). Host-less (global) annotations are included when filtering by host.
+func (h *DashboardHandler) ListAnnotations(c *gin.Context) {
+	start, end, err := parseTimeRange(c, "24h")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	annotations, err := h.metaStore.ListAnnotations(start, end, c.Query("host_id"))
+	if err != nil {
+		appLogger.Error("Failed to list annotations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve annotations"})
+		return
+	}
+	c.JSON(http.StatusOK, annotations)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+)
+
+func TestListAnnotationsRoute(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	router := d.router(RouteGuards{})
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, body := range []string{
+		`{"time":"2026-03-01T12:00:00Z","host_id":"host-1","title":"deploy","tags":["release"]}`,
+		`{"time":"2026-03-01T13:00:00Z","title":"network maintenance"}`,
+		`{"time":"2026-03-01T13:30:00Z","host_id":"host-2","title":"disk swap"}`,
+		`{"time":"2026-03-01T15:00:00Z","host_id":"host-1","title":"rollback"}`,
+	} {
+		if w := serve(router, http.MethodPost, "/api/dashboard/annotations", body); w.Code != http.StatusCreated {
+			t.Fatalf("create %s: status %d, want 201: %s", body, w.Code, w.Body)
+		}
+	}
+	if w := serve(router, http.MethodPost, "/api/dashboard/annotations", `{"title":"  "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("blank title: status %d, want 400", w.Code)
+	}
+
+	list := func(query string) string {
+		t.Helper()
+		w := serve(router, http.MethodGet, "/api/dashboard/annotations?"+query, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("?%s: status %d, want 200: %s", query, w.Code, w.Body)
+		}
+		var annotations []metadata.Annotation
+		decodeJSON(t, w, &annotations)
+		var titles []string
+		for _, a := range annotations {
+			titles = append(titles, a.Title)
+		}
+		return fmt.Sprint(titles)
+	}
+
+	window := "start=" + base.Format(time.RFC3339) + "&end=" + base.Add(2*time.Hour).Format(time.RFC3339)
+	if got := list(window); got != "[deploy network maintenance disk swap]" {
+		t.Errorf("12:00-14:00 = %s", got)
+	}
+	if got := list(window + "&host_id=host-1"); got != "[deploy network maintenance]" {
+		t.Errorf("12:00-14:00 for host-1 = %s, want its own and the global one", got)
+	}
+	if got := list("range=1h"); got != "[]" {
+		t.Errorf("last hour = %s, want none", got)
+	}
+	if w := serve(router, http.MethodGet, "/api/dashboard/annotations?start=2026-03-01T14:00:00Z&end=2026-03-01T12:00:00Z", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("start after end: status %d, want 400", w.Code)
+	}
+}
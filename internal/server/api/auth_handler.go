@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler serves the dashboard login endpoint.
+type AuthHandler struct {
+	authenticator *auth.Authenticator
+}
+
+// NewAuthHandler creates a new AuthHandler.
+func NewAuthHandler(authenticator *auth.Authenticator) *AuthHandler {
+	return &AuthHandler{
+		authenticator: authenticator,
+	}
+}
+
+type loginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Login handles POST /api/auth/login
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid login payload", "details": err.Error()})
+		return
+	}
+
+	token, expiresAt, err := h.authenticator.Login(req.Username, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			appLogger.Warn("Failed login for user %q from %s", req.Username, c.ClientIP())
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password", "code": auth.CodeInvalidCredentials})
+		case errors.Is(err, auth.ErrLoginDisabled):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "Login is disabled, use a token from the identity provider"})
+		default:
+			appLogger.Error("Login failed for user %q: %v", req.Username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue token"})
+		}
+		return
+	}
+
+	appLogger.Info("User %q logged in from %s", req.Username, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"token": token, "expires_at": expiresAt})
+}
+
+// RegisterAuthRoutes registers the authentication routes.
+func (h *AuthHandler) RegisterAuthRoutes(router *gin.Engine) {
+	authGroup := router.Group("/api/auth")
+	{
+		authGroup.POST("/login", h.Login)
+	}
+}
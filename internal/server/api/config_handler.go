@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigHandler serves the configuration the server is running with.
+type ConfigHandler struct {
+	current func() *config.ServerConfig // changes on SIGHUP reloads
+}
+
+// NewConfigHandler creates a new ConfigHandler.
+func NewConfigHandler(current func() *config.ServerConfig) *ConfigHandler {
+	return &ConfigHandler{current: current}
+}
+
+// RegisterConfigRoutes registers GET /api/config. It shows deployment details
+// (URLs, bucket, users), so it needs the admin role when auth is enabled.
+func (h *ConfigHandler) RegisterConfigRoutes(router *gin.Engine, guards RouteGuards) {
+	adminGroup := router.Group("/api", guards.Read...).Group("", guards.Admin...)
+	adminGroup.GET("/config", h.GetConfig)
+}
+
+// GetConfig handles GET /api/config. Secrets are redacted by the config's
+// JSON encoding, they are never part of the response.
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.current())
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetConfigMasksSecrets(t *testing.T) {
+	const token, jwtSecret, passwordHash, slackURL = "influx-token-Zq81x", "jwt-secret-4Kd0", "$2a$10$hash.of.a.password", "https://hooks.slack.com/services/T0/B0/secret"
+	cfg := &config.ServerConfig{
+		ListenAddress: "127.0.0.1:9090",
+		InfluxDB:      config.InfluxDBConfig{URLs: []string{"http://influx-1:8086"}, Token: token, Org: "ops", Bucket: "system_stats"},
+		Auth: config.AuthConfig{
+			Enabled:   true,
+			JWTSecret: jwtSecret,
+			Users:     map[string]config.UserEntry{"alice": {PasswordHash: passwordHash, Role: "admin"}},
+		},
+		Alerting: config.AlertingConfig{SlackWebhookURL: slackURL},
+	}
+	router := gin.New()
+	NewConfigHandler(func() *config.ServerConfig { return cfg }).RegisterConfigRoutes(router, RouteGuards{})
+
+	w := serve(router, http.MethodGet, "/api/config", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+	}
+	for _, secret := range []string{token, jwtSecret, passwordHash, slackURL} {
+		if strings.Contains(w.Body.String(), secret) {
+			t.Errorf("response echoes the secret %q: %s", secret, w.Body)
+		}
+	}
+
+	var body struct {
+		ListenAddress string `json:"listenAddress"`
+		InfluxDB      struct {
+			URLs   []string `json:"urls"`
+			Token  string   `json:"token"`
+			Bucket string   `json:"bucket"`
+		} `json:"influxDB"`
+		Auth struct {
+			JWTSecret string `json:"jwtSecret"`
+			Users     map[string]struct {
+				PasswordHash string `json:"passwordHash"`
+				Role         string `json:"role"`
+			} `json:"users"`
+		} `json:"auth"`
+	}
+	decodeJSON(t, w, &body)
+	if body.ListenAddress != cfg.ListenAddress {
+		t.Errorf("listenAddress = %q, want %q", body.ListenAddress, cfg.ListenAddress)
+	}
+	if body.InfluxDB.Token != "[redacted]" || body.Auth.JWTSecret != "[redacted]" || body.Auth.Users["alice"].PasswordHash != "[redacted]" {
+		t.Errorf("token %q, JWT secret %q, password hash %q, want each [redacted]",
+			body.InfluxDB.Token, body.Auth.JWTSecret, body.Auth.Users["alice"].PasswordHash)
+	}
+	// the settings to debug a deployment with are shown as they are
+	if len(body.InfluxDB.URLs) != 1 || body.InfluxDB.URLs[0] != "http://influx-1:8086" || body.InfluxDB.Bucket != "system_stats" || body.Auth.Users["alice"].Role != "admin" {
+		t.Errorf("urls %q, bucket %q, alice's role %q, want them unredacted", body.InfluxDB.URLs, body.InfluxDB.Bucket, body.Auth.Users["alice"].Role)
+	}
+
+	// a secret that is not set stays visibly unset
+	cfg.InfluxDB.Token = ""
+	w = serve(router, http.MethodGet, "/api/config", "")
+	decodeJSON(t, w, &body)
+	if body.InfluxDB.Token != "" {
+		t.Errorf("unset token = %q, want empty", body.InfluxDB.Token)
+	}
+}
@@ -1,49 +1,174 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/database"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/tracker"
 
 	"github.com/gin-gonic/gin"
 )
 
 // DashboardHandler holds dependencies for the dashboard API handlers.
 type DashboardHandler struct {
-	dbReader *database.InfluxDBReader
+	dbReader    *database.InfluxDBReader
+	metaStore   *metadata.Store
+	hostTracker *tracker.HostTracker
+
+	hostOfflineAfter time.Duration // default threshold for host_offline alert rules
 }
 
 // NewDashboardHandler creates a new DashboardHandler.
-func NewDashboardHandler(dbReader *database.InfluxDBReader) *DashboardHandler {
+func NewDashboardHandler(dbReader *database.InfluxDBReader, metaStore *metadata.Store, hostTracker *tracker.HostTracker, hostOfflineAfter time.Duration) *DashboardHandler {
 	return &DashboardHandler{
-		dbReader: dbReader,
+		dbReader:         dbReader,
+		metaStore:        metaStore,
+		hostTracker:      hostTracker,
+		hostOfflineAfter: hostOfflineAfter,
 	}
 }
 
+const (
+	sparklineWindow = 15 * time.Minute
+	sparklineEvery  = 1 * time.Minute
+)
+
 // GetHostsOverview handles GET /api/dashboard/hosts/overview
+// Optional ?group=<name> limits the list to members of that group.
+// Optional ?role=<role> limits it to hosts with that inferred role.
+// Optional ?sparklines=true adds 15 minute CPU/RAM trend lines (one extra query for all hosts).
+// Optional ?netUnit=bps|Bps|Mbps|MBps converts network rates (default Bps, bytes/sec).
+// Optional ?include_decommissioned=true also lists decommissioned hosts.
 func (h *DashboardHandler) GetHostsOverview(c *gin.Context) {
+	h.serveOverview(c, nil)
+}
+
+// serveOverview answers an overview request. A non-nil onlyHosts limits the
+// list to those host IDs (label views); the query parameters apply either way.
+func (h *DashboardHandler) serveOverview(c *gin.Context, onlyHosts map[string]bool) {
+	groupFilter := c.Query("group")
+	roleFilter := c.Query("role")
+	withSparklines := c.Query("sparklines") == "true"
+	includeDecommissioned := c.Query("include_decommissioned") == "true"
+	unit, err := parseNetUnit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	overviews, err := h.dbReader.GetHostOverviewList(c.Request.Context())
 	if err != nil {
 		appLogger.Error("Failed to get hosts overview: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve hosts overview"})
+		respondReaderError(c, err, "Failed to retrieve hosts overview")
 		return
 	}
-	if overviews == nil { // Ensure we send an empty array instead of null if no hosts
-		overviews = []models.HostOverviewData{}
+
+	groupsByHost, err := h.metaStore.GroupsByHost()
+	if err != nil {
+		// Group info is an add-on, still serve the metrics without it
+		appLogger.Error("Failed to load host groups for overview: %v", err)
+		groupsByHost = map[string][]string{}
+	}
+
+	var sparklines map[string]map[string][]float64
+	if withSparklines {
+		sparklines, err = h.dbReader.GetOverviewSparklines(c.Request.Context(), sparklineWindow, sparklineEvery)
+		if err != nil {
+			// Trend lines are decoration, serve the overview without them
+			appLogger.Error("Failed to get overview sparklines: %v", err)
+		}
+	}
+
+	silences, err := h.metaStore.ActiveSilences(time.Now())
+	if err != nil {
+		appLogger.Error("Failed to load host silences for overview: %v", err)
+		silences = map[string]metadata.Silence{}
+	}
+
+	roles, err := h.metaStore.HostRoles()
+	if err != nil {
+		appLogger.Error("Failed to load host roles for overview: %v", err)
+		roles = map[string]metadata.HostRole{}
+	}
+
+	decommissioned, err := h.metaStore.DecommissionedHosts()
+	if err != nil {
+		appLogger.Error("Failed to load decommissioned hosts for overview: %v", err)
+		decommissioned = map[string]metadata.Decommission{}
+	}
+
+	filtered := []models.HostOverviewData{} // Ensure we send an empty array instead of null if no hosts
+	for _, overview := range overviews {
+		if onlyHosts != nil && !onlyHosts[overview.ID] {
+			continue
+		}
+		if silence, ok := silences[overview.ID]; ok {
+			applySilence(&overview.Status, &overview.Silence, &silence)
+		}
+		if decommission, ok := decommissioned[overview.ID]; ok {
+			if !includeDecommissioned {
+				continue
+			}
+			applyDecommission(&overview.Status, &overview.Decommissioned, &decommission)
+		}
+		if hostLines, ok := sparklines[overview.ID]; ok {
+			overview.CPUSparkline = hostLines["cpu_usage_percent"]
+			overview.RAMSparkline = hostLines["mem_usage_percent"]
+		}
+		unit.apply(&overview.NetworkUpload, &overview.NetworkDownload)
+		overview.NetworkUnit = unit.name
+		overview.Groups = groupsByHost[overview.ID]
+		if overview.Groups == nil {
+			overview.Groups = []string{}
+		}
+		if groupFilter != "" && !containsString(overview.Groups, groupFilter) {
+			continue
+		}
+		overview.Role = roles[overview.ID].Role
+		if roleFilter != "" && overview.Role != roleFilter {
+			continue
+		}
+		filtered = append(filtered, overview)
 	}
-	c.JSON(http.StatusOK, overviews)
+	c.JSON(http.StatusOK, filtered)
 }
 
 // GetHostDetailsByName handles GET /api/dashboard/host/:hostID/details
+// Optional ?netUnit=bps|Bps|Mbps|MBps as for the overview; the totals stay in bytes.
 func (h *DashboardHandler) GetHostDetailsByID(c *gin.Context) {
+	if details, ok := h.hostDetails(c); ok {
+		c.JSON(http.StatusOK, details)
+	}
+}
+
+// GetHostDetailsV2 handles GET /api/v2/dashboard/host/:hostID/details
+// The same data as GetHostDetailsByID in the v2 schema (models.HostDetailsV2).
+func (h *DashboardHandler) GetHostDetailsV2(c *gin.Context) {
+	if details, ok := h.hostDetails(c); ok {
+		c.JSON(http.StatusOK, models.NewHostDetailsV2(details))
+	}
+}
+
+// hostDetails loads the details of the :hostID host with silence,
+// decommission and notes applied. If it fails the error response is written and ok is false.
+func (h *DashboardHandler) hostDetails(c *gin.Context) (*models.HostDetailsData, bool) {
 	hostID := c.Param("hostID")
 	if hostID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "HostID parameter is required"})
-		return
+		return nil, false
+	}
+	unit, err := parseNetUnit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
 	}
 
 	details, err := h.dbReader.GetHostDetails(c.Request.Context(), hostID)
@@ -55,11 +180,31 @@ func (h *DashboardHandler) GetHostDetailsByID(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Host details not found"})
 		} else {
 			appLogger.Error("Failed to get host details for hostID %s: %v", hostID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve host details"})
+			respondReaderError(c, err, "Failed to retrieve host details")
 		}
-		return
+		return nil, false
+	}
+	unit.apply(&details.NetworkUpload, &details.NetworkDownload)
+	details.NetworkUnit = unit.name
+
+	if silence, err := h.metaStore.GetActiveSilence(hostID, time.Now()); err == nil {
+		applySilence(&details.Status, &details.Silence, silence)
+	} else if !errors.Is(err, metadata.ErrNotFound) {
+		appLogger.Error("Failed to load silence for host %s: %v", hostID, err)
+	}
+	if decommission, err := h.metaStore.GetDecommission(hostID); err == nil {
+		applyDecommission(&details.Status, &details.Decommissioned, decommission)
+	} else if !errors.Is(err, metadata.ErrNotFound) {
+		appLogger.Error("Failed to load decommission for host %s: %v", hostID, err)
+	}
+
+	notes, err := h.metaStore.GetHostNotes(hostID)
+	if err != nil {
+		appLogger.Error("Failed to load notes for host %s: %v", hostID, err)
+	} else {
+		details.Notes = toNotesModel(notes)
 	}
-	c.JSON(http.StatusOK, details)
+	return details, true
 }
 
 // GetHostMetricHistory handles GET /api/dashboard/host/:hostID/metrics/:metricName
@@ -74,35 +219,43 @@ func (h *DashboardHandler) GetHostMetricHistory(c *gin.Context) {
 
 	// Query parameters for time range and aggregation
 	// Example: /api/dashboard/host/123/metrics/cpu_usage_percent?range=1h&aggregate=30s
-	rangeStr := c.DefaultQuery("range", "1h")          // Default to 1 hour
+	//      or: ...?start=2024-05-07T02:10:00Z&end=2024-05-07T02:40:00Z&aggregate=30s
 	aggregateStr := c.DefaultQuery("aggregate", "30s") // Default to 30 second aggregates
 
-	rangeDuration, err := time.ParseDuration(rangeStr)
+	start, end, err := parseTimeRange(c, "1h") // Default to 1 hour
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid range duration format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	aggregateInterval, err := time.ParseDuration(aggregateStr)
-	if err != nil {
+	if err != nil || aggregateInterval <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid aggregate interval format"})
 		return
 	}
 
 	// Basic validation for metricName (already done in dbReader, but good for early exit)
-	// This could be more sophisticated, checking against a list of allowed metrics.
-	allowedMetrics := map[string]bool{
-		"cpu_usage_percent": true, "mem_usage_percent": true,
-		"net_upload_bytes_sec": true, "net_download_bytes_sec": true,
-	}
-	if !allowedMetrics[metricName] {
+	if !database.HistoryFields[metricName] {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metric name specified"})
 		return
 	}
 
-	history, err := h.dbReader.GetHostMetricHistory(c.Request.Context(), hostID, metricName, rangeDuration, aggregateInterval)
+	// Accept: application/x-ndjson streams the points instead of buffering them
+	if wantsNDJSON(c) {
+		stream := newNDJSONStream(c)
+		err := h.dbReader.StreamHostMetricHistory(c.Request.Context(), hostID, metricName, start, end, aggregateInterval, func(p models.MetricPoint) error {
+			return stream.Write(p)
+		})
+		if err != nil {
+			appLogger.Error("Failed to stream metric history for host %s, metric %s: %v", hostID, metricName, err)
+		}
+		stream.Finish(err, "Failed to retrieve metric history")
+		return
+	}
+
+	history, err := h.dbReader.GetHostMetricHistory(c.Request.Context(), hostID, metricName, start, end, aggregateInterval)
 	if err != nil {
 		appLogger.Error("Failed to get metric history for host %s, metric %s: %v", hostID, metricName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve metric history"})
+		respondReaderError(c, err, "Failed to retrieve metric history")
 		return
 	}
 	if history == nil { // Ensure empty array instead of null
@@ -111,14 +264,350 @@ func (h *DashboardHandler) GetHostMetricHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, history)
 }
 
+// GetHostMetricsHistory handles GET /api/dashboard/host/:hostID/metrics?fields=cpu_usage_percent,mem_usage_percent
+// The history of several metrics in one query, with the same range and
+// aggregate parameters as GetHostMetricHistory. fields is comma-separated
+// and may be repeated; duplicates are dropped, and more distinct fields than
+// INFLUXDB_HISTORY_MAX_FIELDS are rejected.
+func (h *DashboardHandler) GetHostMetricsHistory(c *gin.Context) {
+	hostID := c.Param("hostID")
+	var fields []string
+	for _, value := range c.QueryArray("fields") {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fields parameter is required, e.g. ?fields=cpu_usage_percent,mem_usage_percent"})
+		return
+	}
+	for _, field := range fields {
+		if !database.HistoryFields[field] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metric name specified: " + field})
+			return
+		}
+	}
+
+	start, end, err := parseTimeRange(c, "1h")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	aggregateInterval, err := time.ParseDuration(c.DefaultQuery("aggregate", "30s"))
+	if err != nil || aggregateInterval <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid aggregate interval format"})
+		return
+	}
+
+	history, err := h.dbReader.GetHostMetricsHistory(c.Request.Context(), hostID, fields, start, end, aggregateInterval)
+	if err != nil {
+		appLogger.Error("Failed to get metrics history for host %s, metrics %v: %v", hostID, fields, err)
+		respondReaderError(c, err, "Failed to retrieve metrics history")
+		return
+	}
+	c.JSON(http.StatusOK, models.HostMetricsHistoryData{ID: hostID, Metrics: history})
+}
+
+// GetHostMetricDelta handles GET /api/dashboard/host/:hostID/metrics/:metricName/delta?window=5m
+// The mean over the last window (default 5m) against the window before it.
+func (h *DashboardHandler) GetHostMetricDelta(c *gin.Context) {
+	hostID := c.Param("hostID")
+	metricName := c.Param("metricName")
+	if !database.HistoryFields[metricName] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metric name specified"})
+		return
+	}
+	window, err := time.ParseDuration(c.DefaultQuery("window", "5m"))
+	if err != nil || window <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window, expected a positive duration such as 5m"})
+		return
+	}
+
+	delta, err := h.dbReader.GetHostMetricDelta(c.Request.Context(), hostID, metricName, window)
+	if err != nil {
+		appLogger.Error("Failed to get %s delta for host %s: %v", metricName, hostID, err)
+		respondReaderError(c, err, "Failed to retrieve metric change")
+		return
+	}
+	c.JSON(http.StatusOK, delta)
+}
+
+// GetHostLatestField handles GET /api/dashboard/host/:hostID/latest/:metricName,
+// the latest value of one field for single-value widgets.
+func (h *DashboardHandler) GetHostLatestField(c *gin.Context) {
+	hostID := c.Param("hostID")
+	metricName := c.Param("metricName")
+	if !database.LatestFields[metricName] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metric name specified"})
+		return
+	}
+
+	point, err := h.dbReader.GetHostLatestField(c.Request.Context(), hostID, metricName)
+	if err != nil {
+		appLogger.Error("Failed to get latest %s for host %s: %v", metricName, hostID, err)
+		respondReaderError(c, err, "Failed to retrieve latest value")
+		return
+	}
+	if point == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No recent value for this host and metric"})
+		return
+	}
+	c.JSON(http.StatusOK, point)
+}
+
+// GetHostInterfaces handles GET /api/dashboard/host/:hostID/interfaces
+// Optional ?netUnit=bps|Bps|Mbps|MBps as for the overview.
+func (h *DashboardHandler) GetHostInterfaces(c *gin.Context) {
+	hostID := c.Param("hostID")
+	unit, err := parseNetUnit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	interfaces, err := h.dbReader.GetHostInterfaces(c.Request.Context(), hostID)
+	if err != nil {
+		appLogger.Error("Failed to get interfaces for host %s: %v", hostID, err)
+		respondReaderError(c, err, "Failed to retrieve network interfaces")
+		return
+	}
+	for i := range interfaces {
+		if interfaces[i].NetworkUpload != nil {
+			unit.apply(interfaces[i].NetworkUpload, interfaces[i].NetworkDownload)
+		}
+	}
+	c.JSON(http.StatusOK, models.HostInterfacesData{ID: hostID, NetworkUnit: unit.name, Interfaces: interfaces})
+}
+
+// GetDiskMetricHistory handles GET /api/dashboard/host/:hostID/disk/:path/history
+// ?field=usage_percent&range=24h&aggregate=5m. path is the URL-encoded mountpoint, e.g. %2Fvar%2Flib.
+func (h *DashboardHandler) GetDiskMetricHistory(c *gin.Context) {
+	hostID := c.Param("hostID")
+	path := c.Param("path") // already unescaped by gin
+
+	field := c.DefaultQuery("field", "usage_percent")
+	if !database.DiskHistoryFields[field] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field, expected usage_percent, used_gb or free_gb"})
+		return
+	}
+	if !database.ValidDiskPath(path) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid disk path, expected a URL-encoded mountpoint such as %2Fvar%2Flib"})
+		return
+	}
+	start, end, err := parseTimeRange(c, "1h")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	aggregateInterval, err := time.ParseDuration(c.DefaultQuery("aggregate", "30s"))
+	if err != nil || aggregateInterval <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid aggregate interval format"})
+		return
+	}
+
+	history, err := h.dbReader.GetDiskMetricHistory(c.Request.Context(), hostID, path, field, start, end, aggregateInterval)
+	if err != nil {
+		appLogger.Error("Failed to get disk history for host %s, path %s, field %s: %v", hostID, path, field, err)
+		respondReaderError(c, err, "Failed to retrieve disk history")
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// ListHosts handles GET /api/dashboard/hosts?range=24h
+// Every host that reported in the range as {id, hostname, lastSeen}, for host
+// pickers that don't need the overview's metrics and status.
+func (h *DashboardHandler) ListHosts(c *gin.Context) {
+	start, end, err := parseTimeRange(c, "24h")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hosts, err := h.dbReader.ListHosts(c.Request.Context(), start, end)
+	if err != nil {
+		appLogger.Error("Failed to list hosts: %v", err)
+		respondReaderError(c, err, "Failed to retrieve host list")
+		return
+	}
+	c.JSON(http.StatusOK, hosts)
+}
+
+// GetAgentVersions handles GET /api/dashboard/agent-versions?range=10m
+// Counts hosts per agent version (latest point of each host in the range).
+func (h *DashboardHandler) GetAgentVersions(c *gin.Context) {
+	start, end, err := parseTimeRange(c, "10m")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	counts, err := h.dbReader.CountHostsByAgentVersion(c.Request.Context(), start, end)
+	if err != nil {
+		appLogger.Error("Failed to count hosts per agent version: %v", err)
+		respondReaderError(c, err, "Failed to retrieve agent versions")
+		return
+	}
+	c.JSON(http.StatusOK, counts)
+}
+
+// GetHostConflicts handles GET /api/dashboard/conflicts
+// Lists host_ids reported by more than one hostname within the offline
+// threshold, typically cloned VMs sharing /etc/machine-id.
+func (h *DashboardHandler) GetHostConflicts(c *gin.Context) {
+	response := models.HostConflictsData{
+		Conflicts:       []models.HostConflict{},
+		CollisionsTotal: h.hostTracker.Collisions(),
+	}
+	for _, conflict := range h.hostTracker.Conflicts(time.Now()) {
+		entry := models.HostConflict{HostID: conflict.HostID, Since: conflict.Since.UTC()}
+		for hostname, lastSeen := range conflict.Hostnames {
+			entry.Hostnames = append(entry.Hostnames, models.ConflictHostname{Hostname: hostname, LastSeen: lastSeen.UTC()})
+		}
+		sort.Slice(entry.Hostnames, func(i, j int) bool { return entry.Hostnames[i].LastSeen.After(entry.Hostnames[j].LastSeen) })
+		response.Conflicts = append(response.Conflicts, entry)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// maxFlappingWindow caps ?window of the flapping hosts route, which reads raw samples.
+const maxFlappingWindow = 24 * time.Hour
+
+// GetFlappingHosts handles GET /api/dashboard/hosts/flapping?window=1h
+// Lists hosts with irregular samples or repeated threshold crossings in the window.
+func (h *DashboardHandler) GetFlappingHosts(c *gin.Context) {
+	window, err := parseRangeDuration(c.DefaultQuery("window", "1h"))
+	if err != nil || window <= 0 || window > maxFlappingWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window, expected a duration up to 24h such as 15m or 1h"})
+		return
+	}
+	hosts, err := h.dbReader.GetFlappingHosts(c.Request.Context(), window)
+	if err != nil {
+		appLogger.Error("Failed to get flapping hosts: %v", err)
+		respondReaderError(c, err, "Failed to retrieve flapping hosts")
+		return
+	}
+	c.JSON(http.StatusOK, hosts)
+}
+
+// hostIDLogField adds the :hostID of host routes as host_id to the request's
+// log fields, so reader errors can be tied to the host.
+func hostIDLogField(c *gin.Context) {
+	if hostID := c.Param("hostID"); hostID != "" {
+		c.Request = c.Request.WithContext(appLogger.WithFields(c.Request.Context(), "host_id", hostID))
+	}
+	c.Next()
+}
+
+// RouteGuards holds the middleware for dashboard routes. Both are empty when auth is disabled.
+type RouteGuards struct {
+	Read  []gin.HandlerFunc // every dashboard route (authentication)
+	Admin []gin.HandlerFunc // added on top of Read for routes that change state
+}
+
 // RegisterDashboardRoutes registers the API routes for dashboard data.
-func (h *DashboardHandler) RegisterDashboardRoutes(router *gin.Engine) {
+// GET routes only need Read; anything that creates, changes or deletes needs Admin too.
+// Query routes get their deadline from timeouts.
+func (h *DashboardHandler) RegisterDashboardRoutes(router *gin.Engine, guards RouteGuards, timeouts config.RouteTimeouts) {
 	// Prefixing with /api/dashboard to group dashboard related endpoints
-	dashboardGroup := router.Group("/api/dashboard")
+	dashboardGroup := router.Group("/api/dashboard", guards.Read...)
+	dashboardGroup.Use(hostIDLogField)
+	adminGroup := dashboardGroup.Group("", guards.Admin...)
 	{
-		dashboardGroup.GET("/hosts/overview", h.GetHostsOverview)
-		dashboardGroup.GET("/host/:hostID/details", h.GetHostDetailsByID)
-		dashboardGroup.GET("/host/:hostID/metrics/:metricName", h.GetHostMetricHistory)
+		dashboardGroup.GET("/hosts", Timeout(timeouts.Default), h.ListHosts)
+		dashboardGroup.GET("/hosts/overview", Timeout(timeouts.Overview), h.GetHostsOverview)
+		dashboardGroup.GET("/hosts/flapping", Timeout(timeouts.History), h.GetFlappingHosts)
+		dashboardGroup.GET("/host/:hostID/details", Timeout(timeouts.Details), h.GetHostDetailsByID)
+		dashboardGroup.GET("/host/:hostID/metrics", Timeout(timeouts.History), h.GetHostMetricsHistory)
+		dashboardGroup.GET("/host/:hostID/metrics/:metricName", Timeout(timeouts.History), h.GetHostMetricHistory)
+		dashboardGroup.GET("/host/:hostID/metrics/:metricName/delta", Timeout(timeouts.History), h.GetHostMetricDelta)
+		dashboardGroup.GET("/host/:hostID/latest/:metricName", Timeout(timeouts.Details), h.GetHostLatestField)
+		dashboardGroup.GET("/host/:hostID/interfaces", Timeout(timeouts.Details), h.GetHostInterfaces)
+		dashboardGroup.GET("/host/:hostID/disk/:path/history", Timeout(timeouts.History), h.GetDiskMetricHistory)
+		dashboardGroup.GET("/agent-versions", Timeout(timeouts.Default), h.GetAgentVersions)
+		dashboardGroup.GET("/conflicts", h.GetHostConflicts)
+
+		// Static agent labels (MONITOR_LABELS), e.g. tier=web. Not under /groups:
+		// GET /groups already lists the metadata store's host groups below.
+		dashboardGroup.GET("/labels", Timeout(timeouts.Default), h.ListLabelGroups)
+		dashboardGroup.GET("/labels/:label/:value/overview", Timeout(timeouts.Overview), h.GetLabelGroupOverview)
+
+		// Host groups (metadata store)
+		dashboardGroup.GET("/groups", h.ListGroups)
+		adminGroup.POST("/groups", h.CreateGroup)
+		adminGroup.DELETE("/groups/:group", h.DeleteGroup)
+		adminGroup.PUT("/groups/:group/hosts/:hostID", h.AddHostToGroup)
+		adminGroup.DELETE("/groups/:group/hosts/:hostID", h.RemoveHostFromGroup)
+
+		// Host notes (metadata store)
+		dashboardGroup.GET("/host/:hostID/notes", h.GetHostNotes)
+		adminGroup.PUT("/host/:hostID/notes", h.PutHostNotes)
+
+		// Silences
+		adminGroup.POST("/host/:hostID/silence", h.SilenceHost)
+		adminGroup.DELETE("/host/:hostID/silence", h.UnsilenceHost)
+
+		// Decommissioning (history is kept)
+		adminGroup.POST("/host/:hostID/decommission", h.DecommissionHost)
+		adminGroup.DELETE("/host/:hostID/decommission", h.RecommissionHost)
+
+		// Annotations / event markers
+		dashboardGroup.GET("/annotations", h.ListAnnotations)
+		adminGroup.POST("/annotations", h.CreateAnnotation)
+
+		// Alerting
+		dashboardGroup.GET("/alerts", h.ListAlerts)
+		dashboardGroup.GET("/alerts/history", h.ListAlertHistory)
+		dashboardGroup.GET("/alert-rules", h.ListAlertRules)
+		adminGroup.POST("/alert-rules", h.CreateAlertRule)
+		adminGroup.DELETE("/alert-rules/:ruleID", h.DeleteAlertRule)
+		adminGroup.PUT("/alert-rules/:ruleID/overrides", h.SetAlertRuleOverrides)
+		dashboardGroup.GET("/host/:hostID/alert-rules", h.GetHostAlertRules)
+	}
+
+	// v2 response schemas, next to the legacy ones above
+	v2Group := router.Group("/api/v2/dashboard", guards.Read...)
+	v2Group.Use(hostIDLogField)
+	v2Group.GET("/host/:hostID/details", Timeout(timeouts.Details), h.GetHostDetailsV2)
+}
 
+// respondReaderError maps reader errors to a status code: 504 when the route's
+// deadline passed, 503 when the reader is saturated (client may retry), 400 when
+// the query is over the cost budget, 500 otherwise.
+func respondReaderError(c *gin.Context, err error, message string) {
+	if deadlineExceeded(c) {
+		respondTimeout(c)
+		return
+	}
+	if errors.Is(err, database.ErrQueryTooExpensive) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Query too broad, narrow the time range",
+			"code":    "query_too_expensive",
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrTooManyFields) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Too many metrics requested",
+			"code":    "too_many_fields",
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrReaderBusy) {
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server busy, please retry"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
 	}
+	return false
 }
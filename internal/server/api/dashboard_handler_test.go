@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/auth"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/tracker"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// testDashboard is a dashboard handler on a fake InfluxDB and a metadata
+// store in a temp dir.
+type testDashboard struct {
+	*DashboardHandler
+	influx *influxtest.Server
+	cfg    config.InfluxDBConfig
+	reader *database.InfluxDBReader
+	store  *metadata.Store
+}
+
+// newTestDashboard creates the handler. configure, if not nil, adjusts the
+// InfluxDB settings first.
+func newTestDashboard(t *testing.T, configure func(*config.InfluxDBConfig)) *testDashboard {
+	t.Helper()
+	influx := influxtest.NewServer(t)
+	cfg := influx.Config()
+	if configure != nil {
+		configure(&cfg)
+	}
+	client, err := database.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+	store, err := metadata.Open(filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatalf("metadata.Open: %v", err)
+	}
+	t.Cleanup(store.Close)
+
+	reader := database.NewInfluxDBReader(client, cfg)
+	hostTracker := tracker.NewHostTracker(store, cfg.OnlineWithin)
+	return &testDashboard{
+		DashboardHandler: NewDashboardHandler(reader, store, hostTracker, cfg.OnlineWithin),
+		influx:           influx,
+		cfg:              cfg,
+		reader:           reader,
+		store:            store,
+	}
+}
+
+// router registers the dashboard routes with guards, on a router set up
+// like the server's.
+func (d *testDashboard) router(guards RouteGuards) *gin.Engine {
+	router := gin.New()
+	router.UseRawPath = true // URL-encoded mountpoints in /disk/:path/history
+	d.RegisterDashboardRoutes(router, guards, config.RouteTimeouts{})
+	return router
+}
+
+// serve sends a request to router and returns the recorded response.
+func serve(router http.Handler, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeJSON decodes the response body into v.
+func decodeJSON(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response %s: %v", w.Body, err)
+	}
+}
+
+func TestRoleMatrix(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{
+		Enabled:   true,
+		JWTSecret: "test-secret",
+		Issuer:    "system-stats-monitoring",
+		TokenTTL:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	guards := RouteGuards{
+		Read:  []gin.HandlerFunc{authenticator.Middleware()},
+		Admin: []gin.HandlerFunc{auth.RequireRole(auth.RoleAdmin)},
+	}
+	router := newTestDashboard(t, nil).router(guards)
+	NewConfigHandler(func() *config.ServerConfig { return &config.ServerConfig{} }).RegisterConfigRoutes(router, guards)
+
+	bearer := func(role string) string {
+		token, _, err := authenticator.IssueToken(role+"-user", role)
+		if err != nil {
+			t.Fatalf("IssueToken: %v", err)
+		}
+		return "Bearer " + token
+	}
+	viewer, admin := bearer(auth.RoleViewer), bearer(auth.RoleAdmin)
+
+	// in order: the admin's requests depend on the ones before them
+	routes := []struct {
+		method, target, body string
+		adminOnly            bool
+		adminStatus          int
+	}{
+		{"GET", "/api/dashboard/groups", "", false, http.StatusOK},
+		{"POST", "/api/dashboard/groups", `{"name":"web"}`, true, http.StatusCreated},
+		{"PUT", "/api/dashboard/groups/web/hosts/host-1", "", true, http.StatusOK},
+		{"DELETE", "/api/dashboard/groups/web/hosts/host-1", "", true, http.StatusOK},
+		{"DELETE", "/api/dashboard/groups/web", "", true, http.StatusNoContent},
+		{"GET", "/api/dashboard/host/host-1/notes", "", false, http.StatusOK},
+		{"PUT", "/api/dashboard/host/host-1/notes", `{"text":"rack 4"}`, true, http.StatusOK},
+		{"POST", "/api/dashboard/host/host-1/silence", `{"until":"2099-01-01T00:00:00Z"}`, true, http.StatusOK},
+		{"DELETE", "/api/dashboard/host/host-1/silence", "", true, http.StatusNoContent},
+		{"GET", "/api/dashboard/alert-rules", "", false, http.StatusOK},
+		{"DELETE", "/api/dashboard/alert-rules/unknown", "", true, http.StatusNotFound},
+		{"GET", "/api/config", "", true, http.StatusOK},
+	}
+	for _, route := range routes {
+		name := route.method + " " + route.target
+
+		if w := serve(router, route.method, route.target, route.body); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s without a token: status %d, want 401", name, w.Code)
+		}
+
+		w := serve(router, route.method, route.target, route.body, "Authorization", viewer)
+		switch {
+		case route.adminOnly && w.Code != http.StatusForbidden:
+			t.Errorf("%s as viewer: status %d, want 403", name, w.Code)
+		case route.adminOnly:
+			var body struct {
+				Code         string `json:"code"`
+				RequiredRole string `json:"required_role"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != auth.CodeForbidden || body.RequiredRole != auth.RoleAdmin {
+				t.Errorf("%s as viewer: body %s, want code forbidden naming the admin role", name, w.Body)
+			}
+		case w.Code != route.adminStatus:
+			t.Errorf("%s as viewer: status %d, want %d", name, w.Code, route.adminStatus)
+		}
+
+		if w := serve(router, route.method, route.target, route.body, "Authorization", admin); w.Code != route.adminStatus {
+			t.Errorf("%s as admin: status %d, want %d (%s)", name, w.Code, route.adminStatus, w.Body)
+		}
+	}
+}
+
+func TestDiskHistoryRoute(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	d.influx.Respond(`"disk_metrics"`, influxtest.NewTable("_time:time", "_value:double").Row(time.Now().Add(-time.Minute), 63.0))
+	router := d.router(RouteGuards{})
+
+	w := serve(router, http.MethodGet, "/api/dashboard/host/host-1/disk/%2Fvar%2Flib/history?field=used_gb&range=6h", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("/var/lib: status %d, want 200: %s", w.Code, w.Body)
+	}
+	var points []models.MetricPoint
+	decodeJSON(t, w, &points)
+	if len(points) != 1 || points[0].Value != 63 {
+		t.Errorf("/var/lib: points %+v, want one of 63", points)
+	}
+	if queries := d.influx.Queries(); len(queries) != 1 || !strings.Contains(queries[0], `r.path == "/var/lib" and r._field == "used_gb"`) {
+		t.Errorf("query doesn't select /var/lib used_gb: %s", queries)
+	}
+
+	for _, target := range []string{
+		"/api/dashboard/host/host-1/disk/%2Fx%22%20or%20true/history", // /x" or true
+		"/api/dashboard/host/host-1/disk/var/history",
+		"/api/dashboard/host/host-1/disk/%2Fvar%2Flib/history?field=inodes_used",
+	} {
+		if w := serve(router, http.MethodGet, target, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", target, w.Code)
+		}
+	}
+	if queries := d.influx.Queries(); len(queries) != 1 {
+		t.Errorf("rejected requests reached InfluxDB: %d queries", len(queries))
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/auth"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const statusDecommissioned = "decommissioned"
+
+type decommissionRequest struct {
+	Reason string `json:"reason"`
+}
+
+func toDecommissionModel(decommission *metadata.Decommission) *models.DecommissionInfo {
+	return &models.DecommissionInfo{
+		Reason:           decommission.Reason,
+		DecommissionedBy: decommission.DecommissionedBy,
+		CreatedAt:        decommission.CreatedAt,
+	}
+}
+
+// applyDecommission marks a decommissioned host. Unlike a silence it also
+// replaces "offline": a retired host not reporting is expected.
+func applyDecommission(status *string, info **models.DecommissionInfo, decommission *metadata.Decommission) {
+	*info = toDecommissionModel(decommission)
+	*status = statusDecommissioned
+}
+
+// DecommissionHost handles POST /api/dashboard/host/:hostID/decommission
+// The body ({"reason": "..."}) is optional. The host's data is kept.
+func (h *DashboardHandler) DecommissionHost(c *gin.Context) {
+	hostID := c.Param("hostID")
+	var req decommissionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid decommission payload", "details": err.Error()})
+			return
+		}
+	}
+	if _, err := h.metaStore.GetHost(hostID); err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Host not found"})
+			return
+		}
+		appLogger.Error("Failed to look up host %s: %v", hostID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decommission host"})
+		return
+	}
+
+	decommissionedBy := "anonymous" // auth disabled
+	if claims := auth.ClaimsFromContext(c); claims != nil {
+		decommissionedBy = claims.Username
+	}
+
+	decommission, err := h.metaStore.Decommission(metadata.Decommission{
+		HostID:           hostID,
+		Reason:           strings.TrimSpace(req.Reason),
+		DecommissionedBy: decommissionedBy,
+	})
+	if err != nil {
+		if errors.Is(err, metadata.ErrAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Host is already decommissioned"})
+			return
+		}
+		appLogger.Error("Failed to decommission host %s: %v", hostID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decommission host"})
+		return
+	}
+	appLogger.Info("Host %s decommissioned by %s: %s", hostID, decommissionedBy, decommission.Reason)
+	c.JSON(http.StatusOK, toDecommissionModel(decommission))
+}
+
+// RecommissionHost handles DELETE /api/dashboard/host/:hostID/decommission
+func (h *DashboardHandler) RecommissionHost(c *gin.Context) {
+	hostID := c.Param("hostID")
+	if err := h.metaStore.Recommission(hostID); err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Host is not decommissioned"})
+			return
+		}
+		appLogger.Error("Failed to recommission host %s: %v", hostID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recommission host"})
+		return
+	}
+	appLogger.Info("Host %s recommissioned", hostID)
+	c.Status(http.StatusNoContent)
+}
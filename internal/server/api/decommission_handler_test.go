@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+func TestDecommissionedHostHiddenUntilItReportsAgain(t *testing.T) {
+	const offlineAfter = 300 * time.Millisecond
+	d := newTestDashboard(t, func(cfg *config.InfluxDBConfig) { cfg.OnlineWithin = offlineAfter })
+	ingest := newTestIngestOn(t, d, nil)
+	router := d.router(RouteGuards{})
+	d.influx.Respond(`yield(name: "overview")`, overviewTable().
+		Row("host-1", "web-1", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, time.Now()).
+		Row("host-2", "web-2", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, time.Now()))
+
+	overview := func(target string) map[string]models.HostOverviewData {
+		t.Helper()
+		var hosts []models.HostOverviewData
+		decodeJSON(t, serve(router, http.MethodGet, target, ""), &hosts)
+		byID := map[string]models.HostOverviewData{}
+		for _, host := range hosts {
+			byID[host.ID] = host
+		}
+		return byID
+	}
+	report := func() {
+		t.Helper()
+		if w := ingest.post(t, "/api/stats", testPayload("host-1", time.Now())); w.Code != http.StatusOK {
+			t.Fatalf("report: status %d: %s", w.Code, w.Body)
+		}
+	}
+
+	if w := serve(router, http.MethodPost, "/api/dashboard/host/host-1/decommission", `{"reason":"retired"}`); w.Code != http.StatusNotFound {
+		t.Errorf("decommissioning a host the store doesn't know: status %d, want 404", w.Code)
+	}
+	report() // registers host-1
+	w := serve(router, http.MethodPost, "/api/dashboard/host/host-1/decommission", `{"reason":" retired "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("decommission: status %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := serve(router, http.MethodPost, "/api/dashboard/host/host-1/decommission", ""); w.Code != http.StatusConflict {
+		t.Errorf("second decommission: status %d, want 409", w.Code)
+	}
+
+	if hosts := overview("/api/dashboard/hosts/overview"); len(hosts) != 1 || hosts["host-2"].ID == "" {
+		t.Errorf("overview = %v, want host-2 only", hosts)
+	}
+	host := overview("/api/dashboard/hosts/overview?include_decommissioned=true")["host-1"]
+	if host.Status != statusDecommissioned || host.Decommissioned == nil || host.Decommissioned.Reason != "retired" {
+		t.Errorf("host-1 with include_decommissioned = status %s, %+v, want decommissioned for the trimmed reason", host.Status, host.Decommissioned)
+	}
+
+	// a report right after decommissioning may have been in flight
+	report()
+	if _, err := d.store.GetDecommission("host-1"); err != nil {
+		t.Fatalf("decommission gone after an in-flight report: %v", err)
+	}
+
+	time.Sleep(offlineAfter + 50*time.Millisecond)
+	report()
+	if _, err := d.store.GetDecommission("host-1"); err == nil {
+		t.Fatal("host-1 still decommissioned after reporting again")
+	}
+	host = overview("/api/dashboard/hosts/overview")["host-1"]
+	if host.ID == "" || host.Status == statusDecommissioned || host.Decommissioned != nil {
+		t.Errorf("host-1 after reporting again = %+v, want it listed with its own status", host)
+	}
+	annotations, err := d.store.ListAnnotations(time.Now().Add(-time.Hour), time.Now().Add(time.Hour), "host-1")
+	if err != nil {
+		t.Fatalf("ListAnnotations: %v", err)
+	}
+	var recommissioned bool
+	for _, annotation := range annotations {
+		for _, tag := range annotation.Tags {
+			recommissioned = recommissioned || tag == "host-recommissioned"
+		}
+	}
+	if !recommissioned {
+		t.Errorf("annotations %+v, want one for the recommission", annotations)
+	}
+
+	if w := serve(router, http.MethodDelete, "/api/dashboard/host/host-1/decommission", ""); w.Code != http.StatusNotFound {
+		t.Errorf("recommission of a host that isn't decommissioned: status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+func TestHostDetailsV2SeparatesUsedGBAndUsagePercent(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	// 12 of 16 GB used is 75%; the agent's usage percent (available-based)
+	// differs, so a field read from the wrong column shows
+	d.influx.Respond("hostDisks = ", influxtest.NewTable(
+		"_time:time", "host_id", "hostname", "disk_found:boolean",
+		"mem_total_gb:double", "mem_available_gb:double", "mem_used_gb:double", "mem_usage_percent:double",
+		"mem_total_bytes:long", "mem_available_bytes:long", "mem_used_bytes:long",
+	).Row(time.Now(), "host-1", "host-1", false,
+		16.0, 4.0, 12.0, 71.5,
+		int64(17_179_869_184), int64(4_294_967_296), int64(12_884_901_888)))
+	router := d.router(RouteGuards{})
+
+	w := serve(router, http.MethodGet, "/api/v2/dashboard/host/host-1/details", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var v2 struct {
+		Memory map[string]float64 `json:"memory"`
+	}
+	decodeJSON(t, w, &v2)
+	want := map[string]float64{
+		"total_gb": 16, "used_gb": 12, "available_gb": 4, "usage_percent": 71.5,
+		"total_bytes": 17_179_869_184, "used_bytes": 12_884_901_888, "available_bytes": 4_294_967_296,
+	}
+	for key, value := range want {
+		if got, ok := v2.Memory[key]; !ok || got != value {
+			t.Errorf("memory.%s = %v (present %t), want %v", key, got, ok, value)
+		}
+	}
+	if len(v2.Memory) != len(want) {
+		t.Errorf("memory = %v, want exactly the keys %v", v2.Memory, want)
+	}
+
+	// the legacy route keeps its shape
+	w = serve(router, http.MethodGet, "/api/dashboard/host/host-1/details", "")
+	var legacy struct {
+		Memory map[string]float64 `json:"memory"`
+	}
+	decodeJSON(t, w, &legacy)
+	if legacy.Memory["free_gb"] != 4 || legacy.Memory["used_gb"] != 12 || legacy.Memory["usage_percent"] != 71.5 {
+		t.Errorf("legacy memory = %v, want free_gb 4, used_gb 12, usage_percent 71.5", legacy.Memory)
+	}
+	if _, ok := legacy.Memory["available_gb"]; ok {
+		t.Errorf("legacy memory has v2's available_gb: %v", legacy.Memory)
+	}
+}
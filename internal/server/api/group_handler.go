@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxGroupNameLength = 64
+
+type createGroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// ListGroups handles GET /api/dashboard/groups
+func (h *DashboardHandler) ListGroups(c *gin.Context) {
+	groups, err := h.metaStore.ListGroups()
+	if err != nil {
+		appLogger.Error("Failed to list groups: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve groups"})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+// CreateGroup handles POST /api/dashboard/groups
+func (h *DashboardHandler) CreateGroup(c *gin.Context) {
+	var req createGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group payload", "details": err.Error()})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxGroupNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group name must be 1-64 characters"})
+		return
+	}
+
+	group, err := h.metaStore.CreateGroup(req.Name, req.Description)
+	if err != nil {
+		if errors.Is(err, metadata.ErrAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Group already exists"})
+			return
+		}
+		appLogger.Error("Failed to create group %s: %v", req.Name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+	appLogger.Info("Created host group %s", group.Name)
+	c.JSON(http.StatusCreated, group)
+}
+
+// DeleteGroup handles DELETE /api/dashboard/groups/:group
+func (h *DashboardHandler) DeleteGroup(c *gin.Context) {
+	name := c.Param("group")
+	if err := h.metaStore.DeleteGroup(name); err != nil {
+		h.respondGroupError(c, name, err)
+		return
+	}
+	appLogger.Info("Deleted host group %s", name)
+	c.Status(http.StatusNoContent)
+}
+
+// AddHostToGroup handles PUT /api/dashboard/groups/:group/hosts/:hostID
+func (h *DashboardHandler) AddHostToGroup(c *gin.Context) {
+	name := c.Param("group")
+	hostID := c.Param("hostID")
+	group, err := h.metaStore.AddHostToGroup(name, hostID)
+	if err != nil {
+		h.respondGroupError(c, name, err)
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// RemoveHostFromGroup handles DELETE /api/dashboard/groups/:group/hosts/:hostID
+func (h *DashboardHandler) RemoveHostFromGroup(c *gin.Context) {
+	name := c.Param("group")
+	hostID := c.Param("hostID")
+	group, err := h.metaStore.RemoveHostFromGroup(name, hostID)
+	if err != nil {
+		h.respondGroupError(c, name, err)
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+func (h *DashboardHandler) respondGroupError(c *gin.Context, name string, err error) {
+	if errors.Is(err, metadata.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	appLogger.Error("Metadata store error for group %s: %v", name, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+}
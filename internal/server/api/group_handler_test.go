@@ -0,0 +1,177 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/auth"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGroupCRUD(t *testing.T) {
+	router := newTestDashboard(t, nil).router(RouteGuards{})
+
+	w := serve(router, http.MethodPost, "/api/dashboard/groups", `{"name":" web ","description":"front ends"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, want 201: %s", w.Code, w.Body)
+	}
+	var group metadata.Group
+	decodeJSON(t, w, &group)
+	if group.Name != "web" || group.Description != "front ends" || group.Hosts == nil || len(group.Hosts) != 0 {
+		t.Errorf("created group = %+v, want web with no hosts", group)
+	}
+
+	for _, tc := range []struct {
+		name, body string
+		want       int
+	}{
+		{"duplicate", `{"name":"web"}`, http.StatusConflict},
+		{"blank name", `{"name":"  "}`, http.StatusBadRequest},
+		{"long name", `{"name":"` + strings.Repeat("x", maxGroupNameLength+1) + `"}`, http.StatusBadRequest},
+		{"no name", `{}`, http.StatusBadRequest},
+	} {
+		if w := serve(router, http.MethodPost, "/api/dashboard/groups", tc.body); w.Code != tc.want {
+			t.Errorf("create %s: status %d, want %d", tc.name, w.Code, tc.want)
+		}
+	}
+
+	// members stay sorted and adding one twice is a no-op
+	for _, hostID := range []string{"host-2", "host-1", "host-2"} {
+		w := serve(router, http.MethodPut, "/api/dashboard/groups/web/hosts/"+hostID, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("add %s: status %d, want 200: %s", hostID, w.Code, w.Body)
+		}
+		decodeJSON(t, w, &group)
+	}
+	if fmt.Sprint(group.Hosts) != "[host-1 host-2]" {
+		t.Errorf("hosts after adding = %v, want [host-1 host-2]", group.Hosts)
+	}
+
+	serve(router, http.MethodPost, "/api/dashboard/groups", `{"name":"db"}`)
+	var groups []metadata.Group
+	decodeJSON(t, serve(router, http.MethodGet, "/api/dashboard/groups", ""), &groups)
+	if len(groups) != 2 || groups[0].Name != "db" || groups[1].Name != "web" || len(groups[1].Hosts) != 2 {
+		t.Errorf("list = %+v, want db then web with 2 hosts", groups)
+	}
+
+	w = serve(router, http.MethodDelete, "/api/dashboard/groups/web/hosts/host-1", "")
+	decodeJSON(t, w, &group)
+	if w.Code != http.StatusOK || fmt.Sprint(group.Hosts) != "[host-2]" {
+		t.Errorf("remove host-1: status %d, hosts %v, want 200 [host-2]", w.Code, group.Hosts)
+	}
+
+	if w := serve(router, http.MethodDelete, "/api/dashboard/groups/web", ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d, want 204", w.Code)
+	}
+	for _, req := range [][2]string{
+		{http.MethodDelete, "/api/dashboard/groups/web"},
+		{http.MethodPut, "/api/dashboard/groups/web/hosts/host-1"},
+		{http.MethodDelete, "/api/dashboard/groups/web/hosts/host-1"},
+	} {
+		if w := serve(router, req[0], req[1], ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s after delete: status %d, want 404", req[0], req[1], w.Code)
+		}
+	}
+}
+
+func TestGroupMutationsNeedAdmin(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{
+		Enabled:   true,
+		JWTSecret: "test-secret",
+		Issuer:    "system-stats-monitoring",
+		TokenTTL:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	d := newTestDashboard(t, nil)
+	router := d.router(RouteGuards{
+		Read:  []gin.HandlerFunc{authenticator.Middleware()},
+		Admin: []gin.HandlerFunc{auth.RequireRole(auth.RoleAdmin)},
+	})
+	token, _, err := authenticator.IssueToken("viewer-user", auth.RoleViewer)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+	viewer := "Bearer " + token
+
+	if _, err := d.store.CreateGroup("web", ""); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	for _, req := range [][3]string{
+		{http.MethodPost, "/api/dashboard/groups", `{"name":"db"}`},
+		{http.MethodPut, "/api/dashboard/groups/web/hosts/host-1", ""},
+		{http.MethodDelete, "/api/dashboard/groups/web", ""},
+	} {
+		if w := serve(router, req[0], req[1], req[2], "Authorization", viewer); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s as viewer: status %d, want 403", req[0], req[1], w.Code)
+		}
+	}
+	if w := serve(router, http.MethodGet, "/api/dashboard/groups", "", "Authorization", viewer); w.Code != http.StatusOK {
+		t.Errorf("list as viewer: status %d, want 200", w.Code)
+	}
+
+	// the rejected requests changed nothing
+	groups, err := d.store.ListGroups()
+	if err != nil {
+		t.Fatalf("ListGroups: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Name != "web" || len(groups[0].Hosts) != 0 {
+		t.Errorf("groups after viewer requests = %+v, want only an empty web", groups)
+	}
+}
+
+func TestOverviewGroupFilter(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	now := time.Now()
+	d.influx.Respond(`yield(name: "overview")`, overviewTable().
+		Row("web-1", "web-1", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now).
+		Row("web-2", "web-2", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now).
+		Row("db-1", "db-1", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now))
+	for _, name := range []string{"web", "all"} {
+		if _, err := d.store.CreateGroup(name, ""); err != nil {
+			t.Fatalf("CreateGroup %s: %v", name, err)
+		}
+	}
+	for _, member := range [][2]string{{"web", "web-1"}, {"web", "web-2"}, {"all", "web-1"}} {
+		if _, err := d.store.AddHostToGroup(member[0], member[1]); err != nil {
+			t.Fatalf("AddHostToGroup: %v", err)
+		}
+	}
+	router := d.router(RouteGuards{})
+
+	overview := func(target string) map[string][]string {
+		t.Helper()
+		w := serve(router, http.MethodGet, target, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d, want 200: %s", target, w.Code, w.Body)
+		}
+		var hosts []models.HostOverviewData
+		decodeJSON(t, w, &hosts)
+		groups := map[string][]string{}
+		for _, host := range hosts {
+			groups[host.ID] = host.Groups
+		}
+		return groups
+	}
+
+	got := overview("/api/dashboard/hosts/overview")
+	if want := "map[db-1:[] web-1:[all web] web-2:[web]]"; fmt.Sprint(got) != want {
+		t.Errorf("unfiltered groups = %v, want %s", got, want)
+	}
+	if got := overview("/api/dashboard/hosts/overview?group=web"); fmt.Sprint(got) != "map[web-1:[all web] web-2:[web]]" {
+		t.Errorf("?group=web = %v, want web-1 and web-2", got)
+	}
+	if got := overview("/api/dashboard/hosts/overview?group=all"); len(got) != 1 || got["web-1"] == nil {
+		t.Errorf("?group=all = %v, want only web-1", got)
+	}
+	if got := overview("/api/dashboard/hosts/overview?group=unknown"); len(got) != 0 {
+		t.Errorf("?group=unknown = %v, want no hosts", got)
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+func TestListHostsReturnsEveryHostWithMinimalFields(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	now := time.Now().UTC().Truncate(time.Second)
+	d.influx.Respond(`keep(columns: ["host_id", "hostname", "_time"])`, influxtest.NewTable("host_id", "hostname", "_time:time").
+		Row("host-3", "web1.example.com", now).
+		Row("host-1", "db1.example.com", now.Add(-20*time.Hour)). // offline for hours, still listed
+		Row("host-2", "cache1.example.com", now.Add(-time.Minute)))
+	router := d.router(RouteGuards{})
+
+	w := serve(router, http.MethodGet, "/api/dashboard/hosts?range=24h", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var hosts []map[string]json.RawMessage
+	decodeJSON(t, w, &hosts)
+	want := []struct {
+		id, hostname string
+		lastSeen     time.Time
+	}{
+		{"host-2", "cache1.example.com", now.Add(-time.Minute)},
+		{"host-1", "db1.example.com", now.Add(-20 * time.Hour)},
+		{"host-3", "web1.example.com", now},
+	}
+	if len(hosts) != len(want) {
+		t.Fatalf("hosts = %s, want %d", w.Body, len(want))
+	}
+	for i, host := range hosts {
+		if len(host) != 3 {
+			t.Errorf("host %d has keys %v, want only id, hostname and lastSeen", i, keys(host))
+		}
+		var id, hostname string
+		var lastSeen time.Time
+		_ = json.Unmarshal(host["id"], &id)
+		_ = json.Unmarshal(host["hostname"], &hostname)
+		_ = json.Unmarshal(host["lastSeen"], &lastSeen)
+		if id != want[i].id || hostname != want[i].hostname || !lastSeen.Equal(want[i].lastSeen) {
+			t.Errorf("host %d = %s %s %v, want %s %s %v (by hostname)", i, id, hostname, lastSeen, want[i].id, want[i].hostname, want[i].lastSeen)
+		}
+	}
+
+	// one query, on system_metrics only
+	queries := d.influx.Queries()
+	if len(queries) != 1 {
+		t.Fatalf("%d queries, want 1", len(queries))
+	}
+	for _, costly := range []string{"disk_metrics", "process_metrics", "join"} {
+		if strings.Contains(queries[0], costly) {
+			t.Errorf("host list query reads %s:\n%s", costly, queries[0])
+		}
+	}
+}
+
+func keys(m map[string]json.RawMessage) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListLabelGroups handles GET /api/dashboard/labels
+// Every label=value reported by agents (MONITOR_LABELS) with its host count.
+// Label groups live under /labels since /groups are the metadata store's
+// host groups (see RegisterDashboardRoutes).
+func (h *DashboardHandler) ListLabelGroups(c *gin.Context) {
+	groups, err := h.dbReader.GetLabelGroups(c.Request.Context())
+	if err != nil {
+		appLogger.Error("Failed to list label groups: %v", err)
+		respondReaderError(c, err, "Failed to retrieve label groups")
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+// GetLabelGroupOverview handles GET /api/dashboard/labels/:label/:value/overview
+// The hosts overview limited to hosts labelled label=value, with the same
+// query parameters. Unknown labels give an empty list.
+func (h *DashboardHandler) GetLabelGroupOverview(c *gin.Context) {
+	label, value := c.Param("label"), c.Param("value")
+	if !database.ValidLabel(label, value) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label, expected a name of lowercase letters, digits and _ and a value of letters, digits and . _ : / -"})
+		return
+	}
+	hostIDs, err := h.dbReader.GetHostIDsByLabel(c.Request.Context(), label, value)
+	if err != nil {
+		appLogger.Error("Failed to get hosts for label %s=%s: %v", label, value, err)
+		respondReaderError(c, err, "Failed to retrieve label group")
+		return
+	}
+	onlyHosts := make(map[string]bool, len(hostIDs))
+	for _, hostID := range hostIDs {
+		onlyHosts[hostID] = true
+	}
+	h.serveOverview(c, onlyHosts)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// overviewTable is an empty result of the overview query; add rows of
+// host_id, hostname, cpu, mem, uptime, lag_found, lag, upload, download,
+// disk_found, disk, inodes_found, inodes, _time.
+func overviewTable() *influxtest.Table {
+	return influxtest.NewTable("host_id", "hostname",
+		"cpu_usage_percent:double", "mem_usage_percent:double", "uptime_seconds:long",
+		"lag_found:boolean", "ingest_lag_seconds:double",
+		"net_upload_bytes_sec:double", "net_download_bytes_sec:double",
+		"disk_found:boolean", "disk_usage_percent:double",
+		"inodes_found:boolean", "inodes_usage_percent:double", "_time:time")
+}
+
+func TestListLabelGroups(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	d.influx.Respond(`"system_metrics"`, influxtest.NewTable("host_id", "label_tier", "label_dc", "_value:double").
+		Row("host-1", "web", "eu-1", 10.0).
+		Row("host-2", "web", "", 10.0). // no dc label
+		Row("host-3", "db", "eu-1", 10.0))
+	router := d.router(RouteGuards{})
+
+	var groups []models.LabelGroup
+	decodeJSON(t, serve(router, http.MethodGet, "/api/dashboard/labels", ""), &groups)
+	want := "[{dc eu-1 2} {tier db 1} {tier web 2}]"
+	if fmt.Sprint(groups) != want {
+		t.Errorf("groups = %v, want %s", groups, want)
+	}
+
+	// no labelled hosts: an empty list, not null
+	d = newTestDashboard(t, nil)
+	w := serve(d.router(RouteGuards{}), http.MethodGet, "/api/dashboard/labels", "")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("without labels: status %d, body %s, want 200 []", w.Code, w.Body)
+	}
+}
+
+func TestLabelGroupOverview(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	now := time.Now()
+	d.influx.Respond(`keep(columns: ["host_id"])`, influxtest.NewTable("host_id").Row("web-1").Row("web-2"))
+	d.influx.Respond(`yield(name: "overview")`, overviewTable().
+		Row("web-1", "web-1", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now).
+		Row("web-2", "web-2", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now).
+		Row("db-1", "db-1", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now))
+	router := d.router(RouteGuards{})
+
+	var hosts []models.HostOverviewData
+	decodeJSON(t, serve(router, http.MethodGet, "/api/dashboard/labels/tier/web/overview", ""), &hosts)
+	var ids []string
+	for _, host := range hosts {
+		ids = append(ids, host.ID)
+	}
+	if fmt.Sprint(ids) != "[web-1 web-2]" {
+		t.Errorf("tier=web hosts = %v, want [web-1 web-2]", ids)
+	}
+	found := false
+	for _, query := range d.influx.Queries() {
+		found = found || strings.Contains(query, `r["label_tier"] == "web"`)
+	}
+	if !found {
+		t.Errorf("no query filters on label_tier == web: %q", d.influx.Queries())
+	}
+
+	// a label value no host carries: an empty list
+	d = newTestDashboard(t, nil)
+	d.influx.Respond(`yield(name: "overview")`, overviewTable().
+		Row("db-1", "db-1", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now))
+	w := serve(d.router(RouteGuards{}), http.MethodGet, "/api/dashboard/labels/tier/cache/overview", "")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("unknown group: status %d, body %s, want 200 []", w.Code, w.Body)
+	}
+
+	// names and values that are not safe in a Flux query
+	for _, target := range []string{"/api/dashboard/labels/Tier/web/overview", `/api/dashboard/labels/tier/web%22%20or%20true/overview`} {
+		if w := serve(router, http.MethodGet, target, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+func TestMetricsHistoryCapsTheFields(t *testing.T) {
+	d := newTestDashboard(t, func(cfg *config.InfluxDBConfig) { cfg.HistoryMaxFields = 2 })
+	router := d.router(RouteGuards{})
+	historyQueries := func() []string {
+		var queries []string
+		for _, query := range d.influx.Queries() {
+			if strings.Contains(query, "targetFields = ") {
+				queries = append(queries, query)
+			}
+		}
+		return queries
+	}
+
+	w := serve(router, http.MethodGet, "/api/dashboard/host/host-1/metrics?fields=cpu_usage_percent,mem_usage_percent,net_upload_bytes_sec", "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("3 fields over a cap of 2: status %d, want 400: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	decodeJSON(t, w, &body)
+	if body.Code != "too_many_fields" {
+		t.Errorf("code %q, want too_many_fields", body.Code)
+	}
+	if queries := historyQueries(); len(queries) != 0 {
+		t.Errorf("%d history queries sent for a rejected request, want none", len(queries))
+	}
+
+	// repeated names count once: two distinct fields are within the cap
+	w = serve(router, http.MethodGet, "/api/dashboard/host/host-1/metrics?fields=cpu_usage_percent,mem_usage_percent&fields=cpu_usage_percent", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("2 distinct fields: status %d, want 200: %s", w.Code, w.Body)
+	}
+	var history models.HostMetricsHistoryData
+	decodeJSON(t, w, &history)
+	if len(history.Metrics) != 2 || history.Metrics["cpu_usage_percent"] == nil || history.Metrics["mem_usage_percent"] == nil {
+		t.Errorf("metrics = %v, want cpu_usage_percent and mem_usage_percent", history.Metrics)
+	}
+	queries := historyQueries()
+	if len(queries) != 1 {
+		t.Fatalf("%d history queries, want 1", len(queries))
+	}
+	if !strings.Contains(queries[0], `targetFields = ["cpu_usage_percent", "mem_usage_percent"]`) {
+		t.Errorf("query does not ask for each field once:\n%s", queries[0])
+	}
+
+	// every field is checked against the allow-list
+	w = serve(router, http.MethodGet, "/api/dashboard/host/host-1/metrics?fields=cpu_usage_percent,hostname", "")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "hostname") {
+		t.Errorf("unknown field: status %d, body %s, want 400 naming it", w.Code, w.Body)
+	}
+}
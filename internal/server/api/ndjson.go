@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+	ndjsonFlushEvery  = 500 // lines between flushes
+)
+
+// wantsNDJSON reports whether the client asked for a JSON-lines stream.
+func wantsNDJSON(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), ndjsonContentType)
+}
+
+// ndjsonStream writes one JSON value per line. The 200 header goes out with
+// the first value, so errors before that still get a normal error response.
+type ndjsonStream struct {
+	c       *gin.Context
+	enc     *json.Encoder
+	lines   int
+	started bool
+}
+
+func newNDJSONStream(c *gin.Context) *ndjsonStream {
+	return &ndjsonStream{c: c}
+}
+
+func (s *ndjsonStream) start() {
+	s.started = true
+	s.c.Header("Content-Type", ndjsonContentType)
+	s.c.Header("X-Content-Type-Options", "nosniff")
+	s.c.Status(http.StatusOK)
+	s.c.Writer.WriteHeaderNow()
+	s.enc = json.NewEncoder(s.c.Writer)
+}
+
+// Write sends v as one line, flushing every ndjsonFlushEvery lines.
+func (s *ndjsonStream) Write(v interface{}) error {
+	if !s.started {
+		s.start()
+	}
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+	s.lines++
+	if s.lines%ndjsonFlushEvery == 0 {
+		s.c.Writer.Flush()
+	}
+	return nil
+}
+
+// Finish ends the stream. An error before the first line is answered like
+// any reader error; after it the stream ends with a terminal
+// {"error", "code"} line, which clients must check for.
+func (s *ndjsonStream) Finish(err error, message string) {
+	if err != nil && !s.started {
+		respondReaderError(s.c, err, message)
+		return
+	}
+	if !s.started {
+		s.start() // empty result: 200 with an empty body
+	}
+	if err != nil {
+		code := "stream_error"
+		if deadlineExceeded(s.c) {
+			code = "timeout"
+		}
+		_ = s.enc.Encode(gin.H{"error": message, "code": code})
+	}
+	s.c.Writer.Flush()
+}
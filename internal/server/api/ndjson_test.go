@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// historyTable is a metric history result of n points, 30s apart.
+func historyTable(n int) *influxtest.Table {
+	table := influxtest.NewTable("_time:time", "_value:double")
+	start := time.Now().Add(-time.Duration(n) * 30 * time.Second)
+	for i := 0; i < n; i++ {
+		table.Row(start.Add(time.Duration(i)*30*time.Second), float64(i%100))
+	}
+	return table
+}
+
+func TestMetricHistoryStreamsNDJSON(t *testing.T) {
+	const points = 1234 // more than ndjsonFlushEvery
+	d := newTestDashboard(t, nil)
+	d.influx.Respond(`yield(name: "mean")`, historyTable(points))
+	router := d.router(RouteGuards{})
+	target := "/api/dashboard/host/host-1/metrics/cpu_usage_percent?range=12h"
+
+	w := serve(router, http.MethodGet, target, "", "Accept", ndjsonContentType)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != ndjsonContentType {
+		t.Fatalf("status %d, Content-Type %q, want 200 %s", w.Code, w.Header().Get("Content-Type"), ndjsonContentType)
+	}
+	count := 0
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var point models.MetricPoint
+		if err := json.Unmarshal(scanner.Bytes(), &point); err != nil {
+			t.Fatalf("line %d: %v: %s", count+1, err, scanner.Text())
+		}
+		if point.Time.IsZero() || point.Value != float64(count%100) {
+			t.Fatalf("line %d: %+v, want value %d with a time", count+1, point, count%100)
+		}
+		count++
+	}
+	if count != points {
+		t.Errorf("streamed %d points, want %d", count, points)
+	}
+
+	// without the Accept header: the usual JSON array
+	w = serve(router, http.MethodGet, target, "")
+	var history []models.MetricPoint
+	decodeJSON(t, w, &history)
+	if len(history) != points {
+		t.Errorf("JSON array of %d points, want %d", len(history), points)
+	}
+}
+
+func TestMetricHistoryStreamEndsWithAnErrorLine(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	body := influxtest.CSV(historyTable(10))
+	// InfluxDB goes away after 10 points
+	d.influx.RespondFunc(`yield(name: "mean")`, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		_, _ = w.Write([]byte(strings.TrimSuffix(body, "\r\n")))
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	})
+	router := d.router(RouteGuards{})
+
+	w := serve(router, http.MethodGet, "/api/dashboard/host/host-1/metrics/cpu_usage_percent?range=1h", "", "Accept", ndjsonContentType)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if w.Code != http.StatusOK || len(lines) < 2 {
+		t.Fatalf("status %d with %d lines, want 200 with points and an error line:\n%s", w.Code, len(lines), w.Body)
+	}
+	var last struct{ Error, Code string }
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil || last.Code != "stream_error" || last.Error == "" {
+		t.Errorf("last line %s, want {\"error\", \"code\": \"stream_error\"}", lines[len(lines)-1])
+	}
+	for _, line := range lines[:len(lines)-1] {
+		var point models.MetricPoint
+		if err := json.Unmarshal([]byte(line), &point); err != nil || point.Time.IsZero() {
+			t.Errorf("line before the error isn't a point: %s", line)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// netUnit is a unit network rates can be returned in. The reader always
+// works in bytes/sec; handlers convert just before responding.
+type netUnit struct {
+	name   string
+	factor float64 // multiplier from bytes/sec
+}
+
+// Decimal units, like link speeds: 1 Mbps = 1,000,000 bits/sec.
+var netUnits = map[string]netUnit{
+	"Bps":  {"Bps", 1},
+	"bps":  {"bps", 8},
+	"MBps": {"MBps", 1e-6},
+	"Mbps": {"Mbps", 8e-6},
+}
+
+var defaultNetUnit = netUnits["Bps"]
+
+var errInvalidNetUnit = errors.New("invalid netUnit, expected bps, Bps, Mbps or MBps")
+
+// parseNetUnit reads ?netUnit (case-sensitive: b is bits, B is bytes).
+func parseNetUnit(c *gin.Context) (netUnit, error) {
+	value, ok := c.GetQuery("netUnit")
+	if !ok || value == "" {
+		return defaultNetUnit, nil
+	}
+	unit, ok := netUnits[value]
+	if !ok {
+		return netUnit{}, errInvalidNetUnit
+	}
+	return unit, nil
+}
+
+// convert converts a rate in bytes/sec to the unit.
+func (u netUnit) convert(bytesPerSec float64) float64 {
+	return bytesPerSec * u.factor
+}
+
+// apply converts upload and download in place.
+func (u netUnit) apply(upload, download *float64) {
+	*upload, *download = u.convert(*upload), u.convert(*download)
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+func TestNetUnitConversion(t *testing.T) {
+	const bytesPerSec = 1_250_000 // 10 Mbit/s
+	for name, want := range map[string]float64{"Bps": 1_250_000, "bps": 10_000_000, "MBps": 1.25, "Mbps": 10} {
+		if got := netUnits[name].convert(bytesPerSec); got != want {
+			t.Errorf("%d B/s in %s = %v, want %v", bytesPerSec, name, got, want)
+		}
+	}
+}
+
+func TestOverviewInMbps(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	d.influx.Respond(`yield(name: "overview")`, overviewTable().
+		Row("host-1", "host-1.example.com", 10.0, 20.0, int64(3600), false, 0.0, 1_250_000.0, 12_500_000.0, true, 30.0, false, 0.0, time.Now()))
+	router := d.router(RouteGuards{})
+
+	var hosts []models.HostOverviewData
+	decodeJSON(t, serve(router, http.MethodGet, "/api/dashboard/hosts/overview?netUnit=Mbps", ""), &hosts)
+	if len(hosts) != 1 {
+		t.Fatalf("hosts = %+v, want host-1", hosts)
+	}
+	if host := hosts[0]; host.NetworkUpload != 10 || host.NetworkDownload != 100 || host.NetworkUnit != "Mbps" {
+		t.Errorf("network = %v up / %v down %s, want 10 / 100 Mbps", host.NetworkUpload, host.NetworkDownload, host.NetworkUnit)
+	}
+
+	// bytes/sec by default
+	decodeJSON(t, serve(router, http.MethodGet, "/api/dashboard/hosts/overview", ""), &hosts)
+	if host := hosts[0]; host.NetworkUpload != 1_250_000 || host.NetworkUnit != "Bps" {
+		t.Errorf("default: upload %v %s, want 1250000 Bps", host.NetworkUpload, host.NetworkUnit)
+	}
+
+	// units are case-sensitive: b is bits, B is bytes
+	if w := serve(router, http.MethodGet, "/api/dashboard/hosts/overview?netUnit=mbps", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("netUnit=mbps: status %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxNotesLength = 16 * 1024
+
+type putNotesRequest struct {
+	Text     string `json:"text"`
+	Revision uint64 `json:"revision"` // revision the client last read, 0 for new notes
+}
+
+func toNotesModel(notes *metadata.HostNotes) models.HostNotes {
+	return models.HostNotes{
+		Text:      notes.Text,
+		Revision:  notes.Revision,
+		UpdatedAt: notes.UpdatedAt,
+	}
+}
+
+// GetHostNotes handles GET /api/dashboard/host/:hostID/notes
+func (h *DashboardHandler) GetHostNotes(c *gin.Context) {
+	hostID := c.Param("hostID")
+	notes, err := h.metaStore.GetHostNotes(hostID)
+	if err != nil {
+		appLogger.Error("Failed to read notes for host %s: %v", hostID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve host notes"})
+		return
+	}
+	c.JSON(http.StatusOK, toNotesModel(notes))
+}
+
+// PutHostNotes handles PUT /api/dashboard/host/:hostID/notes
+// Returns 409 with the current notes if someone else saved in the meantime.
+func (h *DashboardHandler) PutHostNotes(c *gin.Context) {
+	hostID := c.Param("hostID")
+	var req putNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notes payload", "details": err.Error()})
+		return
+	}
+	if len(req.Text) > maxNotesLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Notes are too long (max 16KB)"})
+		return
+	}
+
+	notes, err := h.metaStore.PutHostNotes(hostID, req.Text, req.Revision)
+	if err != nil {
+		if errors.Is(err, metadata.ErrRevisionConflict) {
+			appLogger.Warn("Notes update conflict for host %s: client revision %d, current %d", hostID, req.Revision, notes.Revision)
+			c.JSON(http.StatusConflict, gin.H{"error": "Notes were modified by someone else", "current": toNotesModel(notes)})
+			return
+		}
+		appLogger.Error("Failed to save notes for host %s: %v", hostID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save host notes"})
+		return
+	}
+	c.JSON(http.StatusOK, toNotesModel(notes))
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+func TestPutHostNotesRevisions(t *testing.T) {
+	router := newTestDashboard(t, nil).router(RouteGuards{})
+	const target = "/api/dashboard/host/host-1/notes"
+
+	var notes models.HostNotes
+	decodeJSON(t, serve(router, http.MethodGet, target, ""), &notes)
+	if notes.Revision != 0 || notes.Text != "" {
+		t.Fatalf("notes before the first write = %+v, want empty at revision 0", notes)
+	}
+
+	// first write: revision 0
+	w := serve(router, http.MethodPut, target, `{"text":"rack 4","revision":0}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first write: status %d, want 200: %s", w.Code, w.Body)
+	}
+	decodeJSON(t, w, &notes)
+	if notes.Revision != 1 || notes.Text != "rack 4" || notes.UpdatedAt.IsZero() {
+		t.Errorf("after the first write = %+v, want rack 4 at revision 1", notes)
+	}
+
+	// matching revision
+	w = serve(router, http.MethodPut, target, `{"text":"rack 5","revision":1}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("matching revision: status %d, want 200: %s", w.Code, w.Body)
+	}
+	decodeJSON(t, w, &notes)
+	if notes.Revision != 2 || notes.Text != "rack 5" {
+		t.Errorf("after the second write = %+v, want rack 5 at revision 2", notes)
+	}
+
+	// stale revisions, including a second "first write": 409 with what is stored now
+	for _, body := range []string{`{"text":"rack 6","revision":1}`, `{"text":"rack 6","revision":0}`, `{"text":"rack 6","revision":7}`} {
+		w := serve(router, http.MethodPut, target, body)
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s: status %d, want 409", body, w.Code)
+			continue
+		}
+		var conflict struct {
+			Current models.HostNotes `json:"current"`
+		}
+		decodeJSON(t, w, &conflict)
+		if conflict.Current.Revision != 2 || conflict.Current.Text != "rack 5" {
+			t.Errorf("%s: current = %+v, want rack 5 at revision 2", body, conflict.Current)
+		}
+	}
+	decodeJSON(t, serve(router, http.MethodGet, target, ""), &notes)
+	if notes.Revision != 2 || notes.Text != "rack 5" {
+		t.Errorf("after the conflicts = %+v, want rack 5 at revision 2 unchanged", notes)
+	}
+
+	// notes are per host
+	decodeJSON(t, serve(router, http.MethodGet, "/api/dashboard/host/host-2/notes", ""), &notes)
+	if notes.Revision != 0 {
+		t.Errorf("host-2 notes = %+v, want none", notes)
+	}
+
+	tooLong := `{"text":"` + strings.Repeat("x", maxNotesLength+1) + `","revision":2}`
+	if w := serve(router, http.MethodPut, target, tooLong); w.Code != http.StatusBadRequest {
+		t.Errorf("oversized notes: status %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxQuerySpan caps how far back a single history request may reach.
+const maxQuerySpan = 31 * 24 * time.Hour
+
+// parseTimeRange reads the time window of a history request. Either
+//   - ?start=<t>[&end=<t>] with t as RFC3339 or epoch milliseconds (end defaults to now), or
+//   - ?range=<duration> relative to now (defaults to defaultRange), e.g. 15m, 24h or 30d.
+//
+// The window must be non-empty and no longer than maxQuerySpan.
+func parseTimeRange(c *gin.Context, defaultRange string) (start, end time.Time, err error) {
+	now := time.Now().UTC()
+	startStr, endStr := c.Query("start"), c.Query("end")
+
+	if startStr == "" && endStr == "" {
+		rangeDuration, err := parseRangeDuration(c.DefaultQuery("range", defaultRange))
+		if err != nil || rangeDuration <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid range duration format")
+		}
+		start, end = now.Add(-rangeDuration), now
+	} else {
+		if startStr == "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("end requires start")
+		}
+		if start, err = parseTimeParam(startStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start: %w", err)
+		}
+		end = now
+		if endStr != "" {
+			if end, err = parseTimeParam(endStr); err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("invalid end: %w", err)
+			}
+		}
+		if !start.Before(end) {
+			return time.Time{}, time.Time{}, fmt.Errorf("start must be before end")
+		}
+	}
+
+	if end.Sub(start) > maxQuerySpan {
+		return time.Time{}, time.Time{}, fmt.Errorf("time range too large (max %s)", maxQuerySpan)
+	}
+	return start, end, nil
+}
+
+// parseRangeDuration is time.ParseDuration plus a whole-day form ("30d").
+func parseRangeDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// parseTimeParam accepts RFC3339 or epoch milliseconds.
+func parseTimeParam(value string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 or epoch milliseconds")
+	}
+	return t.UTC(), nil
+}
+
+// bindJSON decodes the request body into v. Empty bodies (400, "empty_body")
+// and non-JSON content types (415, "unsupported_media_type") are answered
+// explicitly; they are client mistakes such as a probe hitting a POST route,
+// so everything here is logged at warn level. A missing Content-Type is
+// accepted. Reports whether v was filled; if not, the response is written.
+func bindJSON(c *gin.Context, v interface{}) bool {
+	if !jsonContentType(c) {
+		return false
+	}
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return emptyBody(c)
+	}
+	if err := c.ShouldBindJSON(v); err != nil {
+		if errors.Is(err, io.EOF) { // chunked request without content
+			return emptyBody(c)
+		}
+		appLogger.FromContext(c.Request.Context()).With("path", c.FullPath(), "client_ip", c.ClientIP()).
+			Warn("Rejected invalid JSON body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload", "details": err.Error(), "code": "invalid_json"})
+		return false
+	}
+	return true
+}
+
+// jsonContentType answers non-JSON content types like bindJSON, for handlers
+// that decode the body themselves.
+func jsonContentType(c *gin.Context) bool {
+	contentType := c.ContentType()
+	if contentType != "" && contentType != "application/json" && !strings.HasSuffix(contentType, "+json") {
+		appLogger.FromContext(c.Request.Context()).With("path", c.FullPath(), "content_type", contentType, "client_ip", c.ClientIP()).
+			Warn("Rejected request body with unsupported content type")
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/json", "code": "unsupported_media_type"})
+		return false
+	}
+	return true
+}
+
+func emptyBody(c *gin.Context) bool {
+	appLogger.FromContext(c.Request.Context()).With("path", c.FullPath(), "client_ip", c.ClientIP()).
+		Warn("Rejected empty request body")
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Request body is empty, expected a JSON object", "code": "empty_body"})
+	return false
+}
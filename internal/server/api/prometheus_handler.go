@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prometheusContentType is the Prometheus text exposition format, version 0.0.4.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// promSample is one sample of a gauge: label name/value pairs and the value.
+type promSample struct {
+	labels [][2]string
+	value  float64
+}
+
+// promGauge is a metric family, written with its HELP and TYPE lines.
+type promGauge struct {
+	name    string
+	help    string
+	samples []promSample
+}
+
+// GetPrometheusMetrics handles GET /api/prometheus: the overview's latest
+// values as Prometheus gauges labeled by host_id and hostname. Decommissioned
+// hosts are left out. host_up and host_last_seen_timestamp_seconds cover every
+// other host in the overview; the usage gauges only hosts that aren't offline,
+// so Prometheus marks the series stale instead of repeating a host's
+// last-known values. Samples carry no timestamps, they are taken at scrape
+// time.
+func (h *DashboardHandler) GetPrometheusMetrics(c *gin.Context) {
+	overviews, err := h.dbReader.GetHostOverviewList(c.Request.Context())
+	if err != nil {
+		appLogger.Error("Failed to get hosts overview for Prometheus: %v", err)
+		respondReaderError(c, err, "Failed to retrieve hosts overview")
+		return
+	}
+
+	decommissioned, err := h.metaStore.DecommissionedHosts()
+	if err != nil {
+		appLogger.Error("Failed to load decommissioned hosts for Prometheus: %v", err)
+	}
+
+	up := promGauge{name: "host_up", help: "1 while the host reports (online, warning or critical), 0 when offline."}
+	lastSeen := promGauge{name: "host_last_seen_timestamp_seconds", help: "Unix time of the host's latest report."}
+	cpu := promGauge{name: "system_cpu_usage_percent", help: "CPU usage percent."}
+	mem := promGauge{name: "system_mem_usage_percent", help: "Memory usage percent."}
+	disk := promGauge{name: "disk_usage_percent", help: "Disk usage percent of the root filesystem."}
+	upload := promGauge{name: "system_net_upload_bytes_per_second", help: "Network upload rate in bytes/sec."}
+	download := promGauge{name: "system_net_download_bytes_per_second", help: "Network download rate in bytes/sec."}
+
+	for _, overview := range overviews {
+		if _, ok := decommissioned[overview.ID]; ok {
+			continue
+		}
+		host := [][2]string{{"host_id", overview.ID}, {"hostname", overview.Hostname}}
+		online := overview.Status != "offline"
+		up.samples = append(up.samples, promSample{host, promBool(online)})
+		lastSeen.samples = append(lastSeen.samples, promSample{host, float64(overview.LastSeen.UnixMilli()) / 1000})
+		if !online {
+			continue
+		}
+		cpu.samples = append(cpu.samples, promSample{host, overview.CPUUsage})
+		mem.samples = append(mem.samples, promSample{host, overview.RAMUsage})
+		if overview.DiskUsage != nil {
+			disk.samples = append(disk.samples, promSample{append(host[:2:2], [2]string{"path", "/"}), *overview.DiskUsage})
+		}
+		upload.samples = append(upload.samples, promSample{host, overview.NetworkUpload})
+		download.samples = append(download.samples, promSample{host, overview.NetworkDownload})
+	}
+
+	var b strings.Builder
+	for _, gauge := range []promGauge{up, lastSeen, cpu, mem, disk, upload, download} {
+		gauge.write(&b)
+	}
+	c.Data(http.StatusOK, prometheusContentType, []byte(b.String()))
+}
+
+// write writes the family in the text format. Families without samples are
+// left out.
+func (g promGauge) write(b *strings.Builder) {
+	if len(g.samples) == 0 {
+		return
+	}
+	b.WriteString("# HELP " + g.name + " " + g.help + "\n")
+	b.WriteString("# TYPE " + g.name + " gauge\n")
+	for _, sample := range g.samples {
+		b.WriteString(g.name)
+		b.WriteByte('{')
+		for i, label := range sample.labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label[0] + `="` + promEscaper.Replace(label[1]) + `"`)
+		}
+		b.WriteString("} " + strconv.FormatFloat(sample.value, 'g', -1, 64) + "\n")
+	}
+}
+
+// promEscaper escapes label values: backslash, double quote and line feed.
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promBool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// RegisterPrometheusRoute registers GET /api/prometheus (PROMETHEUS_ENABLED).
+// It is a dashboard read route: with auth enabled the scraper sends a bearer
+// token like any dashboard client.
+func (h *DashboardHandler) RegisterPrometheusRoute(router *gin.Engine, guards RouteGuards, timeouts config.RouteTimeouts) {
+	router.Group("/api", guards.Read...).GET("/prometheus", Timeout(timeouts.Overview), h.GetPrometheusMetrics)
+}
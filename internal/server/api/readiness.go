@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Readiness flips to ready once a test write to InfluxDB succeeded. Until
+// then ingest is answered with 503 "not ready" instead of failing every
+// write against a bucket the token can't write to.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
+
+// Run calls check every retryEvery until it succeeds, then marks the server ready.
+func (r *Readiness) Run(ctx context.Context, check func(context.Context) error, retryEvery time.Duration) {
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := check(checkCtx)
+		cancel()
+		if err == nil {
+			r.ready.Store(true)
+			appLogger.Info("Readiness test write succeeded, accepting ingest.")
+			return
+		}
+		appLogger.Error("Readiness test write failed, ingest stays disabled (check the token's write permission and the bucket): %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryEvery):
+		}
+	}
+}
+
+// Gate rejects requests with 503 until the server is ready.
+func (r *Readiness) Gate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !r.Ready() {
+			c.Header("Retry-After", "5")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "not ready", "code": "not_ready"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Healthz always answers 200 (the process is up) and reports readiness in the body.
+func (r *Readiness) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "ready": r.Ready()})
+}
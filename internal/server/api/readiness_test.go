@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database"
+	"github.com/gin-gonic/gin"
+)
+
+func TestIngestWaitsForReadiness(t *testing.T) {
+	ingest := newTestIngest(t, nil)
+	client, err := database.NewClient(ingest.cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+	writer := database.NewInfluxDBWriter(client, ingest.cfg)
+
+	// the test write fails (no write permission yet) until writable is closed
+	writable := make(chan struct{})
+	check := func(ctx context.Context) error {
+		select {
+		case <-writable:
+			return writer.CheckWrite(ctx)
+		default:
+			return errors.New("401 Unauthorized: write:buckets/system_stats is unauthorized")
+		}
+	}
+	readiness := NewReadiness()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go readiness.Run(ctx, check, 10*time.Millisecond)
+	ingest.router = gin.New()
+	ingest.handler.RegisterRoutes(ingest.router, readiness)
+
+	w := ingest.post(t, "/api/stats", testPayload("host-1", time.Now()))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("before readiness: status %d, Retry-After %q, want 503 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	var body struct{ Code string }
+	decodeJSON(t, w, &body)
+	if body.Code != "not_ready" {
+		t.Errorf("before readiness: code %q, want not_ready", body.Code)
+	}
+	if w := serve(ingest.router, http.MethodGet, "/healthz", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ready":false`) {
+		t.Errorf("healthz before readiness: %d %s, want 200 with ready false", w.Code, w.Body)
+	}
+	if lines := ingest.influx.Lines(); len(lines) != 0 {
+		t.Errorf("rejected payload was written: %q", lines)
+	}
+
+	close(writable)
+	deadline := time.Now().Add(5 * time.Second)
+	for !readiness.Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("not ready 5s after the test write can succeed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if w := ingest.post(t, "/api/stats", testPayload("host-1", time.Now())); w.Code != http.StatusOK {
+		t.Fatalf("after readiness: status %d, want 200: %s", w.Code, w.Body)
+	}
+	var written bool
+	for _, line := range ingest.influx.Lines() {
+		written = written || strings.HasPrefix(line, "system_metrics,")
+	}
+	if !written {
+		t.Errorf("payload accepted after readiness not written: %q", ingest.influx.Lines())
+	}
+	if w := serve(ingest.router, http.MethodGet, "/healthz", ""); !strings.Contains(w.Body.String(), `"ready":true`) {
+		t.Errorf("healthz after readiness: %s, want ready true", w.Body)
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestID gives every request an ID: the client's X-Request-ID when it
+// sends a usable one (e.g. from a load balancer), otherwise a random one. It
+// is echoed in the response header and attached to the request context as
+// the request_id log field (see logger.FromContext).
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(requestIDHeader, id)
+		c.Request = c.Request.WithContext(appLogger.WithFields(c.Request.Context(), "request_id", id))
+		c.Next()
+	}
+}
+
+// validRequestID accepts short IDs of printable ASCII without spaces, so a
+// client can't inject log lines or huge fields.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/auth"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const statusSilenced = "silenced"
+
+type silenceRequest struct {
+	Until  time.Time `json:"until" binding:"required"`
+	Reason string    `json:"reason"`
+}
+
+func toSilenceModel(silence *metadata.Silence) *models.SilenceInfo {
+	return &models.SilenceInfo{
+		Until:      silence.Until,
+		Reason:     silence.Reason,
+		SilencedBy: silence.SilencedBy,
+		CreatedAt:  silence.CreatedAt,
+	}
+}
+
+// applySilence marks a silenced host. Metric values are left untouched, only the
+// status changes; an offline host stays "offline" since that is real information.
+func applySilence(status *string, info **models.SilenceInfo, silence *metadata.Silence) {
+	*info = toSilenceModel(silence)
+	if *status != "offline" {
+		*status = statusSilenced
+	}
+}
+
+// SilenceHost handles POST /api/dashboard/host/:hostID/silence
+func (h *DashboardHandler) SilenceHost(c *gin.Context) {
+	hostID := c.Param("hostID")
+	var req silenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid silence payload", "details": err.Error()})
+		return
+	}
+	if !req.Until.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until must be in the future"})
+		return
+	}
+
+	silencedBy := "anonymous" // auth disabled
+	if claims := auth.ClaimsFromContext(c); claims != nil {
+		silencedBy = claims.Username
+	}
+
+	silence, err := h.metaStore.SetSilence(metadata.Silence{
+		HostID:     hostID,
+		Until:      req.Until,
+		Reason:     strings.TrimSpace(req.Reason),
+		SilencedBy: silencedBy,
+	})
+	if err != nil {
+		appLogger.Error("Failed to silence host %s: %v", hostID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to silence host"})
+		return
+	}
+	appLogger.Info("Host %s silenced by %s until %s: %s", hostID, silencedBy, silence.Until.Format(time.RFC3339), silence.Reason)
+	c.JSON(http.StatusOK, toSilenceModel(silence))
+}
+
+// UnsilenceHost handles DELETE /api/dashboard/host/:hostID/silence
+func (h *DashboardHandler) UnsilenceHost(c *gin.Context) {
+	hostID := c.Param("hostID")
+	if err := h.metaStore.ClearSilence(hostID); err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Host is not silenced"})
+			return
+		}
+		appLogger.Error("Failed to clear silence for host %s: %v", hostID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear silence"})
+		return
+	}
+	appLogger.Info("Silence cleared for host %s", hostID)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+func TestSilenceExpires(t *testing.T) {
+	d := newTestDashboard(t, nil)
+	now := time.Now()
+	d.influx.Respond(`yield(name: "overview")`, overviewTable().
+		Row("host-1", "web-1", 10.0, 20.0, int64(3600), false, 0.0, 0.0, 0.0, true, 30.0, false, 0.0, now))
+	router := d.router(RouteGuards{})
+
+	if w := serve(router, http.MethodPost, "/api/dashboard/host/host-1/silence", `{"until":"2020-01-01T00:00:00Z"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("silence ending in the past: status %d, want 400", w.Code)
+	}
+
+	until := now.Add(400 * time.Millisecond)
+	w := serve(router, http.MethodPost, "/api/dashboard/host/host-1/silence", `{"until":"`+until.Format(time.RFC3339Nano)+`","reason":" disk swap "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("silence: status %d, want 200: %s", w.Code, w.Body)
+	}
+	var silence models.SilenceInfo
+	decodeJSON(t, w, &silence)
+	if silence.Reason != "disk swap" || silence.SilencedBy != "anonymous" {
+		t.Errorf("silence = %+v, want the trimmed reason by anonymous", silence)
+	}
+
+	overviewOf := func() models.HostOverviewData {
+		t.Helper()
+		var hosts []models.HostOverviewData
+		decodeJSON(t, serve(router, http.MethodGet, "/api/dashboard/hosts/overview", ""), &hosts)
+		if len(hosts) != 1 {
+			t.Fatalf("overview = %+v, want host-1", hosts)
+		}
+		return hosts[0]
+	}
+	if host := overviewOf(); host.Status != statusSilenced || host.Silence == nil {
+		t.Errorf("while silenced: status %s, silence %+v, want silenced", host.Status, host.Silence)
+	}
+
+	// the store decides by the time asked about, nothing has to delete the record
+	if active, err := d.store.ActiveSilences(until.Add(time.Nanosecond)); err != nil || len(active) != 0 {
+		t.Errorf("ActiveSilences after until = %v, %v, want none", active, err)
+	}
+	if _, err := d.store.GetActiveSilence("host-1", until); !errors.Is(err, metadata.ErrNotFound) {
+		t.Errorf("GetActiveSilence at until: %v, want ErrNotFound", err)
+	}
+
+	time.Sleep(time.Until(until) + 50*time.Millisecond)
+	if host := overviewOf(); host.Status == statusSilenced || host.Silence != nil {
+		t.Errorf("after expiry: status %s, silence %+v, want its own status without a silence", host.Status, host.Silence)
+	}
+
+	// the expired record is still there to clear, a second clear finds nothing
+	if w := serve(router, http.MethodDelete, "/api/dashboard/host/host-1/silence", ""); w.Code != http.StatusNoContent {
+		t.Errorf("clear: status %d, want 204", w.Code)
+	}
+	if w := serve(router, http.MethodDelete, "/api/dashboard/host/host-1/silence", ""); w.Code != http.StatusNotFound {
+		t.Errorf("second clear: status %d, want 404", w.Code)
+	}
+}
@@ -1,71 +1,290 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/database"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/hostrole"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/tracker"
 	"github.com/gin-gonic/gin"
 )
 
 // holds depebndencies for the stats API handlers
 type StatsHandler struct {
-	dbWriter *database.InfluxDBWriter
+	dbWriter            *database.InfluxDBWriter
+	dbReader            *database.InfluxDBReader // its overview table is updated on ingest
+	hostTracker         *tracker.HostTracker
+	roles               *hostrole.Classifier // nil = no HOST_ROLE_RULES
+	clockDriftThreshold time.Duration
+	hostIDWithHostname  bool // HOST_ID_INCLUDE_HOSTNAME
+
+	batch atomic.Pointer[config.BatchIngest] // replaced on SIGHUP
 }
 
 // creates a new StatsHandler
-func NewStatsHandler(dbWriter *database.InfluxDBWriter) *StatsHandler {
-	return &StatsHandler{
-		dbWriter: dbWriter,
+func NewStatsHandler(dbWriter *database.InfluxDBWriter, dbReader *database.InfluxDBReader, hostTracker *tracker.HostTracker, roles *hostrole.Classifier, clockDriftThreshold time.Duration, hostIDWithHostname bool, batch config.BatchIngest) *StatsHandler {
+	h := &StatsHandler{
+		dbWriter:            dbWriter,
+		dbReader:            dbReader,
+		hostTracker:         hostTracker,
+		roles:               roles,
+		clockDriftThreshold: clockDriftThreshold,
+		hostIDWithHostname:  hostIDWithHostname,
 	}
+	h.SetBatchLimits(batch)
+	return h
+}
+
+// SetBatchLimits replaces the batch ingest limits. Batches already being
+// received keep the limits they started with.
+func (h *StatsHandler) SetBatchLimits(batch config.BatchIngest) {
+	h.batch.Store(&batch)
 }
 
 // Gin handler for receiving stats from clients
 func (h *StatsHandler) PostStats(c *gin.Context) {
+	receivedAt := time.Now()
 	var payload models.ClientPayload
 
-	// 1. Bind JSON payload to the struct
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		appLogger.Error("Failed to bind JSON payload: %v. Client IP: %s", err, c.ClientIP())
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload", "details": err.Error()})
+	// 1. Bind JSON payload to the struct (empty / non-JSON bodies are answered there)
+	if !bindJSON(c, &payload) {
 		return
 	}
-	// 2. Basic validation (ensure HostID is present)
-	if payload.System.HostID == "" {
-		appLogger.Warn("Received payload with empty HostID from %s. Payload Hostname: %s", c.ClientIP(), payload.System.Hostname)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "HostID is missing in system_info"})
+	h.applyHostIdentity(&payload)
+	// Every later line of this request, including the writer's, carries host_id
+	if payload.System.HostID != "" {
+		c.Request = c.Request.WithContext(appLogger.WithFields(c.Request.Context(), "host_id", payload.System.HostID))
+	}
+
+	// 2. Validate and write stats to the database
+	result := h.ingest(c.Request.Context(), &payload, c.ClientIP(), receivedAt)
+	if result.err != "" {
+		c.JSON(result.status, gin.H{"error": result.err})
 		return
 	}
+
+	// 3. Respond with success
+	response := gin.H{"status": "success", "message": "Statistics received and processed"}
+	if result.driftExceeded {
+		response["clockDriftSeconds"] = result.drift.Seconds() // lets the agent notice its own drift
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// applyHostIdentity rewrites the host_id to "<host_id>@<hostname>" with
+// HOST_ID_INCLUDE_HOSTNAME, before anything else sees the payload.
+func (h *StatsHandler) applyHostIdentity(payload *models.ClientPayload) {
+	if h.hostIDWithHostname && payload.System.HostID != "" && payload.System.Hostname != "" {
+		payload.System.HostID += "@" + payload.System.Hostname
+	}
+}
+
+// ingestResult is the outcome of one payload. err is set, with the HTTP
+// status to answer with, when it was rejected (400) or not stored (500).
+type ingestResult struct {
+	status        int
+	err           string
+	drift         time.Duration // receive time - collected_at
+	driftExceeded bool
+}
+
+// ingest validates one payload and stores it. Log lines go to ctx's logger.
+func (h *StatsHandler) ingest(ctx context.Context, payload *models.ClientPayload, clientIP string, receivedAt time.Time) ingestResult {
+	log := appLogger.FromContext(ctx)
+
+	// Basic validation (ensure HostID is present)
+	if payload.System.HostID == "" {
+		log.Warn("Received payload with empty HostID from %s. Payload Hostname: %s", clientIP, payload.System.Hostname)
+		return ingestResult{status: http.StatusBadRequest, err: "HostID is missing in system_info"}
+	}
+
+	switch payload.ProcessCPU {
+	case "", models.ProcessCPUPerCore, models.ProcessCPUTotal:
+	default:
+		return ingestResult{status: http.StatusBadRequest, err: "process_cpu_convention must be per_core or total"}
+	}
+
 	if payload.CollectedAt.IsZero() {
-		appLogger.Warn("Received payload with zero CollectedAt timestamp from HostID %s", payload.System.HostID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CollectedAt timestamp is missing or zero"})
-		return
+		log.Warn("Received payload with zero CollectedAt timestamp")
+		return ingestResult{status: http.StatusBadRequest, err: "CollectedAt timestamp is missing or zero"}
+	}
+
+	// Positive drift: collected_at is in the past (agent clock behind or a slow send),
+	// negative: agent clock ahead of ours. Stored as the ingest lag.
+	payload.ReceivedAt = receivedAt
+	result := ingestResult{drift: receivedAt.Sub(payload.CollectedAt)}
+	result.driftExceeded = h.clockDriftThreshold > 0 && (result.drift > h.clockDriftThreshold || result.drift < -h.clockDriftThreshold)
+	if result.driftExceeded {
+		log.With("hostname", payload.System.Hostname, "drift", result.drift.Round(time.Millisecond), "threshold", h.clockDriftThreshold).
+			Warn("Agent clock drift exceeds the threshold")
 	}
 
-	appLogger.Info("Received stats from HostID: %s, Hostname: %s", payload.System.HostID, payload.System.Hostname)
-	appLogger.Debug("Payload received: %+v", payload) // Log full payload only in debug mode
+	log.With("hostname", payload.System.Hostname).Info("Received stats")
+	log.Debug("Payload received: %+v", *payload) // Log full payload only in debug mode
 
-	// 3. Write stats to the database
-	// The context from Gin (c.Request.Context()) can be used for cancellation propagation
-	// if the client disconnects or the request times out.
-	if err := h.dbWriter.WriteStats(c.Request.Context(), &payload); err != nil {
+	// The request context cancels the write if the client disconnects or
+	// the request times out.
+	if err := h.dbWriter.WriteStats(ctx, payload); err != nil {
 		// dbWriter already logs detailed errors
-		appLogger.Error("Failed to write stats to database for HostID %s: %v", payload.System.HostID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store statistics"})
+		log.Error("Failed to write stats to database: %v", err)
+		return ingestResult{status: http.StatusInternalServerError, err: "Failed to store statistics"}
+	}
+
+	h.dbReader.RecordIngest(payload) // the overview shows it right away
+	h.hostTracker.Seen(payload.System.HostID, payload.System.Hostname, time.Now())
+	h.roles.Observe(payload.System.HostID, payload.ProcessNames(), time.Now())
+	log.Info("Stored stats")
+	return result
+}
+
+// batchItemResult is the outcome of one payload of a batch, by its index in
+// the array.
+type batchItemResult struct {
+	Index             int      `json:"index"`
+	HostID            string   `json:"hostId,omitempty"`
+	Status            string   `json:"status"` // stored, rejected (invalid payload) or failed (not stored)
+	Error             string   `json:"error,omitempty"`
+	ClockDriftSeconds *float64 `json:"clockDriftSeconds,omitempty"`
+}
+
+// batchResponse is the answer to POST /api/stats/batch. When the request is
+// cut short (too many items, body too large, malformed JSON) it also carries
+// error and code, and results covers the items handled before that.
+type batchResponse struct {
+	Error    string            `json:"error,omitempty"`
+	Code     string            `json:"code,omitempty"`
+	Received int               `json:"received"`
+	Stored   int               `json:"stored"`
+	Rejected int               `json:"rejected"`
+	Failed   int               `json:"failed"`
+	Results  []batchItemResult `json:"results"`
+}
+
+func (r *batchResponse) add(item batchItemResult) {
+	r.Received++
+	switch item.Status {
+	case "stored":
+		r.Stored++
+	case "rejected":
+		r.Rejected++
+	default:
+		r.Failed++
+	}
+	r.Results = append(r.Results, item)
+}
+
+// PostStatsBatch receives a JSON array of payloads, e.g. from an agent
+// flushing its buffer after an outage. The array is decoded and stored one
+// element at a time, so memory doesn't grow with the batch; the body is
+// limited to Batch.MaxBytes and the array to Batch.MaxItems. Each payload is
+// validated and stored like POST /api/stats, and a failing one doesn't stop
+// the others.
+func (h *StatsHandler) PostStatsBatch(c *gin.Context) {
+	if !jsonContentType(c) {
 		return
 	}
+	limits := h.batch.Load()
+	log := appLogger.FromContext(c.Request.Context()).With("path", c.FullPath(), "client_ip", c.ClientIP())
+	response := batchResponse{Results: []batchItemResult{}}
+	stop := func(status int, code, message string) {
+		log.With("received", response.Received).Warn("Batch cut short: %s", message)
+		response.Error, response.Code = message, code
+		c.JSON(status, response)
+	}
+	// decodeError answers an error from the decoder; it reports false for
+	// a payload of the wrong shape, which only rejects that item.
+	decodeError := func(err error) bool {
+		var tooLarge *http.MaxBytesError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &tooLarge):
+			stop(http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("request body exceeds %d bytes", limits.MaxBytes))
+		case errors.As(err, &typeErr):
+			return false
+		default:
+			stop(http.StatusBadRequest, "invalid_json", "Invalid JSON payload: "+err.Error())
+		}
+		return true
+	}
 
-	// 4. Respond with success
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Statistics received and processed"})
-	appLogger.Info("Successfully processed and stored stats for HostID: %s", payload.System.HostID)
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		emptyBody(c)
+		return
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBytes))
+	token, err := dec.Token()
+	if errors.Is(err, io.EOF) { // chunked request without content
+		emptyBody(c)
+		return
+	}
+	if err != nil {
+		decodeError(err)
+		return
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		stop(http.StatusBadRequest, "invalid_json", "expected a JSON array of payloads")
+		return
+	}
 
+	for index := 0; dec.More(); index++ {
+		if index == limits.MaxItems {
+			stop(http.StatusRequestEntityTooLarge, "too_many_items", fmt.Sprintf("batch exceeds %d payloads", limits.MaxItems))
+			return
+		}
+		receivedAt := time.Now()
+		var payload models.ClientPayload
+		if err := dec.Decode(&payload); err != nil {
+			if decodeError(err) {
+				return
+			}
+			// The decoder skips the rest of the element, the next one is read normally
+			response.add(batchItemResult{Index: index, Status: "rejected", Error: "invalid payload: " + err.Error()})
+			continue
+		}
+
+		h.applyHostIdentity(&payload)
+		ctx := appLogger.WithFields(c.Request.Context(), "host_id", payload.System.HostID, "batch_index", index)
+		result := h.ingest(ctx, &payload, c.ClientIP(), receivedAt)
+		item := batchItemResult{Index: index, HostID: payload.System.HostID, Status: "stored"}
+		if result.err != "" {
+			item.Status, item.Error = "rejected", result.err
+			if result.status >= http.StatusInternalServerError {
+				item.Status = "failed"
+			}
+		} else if result.driftExceeded {
+			drift := result.drift.Seconds()
+			item.ClockDriftSeconds = &drift
+		}
+		response.add(item)
+	}
+	if _, err := dec.Token(); err != nil { // the closing ']'
+		decodeError(err)
+		return
+	}
+
+	log.With("received", response.Received, "stored", response.Stored, "rejected", response.Rejected, "failed", response.Failed).
+		Info("Handled stats batch")
+	c.JSON(http.StatusOK, response)
 }
 
-// RegisterRoutes registers the API routes for stats handling.
-func (h *StatsHandler) RegisterRoutes(router *gin.Engine) {
+// RegisterRoutes registers the API routes for stats handling. Ingest is gated
+// by readiness, /healthz always answers.
+func (h *StatsHandler) RegisterRoutes(router *gin.Engine, readiness *Readiness) {
+	router.GET("/healthz", readiness.Healthz)
+
 	apiGroup := router.Group("/api")
 	{
-		apiGroup.POST("/stats", h.PostStats)
+		apiGroup.POST("/stats", readiness.Gate(), h.PostStats)
+		apiGroup.POST("/stats/batch", readiness.Gate(), h.PostStatsBatch)
 	}
 }
@@ -0,0 +1,411 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/hostrole"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+	"github.com/gin-gonic/gin"
+)
+
+// testIngest is a stats handler writing to a fake InfluxDB, with its routes
+// on router.
+type testIngest struct {
+	*testDashboard // the reader's overview and the tracker are shared with the dashboard
+	handler        *StatsHandler
+	readiness      *Readiness
+	router         *gin.Engine
+}
+
+// newTestIngest creates a ready handler. configure, if not nil, adjusts
+// the handler before the routes are registered.
+func newTestIngest(t *testing.T, configure func(*StatsHandler)) *testIngest {
+	t.Helper()
+	return newTestIngestOn(t, newTestDashboard(t, nil), configure)
+}
+
+// newTestIngestOn is newTestIngest sharing d's fake InfluxDB, reader and
+// tracker.
+func newTestIngestOn(t *testing.T, d *testDashboard, configure func(*StatsHandler)) *testIngest {
+	t.Helper()
+	client, err := database.NewClient(d.cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+	handler := NewStatsHandler(database.NewInfluxDBWriter(client, d.cfg), d.reader, d.hostTracker, nil,
+		30*time.Second, false, config.BatchIngest{MaxItems: 100, MaxBytes: 8 << 20})
+	if configure != nil {
+		configure(handler)
+	}
+	readiness := NewReadiness()
+	readiness.Run(context.Background(), func(context.Context) error { return nil }, time.Second)
+
+	router := gin.New()
+	handler.RegisterRoutes(router, readiness)
+	return &testIngest{testDashboard: d, handler: handler, readiness: readiness, router: router}
+}
+
+// testPayload is a minimal valid payload of hostID collected at collectedAt.
+func testPayload(hostID string, collectedAt time.Time) models.ClientPayload {
+	return models.ClientPayload{
+		CollectedAt: collectedAt,
+		System:      models.SystemInfoPayload{HostID: hostID, Hostname: hostID + ".example.com", OS: "linux"},
+		CPU:         models.CPUInfoPayload{ModelName: "Test CPU", Cores: 4, Usage: 12.5},
+		Memory:      models.MemInfoPayload{TotalGB: 16, FreeGB: 8, UsagePercent: 50},
+		Disks:       []models.DiskUsagePayload{{Path: "/", TotalGB: 100, UsedGB: 30, FreeGB: 70, UsagePercent: 30}},
+	}
+}
+
+// post sends body as JSON.
+func (i *testIngest) post(t *testing.T, target string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return serve(i.router, http.MethodPost, target, string(data))
+}
+
+// logBuffer collects log output, safe for the handlers' goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *logBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+// captureLog collects the log output of the rest of the test.
+func captureLog(t *testing.T) *logBuffer {
+	t.Helper()
+	buf := &logBuffer{}
+	appLogger.SetOutput(buf, true)
+	t.Cleanup(func() { appLogger.SetOutput(&bytes.Buffer{}, true) })
+	return buf
+}
+
+func TestPostStatsReportsClockDrift(t *testing.T) {
+	ingest := newTestIngest(t, nil)
+	logs := captureLog(t)
+
+	w := ingest.post(t, "/api/stats", testPayload("host-1", time.Now().Add(-30*time.Second-500*time.Millisecond)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+	}
+	var body struct {
+		ClockDriftSeconds *float64 `json:"clockDriftSeconds"`
+	}
+	decodeJSON(t, w, &body)
+	if body.ClockDriftSeconds == nil || *body.ClockDriftSeconds < 30 || *body.ClockDriftSeconds > 35 {
+		t.Errorf("clockDriftSeconds = %v, want about 30", body.ClockDriftSeconds)
+	}
+	if !strings.Contains(logs.String(), "Agent clock drift exceeds the threshold") {
+		t.Errorf("no drift warning logged:\n%s", logs)
+	}
+
+	// within the threshold: no field, no warning
+	logs.Reset()
+	w = ingest.post(t, "/api/stats", testPayload("host-1", time.Now().Add(-time.Second)))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "clockDriftSeconds") {
+		t.Errorf("recent payload: status %d, body %s, want 200 without clockDriftSeconds", w.Code, w.Body)
+	}
+	if strings.Contains(logs.String(), "clock drift") {
+		t.Errorf("drift warning logged for a recent payload:\n%s", logs)
+	}
+}
+
+func TestIngestRejectsEmptyAndNonJSONBodies(t *testing.T) {
+	ingest := newTestIngest(t, nil)
+	logs := captureLog(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		header []string
+		status int
+		code   string
+	}{
+		{"empty body", "", nil, http.StatusBadRequest, "empty_body"},
+		{"empty JSON body", "", []string{"Content-Type", "application/json"}, http.StatusBadRequest, "empty_body"},
+		{"text/plain probe", "ping", []string{"Content-Type", "text/plain"}, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"form post", "a=1", []string{"Content-Type", "application/x-www-form-urlencoded"}, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	}
+	for _, target := range []string{"/api/stats", "/api/stats/batch"} {
+		for _, tt := range tests {
+			t.Run(target+" "+tt.name, func(t *testing.T) {
+				logs.Reset()
+				w := serve(ingest.router, http.MethodPost, target, tt.body, tt.header...)
+				if w.Code != tt.status {
+					t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+				var body struct {
+					Error string `json:"error"`
+					Code  string `json:"code"`
+				}
+				decodeJSON(t, w, &body)
+				if body.Code != tt.code || body.Error == "" {
+					t.Errorf("body %s, want code %s with a message", w.Body, tt.code)
+				}
+				// a client mistake, not a server fault
+				if !strings.Contains(logs.String(), "WARN: ") || strings.Contains(logs.String(), "ERROR: ") {
+					t.Errorf("want a warning and no error logged:\n%s", logs)
+				}
+			})
+		}
+	}
+	if lines := ingest.influx.Lines(); len(lines) != 0 {
+		t.Errorf("%d lines written, want none", len(lines))
+	}
+}
+
+func TestIngestedPayloadIsInTheOverviewWithoutAQuery(t *testing.T) {
+	ingest := newTestIngest(t, nil)
+	router := ingest.router
+	ingest.RegisterDashboardRoutes(router, RouteGuards{}, config.RouteTimeouts{})
+	overviewQueries := func() int {
+		n := 0
+		for _, query := range ingest.influx.Queries() {
+			if strings.Contains(query, `yield(name: "overview")`) {
+				n++
+			}
+		}
+		return n
+	}
+	overview := func() map[string]models.HostOverviewData {
+		t.Helper()
+		w := serve(router, http.MethodGet, "/api/dashboard/hosts/overview", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("overview: status %d: %s", w.Code, w.Body)
+		}
+		var hosts []models.HostOverviewData
+		decodeJSON(t, w, &hosts)
+		byID := make(map[string]models.HostOverviewData, len(hosts))
+		for _, host := range hosts {
+			byID[host.ID] = host
+		}
+		return byID
+	}
+
+	// cold start: the first overview seeds the latest table with a query
+	if hosts := overview(); len(hosts) != 0 {
+		t.Fatalf("overview before ingest = %+v, want none", hosts)
+	}
+	if n := overviewQueries(); n != 1 {
+		t.Fatalf("%d overview queries on cold start, want 1", n)
+	}
+
+	payload := testPayload("host-1", time.Now())
+	if w := ingest.post(t, "/api/stats", payload); w.Code != http.StatusOK {
+		t.Fatalf("ingest: status %d: %s", w.Code, w.Body)
+	}
+	host, ok := overview()["host-1"]
+	if !ok {
+		t.Fatal("the ingested host is not in the overview")
+	}
+	if host.CPUUsage != payload.CPU.Usage || host.Status != "online" || !host.LastSeen.Equal(payload.CollectedAt) {
+		t.Errorf("host-1 = CPU %v, status %q, last seen %v, want the payload's %v, online, %v",
+			host.CPUUsage, host.Status, host.LastSeen, payload.CPU.Usage, payload.CollectedAt)
+	}
+	if n := overviewQueries(); n != 1 {
+		t.Errorf("%d overview queries, want none after the cold start", n)
+	}
+}
+
+func TestIngestedNginxHostIsAWebHost(t *testing.T) {
+	ingest := newTestIngest(t, nil)
+	ingest.handler.roles = hostrole.NewClassifier([]config.RoleRule{{Contains: "postgres", Role: "db"}, {Contains: "nginx", Role: "web"}}, ingest.store)
+	router := ingest.router
+	ingest.RegisterDashboardRoutes(router, RouteGuards{}, config.RouteTimeouts{})
+
+	payload := testPayload("host-1", time.Now())
+	payload.Processes = []models.ProcessPayload{{PID: 1, Name: "systemd"}, {PID: 812, Name: "nginx", CPUPercent: 3}}
+	if w := ingest.post(t, "/api/stats", payload); w.Code != http.StatusOK {
+		t.Fatalf("ingest: status %d: %s", w.Code, w.Body)
+	}
+	if w := ingest.post(t, "/api/stats", testPayload("host-2", time.Now())); w.Code != http.StatusOK {
+		t.Fatalf("ingest: status %d: %s", w.Code, w.Body)
+	}
+
+	w := serve(router, http.MethodGet, "/api/dashboard/hosts/overview", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("overview: status %d: %s", w.Code, w.Body)
+	}
+	var hosts []models.HostOverviewData
+	decodeJSON(t, w, &hosts)
+	roles := map[string]string{}
+	for _, host := range hosts {
+		roles[host.ID] = host.Role
+	}
+	if roles["host-1"] != "web" || roles["host-2"] != "" {
+		t.Errorf("roles = %v, want host-1 web and host-2 none", roles)
+	}
+
+	w = serve(router, http.MethodGet, "/api/dashboard/hosts/overview?role=web", "")
+	decodeJSON(t, w, &hosts)
+	if len(hosts) != 1 || hosts[0].ID != "host-1" {
+		t.Errorf("role=web overview = %+v, want host-1 only", hosts)
+	}
+}
+
+func TestPostStatsBatchStreamsALargeBatch(t *testing.T) {
+	const items = 2000
+	ingest := newTestIngest(t, func(h *StatsHandler) { h.SetBatchLimits(config.BatchIngest{MaxItems: items, MaxBytes: 8 << 20}) })
+	storedSoFar := func() int {
+		n := 0
+		for _, line := range ingest.influx.Lines() {
+			if strings.HasPrefix(line, "system_metrics,") {
+				n++
+			}
+		}
+		return n
+	}
+	// every 100th item has no host_id, every 100th (offset 50) has the wrong shape
+	item := func(i int) string {
+		switch i % 100 {
+		case 0:
+			return `{"collected_at":"` + time.Now().Format(time.RFC3339Nano) + `","system_info":{"hostname":"nameless"}}`
+		case 50:
+			return `{"cpu_info":{"cores":"four"}}`
+		}
+		data, err := json.Marshal(testPayload(fmt.Sprintf("host-%d", i), time.Now()))
+		if err != nil {
+			t.Errorf("marshal: %v", err)
+		}
+		return string(data)
+	}
+
+	// The body is written through a pipe, an element at a time: writing item i
+	// only returns once the handler read it, so the handler must have stored
+	// the items before it by then. A handler decoding the whole array first
+	// would still be reading.
+	body, pipe := io.Pipe()
+	lagging := make(chan string, 1)
+	go func() {
+		defer pipe.Close()
+		_, _ = io.WriteString(pipe, "[")
+		for i := 0; i < items; i++ {
+			if i%100 == 10 {
+				// items 0..i-2 are done (i-1 may still be in the decoder)
+				want := 0
+				for j := 0; j < i-1; j++ {
+					if j%50 != 0 { // not rejected
+						want++
+					}
+				}
+				if stored := storedSoFar(); stored < want {
+					select {
+					case lagging <- fmt.Sprintf("before item %d: %d items stored, want %d", i, stored, want):
+					default:
+					}
+				}
+			}
+			separator := ","
+			if i == 0 {
+				separator = ""
+			}
+			if _, err := io.WriteString(pipe, separator+item(i)); err != nil {
+				return
+			}
+		}
+		_, _ = io.WriteString(pipe, "]")
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/stats/batch", body) // unknown length, as when streamed
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ingest.router.ServeHTTP(w, req)
+
+	select {
+	case message := <-lagging:
+		t.Errorf("the batch is not stored as it is read: %s", message)
+	default:
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %.300s", w.Code, w.Body)
+	}
+	var response batchResponse
+	decodeJSON(t, w, &response)
+	if response.Received != items || response.Stored != items-40 || response.Rejected != 40 || response.Failed != 0 {
+		t.Errorf("received %d, stored %d, rejected %d, failed %d, want %d, %d, 40, 0",
+			response.Received, response.Stored, response.Rejected, response.Failed, items, items-40)
+	}
+	if len(response.Results) != items {
+		t.Fatalf("%d results, want one per item", len(response.Results))
+	}
+	for i, result := range response.Results {
+		want := batchItemResult{Index: i, HostID: fmt.Sprintf("host-%d", i), Status: "stored"}
+		switch i % 100 {
+		case 0:
+			want = batchItemResult{Index: i, Status: "rejected", Error: "HostID is missing in system_info"}
+		case 50:
+			if result.Index != i || result.Status != "rejected" || !strings.HasPrefix(result.Error, "invalid payload: ") {
+				t.Errorf("result %d = %+v, want an invalid payload", i, result)
+			}
+			continue
+		}
+		if result.Index != want.Index || result.HostID != want.HostID || result.Status != want.Status || result.Error != want.Error {
+			t.Errorf("result %d = %+v, want %+v", i, result, want)
+		}
+	}
+	if stored := storedSoFar(); stored != items-40 {
+		t.Errorf("%d system_metrics lines, want %d", stored, items-40)
+	}
+}
+
+func TestPostStatsBatchEnforcesMaxItems(t *testing.T) {
+	ingest := newTestIngest(t, func(h *StatsHandler) { h.SetBatchLimits(config.BatchIngest{MaxItems: 10, MaxBytes: 8 << 20}) })
+	logs := captureLog(t)
+
+	batch := make([]models.ClientPayload, 11)
+	for i := range batch {
+		batch[i] = testPayload(fmt.Sprintf("host-%d", i), time.Now())
+	}
+	w := ingest.post(t, "/api/stats/batch", batch)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want 413: %s", w.Code, w.Body)
+	}
+	var response batchResponse
+	decodeJSON(t, w, &response)
+	if response.Code != "too_many_items" || response.Received != 10 || response.Stored != 10 || len(response.Results) != 10 {
+		t.Errorf("response = code %q, received %d, stored %d, %d results, want too_many_items after the first 10 stored",
+			response.Code, response.Received, response.Stored, len(response.Results))
+	}
+	if !strings.Contains(logs.String(), "Batch cut short: batch exceeds 10 payloads") {
+		t.Errorf("no warning logged:\n%s", logs)
+	}
+
+	// exactly the maximum is accepted
+	w = ingest.post(t, "/api/stats/batch", batch[:10])
+	if w.Code != http.StatusOK {
+		t.Errorf("10 items: status %d, want 200: %s", w.Code, w.Body)
+	}
+}
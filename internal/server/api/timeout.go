@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const timeoutKey = "route_timeout"
+
+// Timeout puts a deadline of d on the request context. Handlers pass that
+// context to the reader, and a query that runs past it is answered with 504
+// (see respondReaderError). d <= 0 leaves the request without a deadline.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	if d <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Set(timeoutKey, d)
+
+		c.Next()
+
+		// Handler gave up without writing a response
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			respondTimeout(c)
+		}
+	}
+}
+
+// deadlineExceeded reports whether the request's own deadline (set by Timeout) has passed.
+func deadlineExceeded(c *gin.Context) bool {
+	return errors.Is(c.Request.Context().Err(), context.DeadlineExceeded)
+}
+
+func respondTimeout(c *gin.Context) {
+	appLogger.Warn("Request %s %s exceeded its %v deadline", c.Request.Method, c.Request.URL.Path, c.GetDuration(timeoutKey))
+	c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{
+		"error": "Query took too long, try a shorter time range",
+		"code":  "timeout",
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeoutAbortsAtTheDeadline(t *testing.T) {
+	const deadline = 100 * time.Millisecond
+	router := gin.New()
+	router.GET("/slow", Timeout(deadline), func(c *gin.Context) {
+		// a handler that waits for its context and gives up without writing
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(10 * time.Second):
+			c.Status(http.StatusOK)
+		}
+	})
+	router.GET("/fast", Timeout(deadline), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	start := time.Now()
+	w := serve(router, http.MethodGet, "/slow", "")
+	elapsed := time.Since(start)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("slow handler status = %d, want 504", w.Code)
+	}
+	if elapsed < deadline || elapsed > deadline+time.Second {
+		t.Errorf("504 after %v, want at the %v deadline", elapsed, deadline)
+	}
+	var body struct{ Code string }
+	decodeJSON(t, w, &body)
+	if body.Code != "timeout" {
+		t.Errorf("code = %q, want timeout", body.Code)
+	}
+
+	if w := serve(router, http.MethodGet, "/fast", ""); w.Code != http.StatusNoContent {
+		t.Errorf("fast handler status = %d, want its own 204", w.Code)
+	}
+}
+
+func TestSlowQueryReturns504AtTheRouteDeadline(t *testing.T) {
+	d := newTestDashboard(t, func(cfg *config.InfluxDBConfig) { cfg.OverviewCacheMaxAge = 0 })
+	// InfluxDB that holds every query until the client gives up
+	d.influx.RespondFunc("", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	})
+	router := gin.New()
+	timeouts := config.RouteTimeouts{Overview: 100 * time.Millisecond, Details: 200 * time.Millisecond}
+	d.RegisterDashboardRoutes(router, RouteGuards{}, timeouts)
+
+	for target, deadline := range map[string]time.Duration{
+		"/api/dashboard/hosts/overview":      timeouts.Overview,
+		"/api/dashboard/host/host-1/details": timeouts.Details,
+	} {
+		start := time.Now()
+		w := serve(router, http.MethodGet, target, "")
+		elapsed := time.Since(start)
+		if w.Code != http.StatusGatewayTimeout {
+			t.Errorf("%s: status = %d, want 504: %s", target, w.Code, w.Body)
+			continue
+		}
+		if elapsed < deadline || elapsed > deadline+time.Second {
+			t.Errorf("%s: 504 after %v, want at its %v deadline", target, elapsed, deadline)
+		}
+	}
+}
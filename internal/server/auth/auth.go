@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("auth: invalid username or password")
+	ErrLoginDisabled      = errors.New("auth: login is not available without AUTH_JWT_SECRET")
+	ErrTokenExpired       = errors.New("auth: token expired")
+	ErrTokenInvalid       = errors.New("auth: token invalid")
+)
+
+// Roles. admin implies viewer.
+const (
+	RoleViewer = "viewer"
+	RoleAdmin  = "admin"
+)
+
+// Claims carried by dashboard tokens.
+type Claims struct {
+	Username string   `json:"username"`
+	Roles    []string `json:"roles,omitempty"` // tokens without roles are treated as viewer
+	jwt.RegisteredClaims
+}
+
+// HasRole reports whether the claims grant role.
+func (c *Claims) HasRole(role string) bool {
+	if role == RoleViewer {
+		return true // every authenticated user can read
+	}
+	for _, r := range c.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator issues and validates dashboard JWTs.
+type Authenticator struct {
+	secret   []byte
+	issuer   string
+	tokenTTL time.Duration
+	users    map[string]config.UserEntry
+	jwks     *jwksCache // nil unless AUTH_JWKS_URL is set
+
+	// claim checks past the signature and expiry: HS256 tokens are ours and
+	// carry our issuer, RS256 tokens come from the IdP and carry its issuer
+	// and our audience
+	localClaims *jwt.Validator
+	idpClaims   *jwt.Validator
+}
+
+// used to keep login timing the same for unknown users
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+// NewAuthenticator creates an Authenticator from the auth config.
+func NewAuthenticator(cfg config.AuthConfig) (*Authenticator, error) {
+	if cfg.JWTSecret == "" && cfg.JWKSURL == "" {
+		return nil, fmt.Errorf("auth enabled but neither a JWT secret nor a JWKS URL is configured")
+	}
+	if cfg.JWKSURL != "" && (cfg.JWKSIssuer == "" || cfg.JWKSAudience == "") {
+		return nil, fmt.Errorf("a JWKS URL needs the identity provider's issuer and the audience its tokens must carry")
+	}
+	a := &Authenticator{
+		secret:      []byte(cfg.JWTSecret),
+		issuer:      cfg.Issuer,
+		tokenTTL:    cfg.TokenTTL,
+		users:       cfg.Users,
+		localClaims: jwt.NewValidator(),
+	}
+	if cfg.Issuer != "" {
+		a.localClaims = jwt.NewValidator(jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.JWKSURL != "" {
+		a.jwks = newJWKSCache(cfg.JWKSURL, cfg.JWKSRefresh)
+		a.idpClaims = jwt.NewValidator(jwt.WithIssuer(cfg.JWKSIssuer), jwt.WithAudience(cfg.JWKSAudience))
+	}
+	if len(a.users) == 0 && len(a.secret) > 0 {
+		appLogger.Warn("Auth: no AUTH_USERS configured, /api/auth/login will reject every attempt.")
+	}
+	return a, nil
+}
+
+// Login checks username/password against the static user list and returns a signed token.
+func (a *Authenticator) Login(username, password string) (token string, expiresAt time.Time, err error) {
+	if len(a.secret) == 0 {
+		return "", time.Time{}, ErrLoginDisabled
+	}
+	user, known := a.users[username]
+	if !known {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password)) // don't leak which usernames exist
+		return "", time.Time{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return "", time.Time{}, ErrInvalidCredentials
+	}
+	return a.IssueToken(username, user.Role)
+}
+
+// IssueToken signs an HS256 token for username with the given role.
+func (a *Authenticator) IssueToken(username, role string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(a.tokenTTL)
+	claims := Claims{
+		Username: username,
+		Roles:    []string{role},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   username,
+			Issuer:    a.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("sign token: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+// ValidateToken parses and verifies a token string. HS256 tokens are checked with
+// the shared secret and AUTH_ISSUER, RS256 tokens against the JWKS keys and
+// the IdP's issuer and audience.
+func (a *Authenticator) ValidateToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, a.keyFunc,
+		jwt.WithValidMethods([]string{"HS256", "RS256"}),
+		jwt.WithExpirationRequired(),
+	)
+	if err == nil {
+		validator := a.localClaims
+		if _, rsa := token.Method.(*jwt.SigningMethodRSA); rsa {
+			validator = a.idpClaims
+		}
+		err = validator.Validate(claims)
+	}
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+	if claims.Username == "" {
+		claims.Username = claims.Subject // external IdPs usually only set sub
+	}
+	return claims, nil
+}
+
+func (a *Authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(a.secret) == 0 {
+			return nil, fmt.Errorf("HMAC tokens are not accepted")
+		}
+		return a.secret, nil
+	case *jwt.SigningMethodRSA:
+		if a.jwks == nil {
+			return nil, fmt.Errorf("RSA tokens are not accepted without a JWKS URL")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return a.jwks.key(kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	idpIssuer   = "https://idp.example.com/"
+	idpAudience = "system-stats-dashboard"
+	idpKeyID    = "test-key"
+)
+
+// newIdP serves a JWKS with one RSA key and returns the key to sign with.
+func newIdP(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	doc := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": idpKeyID,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(server.Close)
+	return key, server.URL
+}
+
+func idpToken(t *testing.T, key *rsa.PrivateKey, issuer string, audience ...string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Subject:   "alice@example.com",
+		Issuer:    issuer,
+		Audience:  audience,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	token.Header["kid"] = idpKeyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+func newTestAuthenticator(t *testing.T, jwksURL string) *Authenticator {
+	t.Helper()
+	a, err := NewAuthenticator(config.AuthConfig{
+		Enabled:      true,
+		JWTSecret:    "test-secret",
+		Issuer:       "system-stats-monitoring", // the AUTH_ISSUER default
+		TokenTTL:     time.Hour,
+		JWKSURL:      jwksURL,
+		JWKSIssuer:   idpIssuer,
+		JWKSAudience: idpAudience,
+		JWKSRefresh:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	return a
+}
+
+func TestValidateTokenChecksIdPIssuerAndAudience(t *testing.T) {
+	key, jwksURL := newIdP(t)
+	a := newTestAuthenticator(t, jwksURL)
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"issuer and audience of the IdP", idpToken(t, key, idpIssuer, idpAudience), true},
+		{"audience among others", idpToken(t, key, idpIssuer, "other-app", idpAudience), true},
+		{"audience of another app", idpToken(t, key, idpIssuer, "other-app"), false},
+		{"no audience", idpToken(t, key, idpIssuer), false},
+		{"another issuer", idpToken(t, key, "https://evil.example.com/", idpAudience), false},
+		{"the local issuer", idpToken(t, key, "system-stats-monitoring", idpAudience), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := a.ValidateToken(tt.token)
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("ValidateToken: %v", err)
+				}
+				if claims.Username != "alice@example.com" {
+					t.Errorf("username = %q, want the subject", claims.Username)
+				}
+				return
+			}
+			if !errors.Is(err, ErrTokenInvalid) {
+				t.Errorf("ValidateToken error = %v, want ErrTokenInvalid", err)
+			}
+		})
+	}
+}
+
+func TestValidateTokenChecksLocalIssuer(t *testing.T) {
+	_, jwksURL := newIdP(t)
+	a := newTestAuthenticator(t, jwksURL)
+
+	token, _, err := a.IssueToken("admin", RoleAdmin)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+	if _, err := a.ValidateToken(token); err != nil {
+		t.Errorf("own token: %v", err)
+	}
+
+	// an HS256 token with the IdP's claims is still not one of ours
+	foreign, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "admin",
+		Issuer:    idpIssuer,
+		Audience:  jwt.ClaimStrings{idpAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := a.ValidateToken(foreign); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("HS256 token with the IdP issuer: error = %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestNewAuthenticatorRequiresIdPIssuerAndAudience(t *testing.T) {
+	for _, cfg := range []config.AuthConfig{
+		{JWKSURL: "https://idp.example.com/jwks", JWKSAudience: idpAudience},
+		{JWKSURL: "https://idp.example.com/jwks", JWKSIssuer: idpIssuer},
+	} {
+		if _, err := NewAuthenticator(cfg); err == nil {
+			t.Errorf("NewAuthenticator(%+v) = nil error, want the missing setting reported", cfg)
+		}
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		roles         []string
+		viewer, admin bool
+	}{
+		{nil, true, false}, // tokens without roles, e.g. from an IdP, can read
+		{[]string{RoleViewer}, true, false},
+		{[]string{RoleAdmin}, true, true},
+		{[]string{"operator", RoleAdmin}, true, true},
+	}
+	for _, tt := range tests {
+		claims := &Claims{Roles: tt.roles}
+		if got := claims.HasRole(RoleViewer); got != tt.viewer {
+			t.Errorf("roles %v: HasRole(viewer) = %t, want %t", tt.roles, got, tt.viewer)
+		}
+		if got := claims.HasRole(RoleAdmin); got != tt.admin {
+			t.Errorf("roles %v: HasRole(admin) = %t, want %t", tt.roles, got, tt.admin)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+)
+
+const jwksMinRefreshGap = 30 * time.Second // unknown kids can't make us hammer the IdP
+
+// jwksCache keeps the RSA keys from a JWKS endpoint, refreshed every
+// refreshEvery and on demand when a token references a key id we haven't seen.
+type jwksCache struct {
+	url          string
+	refreshEvery time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	lastFetched time.Time
+}
+
+type jwksDocument struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func newJWKSCache(url string, refreshEvery time.Duration) *jwksCache {
+	return &jwksCache{url: url, refreshEvery: refreshEvery, keys: make(map[string]*rsa.PublicKey)}
+}
+
+func (c *jwksCache) key(kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.keys[kid]
+	stale := time.Since(c.lastFetched) > c.refreshEvery
+	if ok && !stale {
+		return key, nil
+	}
+	if time.Since(c.lastFetched) > jwksMinRefreshGap || stale {
+		if err := c.refresh(); err != nil {
+			appLogger.Error("Failed to refresh JWKS from %s: %v", c.url, err)
+			if ok {
+				return key, nil // keep using the cached key
+			}
+			return nil, err
+		}
+	}
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// refresh must be called with c.mu held.
+func (c *jwksCache) refresh() error {
+	c.lastFetched = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return fmt.Errorf("create JWKS request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch JWKS: unexpected status %s", resp.Status)
+	}
+
+	var doc jwksDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			appLogger.Warn("Skipping malformed JWKS key %q", k.Kid)
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	c.keys = keys
+	appLogger.Info("Loaded %d signing keys from JWKS %s", len(keys), c.url)
+	return nil
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/gin-gonic/gin"
+)
+
+const claimsContextKey = "auth.claims"
+
+// Error codes in 401/403 bodies so the frontend can tell "log in again" from "not allowed".
+const (
+	CodeTokenMissing = "token_missing"
+	CodeTokenInvalid = "token_invalid"
+	CodeTokenExpired = "token_expired"
+
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeForbidden          = "forbidden"
+)
+
+// Middleware requires a valid "Authorization: Bearer <jwt>" header.
+// Responds 401 with a machine readable code otherwise.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString, found := strings.CutPrefix(header, "Bearer ")
+		if !found || strings.TrimSpace(tokenString) == "" {
+			abortUnauthorized(c, CodeTokenMissing, "Missing bearer token")
+			return
+		}
+
+		claims, err := a.ValidateToken(strings.TrimSpace(tokenString))
+		if err != nil {
+			if errors.Is(err, ErrTokenExpired) {
+				abortUnauthorized(c, CodeTokenExpired, "Token expired")
+				return
+			}
+			appLogger.Warn("Rejected dashboard token from %s: %v", c.ClientIP(), err)
+			abortUnauthorized(c, CodeTokenInvalid, "Invalid token")
+			return
+		}
+
+		c.Set(claimsContextKey, claims)
+		c.Next()
+	}
+}
+
+// RequireRole must run after Middleware. Responds 403 naming the missing role.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := ClaimsFromContext(c)
+		if claims == nil || !claims.HasRole(role) {
+			username := ""
+			if claims != nil {
+				username = claims.Username
+			}
+			appLogger.Warn("User %q denied %s %s: missing role %s", username, c.Request.Method, c.Request.URL.Path, role)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":         "Missing required role: " + role,
+				"code":          CodeForbidden,
+				"required_role": role,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ClaimsFromContext returns the validated claims, or nil when auth is disabled.
+func ClaimsFromContext(c *gin.Context) *Claims {
+	value, exists := c.Get(claimsContextKey)
+	if !exists {
+		return nil
+	}
+	claims, _ := value.(*Claims)
+	return claims
+}
+
+func abortUnauthorized(c *gin.Context, code, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="dashboard"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message, "code": code})
+}
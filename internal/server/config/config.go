@@ -1,8 +1,14 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
 )
@@ -13,63 +19,788 @@ import (
 
 // holds the configuration for connecting to InfluxDB
 type InfluxDBConfig struct {
-	URL    string
+	URLs   []string // endpoints in order of preference, later ones are failovers
 	Token  string
 	Org    string
 	Bucket string
+
+	// Optional bucket with downsampled data (filled by an InfluxDB task).
+	// Metric history windows longer than ColdBucketAfter are read from it;
+	// ColdBucketResolution is its point interval, for the query cost estimate.
+	// Empty: everything is read from Bucket.
+	ColdBucket           string
+	ColdBucketAfter      time.Duration
+	ColdBucketResolution time.Duration
+
+	// Read-side protection: at most MaxConcurrentQueries dashboard reads hit
+	// InfluxDB at once, others wait up to QueryQueueTimeout for a slot.
+	MaxConcurrentQueries int
+	QueryQueueTimeout    time.Duration
+
+	// Disk, SMART and process points of one payload are written with up to
+	// WriteConcurrency writes in flight, 1 = one after the other.
+	WriteConcurrency int
+
+	// Range queries estimated to read more than QueryPointBudget raw points
+	// (hosts x fields x span / 5s) are rejected, or only logged when
+	// RejectOverBudget is false. 0 disables the check.
+	QueryPointBudget int64
+	RejectOverBudget bool
+	// A multi-field history request may ask for at most HistoryMaxFields
+	// distinct fields, all read in one query.
+	HistoryMaxFields int
+
+	// The client is recreated after ReconnectAfterErrors consecutive
+	// connection errors, at most once per ReconnectCooldown, failing over to
+	// the next endpoint when there are several. Preferred endpoints are
+	// probed every FailbackProbeInterval while a later one is in use.
+	ReconnectAfterErrors  int
+	ReconnectCooldown     time.Duration
+	FailbackProbeInterval time.Duration
+
+	// Hosts silent for longer than this are left out of the overview (they
+	// are not deleted). Also the overview query's range.
+	OverviewMaxOfflineAge time.Duration
+	// Hosts seen within OnlineWithin are online, older ones are listed as
+	// offline with their last-known values. Same value as HostOfflineAfter.
+	OnlineWithin time.Duration
+	// Online hosts with an uptime under RecentRebootWithin are flagged as
+	// recently rebooted and listed as warning. 0 disables the check.
+	RecentRebootWithin time.Duration
+	// Usage levels at which online hosts are listed as warning or critical.
+	Status StatusThresholds
+	// History points carry an "HH:MM" label in this zone, from DisplayTimezone.
+	DisplayLocation *time.Location
+
+	// Agents send processes every ProcessSampleEvery cycles (MONITOR_PROCESS_EVERY);
+	// the host details' process lookback is widened to match.
+	ProcessSampleEvery int
+	// How process_metrics are stored: "aggregated" (default) sums the
+	// processes of each name into one point tagged by name only; "instance"
+	// keeps one point per process, tagged by name and an instance ordinal,
+	// with the PID as a field.
+	ProcessSeries string
+
+	// Rolling CPU/RAM averages over these windows are added to the overview,
+	// recomputed every OverviewAverageRefresh in the background. Empty disables them.
+	OverviewAverageWindows []time.Duration
+	OverviewAverageRefresh time.Duration
+	// The overview is served from a table of each host's latest values that
+	// ingest keeps current; the Flux query re-seeds it when it is older than
+	// this (cold start, other server instances). 0 always queries.
+	OverviewCacheMaxAge time.Duration
+
+	HealthCheckTimeout time.Duration // startup health check of the writer and reader
+	DetailsLookback    time.Duration // host details look for the latest system/disk point this far back
+
+	// Set by --allow-incomplete-config: a failed startup health check is only
+	// logged and the server starts anyway (ingest stays not ready).
+	AllowUnavailable bool
+}
+
+// holds dashboard API authentication settings. Tokens are verified either with
+// the shared HMAC secret (HS256, also used to sign /api/auth/login tokens) or
+// against keys from a JWKS URL (RS256, tokens issued by an external IdP).
+type AuthConfig struct {
+	Enabled   bool
+	JWTSecret string
+	Issuer    string // set as "iss" on issued HS256 tokens and required on incoming ones when non-empty
+	TokenTTL  time.Duration
+	Users     map[string]UserEntry // static users for /api/auth/login
+
+	// RS256 tokens of the IdP must carry JWKSIssuer as "iss" and JWKSAudience
+	// in "aud"; both are required with a JWKSURL.
+	JWKSURL      string
+	JWKSIssuer   string
+	JWKSAudience string
+
+	JWKSRefresh time.Duration // JWKS keys are re-fetched after this (and on unknown key ids)
+}
+
+// a static dashboard user
+type UserEntry struct {
+	PasswordHash string // bcrypt
+	Role         string // "viewer" (default) or "admin"
+}
+
+// holds alert notification settings. Notifiers lists which targets are used
+// ("webhook", "slack", "email"); leave it empty to only log alerts.
+type AlertingConfig struct {
+	EvaluationInterval time.Duration // how often alert rules are checked
+
+	HostDown HostDownConfig
+
+	Notifiers         []string
+	WebhookURLs       []string
+	WebhookSecret     string // HMAC-SHA256 key for the X-Signature-256 header, unsigned when empty
+	WebhookMaxRetries int
+	SlackWebhookURL   string            // default channel
+	SlackChannels     map[string]string // channel name -> incoming webhook URL, picked per rule
+	DashboardURL      string            // frontend base URL for links in notifications
+	SMTP              SMTPConfig
+
+	NotifyTimeout   time.Duration // limit for sending one round of notifications, retries included
+	ForecastRefresh time.Duration // how often disk_full_days rules re-query their disk series
+}
+
+// holds the built-in host-down alert settings. A host is down once it has been
+// silent for IntervalMultiplier times its observed reporting interval
+// (DefaultInterval until that is known).
+type HostDownConfig struct {
+	Enabled            bool
+	IntervalMultiplier int
+	DefaultInterval    time.Duration
+	MinFiring          time.Duration // a firing alert isn't resolved before this, debounces flapping hosts
+}
+
+// holds outgoing mail settings for the email notifier
+type SMTPConfig struct {
+	Host       string
+	Port       int
+	TLSMode    string // "starttls" (default), "ssl" (implicit TLS) or "none"
+	Username   string // PLAIN auth is skipped when empty
+	Password   string
+	From       string
+	To         []string // default recipients, rules can override
+	MaxRetries int      // retries for connection errors and 4xx replies
+}
+
+// holds HTTPS settings; the server uses plain HTTP unless both files are set
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
 }
 
 // holds overall server config
 type ServerConfig struct {
-	ListenAddress  string
+	ListenAddress string
+	TLS           TLSConfig
+	CORSOrigins   []string // frontend origins allowed to call the API
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For / X-Real-IP headers are
+	// trusted for the client IP. Empty trusts every proxy (gin's default),
+	// "none" trusts none.
+	TrustedProxies []string
 	InfluxDB       InfluxDBConfig
+	MetadataDBPath string // bbolt file for groups and other mutable metadata
 	EnableDebugLog bool
+	LogFormat      string // "text" or "json"
+	LogLevel       string // debug, info, warn or error; EnableDebugLog forces debug
+
+	// IANA zone for timestamps the server formats for humans ("Local" is the
+	// server's own). Machine-readable timestamps are always UTC RFC 3339.
+	DisplayTimezone string
+
+	// A host that hasn't posted for HostOfflineAfter is annotated as offline.
+	HostOfflineAfter time.Duration
+
+	// |receive time - collected_at| above this is reported back to the agent as clock drift.
+	ClockDriftThreshold time.Duration
+
+	// Store hosts as "<host_id>@<hostname>", keeping machines that share a
+	// host_id (cloned VMs) apart until their IDs are fixed.
+	HostIDIncludeHostname bool
+
+	// Rules inferring a host's role from its reported process names, in
+	// order of precedence. Empty disables role inference.
+	HostRoleRules []RoleRule
+
+	// Serve GET /api/prometheus, the latest values of every host in the
+	// Prometheus text format, for an existing Prometheus to scrape.
+	PrometheusEnabled bool
+
+	Auth AuthConfig
+
+	Alerting AlertingConfig
+
+	Timeouts RouteTimeouts
+	HTTP     HTTPTimeouts
+	Batch    BatchIngest
+
+	HostTrackerInterval time.Duration // how often hosts are checked for going offline
+	ReadinessRetry      time.Duration // delay between failed readiness test writes
+}
+
+// RoleRule gives hosts reporting a process whose name contains Contains
+// (case-insensitive) the role Role, e.g. postgres => db.
+type RoleRule struct {
+	Contains string `json:"contains"`
+	Role     string `json:"role"`
+}
+
+// HTTPTimeouts are the HTTP server's connection timeouts and the grace period
+// for in-flight requests on shutdown.
+type HTTPTimeouts struct {
+	Read     time.Duration
+	Write    time.Duration
+	Idle     time.Duration
+	Shutdown time.Duration
+}
+
+// StatusThresholds are the usage percentages from which an online host's
+// status is warning or critical (at the value or above it). Usage covers
+// CPU and RAM, Disk the root disk's space and inodes.
+type StatusThresholds struct {
+	UsageWarning  float64
+	UsageCritical float64
+	DiskWarning   float64
+	DiskCritical  float64
+}
+
+// BatchIngest limits POST /api/stats/batch. Its payloads are decoded and
+// stored one at a time, so memory doesn't grow with the batch; these bound
+// how long one request can take.
+type BatchIngest struct {
+	MaxItems int   // payloads per request
+	MaxBytes int64 // request body size
 }
 
-// Load loads configuration from environment variables.
-func Load() (*ServerConfig, error) {
+// RouteTimeouts are per-request deadlines for the dashboard query routes. They
+// should stay below the HTTP server's 10s WriteTimeout so a slow query gets a
+// 504 instead of a dropped connection.
+type RouteTimeouts struct {
+	Overview time.Duration // GET /hosts/overview
+	Details  time.Duration // GET /host/:hostID/details
+	History  time.Duration // GET /host/:hostID/metrics/:metricName
+	Default  time.Duration // other InfluxDB backed routes
+	// 0 disables the deadline for that route.
+}
+
+// Load loads configuration from environment variables and, when path is not
+// empty, the YAML config file at path. Environment variables take precedence
+// over the file, defaults apply to settings set in neither.
+//
+// Missing or invalid settings are all reported in one joined error. The
+// config is still returned with it (unusable values reset to defaults), only
+// an unreadable config file returns a nil config.
+func Load(path string) (*ServerConfig, error) {
+	l := &loader{}
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		l.fileValues = values
+	}
+
 	cfg := &ServerConfig{
-		ListenAddress: getEnv("SERVER_LISTEN_ADDRESS", ":8080"), //default port
+		ListenAddress: l.getEnv("SERVER_LISTEN_ADDRESS", ":8080"), //default port
+		TLS: TLSConfig{
+			CertFile: l.getEnv("SERVER_TLS_CERT_FILE", ""),
+			KeyFile:  l.getEnv("SERVER_TLS_KEY_FILE", ""),
+		},
+		CORSOrigins:    l.getEnvAsStringSlice("CORS_ALLOW_ORIGINS", []string{"http://localhost:5173"}),
+		TrustedProxies: l.getEnvAsStringSlice("SERVER_TRUSTED_PROXIES", nil),
 
 		InfluxDB: InfluxDBConfig{
-			URL:    getEnv("INFLUXDB_URL", "http://localhost:8086"),
-			Token:  getEnv("INFLUXDB_TOKEN", "API-KEY"),      // Add API Key
-			Org:    getEnv("INFLUXDB_ORG", "ORG-NAME"),       // Add organization name                                                                                   //
-			Bucket: getEnv("INFLUXDB_BUCKET", "BUCKET-NAME"), // Add bucket                                                                            //
+			URLs:   l.getEnvAsStringSlice("INFLUXDB_URL", []string{"http://localhost:8086"}),
+			Token:  l.getSecret("INFLUXDB_TOKEN"),   // required
+			Org:    l.getEnv("INFLUXDB_ORG", ""),    // required
+			Bucket: l.getEnv("INFLUXDB_BUCKET", ""), // required
+
+			ColdBucket:           l.getEnv("INFLUXDB_COLD_BUCKET", ""),
+			ColdBucketAfter:      l.getEnvAsDuration("INFLUXDB_COLD_BUCKET_AFTER", 24*time.Hour),
+			ColdBucketResolution: l.getEnvAsDuration("INFLUXDB_COLD_BUCKET_RESOLUTION", 5*time.Minute),
+
+			MaxConcurrentQueries: l.getEnvAsInt("INFLUXDB_MAX_CONCURRENT_QUERIES", 8),
+			QueryQueueTimeout:    l.getEnvAsDuration("INFLUXDB_QUERY_QUEUE_TIMEOUT", 5*time.Second),
+			WriteConcurrency:     l.getEnvAsInt("INFLUXDB_WRITE_CONCURRENCY", 4),
+
+			QueryPointBudget: int64(l.getEnvAsInt("INFLUXDB_QUERY_POINT_BUDGET", 1000000)),
+			RejectOverBudget: l.getEnvAsBool("INFLUXDB_QUERY_REJECT_OVER_BUDGET", true),
+			HistoryMaxFields: l.getEnvAsInt("INFLUXDB_HISTORY_MAX_FIELDS", 6),
+
+			ReconnectAfterErrors:  l.getEnvAsInt("INFLUXDB_RECONNECT_AFTER_ERRORS", 3),
+			ReconnectCooldown:     l.getEnvAsDuration("INFLUXDB_RECONNECT_COOLDOWN", 30*time.Second),
+			FailbackProbeInterval: l.getEnvAsDuration("INFLUXDB_FAILBACK_PROBE_INTERVAL", 30*time.Second),
+
+			OverviewMaxOfflineAge: l.getEnvAsDuration("OVERVIEW_MAX_OFFLINE_AGE", 24*time.Hour),
+			RecentRebootWithin:    l.getEnvAsDuration("RECENT_REBOOT_THRESHOLD", 12*time.Hour),
+			Status: StatusThresholds{
+				UsageWarning:  l.getEnvAsFloat("STATUS_USAGE_WARNING", 85),
+				UsageCritical: l.getEnvAsFloat("STATUS_USAGE_CRITICAL", 95),
+				DiskWarning:   l.getEnvAsFloat("STATUS_DISK_WARNING", 90),
+				DiskCritical:  l.getEnvAsFloat("STATUS_DISK_CRITICAL", 95),
+			},
+			ProcessSampleEvery: l.getEnvAsInt("PROCESS_SAMPLE_EVERY", 1),
+			ProcessSeries:      strings.ToLower(l.getEnv("PROCESS_SERIES", "aggregated")),
+
+			OverviewAverageWindows: l.getEnvAsDurationList("OVERVIEW_AVERAGE_WINDOWS", nil),
+			OverviewAverageRefresh: l.getEnvAsDuration("OVERVIEW_AVERAGE_REFRESH", 30*time.Second),
+			OverviewCacheMaxAge:    l.getEnvAsDuration("OVERVIEW_CACHE_MAX_AGE", 5*time.Minute),
+
+			HealthCheckTimeout: l.getEnvAsDuration("INFLUXDB_HEALTH_CHECK_TIMEOUT", 5*time.Second),
+			DetailsLookback:    l.getEnvAsDuration("INFLUXDB_DETAILS_LOOKBACK", 15*time.Second),
+		},
+		MetadataDBPath: l.getEnv("METADATA_DB_PATH", "metadata.db"),
+		EnableDebugLog: l.getEnvAsBool("SERVER_ENABLE_DEBUG_LOG", false),
+		LogFormat:      strings.ToLower(l.getEnv("LOG_FORMAT", "text")),
+		LogLevel:       strings.ToLower(l.getEnv("LOG_LEVEL", "info")),
+
+		DisplayTimezone: l.getEnv("SERVER_DISPLAY_TZ", "Local"),
+
+		HostOfflineAfter: l.getEnvAsDuration("HOST_OFFLINE_AFTER", 35*time.Second),
+
+		ClockDriftThreshold: l.getEnvAsDuration("SERVER_CLOCK_DRIFT_THRESHOLD", 10*time.Second),
+
+		HostIDIncludeHostname: l.getEnvAsBool("HOST_ID_INCLUDE_HOSTNAME", false),
+
+		HostRoleRules: l.parseRoleRules(l.getEnvAsStringSlice("HOST_ROLE_RULES", nil)),
+
+		PrometheusEnabled: l.getEnvAsBool("PROMETHEUS_ENABLED", false),
+
+		Batch: BatchIngest{
+			MaxItems: l.getEnvAsInt("INGEST_BATCH_MAX_ITEMS", 1000),
+			MaxBytes: int64(l.getEnvAsInt("INGEST_BATCH_MAX_BYTES", 64<<20)),
+		},
+
+		Timeouts: RouteTimeouts{
+			Overview: l.getEnvAsDuration("API_TIMEOUT_OVERVIEW", 4*time.Second),
+			Details:  l.getEnvAsDuration("API_TIMEOUT_DETAILS", 5*time.Second),
+			History:  l.getEnvAsDuration("API_TIMEOUT_HISTORY", 8*time.Second),
+			Default:  l.getEnvAsDuration("API_TIMEOUT_DEFAULT", 5*time.Second),
 		},
-		EnableDebugLog: getEnvAsBool("SERVER_ENABLE_DEBUG_LOG", false),
+		HTTP: HTTPTimeouts{
+			Read:     l.getEnvAsDuration("SERVER_READ_TIMEOUT", 5*time.Second),
+			Write:    l.getEnvAsDuration("SERVER_WRITE_TIMEOUT", 10*time.Second),
+			Idle:     l.getEnvAsDuration("SERVER_IDLE_TIMEOUT", 120*time.Second),
+			Shutdown: l.getEnvAsDuration("SERVER_SHUTDOWN_TIMEOUT", 5*time.Second),
+		},
+
+		HostTrackerInterval: l.getEnvAsDuration("HOST_TRACKER_INTERVAL", 10*time.Second),
+		ReadinessRetry:      l.getEnvAsDuration("SERVER_READINESS_RETRY", 5*time.Second),
+
+		Auth: AuthConfig{
+			Enabled:   l.getEnvAsBool("AUTH_ENABLED", false),
+			JWTSecret: l.getSecret("AUTH_JWT_SECRET"),
+			Issuer:    l.getEnv("AUTH_ISSUER", "system-stats-monitoring"),
+			TokenTTL:  l.getEnvAsDuration("AUTH_TOKEN_TTL", 12*time.Hour),
+			Users:     parseUserList(l.getEnv("AUTH_USERS", "")),
+
+			JWKSURL:      l.getEnv("AUTH_JWKS_URL", ""),
+			JWKSIssuer:   l.getEnv("AUTH_JWKS_ISSUER", ""),
+			JWKSAudience: l.getEnv("AUTH_JWKS_AUDIENCE", ""),
+
+			JWKSRefresh: l.getEnvAsDuration("AUTH_JWKS_REFRESH", 1*time.Hour),
+		},
+
+		Alerting: AlertingConfig{
+			EvaluationInterval: l.getEnvAsDuration("ALERT_EVAL_INTERVAL", 15*time.Second),
+
+			HostDown: HostDownConfig{
+				Enabled:            l.getEnvAsBool("ALERT_HOST_DOWN_ENABLED", true),
+				IntervalMultiplier: l.getEnvAsInt("ALERT_HOST_DOWN_INTERVAL_MULTIPLIER", 6),
+				DefaultInterval:    l.getEnvAsDuration("ALERT_HOST_DOWN_DEFAULT_INTERVAL", 5*time.Second),
+				MinFiring:          l.getEnvAsDuration("ALERT_HOST_DOWN_MIN_FIRING", 1*time.Minute),
+			},
+
+			Notifiers:         l.getEnvAsStringSlice("ALERT_NOTIFIERS", nil),
+			WebhookURLs:       l.getEnvAsStringSlice("ALERT_WEBHOOK_URLS", nil),
+			WebhookSecret:     l.getSecret("ALERT_WEBHOOK_SECRET"),
+			WebhookMaxRetries: l.getEnvAsInt("ALERT_WEBHOOK_MAX_RETRIES", 3),
+			SlackWebhookURL:   l.getEnv("ALERT_SLACK_WEBHOOK_URL", ""),
+			SlackChannels:     parseKeyValueList(l.getEnv("ALERT_SLACK_CHANNELS", ""), "ALERT_SLACK_CHANNELS"),
+			DashboardURL:      l.getEnv("ALERT_DASHBOARD_URL", ""),
+			SMTP: SMTPConfig{
+				Host:     l.getEnv("ALERT_SMTP_HOST", ""),
+				Port:     l.getEnvAsInt("ALERT_SMTP_PORT", 587),
+				TLSMode:  strings.ToLower(l.getEnv("ALERT_SMTP_TLS", "starttls")),
+				Username: l.getEnv("ALERT_SMTP_USERNAME", ""),
+				Password: l.getSecret("ALERT_SMTP_PASSWORD"),
+				From:     l.getEnv("ALERT_SMTP_FROM", ""),
+				To:       l.getEnvAsStringSlice("ALERT_SMTP_TO", nil),
+
+				MaxRetries: l.getEnvAsInt("ALERT_SMTP_MAX_RETRIES", 3),
+			},
+
+			NotifyTimeout:   l.getEnvAsDuration("ALERT_NOTIFY_TIMEOUT", 2*time.Minute),
+			ForecastRefresh: l.getEnvAsDuration("ALERT_FORECAST_REFRESH", 5*time.Minute),
+		},
+	}
+	if loc, err := time.LoadLocation(cfg.DisplayTimezone); err != nil {
+		l.invalid("SERVER_DISPLAY_TZ %q is not a known time zone (e.g. UTC, Europe/Berlin): %v", cfg.DisplayTimezone, err)
+		cfg.InfluxDB.DisplayLocation = time.Local
+	} else {
+		cfg.InfluxDB.DisplayLocation = loc
+	}
+
+	l.validate(cfg)
+	cfg.InfluxDB.OnlineWithin = cfg.HostOfflineAfter
+	if len(l.problems) > 0 {
+		return cfg, errors.Join(l.problems...)
+	}
+	return cfg, nil
+}
+
+// loader is the state of one Load: the config file's values and every
+// invalid or missing setting found so far. Each Load has its own, so a
+// SIGHUP reload can run while other code reads the config.
+type loader struct {
+	fileValues map[string]string // keyed by env var name
+	problems   []error
+}
+
+func (l *loader) invalid(format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Errorf(format, args...))
+}
+
+// validate reports missing and nonsensical settings. Settings the server
+// can't run with at all are reset to their defaults, so a config loaded with
+// --allow-incomplete-config is still usable.
+func (l *loader) validate(cfg *ServerConfig) {
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		l.invalid("LOG_FORMAT %q must be text or json", cfg.LogFormat)
+		cfg.LogFormat = "text"
+	}
+	if _, err := appLogger.ParseLevel(cfg.LogLevel); err != nil {
+		l.invalid("%v", err)
+		cfg.LogLevel = "info"
+	}
+	if _, port, err := net.SplitHostPort(cfg.ListenAddress); err != nil {
+		l.invalid("SERVER_LISTEN_ADDRESS %q is not a host:port address: %v", cfg.ListenAddress, err)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		l.invalid("SERVER_LISTEN_ADDRESS %q has an invalid port", cfg.ListenAddress)
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if proxy == "none" {
+			if len(cfg.TrustedProxies) > 1 {
+				l.invalid("SERVER_TRUSTED_PROXIES: \"none\" can't be combined with proxy addresses")
+			}
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			l.invalid("SERVER_TRUSTED_PROXIES entry %q is not an IP address or CIDR", proxy)
+		}
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		l.invalid("SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set together")
+	}
+
+	// Essential InfluxDB settings
+	if len(cfg.InfluxDB.URLs) == 0 {
+		l.invalid("INFLUXDB_URL is required")
+		cfg.InfluxDB.URLs = []string{"http://localhost:8086"}
+	}
+	for _, endpoint := range cfg.InfluxDB.URLs {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			l.invalid("INFLUXDB_URL %q must be an http(s) URL", endpoint)
+		}
 	}
-	// Validate essential InfluxDB settings
 	if cfg.InfluxDB.Token == "" {
-		appLogger.Error("INFLUXDB_TOKEN environment variable is not set.")
+		l.invalid("INFLUXDB_TOKEN is not set")
 	}
 	if cfg.InfluxDB.Org == "" {
-		appLogger.Error("INFLUXDB_ORG environment variable is not set.")
+		l.invalid("INFLUXDB_ORG is not set")
 	}
 	if cfg.InfluxDB.Bucket == "" {
-		appLogger.Error("INFLUXDB_BUCKET environment variable is not set.")
+		l.invalid("INFLUXDB_BUCKET is not set")
+	}
+	if cfg.InfluxDB.ColdBucket != "" {
+		if cfg.InfluxDB.ColdBucketAfter <= 0 {
+			l.invalid("INFLUXDB_COLD_BUCKET_AFTER must be positive, got %s", cfg.InfluxDB.ColdBucketAfter)
+			cfg.InfluxDB.ColdBucketAfter = 24 * time.Hour
+		}
+		if cfg.InfluxDB.ColdBucketResolution <= 0 {
+			l.invalid("INFLUXDB_COLD_BUCKET_RESOLUTION must be positive, got %s", cfg.InfluxDB.ColdBucketResolution)
+			cfg.InfluxDB.ColdBucketResolution = 5 * time.Minute
+		}
+	}
+	if cfg.InfluxDB.MaxConcurrentQueries < 1 {
+		l.invalid("INFLUXDB_MAX_CONCURRENT_QUERIES must be at least 1")
+		cfg.InfluxDB.MaxConcurrentQueries = 1
+	}
+	if cfg.InfluxDB.WriteConcurrency < 1 {
+		l.invalid("INFLUXDB_WRITE_CONCURRENCY must be at least 1")
+		cfg.InfluxDB.WriteConcurrency = 1
+	}
+	windows := cfg.InfluxDB.OverviewAverageWindows[:0]
+	for _, window := range cfg.InfluxDB.OverviewAverageWindows {
+		if window <= 0 {
+			l.invalid("OVERVIEW_AVERAGE_WINDOWS entries must be positive, got %s", window)
+			continue
+		}
+		windows = append(windows, window)
+	}
+	cfg.InfluxDB.OverviewAverageWindows = windows
+	if len(cfg.InfluxDB.OverviewAverageWindows) > 0 && cfg.InfluxDB.OverviewAverageRefresh <= 0 {
+		l.invalid("OVERVIEW_AVERAGE_REFRESH must be positive, got %s", cfg.InfluxDB.OverviewAverageRefresh)
+		cfg.InfluxDB.OverviewAverageRefresh = 30 * time.Second
+	}
+	if cfg.Batch.MaxItems < 1 {
+		l.invalid("INGEST_BATCH_MAX_ITEMS must be at least 1")
+		cfg.Batch.MaxItems = 1
+	}
+	if cfg.Batch.MaxBytes < 1 {
+		l.invalid("INGEST_BATCH_MAX_BYTES must be at least 1")
+		cfg.Batch.MaxBytes = 64 << 20
+	}
+	l.validateStatusThresholds("STATUS_USAGE", cfg.InfluxDB.Status.UsageWarning, cfg.InfluxDB.Status.UsageCritical)
+	l.validateStatusThresholds("STATUS_DISK", cfg.InfluxDB.Status.DiskWarning, cfg.InfluxDB.Status.DiskCritical)
+	if cfg.InfluxDB.HistoryMaxFields < 1 {
+		l.invalid("INFLUXDB_HISTORY_MAX_FIELDS must be at least 1")
+		cfg.InfluxDB.HistoryMaxFields = 6
+	}
+	if cfg.InfluxDB.ProcessSampleEvery < 1 {
+		l.invalid("PROCESS_SAMPLE_EVERY must be at least 1")
+		cfg.InfluxDB.ProcessSampleEvery = 1
+	}
+	if cfg.InfluxDB.ProcessSeries != "aggregated" && cfg.InfluxDB.ProcessSeries != "instance" {
+		l.invalid("PROCESS_SERIES %q must be aggregated or instance", cfg.InfluxDB.ProcessSeries)
+		cfg.InfluxDB.ProcessSeries = "aggregated"
+	}
+
+	// Reset like the numeric settings above, so --allow-incomplete-config
+	// doesn't start timers and deadlines with nonsensical values.
+	positive := []durationSetting{
+		{"ALERT_FORECAST_REFRESH", &cfg.Alerting.ForecastRefresh, 5 * time.Minute},
+		{"ALERT_NOTIFY_TIMEOUT", &cfg.Alerting.NotifyTimeout, 2 * time.Minute},
+		{"AUTH_JWKS_REFRESH", &cfg.Auth.JWKSRefresh, 1 * time.Hour},
+		{"AUTH_TOKEN_TTL", &cfg.Auth.TokenTTL, 12 * time.Hour},
+		{"HOST_OFFLINE_AFTER", &cfg.HostOfflineAfter, 35 * time.Second},
+		{"INFLUXDB_DETAILS_LOOKBACK", &cfg.InfluxDB.DetailsLookback, 15 * time.Second},
+		{"INFLUXDB_FAILBACK_PROBE_INTERVAL", &cfg.InfluxDB.FailbackProbeInterval, 30 * time.Second},
+		{"INFLUXDB_HEALTH_CHECK_TIMEOUT", &cfg.InfluxDB.HealthCheckTimeout, 5 * time.Second},
+		{"INFLUXDB_QUERY_QUEUE_TIMEOUT", &cfg.InfluxDB.QueryQueueTimeout, 5 * time.Second},
+		{"OVERVIEW_MAX_OFFLINE_AGE", &cfg.InfluxDB.OverviewMaxOfflineAge, 24 * time.Hour},
+		{"SERVER_SHUTDOWN_TIMEOUT", &cfg.HTTP.Shutdown, 5 * time.Second},
+	}
+	notNegative := []durationSetting{
+		{"ALERT_HOST_DOWN_MIN_FIRING", &cfg.Alerting.HostDown.MinFiring, 1 * time.Minute},
+		{"API_TIMEOUT_DEFAULT", &cfg.Timeouts.Default, 5 * time.Second},
+		{"API_TIMEOUT_DETAILS", &cfg.Timeouts.Details, 5 * time.Second},
+		{"API_TIMEOUT_HISTORY", &cfg.Timeouts.History, 8 * time.Second},
+		{"API_TIMEOUT_OVERVIEW", &cfg.Timeouts.Overview, 4 * time.Second},
+		{"INFLUXDB_RECONNECT_COOLDOWN", &cfg.InfluxDB.ReconnectCooldown, 30 * time.Second},
+		{"OVERVIEW_CACHE_MAX_AGE", &cfg.InfluxDB.OverviewCacheMaxAge, 5 * time.Minute},
+		{"RECENT_REBOOT_THRESHOLD", &cfg.InfluxDB.RecentRebootWithin, 12 * time.Hour},
+		{"SERVER_CLOCK_DRIFT_THRESHOLD", &cfg.ClockDriftThreshold, 10 * time.Second},
+		{"SERVER_IDLE_TIMEOUT", &cfg.HTTP.Idle, 120 * time.Second},
+		{"SERVER_READ_TIMEOUT", &cfg.HTTP.Read, 5 * time.Second},
+		{"SERVER_WRITE_TIMEOUT", &cfg.HTTP.Write, 10 * time.Second},
+	}
+	for _, d := range positive {
+		if *d.value <= 0 {
+			l.invalid("%s must be positive, got %s", d.name, *d.value)
+			*d.value = d.fallback
+		}
+	}
+	for _, d := range notNegative {
+		if *d.value < 0 {
+			l.invalid("%s must not be negative, got %s", d.name, *d.value)
+			*d.value = d.fallback
+		}
+	}
 
+	if cfg.Auth.Enabled && cfg.Auth.JWTSecret == "" && cfg.Auth.JWKSURL == "" {
+		l.invalid("AUTH_ENABLED is set but neither AUTH_JWT_SECRET nor AUTH_JWKS_URL is configured")
+	}
+	if cfg.Auth.Enabled && cfg.Auth.JWKSURL != "" && (cfg.Auth.JWKSIssuer == "" || cfg.Auth.JWKSAudience == "") {
+		l.invalid("AUTH_JWKS_URL needs AUTH_JWKS_ISSUER and AUTH_JWKS_AUDIENCE, the issuer and audience the identity provider's tokens must carry")
 	}
 
-	return cfg, nil
+	// Ticker intervals, a zero one would panic with --allow-incomplete-config
+	if cfg.HostTrackerInterval <= 0 {
+		l.invalid("HOST_TRACKER_INTERVAL must be positive, got %s", cfg.HostTrackerInterval)
+		cfg.HostTrackerInterval = 10 * time.Second
+	}
+	if cfg.ReadinessRetry <= 0 {
+		l.invalid("SERVER_READINESS_RETRY must be positive, got %s", cfg.ReadinessRetry)
+		cfg.ReadinessRetry = 5 * time.Second
+	}
+	if cfg.Alerting.EvaluationInterval <= 0 {
+		l.invalid("ALERT_EVAL_INTERVAL must be positive, got %s", cfg.Alerting.EvaluationInterval)
+		cfg.Alerting.EvaluationInterval = 15 * time.Second
+	}
+	if cfg.Alerting.HostDown.IntervalMultiplier < 1 {
+		l.invalid("ALERT_HOST_DOWN_INTERVAL_MULTIPLIER must be at least 1")
+		cfg.Alerting.HostDown.IntervalMultiplier = 6
+	}
+	if cfg.Alerting.HostDown.DefaultInterval <= 0 {
+		l.invalid("ALERT_HOST_DOWN_DEFAULT_INTERVAL must be positive, got %s", cfg.Alerting.HostDown.DefaultInterval)
+		cfg.Alerting.HostDown.DefaultInterval = 5 * time.Second
+	}
+}
+
+// validateStatusThresholds checks one warning/critical pair (<prefix>_WARNING,
+// <prefix>_CRITICAL): percentages with warning below critical.
+func (l *loader) validateStatusThresholds(prefix string, warning, critical float64) {
+	if warning <= 0 || warning >= critical || critical > 100 {
+		l.invalid("%s_WARNING (%g) and %s_CRITICAL (%g) must satisfy 0 < warning < critical <= 100", prefix, warning, prefix, critical)
+	}
 }
 
-// get an environment variable or return a default value.
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
+// durationSetting is a duration to check, with the default it is reset to
+// when it is out of range.
+type durationSetting struct {
+	name     string
+	value    *time.Duration
+	fallback time.Duration
+}
+
+// get an environment variable (or its config file value) or return a default value.
+func (l *loader) getEnv(key, fallback string) string {
+	if value, exists := l.lookup(key); exists {
 		return value
 	}
 	return fallback
 }
 
+// getSecret reads a secret setting. <key>_FILE names a file holding the
+// secret (Kubernetes / systemd credentials) and takes precedence over <key>,
+// which would expose the secret in /proc. Surrounding whitespace is trimmed.
+func (l *loader) getSecret(key string) string {
+	if path, exists := l.lookup(key + "_FILE"); exists && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			l.invalid("%s_FILE: %v", key, err)
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	return l.getEnv(key, "")
+}
+
 // Helper function to get an environment variable as a boolean.
-func getEnvAsBool(key string, fallback bool) bool {
-	if value, exists := os.LookupEnv(key); exists {
+func (l *loader) getEnvAsBool(key string, fallback bool) bool {
+	if value, exists := l.lookup(key); exists {
 		b, err := strconv.ParseBool(value)
 		if err == nil {
 			return b
 		}
-		appLogger.Warn("Failed to parse env var %s as bool: %v. Using fallback: %t", key, err, fallback)
+		l.invalid("%s %q is not a boolean", key, value)
+	}
+	return fallback
+}
+
+// Helper function to get an environment variable as an int.
+func (l *loader) getEnvAsInt(key string, fallback int) int {
+	if value, exists := l.lookup(key); exists {
+		i, err := strconv.Atoi(value)
+		if err == nil {
+			return i
+		}
+		l.invalid("%s %q is not an integer", key, value)
+	}
+	return fallback
+}
+
+// Helper function to get an environment variable as a float64.
+func (l *loader) getEnvAsFloat(key string, fallback float64) float64 {
+	if value, exists := l.lookup(key); exists {
+		f, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return f
+		}
+		l.invalid("%s %q is not a number", key, value)
+	}
+	return fallback
+}
+
+// Helper function to get an environment variable as a time.Duration (e.g. "5s", "1m").
+func (l *loader) getEnvAsDuration(key string, fallback time.Duration) time.Duration {
+	if value, exists := l.lookup(key); exists {
+		d, err := time.ParseDuration(value)
+		if err == nil {
+			return d
+		}
+		l.invalid("%s %q is not a duration (e.g. 5s, 1m)", key, value)
+	}
+	return fallback
+}
+
+// Helper function to get an environment variable as a comma separated list,
+// empty entries dropped. An explicitly empty value gives an empty list.
+func (l *loader) getEnvAsStringSlice(key string, fallback []string) []string {
+	if value, exists := l.lookup(key); exists {
+		return splitList(value)
 	}
 	return fallback
 }
+
+// Helper function to get an environment variable as a comma separated list of durations.
+func (l *loader) getEnvAsDurationList(key string, fallback []time.Duration) []time.Duration {
+	value, exists := l.lookup(key)
+	if !exists {
+		return fallback
+	}
+	var durations []time.Duration
+	for _, item := range splitList(value) {
+		d, err := time.ParseDuration(item)
+		if err != nil {
+			l.invalid("%s entry %q is not a duration (e.g. 5s, 1m)", key, item)
+			continue
+		}
+		durations = append(durations, d)
+	}
+	return durations
+}
+
+// splitList splits a comma separated value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseKeyValueList parses "a=x,b=y" into a map. envName is only used in warnings.
+func parseKeyValueList(value, envName string) map[string]string {
+	pairs := make(map[string]string)
+	for _, entry := range splitList(value) {
+		key, val, found := strings.Cut(entry, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !found || key == "" || val == "" {
+			appLogger.Warn("Ignoring malformed %s entry %q (expected name=value)", envName, entry)
+			continue
+		}
+		pairs[key] = val
+	}
+	return pairs
+}
+
+// parseRoleRules parses "postgres=db" entries, keeping their order.
+func (l *loader) parseRoleRules(entries []string) []RoleRule {
+	var rules []RoleRule
+	for _, entry := range entries {
+		contains, role, found := strings.Cut(entry, "=")
+		contains, role = strings.TrimSpace(contains), strings.TrimSpace(role)
+		if !found || contains == "" || role == "" {
+			l.invalid("HOST_ROLE_RULES entry %q must be process-name-part=role, e.g. postgres=db", entry)
+			continue
+		}
+		rules = append(rules, RoleRule{Contains: contains, Role: role})
+	}
+	return rules
+}
+
+// parseUserList parses "alice:<bcrypt hash>:admin,bob:<bcrypt hash>" into a map.
+// The role part is optional and defaults to viewer. bcrypt hashes never contain
+// ':' or ',' so no escaping is needed.
+func parseUserList(value string) map[string]UserEntry {
+	users := make(map[string]UserEntry)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			appLogger.Warn("Ignoring malformed AUTH_USERS entry for %q (expected user:bcrypt-hash[:role])", parts[0])
+			continue
+		}
+		user := UserEntry{PasswordHash: parts[1], Role: "viewer"}
+		if len(parts) == 3 {
+			switch parts[2] {
+			case "viewer", "admin":
+				user.Role = parts[2]
+			default:
+				appLogger.Warn("Unknown role %q for AUTH_USERS entry %q, using viewer", parts[2], parts[0])
+			}
+		}
+		users[parts[0]] = user
+	}
+	return users
+}
@@ -0,0 +1,325 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setRequired sets the settings Load can't run without.
+func setRequired(t *testing.T) {
+	t.Helper()
+	t.Setenv("INFLUXDB_TOKEN", "test-token")
+	t.Setenv("INFLUXDB_ORG", "test-org")
+	t.Setenv("INFLUXDB_BUCKET", "test-bucket")
+}
+
+func TestLoadRequiresIssuerAndAudienceWithJWKS(t *testing.T) {
+	setRequired(t)
+	t.Setenv("AUTH_ENABLED", "true")
+	t.Setenv("AUTH_JWKS_URL", "https://idp.example.com/.well-known/jwks.json")
+
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "AUTH_JWKS_ISSUER") {
+		t.Fatalf("Load error = %v, want AUTH_JWKS_ISSUER and AUTH_JWKS_AUDIENCE reported", err)
+	}
+
+	t.Setenv("AUTH_JWKS_ISSUER", "https://idp.example.com/")
+	t.Setenv("AUTH_JWKS_AUDIENCE", "system-stats-dashboard")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Auth.Issuer != "system-stats-monitoring" {
+		t.Errorf("AUTH_ISSUER = %q, want the default for local tokens", cfg.Auth.Issuer)
+	}
+}
+
+// unsetenv removes key from the environment for the test.
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "") // restores the previous value after the test
+	os.Unsetenv(key)
+}
+
+// writeConfigFile writes a YAML config file and returns its path.
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	for _, key := range []string{
+		"SERVER_LISTEN_ADDRESS", "CORS_ALLOW_ORIGINS", "INFLUXDB_TOKEN", "INFLUXDB_TOKEN_FILE", "INFLUXDB_ORG", "INFLUXDB_BUCKET",
+		"INFLUXDB_MAX_CONCURRENT_QUERIES", "INFLUXDB_QUERY_QUEUE_TIMEOUT", "INFLUXDB_WRITE_CONCURRENCY", "INFLUXDB_RECONNECT_COOLDOWN",
+		"STATUS_USAGE_WARNING", "STATUS_USAGE_CRITICAL",
+	} {
+		unsetenv(t, key)
+	}
+	path := writeConfigFile(t, `
+listen_address: ":9090"
+cors:
+  allow_origins: ["https://file.example.com", "https://other.example.com"]
+influxdb:
+  token: file-token
+  org: file-org
+  bucket: file-bucket
+  max_concurrent_queries: 16
+  query_queue_timeout: 2s
+status:
+  usage_warning: 70
+`)
+	t.Setenv("SERVER_LISTEN_ADDRESS", ":7070")
+	t.Setenv("INFLUXDB_ORG", "env-org")
+	t.Setenv("INFLUXDB_MAX_CONCURRENT_QUERIES", "4")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	tests := []struct {
+		setting   string
+		got, want any
+	}{
+		// env over file
+		{"SERVER_LISTEN_ADDRESS", cfg.ListenAddress, ":7070"},
+		{"INFLUXDB_ORG", cfg.InfluxDB.Org, "env-org"},
+		{"INFLUXDB_MAX_CONCURRENT_QUERIES", cfg.InfluxDB.MaxConcurrentQueries, 4},
+		// file over default
+		{"CORS_ALLOW_ORIGINS", strings.Join(cfg.CORSOrigins, " "), "https://file.example.com https://other.example.com"},
+		{"INFLUXDB_TOKEN", cfg.InfluxDB.Token, "file-token"},
+		{"INFLUXDB_BUCKET", cfg.InfluxDB.Bucket, "file-bucket"},
+		{"INFLUXDB_QUERY_QUEUE_TIMEOUT", cfg.InfluxDB.QueryQueueTimeout, 2 * time.Second},
+		{"STATUS_USAGE_WARNING", cfg.InfluxDB.Status.UsageWarning, 70.0},
+		// neither: the default
+		{"STATUS_USAGE_CRITICAL", cfg.InfluxDB.Status.UsageCritical, 95.0},
+		{"INFLUXDB_WRITE_CONCURRENCY", cfg.InfluxDB.WriteConcurrency, 4},
+		{"INFLUXDB_RECONNECT_COOLDOWN", cfg.InfluxDB.ReconnectCooldown, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.setting, tt.got, tt.want)
+		}
+	}
+
+	// an env var set to empty still wins over the file
+	t.Setenv("CORS_ALLOW_ORIGINS", "")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.CORSOrigins) != 0 {
+		t.Errorf("CORS_ALLOW_ORIGINS=\"\" over a file list: %v, want none", cfg.CORSOrigins)
+	}
+
+	// without the file the file's values fall back to the defaults
+	setRequired(t)
+	cfg, err = Load("")
+	if err != nil {
+		t.Fatalf("Load without a file: %v", err)
+	}
+	if cfg.InfluxDB.QueryQueueTimeout != 5*time.Second || cfg.InfluxDB.Status.UsageWarning != 85 {
+		t.Errorf("no file: queue timeout %v, usage warning %v, want the defaults 5s and 85",
+			cfg.InfluxDB.QueryQueueTimeout, cfg.InfluxDB.Status.UsageWarning)
+	}
+}
+
+func TestLoadRejectsUnknownFileSettings(t *testing.T) {
+	setRequired(t)
+	path := writeConfigFile(t, "influxdb:\n  max_concurent_queries: 4\n")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "influxdb.max_concurent_queries") {
+		t.Errorf("Load error = %v, want the misspelt setting reported", err)
+	}
+}
+
+func TestExampleYAMLLoadsWithDefaults(t *testing.T) {
+	setRequired(t) // the example's connection values are placeholders
+	path := writeConfigFile(t, ExampleYAML())
+	fromFile, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load(example): %v", err)
+	}
+	defaults, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if fromFile.String() != defaults.String() {
+		t.Errorf("example file settings differ from the defaults:\nfile:     %s\ndefaults: %s", fromFile.String(), defaults.String())
+	}
+}
+
+func TestEnvHelpersRejectMalformedValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		get     func(l *loader, key string) any
+		want    any // the fallback for malformed values
+		problem string
+	}{
+		{"int", "ten", func(l *loader, k string) any { return l.getEnvAsInt(k, 8) }, 8, "is not an integer"},
+		{"int with unit", "8s", func(l *loader, k string) any { return l.getEnvAsInt(k, 8) }, 8, "is not an integer"},
+		{"float", "85%", func(l *loader, k string) any { return l.getEnvAsFloat(k, 85) }, 85.0, "is not a number"},
+		{"bool", "yes please", func(l *loader, k string) any { return l.getEnvAsBool(k, true) }, true, "is not a boolean"},
+		{"duration without unit", "30", func(l *loader, k string) any { return l.getEnvAsDuration(k, 5*time.Second) }, 5 * time.Second, "is not a duration"},
+		{"duration", "soon", func(l *loader, k string) any { return l.getEnvAsDuration(k, 5*time.Second) }, 5 * time.Second, "is not a duration"},
+		{
+			"duration list keeps the valid entries", "1m, 5x, 15m",
+			func(l *loader, k string) any { return fmt.Sprint(l.getEnvAsDurationList(k, nil)) }, "[1m0s 15m0s]", `entry "5x" is not a duration`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &loader{}
+			t.Setenv("TEST_SETTING", tt.value)
+			if got := tt.get(l, "TEST_SETTING"); got != tt.want {
+				t.Errorf("%q = %v, want %v", tt.value, got, tt.want)
+			}
+			if len(l.problems) != 1 || !strings.Contains(l.problems[0].Error(), "TEST_SETTING") || !strings.Contains(l.problems[0].Error(), tt.problem) {
+				t.Errorf("problems = %v, want one naming TEST_SETTING with %q", l.problems, tt.problem)
+			}
+		})
+	}
+
+	t.Run("well-formed values", func(t *testing.T) {
+		l := &loader{}
+		for key, value := range map[string]string{"TEST_INT": "12", "TEST_DURATION": "1m30s", "TEST_BOOL": "false", "TEST_LIST": " a, ,b,"} {
+			t.Setenv(key, value)
+		}
+		if got := l.getEnvAsInt("TEST_INT", 8); got != 12 {
+			t.Errorf("int = %d, want 12", got)
+		}
+		if got := l.getEnvAsDuration("TEST_DURATION", time.Second); got != 90*time.Second {
+			t.Errorf("duration = %v, want 1m30s", got)
+		}
+		if got := l.getEnvAsBool("TEST_BOOL", true); got {
+			t.Error("bool = true, want false")
+		}
+		if got := l.getEnvAsStringSlice("TEST_LIST", nil); fmt.Sprint(got) != "[a b]" {
+			t.Errorf("list = %q, want [a b] without empty entries", got)
+		}
+		if got := l.getEnvAsInt("TEST_UNSET", 8); got != 8 {
+			t.Errorf("unset int = %d, want the fallback 8", got)
+		}
+		if len(l.problems) != 0 {
+			t.Errorf("problems = %v, want none", l.problems)
+		}
+	})
+
+	// Load reports every malformed setting at once, not just the first
+	setRequired(t)
+	t.Setenv("INFLUXDB_MAX_CONCURRENT_QUERIES", "many")
+	t.Setenv("INFLUXDB_RECONNECT_COOLDOWN", "30")
+	t.Setenv("STATUS_USAGE_WARNING", "high")
+	cfg, err := Load("")
+	if err == nil {
+		t.Fatal("Load accepted malformed settings")
+	}
+	for _, key := range []string{"INFLUXDB_MAX_CONCURRENT_QUERIES", "INFLUXDB_RECONNECT_COOLDOWN", "STATUS_USAGE_WARNING"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Load error lacks %s:\n%v", key, err)
+		}
+	}
+	// the config returned with the error (--allow-incomplete-config) has the defaults
+	if cfg.InfluxDB.MaxConcurrentQueries != 8 || cfg.InfluxDB.ReconnectCooldown != 30*time.Second {
+		t.Errorf("max concurrent queries %d, reconnect cooldown %v, want the defaults 8 and 30s",
+			cfg.InfluxDB.MaxConcurrentQueries, cfg.InfluxDB.ReconnectCooldown)
+	}
+}
+
+func TestLoadDisplayTimeZone(t *testing.T) {
+	setRequired(t)
+	t.Setenv("SERVER_DISPLAY_TZ", "Asia/Kathmandu")
+	cfg, err := Load("")
+	if err != nil {
+		if strings.Contains(err.Error(), "SERVER_DISPLAY_TZ") {
+			t.Skipf("no time zone database: %v", err)
+		}
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.InfluxDB.DisplayLocation == nil || cfg.InfluxDB.DisplayLocation.String() != "Asia/Kathmandu" {
+		t.Errorf("display location = %v, want Asia/Kathmandu", cfg.InfluxDB.DisplayLocation)
+	}
+
+	t.Setenv("SERVER_DISPLAY_TZ", "Mars/Olympus_Mons")
+	cfg, err = Load("")
+	if err == nil || !strings.Contains(err.Error(), `SERVER_DISPLAY_TZ "Mars/Olympus_Mons" is not a known time zone`) {
+		t.Errorf("Load with an unknown zone: %v, want an error naming it", err)
+	}
+	if cfg.InfluxDB.DisplayLocation != time.Local {
+		t.Errorf("display location with an unknown zone = %v, want Local", cfg.InfluxDB.DisplayLocation)
+	}
+}
+
+func TestOutOfRangeDurationsResetToDefaults(t *testing.T) {
+	setRequired(t)
+	t.Setenv("HOST_OFFLINE_AFTER", "0s")
+	t.Setenv("SERVER_SHUTDOWN_TIMEOUT", "-5s")
+	t.Setenv("API_TIMEOUT_HISTORY", "-1s")
+	t.Setenv("API_TIMEOUT_DEFAULT", "0s") // 0 disables the deadline, that's allowed
+	t.Setenv("OVERVIEW_AVERAGE_WINDOWS", "5m,-1m,1h")
+
+	cfg, err := Load("")
+	if err == nil {
+		t.Fatal("Load accepted non-positive durations")
+	}
+	for _, key := range []string{"HOST_OFFLINE_AFTER", "SERVER_SHUTDOWN_TIMEOUT", "API_TIMEOUT_HISTORY", "OVERVIEW_AVERAGE_WINDOWS"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Load error lacks %s:\n%v", key, err)
+		}
+	}
+	if strings.Contains(err.Error(), "API_TIMEOUT_DEFAULT") {
+		t.Errorf("Load rejected API_TIMEOUT_DEFAULT=0:\n%v", err)
+	}
+	// the config returned for --allow-incomplete-config has the defaults instead
+	if cfg.HostOfflineAfter != 35*time.Second || cfg.InfluxDB.OnlineWithin != 35*time.Second {
+		t.Errorf("host offline after %v, online within %v, want the default 35s", cfg.HostOfflineAfter, cfg.InfluxDB.OnlineWithin)
+	}
+	if cfg.HTTP.Shutdown != 5*time.Second || cfg.Timeouts.History != 8*time.Second || cfg.Timeouts.Default != 0 {
+		t.Errorf("shutdown %v, history timeout %v, default timeout %v; want 5s, 8s and 0", cfg.HTTP.Shutdown, cfg.Timeouts.History, cfg.Timeouts.Default)
+	}
+	if got := fmt.Sprint(cfg.InfluxDB.OverviewAverageWindows); got != "[5m0s 1h0m0s]" {
+		t.Errorf("average windows = %s, want the negative one dropped", got)
+	}
+}
+
+func TestConcurrentLoadsKeepTheirOwnState(t *testing.T) {
+	setRequired(t)
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	broken := filepath.Join(dir, "broken.yaml")
+	if err := os.WriteFile(valid, []byte("listen_address: \":9000\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(broken, []byte("listen_address: \":9001\"\nhost_offline_after: 0s\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// a reload and anything else calling Load must not see each other's file or problems
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cfg, err := Load(valid)
+			if err != nil || cfg.ListenAddress != ":9000" {
+				t.Errorf("valid file: listen %q, error %v; want :9000 without an error", cfg.ListenAddress, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			cfg, err := Load(broken)
+			if err == nil || strings.Count(err.Error(), "\n") != 0 || cfg.ListenAddress != ":9001" {
+				t.Errorf("broken file: listen %q, error %v; want :9001 with only the HOST_OFFLINE_AFTER problem", cfg.ListenAddress, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A setting maps a key of the YAML config file to the environment variable
+// that overrides it. The file is flattened into env-style values, so every
+// setting is parsed by the same getEnv* helper either way.
+type setting struct {
+	Key      string // dotted YAML path, e.g. "influxdb.url"
+	Env      string
+	Example  string // YAML value written by ExampleYAML
+	Help     string
+	KeyValue bool // a name -> value mapping (stored as "a=x,b=y")
+}
+
+// settings lists every file setting, in the order ExampleYAML writes them.
+// Keys sharing a section must be next to each other.
+var settings = []setting{
+	{Key: "listen_address", Env: "SERVER_LISTEN_ADDRESS", Example: `":8080"`, Help: "address the HTTP server listens on"},
+	{Key: "metadata_db_path", Env: "METADATA_DB_PATH", Example: "metadata.db", Help: "bbolt file for groups, alert rules and other mutable metadata"},
+	{Key: "debug_log", Env: "SERVER_ENABLE_DEBUG_LOG", Example: "false", Help: "debug logging and gin debug mode"},
+	{Key: "display_timezone", Env: "SERVER_DISPLAY_TZ", Example: "Local", Help: `IANA zone for the "HH:MM" history labels, e.g. UTC or Europe/Berlin; Local is the server's`},
+	{Key: "log_level", Env: "LOG_LEVEL", Example: "info", Help: "debug, info, warn or error; debug_log forces debug"},
+	{Key: "log_format", Env: "LOG_FORMAT", Example: "text", Help: "text, or json for one JSON object per line (level, timestamp, caller, message, fields)"},
+	{Key: "host_offline_after", Env: "HOST_OFFLINE_AFTER", Example: "35s", Help: "a host that hasn't posted for this long is annotated as offline"},
+	{Key: "clock_drift_threshold", Env: "SERVER_CLOCK_DRIFT_THRESHOLD", Example: "10s", Help: "agent clock drift above this is logged and reported back to the agent"},
+	{Key: "host_id_include_hostname", Env: "HOST_ID_INCLUDE_HOSTNAME", Example: "false", Help: `store hosts as "<host_id>@<hostname>" so machines sharing a host_id stay apart`},
+	{Key: "host_role_rules", Env: "HOST_ROLE_RULES", Example: "[]", Help: `infer host roles from process names, first match wins, e.g. ["postgres=db", "nginx=web", "redis=cache"]`},
+	{Key: "host_tracker_interval", Env: "HOST_TRACKER_INTERVAL", Example: "10s", Help: "how often hosts are checked for going offline"},
+	{Key: "readiness_retry", Env: "SERVER_READINESS_RETRY", Example: "5s", Help: "delay between readiness test writes until InfluxDB accepts one"},
+	{Key: "prometheus_enabled", Env: "PROMETHEUS_ENABLED", Example: "false", Help: "serve the latest host values for Prometheus at GET /api/prometheus (dashboard auth applies)"},
+
+	{Key: "http.read_timeout", Env: "SERVER_READ_TIMEOUT", Example: "5s", Help: "HTTP server connection timeouts, 0 disables"},
+	{Key: "http.write_timeout", Env: "SERVER_WRITE_TIMEOUT", Example: "10s"},
+	{Key: "http.idle_timeout", Env: "SERVER_IDLE_TIMEOUT", Example: "120s"},
+	{Key: "http.shutdown_timeout", Env: "SERVER_SHUTDOWN_TIMEOUT", Example: "5s", Help: "grace period for in-flight requests on shutdown"},
+
+	{Key: "cors.allow_origins", Env: "CORS_ALLOW_ORIGINS", Example: `["http://localhost:5173"]`, Help: "frontend origins allowed to call the API"},
+
+	{Key: "trusted_proxies", Env: "SERVER_TRUSTED_PROXIES", Example: "[]", Help: `load balancer IPs/CIDRs whose X-Forwarded-For is trusted; empty trusts all, ["none"] none`},
+
+	{Key: "tls.cert_file", Env: "SERVER_TLS_CERT_FILE", Example: `""`, Help: "serve HTTPS when both cert_file and key_file are set"},
+	{Key: "tls.key_file", Env: "SERVER_TLS_KEY_FILE", Example: `""`},
+
+	{Key: "influxdb.url", Env: "INFLUXDB_URL", Example: "http://localhost:8086", Help: `a list (["http://influx-a:8086", "http://influx-b:8086"]) fails over in order`},
+	{Key: "influxdb.token", Env: "INFLUXDB_TOKEN", Example: "YOUR_INFLUXDB_API_TOKEN", Help: "better kept in the INFLUXDB_TOKEN environment variable or token_file"},
+	{Key: "influxdb.token_file", Env: "INFLUXDB_TOKEN_FILE", Example: `""`, Help: "file holding the token, takes precedence over token"},
+	{Key: "influxdb.org", Env: "INFLUXDB_ORG", Example: "my-org"},
+	{Key: "influxdb.bucket", Env: "INFLUXDB_BUCKET", Example: "system_stats"},
+	{Key: "influxdb.cold_bucket", Env: "INFLUXDB_COLD_BUCKET", Example: `""`, Help: "downsampled bucket for long metric history ranges, empty = only use bucket"},
+	{Key: "influxdb.cold_bucket_after", Env: "INFLUXDB_COLD_BUCKET_AFTER", Example: "24h", Help: "history windows longer than this read the cold bucket"},
+	{Key: "influxdb.cold_bucket_resolution", Env: "INFLUXDB_COLD_BUCKET_RESOLUTION", Example: "5m", Help: "point interval of the cold bucket"},
+	{Key: "influxdb.max_concurrent_queries", Env: "INFLUXDB_MAX_CONCURRENT_QUERIES", Example: "8", Help: "dashboard reads running against InfluxDB at once"},
+	{Key: "influxdb.query_queue_timeout", Env: "INFLUXDB_QUERY_QUEUE_TIMEOUT", Example: "5s", Help: "how long a read waits for a free slot"},
+	{Key: "influxdb.write_concurrency", Env: "INFLUXDB_WRITE_CONCURRENCY", Example: "4", Help: "disk and process points of one payload written at once, 1 = sequential"},
+	{Key: "influxdb.query_point_budget", Env: "INFLUXDB_QUERY_POINT_BUDGET", Example: "1000000", Help: "estimated raw points a range query may read, 0 disables the check"},
+	{Key: "influxdb.reject_over_budget", Env: "INFLUXDB_QUERY_REJECT_OVER_BUDGET", Example: "true", Help: "reject over-budget queries instead of only logging them"},
+	{Key: "influxdb.history_max_fields", Env: "INFLUXDB_HISTORY_MAX_FIELDS", Example: "6", Help: "distinct fields one multi-field history request may ask for"},
+	{Key: "influxdb.reconnect_after_errors", Env: "INFLUXDB_RECONNECT_AFTER_ERRORS", Example: "3"},
+	{Key: "influxdb.reconnect_cooldown", Env: "INFLUXDB_RECONNECT_COOLDOWN", Example: "30s"},
+	{Key: "influxdb.failback_probe_interval", Env: "INFLUXDB_FAILBACK_PROBE_INTERVAL", Example: "30s", Help: "how often preferred endpoints are re-checked after a failover"},
+	{Key: "influxdb.overview_max_offline_age", Env: "OVERVIEW_MAX_OFFLINE_AGE", Example: "24h", Help: "offline hosts stay in the overview (with their last-known values) for this long"},
+	{Key: "influxdb.recent_reboot_threshold", Env: "RECENT_REBOOT_THRESHOLD", Example: "12h", Help: "online hosts up for less than this are flagged as recently rebooted (warning), 0 disables"},
+	{Key: "influxdb.process_sample_every", Env: "PROCESS_SAMPLE_EVERY", Example: "1", Help: "match the agents' MONITOR_PROCESS_EVERY"},
+	{Key: "influxdb.process_series", Env: "PROCESS_SERIES", Example: "aggregated", Help: "aggregated: one series per process name; instance: per-process detail, PID as a field"},
+	{Key: "influxdb.overview_average_windows", Env: "OVERVIEW_AVERAGE_WINDOWS", Example: "[]", Help: `rolling CPU/RAM averages in the overview, e.g. ["1m", "5m", "15m"]`},
+	{Key: "influxdb.overview_average_refresh", Env: "OVERVIEW_AVERAGE_REFRESH", Example: "30s", Help: "how often the rolling averages are recomputed"},
+	{Key: "influxdb.overview_cache_max_age", Env: "OVERVIEW_CACHE_MAX_AGE", Example: "5m", Help: "the overview is served from ingest and re-seeded by a query this often, 0 always queries"},
+	{Key: "influxdb.health_check_timeout", Env: "INFLUXDB_HEALTH_CHECK_TIMEOUT", Example: "5s", Help: "startup health check of the InfluxDB connection"},
+	{Key: "influxdb.details_lookback", Env: "INFLUXDB_DETAILS_LOOKBACK", Example: "15s", Help: "host details show the latest point within this window"},
+
+	{Key: "status.usage_warning", Env: "STATUS_USAGE_WARNING", Example: "85", Help: "CPU or RAM percent above which an online host is warning / critical"},
+	{Key: "status.usage_critical", Env: "STATUS_USAGE_CRITICAL", Example: "95"},
+	{Key: "status.disk_warning", Env: "STATUS_DISK_WARNING", Example: "90", Help: "the same for root disk space or inodes"},
+	{Key: "status.disk_critical", Env: "STATUS_DISK_CRITICAL", Example: "95"},
+
+	{Key: "batch.max_items", Env: "INGEST_BATCH_MAX_ITEMS", Example: "1000", Help: "payloads per POST /api/stats/batch request"},
+	{Key: "batch.max_bytes", Env: "INGEST_BATCH_MAX_BYTES", Example: "67108864", Help: "body size of a batch request, larger ones are cut off with 413"},
+
+	{Key: "timeouts.overview", Env: "API_TIMEOUT_OVERVIEW", Example: "4s", Help: "per-route query deadlines, 0 disables"},
+	{Key: "timeouts.details", Env: "API_TIMEOUT_DETAILS", Example: "5s"},
+	{Key: "timeouts.history", Env: "API_TIMEOUT_HISTORY", Example: "8s"},
+	{Key: "timeouts.default", Env: "API_TIMEOUT_DEFAULT", Example: "5s"},
+
+	{Key: "auth.enabled", Env: "AUTH_ENABLED", Example: "false"},
+	{Key: "auth.jwt_secret", Env: "AUTH_JWT_SECRET", Example: `""`, Help: "HS256 secret, better kept in the AUTH_JWT_SECRET environment variable or jwt_secret_file"},
+	{Key: "auth.jwt_secret_file", Env: "AUTH_JWT_SECRET_FILE", Example: `""`},
+	{Key: "auth.issuer", Env: "AUTH_ISSUER", Example: "system-stats-monitoring", Help: "iss of the HS256 tokens the server issues"},
+	{Key: "auth.jwks_url", Env: "AUTH_JWKS_URL", Example: `""`, Help: "verify RS256 tokens of an external identity provider"},
+	{Key: "auth.jwks_issuer", Env: "AUTH_JWKS_ISSUER", Example: `""`, Help: "iss the identity provider's tokens must carry, required with jwks_url"},
+	{Key: "auth.jwks_audience", Env: "AUTH_JWKS_AUDIENCE", Example: `""`, Help: "aud the identity provider's tokens must include, required with jwks_url"},
+	{Key: "auth.token_ttl", Env: "AUTH_TOKEN_TTL", Example: "12h"},
+	{Key: "auth.users", Env: "AUTH_USERS", Example: "[]", Help: `static users as "name:bcrypt-hash[:role]"`},
+	{Key: "auth.jwks_refresh", Env: "AUTH_JWKS_REFRESH", Example: "1h", Help: "JWKS keys are re-fetched after this, and on unknown key ids"},
+
+	{Key: "alerting.evaluation_interval", Env: "ALERT_EVAL_INTERVAL", Example: "15s"},
+	{Key: "alerting.notify_timeout", Env: "ALERT_NOTIFY_TIMEOUT", Example: "2m", Help: "limit for one round of notifications, retries included"},
+	{Key: "alerting.forecast_refresh", Env: "ALERT_FORECAST_REFRESH", Example: "5m", Help: "how often disk_full_days rules re-query their disk series"},
+	{Key: "alerting.notifiers", Env: "ALERT_NOTIFIERS", Example: "[]", Help: "any of webhook, slack, email; empty only logs alerts"},
+	{Key: "alerting.webhook_urls", Env: "ALERT_WEBHOOK_URLS", Example: "[]"},
+	{Key: "alerting.webhook_secret", Env: "ALERT_WEBHOOK_SECRET", Example: `""`},
+	{Key: "alerting.webhook_secret_file", Env: "ALERT_WEBHOOK_SECRET_FILE", Example: `""`},
+	{Key: "alerting.webhook_max_retries", Env: "ALERT_WEBHOOK_MAX_RETRIES", Example: "3"},
+	{Key: "alerting.slack_webhook_url", Env: "ALERT_SLACK_WEBHOOK_URL", Example: `""`},
+	{Key: "alerting.slack_channels", Env: "ALERT_SLACK_CHANNELS", Example: "{}", Help: "channel name -> incoming webhook URL", KeyValue: true},
+	{Key: "alerting.dashboard_url", Env: "ALERT_DASHBOARD_URL", Example: `""`, Help: "frontend base URL for links in notifications"},
+	{Key: "alerting.host_down.enabled", Env: "ALERT_HOST_DOWN_ENABLED", Example: "true"},
+	{Key: "alerting.host_down.interval_multiplier", Env: "ALERT_HOST_DOWN_INTERVAL_MULTIPLIER", Example: "6"},
+	{Key: "alerting.host_down.default_interval", Env: "ALERT_HOST_DOWN_DEFAULT_INTERVAL", Example: "5s"},
+	{Key: "alerting.host_down.min_firing", Env: "ALERT_HOST_DOWN_MIN_FIRING", Example: "1m"},
+	{Key: "alerting.smtp.host", Env: "ALERT_SMTP_HOST", Example: `""`},
+	{Key: "alerting.smtp.port", Env: "ALERT_SMTP_PORT", Example: "587"},
+	{Key: "alerting.smtp.tls", Env: "ALERT_SMTP_TLS", Example: "starttls", Help: "starttls, ssl or none"},
+	{Key: "alerting.smtp.username", Env: "ALERT_SMTP_USERNAME", Example: `""`},
+	{Key: "alerting.smtp.password", Env: "ALERT_SMTP_PASSWORD", Example: `""`},
+	{Key: "alerting.smtp.password_file", Env: "ALERT_SMTP_PASSWORD_FILE", Example: `""`},
+	{Key: "alerting.smtp.from", Env: "ALERT_SMTP_FROM", Example: `""`},
+	{Key: "alerting.smtp.to", Env: "ALERT_SMTP_TO", Example: "[]"},
+	{Key: "alerting.smtp.max_retries", Env: "ALERT_SMTP_MAX_RETRIES", Example: "3"},
+}
+
+// lookup returns the environment variable, or else the config file value, for key.
+func (l *loader) lookup(key string) (string, bool) {
+	if value, exists := os.LookupEnv(key); exists {
+		return value, true
+	}
+	value, exists := l.fileValues[key]
+	return value, exists
+}
+
+// readConfigFile parses the YAML file at path into env-style values.
+// Unknown keys are an error so typos don't go unnoticed.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.Key] = s
+	}
+	values := make(map[string]string)
+	if err := flattenSection(doc, "", byKey, values); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flattenSection(section map[string]interface{}, prefix string, byKey map[string]setting, values map[string]string) error {
+	for name, raw := range section {
+		key := prefix + name
+		s, known := byKey[key]
+		if !known {
+			if sub, ok := raw.(map[string]interface{}); ok {
+				if err := flattenSection(sub, key+".", byKey, values); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("unknown setting %q", key)
+		}
+		if raw == nil {
+			continue // "key:" without a value leaves the default
+		}
+		value, err := settingValue(s, raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		values[s.Env] = value
+	}
+	return nil
+}
+
+// settingValue converts a YAML value into the string format of the setting's env var.
+func settingValue(s setting, raw interface{}) (string, error) {
+	switch v := raw.(type) {
+	case []interface{}:
+		if s.KeyValue {
+			return "", fmt.Errorf("expected a mapping of name: value")
+		}
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			str, err := scalarValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		if !s.KeyValue {
+			return "", fmt.Errorf("expected a value, got a mapping")
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		pairs := make([]string, 0, len(v))
+		for _, name := range names {
+			str, err := scalarValue(v[name])
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, name+"="+str)
+		}
+		return strings.Join(pairs, ","), nil
+	default:
+		return scalarValue(v)
+	}
+}
+
+func scalarValue(raw interface{}) (string, error) {
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// ExampleYAML returns a documented config file with every setting at its
+// default (placeholders for the InfluxDB connection).
+func ExampleYAML() string {
+	var b strings.Builder
+	b.WriteString("# system-stats-monitoring server configuration.\n")
+	b.WriteString("# Start the server with --config <file>. Every setting can be overridden by the\n")
+	b.WriteString("# environment variable named next to it, which takes precedence over this file.\n\n")
+
+	var open []string // currently open sections
+	for _, s := range settings {
+		path := strings.Split(s.Key, ".")
+		sections, name := path[:len(path)-1], path[len(path)-1]
+
+		common := 0
+		for common < len(open) && common < len(sections) && open[common] == sections[common] {
+			common++
+		}
+		if common == 0 && (len(sections) > 0 || len(open) > 0) {
+			b.WriteString("\n")
+		}
+		for depth := common; depth < len(sections); depth++ {
+			fmt.Fprintf(&b, "%s%s:\n", strings.Repeat("  ", depth), sections[depth])
+		}
+		open = sections
+
+		indent := strings.Repeat("  ", len(sections))
+		if s.Help != "" {
+			fmt.Fprintf(&b, "%s# %s\n", indent, s.Help)
+		}
+		fmt.Fprintf(&b, "%s%s: %s # %s\n", indent, name, s.Example, s.Env)
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+	"unicode"
+)
+
+const redacted = "[redacted]"
+
+// String prints the config like %+v with secrets (tokens, passwords, password
+// hashes, webhook URLs carrying tokens) replaced, so it is safe to log.
+func (c *ServerConfig) String() string {
+	type plain ServerConfig // no String method, avoids recursion
+	return fmt.Sprintf("%+v", plain(c.redacted()))
+}
+
+// MarshalJSON encodes the config redacted like String, with camelCase keys
+// and durations as strings ("15s"), for GET /api/config.
+func (c *ServerConfig) MarshalJSON() ([]byte, error) {
+	safe := c.redacted()
+	return json.Marshal(jsonValue(reflect.ValueOf(safe)))
+}
+
+// redacted returns a copy of the config with every secret replaced.
+func (c *ServerConfig) redacted() ServerConfig {
+	safe := *c
+	safe.InfluxDB.Token = redact(c.InfluxDB.Token)
+	safe.Auth.JWTSecret = redact(c.Auth.JWTSecret)
+	safe.Alerting.WebhookSecret = redact(c.Alerting.WebhookSecret)
+	safe.Alerting.SlackWebhookURL = redact(c.Alerting.SlackWebhookURL)
+	safe.Alerting.SMTP.Password = redact(c.Alerting.SMTP.Password)
+
+	safe.Auth.Users = make(map[string]UserEntry, len(c.Auth.Users))
+	for name, user := range c.Auth.Users {
+		user.PasswordHash = redact(user.PasswordHash)
+		safe.Auth.Users[name] = user
+	}
+	safe.Alerting.SlackChannels = make(map[string]string, len(c.Alerting.SlackChannels))
+	for name, url := range c.Alerting.SlackChannels {
+		safe.Alerting.SlackChannels[name] = redact(url)
+	}
+	return safe
+}
+
+// redact hides a set secret but keeps "not set" visible.
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	locationType = reflect.TypeOf(&time.Location{})
+)
+
+// jsonValue converts config structs into maps keyed by camelCase field names,
+// so the config types don't need json tags.
+func jsonValue(v reflect.Value) interface{} {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Type() == locationType:
+		if v.IsNil() {
+			return nil
+		}
+		return v.Interface().(*time.Location).String()
+	case v.Kind() == reflect.Struct:
+		fields := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.IsExported() {
+				fields[camelCase(field.Name)] = jsonValue(v.Field(i))
+			}
+		}
+		return fields
+	case v.Kind() == reflect.Slice && v.Type().Elem() == durationType:
+		durations := make([]string, v.Len())
+		for i := range durations {
+			durations[i] = time.Duration(v.Index(i).Int()).String()
+		}
+		return durations
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.Struct:
+		entries := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries[iter.Key().String()] = jsonValue(iter.Value())
+		}
+		return entries
+	default:
+		return v.Interface()
+	}
+}
+
+// camelCase lowercases a field name's leading word or acronym:
+// ListenAddress -> listenAddress, TLS -> tls, CORSOrigins -> corsOrigins, URLs -> urls.
+func camelCase(name string) string {
+	runes := []rune(name)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) && unicode.IsLower(runes[n]) {
+		plural := runes[n] == 's' && (n+1 == len(runes) || unicode.IsUpper(runes[n+1]))
+		if !plural {
+			n-- // keep the first letter of the next word: CORSOrigins
+		}
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
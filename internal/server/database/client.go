@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// Client is the InfluxDB connection shared by InfluxDBWriter and
+// InfluxDBReader: one influxdb2.Client (recreated after connection errors,
+// see reconnectingClient), one startup health check and one Close. Writes and
+// queries go through it so retries are handled in one place.
+type Client struct {
+	conn *reconnectingClient
+	org  string
+}
+
+// NewClient connects to InfluxDB and runs the health check; with several
+// endpoints one healthy is enough. With cfg.AllowUnavailable
+// (--allow-incomplete-config) a failed check is only logged.
+func NewClient(cfg config.InfluxDBConfig) (*Client, error) {
+	conn := newReconnectingClient("client", cfg)
+	url, err := conn.connect()
+	switch {
+	case err != nil && !cfg.AllowUnavailable:
+		conn.close()
+		return nil, err
+	case err != nil:
+		appLogger.Warn("Starting without a working InfluxDB connection (--allow-incomplete-config): %v", err)
+	default:
+		appLogger.Info("Successfully connected to InfluxDB at %s", url)
+	}
+	return &Client{conn: conn, org: cfg.Org}, nil
+}
+
+// WritePoint writes one point to bucket, retrying once if the write triggered
+// a client reconnect.
+func (c *Client) WritePoint(ctx context.Context, bucket string, p *write.Point) error {
+	err := c.conn.get().WriteAPIBlocking(c.org, bucket).WritePoint(ctx, p)
+	if c.conn.observe(err) {
+		err = c.conn.get().WriteAPIBlocking(c.org, bucket).WritePoint(ctx, p)
+		c.conn.observe(err)
+	}
+	return err
+}
+
+// Query runs a Flux query, retrying once if it triggered a client reconnect.
+func (c *Client) Query(ctx context.Context, flux string) (*api.QueryTableResult, error) {
+	result, err := c.conn.get().QueryAPI(c.org).Query(ctx, flux)
+	if ctx.Err() != nil {
+		return result, err // caller's deadline or cancellation, not a connection problem
+	}
+	if c.conn.observe(err) {
+		result, err = c.conn.get().QueryAPI(c.org).Query(ctx, flux)
+		c.conn.observe(err)
+	}
+	return result, err
+}
+
+// Reconnects is how many times the client was recreated after connection errors.
+func (c *Client) Reconnects() int64 {
+	return c.conn.Reconnects()
+}
+
+// Close closes the client. The writer and reader must not be used afterwards.
+func (c *Client) Close() {
+	c.conn.close()
+	appLogger.Info("InfluxDB client closed.")
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// DiskHistoryFields are the disk_metrics fields GetDiskMetricHistory accepts.
+var DiskHistoryFields = map[string]bool{
+	"usage_percent": true,
+	"used_gb":       true,
+	"free_gb":       true,
+}
+
+// Mountpoints as agents report them: absolute Unix paths or Windows drives
+// ("C:", "C:\"). Quotes, backslashes elsewhere, "$" (Flux interpolation) and
+// control characters are rejected since the path ends up in a Flux string
+// literal (the drive's trailing backslash is escaped there).
+var diskPathPattern = regexp.MustCompile(`^(/[^"\\$\x00-\x1f]*|[A-Za-z]:\\?)$`)
+
+// ValidDiskPath reports whether path is a mountpoint that is safe to query.
+func ValidDiskPath(path string) bool {
+	return len(path) <= 256 && diskPathPattern.MatchString(path)
+}
+
+// GetDiskMetricHistory fetches the history of one disk_metrics field for one
+// mountpoint of a host, aggregated like GetHostMetricHistory.
+func (r *InfluxDBReader) GetDiskMetricHistory(ctx context.Context, hostID, path, field string, start, end time.Time, aggregateInterval time.Duration) ([]models.MetricPoint, error) {
+	if !DiskHistoryFields[field] {
+		return nil, fmt.Errorf("invalid disk metric field for history: %s", field)
+	}
+	if !ValidDiskPath(path) {
+		return nil, fmt.Errorf("invalid disk path: %q", path)
+	}
+	if err := r.checkQueryCost("GetDiskMetricHistory", queryEstimate{Hosts: 1, Fields: 1, Span: end.Sub(start)}); err != nil {
+		return nil, err
+	}
+
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => r._measurement == "disk_metrics" and r.host_id == %s and r.path == %s and r._field == "%s")
+			|> aggregateWindow(every: %s, fn: mean, createEmpty: false)
+	`, r.bucket, fluxTime(start), fluxTime(end), fluxString(hostID), fluxString(path), field, aggregateInterval.String())
+
+	appLogger.FromContext(ctx).Debug("GetDiskMetricHistory Query for host %s, path %s, field %s:\n%s", hostID, path, field, query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetDiskMetricHistory (host %s, path %s, field %s): %v", hostID, path, field, err)
+		return nil, fmt.Errorf("query influxdb for disk metric history: %w", err)
+	}
+	defer results.Close()
+
+	points := []models.MetricPoint{}
+	for results.Next() {
+		if point, ok := r.metricPoint(results.Record()); ok {
+			points = append(points, point)
+		}
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetDiskMetricHistory (host %s, path %s, field %s): %v", hostID, path, field, results.Err())
+		return nil, fmt.Errorf("process query results for disk metric history: %w", results.Err())
+	}
+	return points, nil
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+func TestDiskMetricHistoryPaths(t *testing.T) {
+	end := time.Now()
+	start := end.Add(-time.Hour)
+
+	for path, filter := range map[string]string{
+		"/":             `r.path == "/"`,
+		"/var/lib":      `r.path == "/var/lib"`,
+		"/mnt/usb disk": `r.path == "/mnt/usb disk"`,
+		"C:":            `r.path == "C:"`,
+		`C:\`:           `r.path == "C:\\"`,
+	} {
+		t.Run(path, func(t *testing.T) {
+			reader, server := newTestReader(t, nil)
+			server.Respond(`"disk_metrics"`, influxtest.NewTable("_time:time", "_value:double").
+				Row(start.Add(time.Minute), 41.5).
+				Row(start.Add(2*time.Minute), 42.0))
+
+			points, err := reader.GetDiskMetricHistory(context.Background(), "host-1", path, "usage_percent", start, end, 30*time.Second)
+			if err != nil {
+				t.Fatalf("GetDiskMetricHistory(%q): %v", path, err)
+			}
+			if len(points) != 2 || points[1].Value != 42 {
+				t.Errorf("points = %+v, want 41.5 and 42", points)
+			}
+			queries := server.Queries()
+			if len(queries) != 1 || !strings.Contains(queries[0], filter) {
+				t.Errorf("query doesn't filter on %s:\n%s", filter, queries)
+			}
+		})
+	}
+
+	for _, path := range []string{
+		`/x" or r.path != "`, // closes the string literal
+		"var/lib",            // relative
+		"/home/${user}",      // Flux interpolation
+		"/a\nb",
+		`/a\b`,
+		"CD:",
+		"",
+		"/" + strings.Repeat("a", 256),
+	} {
+		reader, server := newTestReader(t, nil)
+		if _, err := reader.GetDiskMetricHistory(context.Background(), "host-1", path, "usage_percent", start, end, 30*time.Second); err == nil {
+			t.Errorf("GetDiskMetricHistory(%q) succeeded, want the path rejected", path)
+		}
+		if queries := server.Queries(); len(queries) != 0 {
+			t.Errorf("path %q reached InfluxDB: %s", path, queries)
+		}
+	}
+
+	reader, _ := newTestReader(t, nil)
+	if _, err := reader.GetDiskMetricHistory(context.Background(), "host-1", "/", "inodes_used", start, end, 30*time.Second); err == nil {
+		t.Error("GetDiskMetricHistory accepted a field outside DiskHistoryFields")
+	}
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+const (
+	// flapGapFactor: a time between two samples this many times the host's
+	// usual interval means the host went away and came back.
+	flapGapFactor = 3
+	// flapScoreThreshold is the score from which a host counts as flapping:
+	// two gaps, four threshold crossings, or a mix.
+	flapScoreThreshold = 4
+)
+
+// flapSample is one system_metrics point of a host.
+type flapSample struct {
+	at       time.Time
+	cpu, ram float64
+}
+
+// GetFlappingHosts inspects every host's raw samples over the last window and
+// returns the hosts that went offline and came back (gaps much larger than
+// their usual interval) or kept crossing the CPU/RAM warning threshold,
+// highest score first.
+func (r *InfluxDBReader) GetFlappingHosts(ctx context.Context, window time.Duration) ([]models.FlappingHost, error) {
+	estimate := queryEstimate{Hosts: int(r.knownHosts.Load()), Fields: 2, Span: window}
+	if err := r.checkQueryCost("GetFlappingHosts", estimate); err != nil {
+		return nil, err
+	}
+
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and (r._field == "cpu_usage_percent" or r._field == "mem_usage_percent"))
+			|> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+			|> group(columns: ["host_id"])
+			|> sort(columns: ["_time"])
+			|> keep(columns: ["_time", "host_id", "hostname", "cpu_usage_percent", "mem_usage_percent"])
+	`, r.bucket, window.String())
+
+	appLogger.FromContext(ctx).Debug("GetFlappingHosts Query:\n%s", query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetFlappingHosts: %v", err)
+		return nil, fmt.Errorf("query influxdb for flapping hosts: %w", err)
+	}
+	defer results.Close()
+
+	hostnames := make(map[string]string)
+	samples := make(map[string][]flapSample)
+	for results.Next() {
+		record := results.Record()
+		hostID, _ := record.ValueByKey("host_id").(string)
+		if hostID == "" {
+			continue
+		}
+		if hostname, ok := record.ValueByKey("hostname").(string); ok {
+			hostnames[hostID] = hostname
+		}
+		cpu, _ := record.ValueByKey("cpu_usage_percent").(float64)
+		ram, _ := record.ValueByKey("mem_usage_percent").(float64)
+		samples[hostID] = append(samples[hostID], flapSample{at: record.Time(), cpu: cpu, ram: ram})
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetFlappingHosts: %v", results.Err())
+		return nil, fmt.Errorf("process query results for flapping hosts: %w", results.Err())
+	}
+
+	flapping := []models.FlappingHost{}
+	usageWarning := r.status.Load().UsageWarning
+	for hostID, hostSamples := range samples {
+		host := scoreFlapping(hostSamples, usageWarning)
+		if host.Score < flapScoreThreshold {
+			continue
+		}
+		host.HostID, host.Hostname = hostID, hostnames[hostID]
+		flapping = append(flapping, host)
+	}
+	sort.Slice(flapping, func(i, j int) bool {
+		if flapping[i].Score != flapping[j].Score {
+			return flapping[i].Score > flapping[j].Score
+		}
+		return flapping[i].Hostname < flapping[j].Hostname
+	})
+	return flapping, nil
+}
+
+// scoreFlapping rates one host's samples (oldest first). The usual interval is
+// the median time between samples, so it adapts to agents running at other
+// intervals; a gap counts double since each one is a full offline/online round.
+// Threshold crossings are of usageWarning, the overview's CPU/RAM warning level.
+func scoreFlapping(samples []flapSample, usageWarning float64) models.FlappingHost {
+	host := models.FlappingHost{Samples: len(samples)}
+	if len(samples) == 0 {
+		return host
+	}
+	host.LastSeen = samples[len(samples)-1].at
+	if len(samples) < 2 {
+		return host
+	}
+
+	intervals := make([]time.Duration, 0, len(samples)-1)
+	for i := 1; i < len(samples); i++ {
+		intervals = append(intervals, samples[i].at.Sub(samples[i-1].at))
+	}
+	sorted := append([]time.Duration(nil), intervals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	usual := sorted[len(sorted)/2]
+	if usual <= 0 {
+		usual = rawSampleInterval
+	}
+	host.IntervalSeconds = usual.Seconds()
+
+	longest := time.Duration(0)
+	for _, interval := range intervals {
+		if interval > flapGapFactor*usual {
+			host.Gaps++
+		}
+		longest = max(longest, interval)
+	}
+	host.LongestGapSeconds = longest.Seconds()
+
+	warning := func(s flapSample) bool { return s.cpu >= usageWarning || s.ram >= usageWarning }
+	for i := 1; i < len(samples); i++ {
+		if warning(samples[i]) != warning(samples[i-1]) {
+			host.ThresholdCrossings++
+		}
+	}
+
+	host.Score = float64(2*host.Gaps + host.ThresholdCrossings)
+	return host
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+func TestGappyHostIsFlapping(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	table := influxtest.NewTable("_time:time", "host_id", "hostname", "cpu_usage_percent:double", "mem_usage_percent:double")
+	add := func(hostID string, offsets []time.Duration, cpu func(i int) float64) {
+		for i, offset := range offsets {
+			table.Row(start.Add(offset), hostID, hostID+".example.com", cpu(i), 40.0)
+		}
+	}
+	// every: samples interval apart over span, skipping those in skip
+	every := func(interval, span time.Duration, skip func(time.Duration) bool) []time.Duration {
+		var offsets []time.Duration
+		for offset := time.Duration(0); offset < span; offset += interval {
+			if skip == nil || !skip(offset) {
+				offsets = append(offsets, offset)
+			}
+		}
+		return offsets
+	}
+	quiet := func(int) float64 { return 20 }
+
+	// 30s samples with two five-minute holes
+	add("gappy", every(30*time.Second, 40*time.Minute, func(offset time.Duration) bool {
+		return (offset > 10*time.Minute && offset < 15*time.Minute) || (offset > 25*time.Minute && offset < 30*time.Minute)
+	}), quiet)
+	add("steady", every(30*time.Second, 40*time.Minute, nil), quiet)
+	// a slower agent: one-minute samples are its usual interval, not gaps
+	add("slow-agent", every(time.Minute, 40*time.Minute, nil), quiet)
+	server.Respond(`"cpu_usage_percent", "mem_usage_percent"])`, table)
+
+	hosts, err := reader.GetFlappingHosts(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("GetFlappingHosts: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].HostID != "gappy" {
+		t.Fatalf("flapping hosts = %+v, want only gappy", hosts)
+	}
+	gappy := hosts[0]
+	if gappy.Gaps != 2 || gappy.IntervalSeconds != 30 || gappy.LongestGapSeconds < 5*60 || gappy.Score < flapScoreThreshold {
+		t.Errorf("gappy = %+v, want 2 gaps of about 5m at a 30s interval, scored >= %d", gappy, flapScoreThreshold)
+	}
+	if gappy.Hostname != "gappy.example.com" || gappy.LastSeen.IsZero() {
+		t.Errorf("gappy: hostname %q, last seen %v", gappy.Hostname, gappy.LastSeen)
+	}
+	if queries := server.Queries(); len(queries) != 1 || !strings.Contains(queries[0], "range(start: -1h0m0s)") {
+		t.Errorf("queries = %q, want one over the last hour", queries)
+	}
+}
+
+func TestScoreFlappingCountsThresholdCrossings(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var samples []flapSample
+	for i, cpu := range []float64{50, 90, 50, 90, 50, 60, 70} { // over 85% twice: four crossings
+		samples = append(samples, flapSample{at: start.Add(time.Duration(i) * 30 * time.Second), cpu: cpu, ram: 40})
+	}
+	host := scoreFlapping(samples, 85)
+	if host.ThresholdCrossings != 4 || host.Gaps != 0 || host.Score != 4 {
+		t.Errorf("scoreFlapping = %+v, want 4 crossings, no gaps, score 4", host)
+	}
+	if host := scoreFlapping(samples[:1], 85); host.Score != 0 || host.Samples != 1 {
+		t.Errorf("one sample: %+v, want no score", host)
+	}
+}
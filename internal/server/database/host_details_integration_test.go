@@ -0,0 +1,138 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// GetHostDetails reads system and disk data in one joined query and the
+// processes in one pivoted query. Its result must be what separate plain
+// queries per measurement, as before the join, return for the same points.
+func TestHostDetailsMatchesPerMeasurementQueries(t *testing.T) {
+	for _, series := range []string{"aggregated", "instance"} {
+		t.Run(series, func(t *testing.T) {
+			it := newIntegration(t, func(cfg *config.InfluxDBConfig) { cfg.ProcessSeries = series })
+			hostID := it.hostID("details-" + series)
+			now := time.Now().UTC().Add(-time.Second)
+			it.write(t, &models.ClientPayload{
+				CollectedAt:  now,
+				AgentVersion: "integration",
+				System:       models.SystemInfoPayload{HostID: hostID, Hostname: hostID, OS: "linux", OSVersion: "12", Kernel: "6.1.0", KernelVersion: "x86_64", UptimeSeconds: 3600, LoggedInUsers: 3},
+				CPU:          models.CPUInfoPayload{ModelName: "Test CPU", Cores: 8, Usage: 42.5},
+				Memory:       models.MemInfoPayload{TotalGB: 32, FreeGB: 8, UsagePercent: 75, TotalBytes: 32 << 30, FreeBytes: 8 << 30, UsedBytes: 24 << 30},
+				Network:      models.NetworkPayload{UploadBytesPerSec: 1500, DownloadBytesPerSec: 9000, CumulativeBytesSent: 1 << 40, CumulativeBytesRecv: 1 << 41},
+				Disks: []models.DiskUsagePayload{
+					{Path: "/", TotalGB: 100, UsedGB: 40, FreeGB: 60, UsagePercent: 40, InodesTotal: 1000, InodesUsed: 250, InodesUsagePercent: 25},
+					{Path: "/data", TotalGB: 500, UsedGB: 350, FreeGB: 150, UsagePercent: 70},
+				},
+				Processes: []models.ProcessPayload{
+					{PID: 300, Name: "nginx", CPUPercent: 2, MemoryPercent: 1, CreateTime: now.Add(-time.Hour).UnixMilli(), ReadBytes: 1 << 20, ReadBytesPerSec: 512},
+					{PID: 100, Name: "nginx", CPUPercent: 4, MemoryPercent: 3, CreateTime: now.Add(-2 * time.Hour).UnixMilli()},
+					{PID: 200, Name: "postgres", CPUPercent: 10.5, MemoryPercent: 20, CreateTime: now.Add(-time.Minute).UnixMilli(), WriteBytes: 1 << 33, WriteBytesPerSec: 4096},
+				},
+			})
+
+			details, err := it.reader.GetHostDetails(context.Background(), hostID)
+			if err != nil {
+				t.Fatalf("GetHostDetails: %v", err)
+			}
+
+			system := it.lastValues(t, "system_metrics", hostID)[""]
+			for _, c := range []struct {
+				field string
+				got   any
+			}{
+				{"hostname", details.Hostname},
+				{"os", details.OS.Name},
+				{"os_version", details.OS.Version},
+				{"kernel", details.OS.Kernel},
+				{"kernel_arch", details.OS.KernelArch},
+				{"cpu_model_name", details.CPU.ModelName},
+				{"cpu_cores", int64(details.CPU.Cores)},
+				{"cpu_usage_percent", details.CPUUsage},
+				{"mem_total_gb", details.Memory.TotalGB},
+				{"mem_available_gb", details.Memory.AvailableGB},
+				{"mem_used_gb", details.Memory.UsedGB},
+				{"mem_usage_percent", details.RAMUsage},
+				{"mem_total_bytes", details.Memory.TotalBytes},
+				{"mem_used_bytes", details.Memory.UsedBytes},
+				{"net_upload_bytes_sec", details.NetworkUpload},
+				{"net_download_bytes_sec", details.NetworkDownload},
+				{"net_bytes_sent_total", details.NetworkSentTotal},
+				{"net_bytes_recv_total", details.NetworkRecvTotal},
+				{"logged_in_users", details.LoggedInUsers},
+				{"system_uptime_seconds", details.UptimeSeconds},
+			} {
+				if want := system[c.field]; c.got != want {
+					t.Errorf("%s = %v (%T), per-measurement query has %v (%T)", c.field, c.got, c.got, want, want)
+				}
+			}
+
+			disks := it.lastValues(t, "disk_metrics", hostID, "path")
+			if len(details.Disks) != len(disks) {
+				t.Fatalf("disks = %+v, per-measurement query has %d", details.Disks, len(disks))
+			}
+			for _, disk := range details.Disks {
+				want := disks[disk.Path]
+				got := map[string]any{
+					"total_gb":      disk.TotalGB,
+					"used_gb":       disk.UsedGB,
+					"free_gb":       disk.FreeGB,
+					"usage_percent": disk.UsagePercent,
+				}
+				if _, ok := want["inodes_total"]; ok {
+					got["inodes_total"] = disk.InodesTotal
+					got["inodes_used"] = disk.InodesUsed
+					got["inodes_usage_percent"] = disk.InodesUsagePercent
+				}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("disk %s = %v, per-measurement query has %v", disk.Path, got, want)
+				}
+			}
+			if details.Disk == nil || details.Disk.Path != "/" {
+				t.Errorf("disk = %+v, want the root disk", details.Disk)
+			}
+
+			processes := it.lastValues(t, "process_metrics", hostID, "name", "instance")
+			if len(details.Processes) != len(processes) {
+				t.Fatalf("processes = %+v, per-measurement query has %d series", details.Processes, len(processes))
+			}
+			instance := map[string]int{}
+			for _, proc := range details.Processes {
+				key := proc.Name + "/"
+				if series == "instance" {
+					key += fmt.Sprint(instance[proc.Name]) // by PID among the name's processes, as sorted
+					instance[proc.Name]++
+				}
+				want := processes[key]
+				got := map[string]any{
+					"cpu_percent":         proc.CPUPercent,
+					"mem_percent":         float64(proc.MemoryPercent),
+					"create_time":         proc.StartedAt.UnixMilli(),
+					"read_bytes":          proc.ReadBytes,
+					"write_bytes":         proc.WriteBytes,
+					"read_bytes_per_sec":  proc.ReadBytesPerSec,
+					"write_bytes_per_sec": proc.WriteBytesPerSec,
+				}
+				for field, value := range got {
+					if value != want[field] {
+						t.Errorf("process %s %s = %v (%T), per-measurement query has %v (%T)", key, field, value, value, want[field], want[field])
+					}
+				}
+				if series == "instance" && int64(proc.PID) != want["process_id"] {
+					t.Errorf("process %s pid = %d, per-measurement query has %v", key, proc.PID, want["process_id"])
+				}
+				if series == "aggregated" && int64(proc.Instances) != want["instance_count"] {
+					t.Errorf("process %s instances = %d, per-measurement query has %v", key, proc.Instances, want["instance_count"])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// hostColumns are the columns of GetHostDetails' joined system and disk
+// query, "name:type" as influxtest.NewTable takes them.
+var hostColumns = []string{
+	"_time:time", "host_id", "hostname",
+	"cpu_cores:long", "cpu_model_name", "cpu_usage_percent:double",
+	"mem_available_gb:double", "mem_total_gb:double", "mem_used_gb:double", "mem_usage_percent:double",
+	"mem_total_bytes:long", "mem_available_bytes:long", "mem_used_bytes:long",
+	"net_download_bytes_sec:double", "net_upload_bytes_sec:double",
+	"net_bytes_sent_total:unsignedLong", "net_bytes_recv_total:unsignedLong",
+	"logged_in_users:long", "process_cpu_convention",
+	"os", "os_version", "kernel", "kernel_arch", "uptime_seconds:long",
+	"throttle_found:boolean", "throttled:boolean", "throttle_count:long", "throttle_flags:long",
+	"disk_found:boolean", "disk_time:time", "disk_path",
+	"disk_total_gb:double", "disk_used_gb:double", "disk_free_gb:double", "disk_usage_percent:double",
+	"disk_inodes_total:long", "disk_inodes_used:long", "disk_inodes_usage_percent:double",
+}
+
+// hostTable is the result of the host query: one row per disk with the
+// system values, or a single row without a disk. Columns missing from
+// system and the disks are zero; _time defaults to now and disk_time to
+// _time.
+func hostTable(system map[string]any, disks ...map[string]any) *influxtest.Table {
+	table := influxtest.NewTable(hostColumns...)
+	if len(disks) == 0 {
+		disks = []map[string]any{nil}
+	}
+	at, ok := system["_time"].(time.Time)
+	if !ok {
+		at = time.Now()
+	}
+	for _, disk := range disks {
+		values := make([]any, len(hostColumns))
+		for i, column := range hostColumns {
+			name, typ, _ := strings.Cut(column, ":")
+			value, ok := disk[name]
+			if !ok {
+				value, ok = system[name]
+			}
+			switch {
+			case ok:
+			case name == "_time", name == "disk_time":
+				value = at
+			case name == "disk_found":
+				value = disk != nil
+			case name == "disk_path" && disk == nil:
+				value = "/"
+			case typ == "double":
+				value = 0.0
+			case typ == "long":
+				value = int64(0)
+			case typ == "unsignedLong":
+				value = uint64(0)
+			case typ == "boolean":
+				value = false
+			default:
+				value = ""
+			}
+			values[i] = value
+		}
+		table.Row(values...)
+	}
+	return table
+}
+
+// respondHost answers GetHostDetails' host query.
+func respondHost(server *influxtest.Server, system map[string]any, disks ...map[string]any) {
+	server.Respond("hostDisks = ", hostTable(system, disks...))
+}
+
+func TestHostDetailsReportsInodes(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	respondHost(server, map[string]any{"host_id": "host-1", "hostname": "host-1", "cpu_usage_percent": 10.0},
+		map[string]any{"disk_path": "/", "disk_total_gb": 100.0, "disk_used_gb": 40.0, "disk_free_gb": 60.0, "disk_usage_percent": 40.0,
+			"disk_inodes_total": int64(6_553_600), "disk_inodes_used": int64(6_094_848), "disk_inodes_usage_percent": 93.0},
+		map[string]any{"disk_path": "/data", "disk_total_gb": 500.0, "disk_usage_percent": 10.0}, // no inodes reported
+	)
+
+	details, err := reader.GetHostDetails(context.Background(), "host-1")
+	if err != nil {
+		t.Fatalf("GetHostDetails: %v", err)
+	}
+	if details.Disk == nil {
+		t.Fatal("disk = null, want the root disk")
+	}
+	root := details.Disk
+	if root.InodesTotal != 6_553_600 || root.InodesUsed != 6_094_848 || root.InodesUsagePercent != 93 {
+		t.Errorf("root inodes = %d total / %d used / %v%%, want 6553600 / 6094848 / 93%%", root.InodesTotal, root.InodesUsed, root.InodesUsagePercent)
+	}
+	if details.Status != "warning" {
+		t.Errorf("status %q with 93%% of the root disk's inodes used, want warning", details.Status)
+	}
+	for _, disk := range details.Disks {
+		if disk.Path == "/data" && (disk.InodesTotal != 0 || disk.InodesUsagePercent != 0) {
+			t.Errorf("/data inodes = %+v, want none", disk)
+		}
+	}
+}
+
+func TestNoDiskDataIsNotZeroUsage(t *testing.T) {
+	t.Run("overview", func(t *testing.T) {
+		reader, server := newTestReader(t, nil)
+		now := time.Now()
+		respondOverview(server,
+			overviewRow{hostID: "no-disks", cpu: 10, mem: 20, at: now},
+			overviewRow{hostID: "empty-disk", cpu: 10, mem: 20, disk: percent(0), at: now},
+		)
+
+		hosts := overviewByID(t, reader)
+		if disk := hosts["no-disks"].DiskUsage; disk != nil {
+			t.Errorf("no-disks: diskUsage = %v, want null", *disk)
+		}
+		if disk := hosts["empty-disk"].DiskUsage; disk == nil || *disk != 0 {
+			t.Errorf("empty-disk: diskUsage = %v, want 0", disk)
+		}
+		for id, want := range map[string]string{"no-disks": `"diskUsage":null`, "empty-disk": `"diskUsage":0`} {
+			data, err := json.Marshal(hosts[id])
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s: JSON %s lacks %s", id, data, want)
+			}
+		}
+	})
+
+	t.Run("details", func(t *testing.T) {
+		reader, server := newTestReader(t, nil)
+		respondHost(server, map[string]any{"host_id": "no-disks", "hostname": "no-disks", "cpu_usage_percent": 10.0})
+
+		details, err := reader.GetHostDetails(context.Background(), "no-disks")
+		if err != nil {
+			t.Fatalf("GetHostDetails: %v", err)
+		}
+		if details.Disk != nil || len(details.Disks) != 0 {
+			t.Errorf("disk = %+v, disks = %+v, want none", details.Disk, details.Disks)
+		}
+		if len(details.Processes) != 0 {
+			t.Errorf("processes = %+v, want none", details.Processes)
+		}
+		data, err := json.Marshal(details)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		for _, want := range []string{`"disk":null`, `"disks":[]`, `"processes":[]`} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("JSON lacks %s: %s", want, data)
+			}
+		}
+
+		// a reported disk at 0% is a disk, not a missing one
+		reader, server = newTestReader(t, nil)
+		respondHost(server, map[string]any{"host_id": "empty-disk", "hostname": "empty-disk"},
+			map[string]any{"disk_path": "/", "disk_total_gb": 100.0, "disk_free_gb": 100.0})
+		details, err = reader.GetHostDetails(context.Background(), "empty-disk")
+		if err != nil {
+			t.Fatalf("GetHostDetails: %v", err)
+		}
+		if details.Disk == nil || details.Disk.UsagePercent != 0 || details.Disk.TotalGB != 100 {
+			t.Errorf("empty-disk: disk = %+v, want / at 0%% of 100 GB", details.Disk)
+		}
+	})
+}
+
+func TestHostDetailsMemoryFixture(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	// distinct values per field, so a field read into the wrong model field shows
+	respondHost(server, map[string]any{
+		"host_id": "host-1", "hostname": "host-1",
+		"mem_total_gb": 16.0, "mem_available_gb": 4.0, "mem_used_gb": 12.0, "mem_usage_percent": 75.0,
+		"mem_total_bytes": int64(17_179_869_184), "mem_available_bytes": int64(4_294_967_296), "mem_used_bytes": int64(12_884_901_888),
+	})
+
+	details, err := reader.GetHostDetails(context.Background(), "host-1")
+	if err != nil {
+		t.Fatalf("GetHostDetails: %v", err)
+	}
+	want := models.MemoryDetails{
+		TotalGB: 16, AvailableGB: 4, UsedGB: 12, UsagePercent: 75,
+		TotalBytes: 17_179_869_184, AvailableBytes: 4_294_967_296, UsedBytes: 12_884_901_888,
+	}
+	if details.Memory != want {
+		t.Errorf("memory = %+v, want %+v", details.Memory, want)
+	}
+
+	data, err := json.Marshal(details.Memory)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	const wantJSON = `{"total_gb":16,"free_gb":4,"used_gb":12,"usage_percent":75,"total_bytes":17179869184,"free_bytes":4294967296,"used_bytes":12884901888}`
+	if string(data) != wantJSON {
+		t.Errorf("memory JSON = %s, want %s", data, wantJSON)
+	}
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// ListHosts returns the ID, hostname and last report of every host that
+// reported between start and end, sorted by hostname. It reads one field
+// and no disk or process data, and computes no status, so it is much cheaper
+// than the overview.
+func (r *InfluxDBReader) ListHosts(ctx context.Context, start, end time.Time) ([]models.HostListEntry, error) {
+	estimate := queryEstimate{Hosts: int(r.knownHosts.Load()), Fields: 1, Span: end.Sub(start)}
+	if err := r.checkQueryCost("ListHosts", estimate); err != nil {
+		return nil, err
+	}
+
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// last() per series first (InfluxDB answers it from the storage layer),
+	// then the newest of a host's series: a host has one series per hostname,
+	// agent version and label set it reported with
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and r._field == "cpu_usage_percent")
+			|> last()
+			|> group(columns: ["host_id"])
+			|> sort(columns: ["_time"])
+			|> last(column: "_time")
+			|> group()
+			|> keep(columns: ["host_id", "hostname", "_time"])
+	`, r.bucket, fluxTime(start), fluxTime(end))
+
+	appLogger.FromContext(ctx).Debug("ListHosts Query:\n%s", query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for ListHosts: %v", err)
+		return nil, fmt.Errorf("query influxdb for host list: %w", err)
+	}
+	defer results.Close()
+
+	hosts := []models.HostListEntry{}
+	for results.Next() {
+		record := results.Record()
+		hostID, _ := record.ValueByKey("host_id").(string)
+		if hostID == "" {
+			continue
+		}
+		hostname, _ := record.ValueByKey("hostname").(string)
+		hosts = append(hosts, models.HostListEntry{ID: hostID, Hostname: hostname, LastSeen: record.Time().UTC()})
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for ListHosts: %v", results.Err())
+		return nil, fmt.Errorf("process query results for host list: %w", results.Err())
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Hostname != hosts[j].Hostname {
+			return hosts[i].Hostname < hosts[j].Hostname
+		}
+		return hosts[i].ID < hosts[j].ID
+	})
+	return hosts, nil
+}
@@ -2,57 +2,162 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
-	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
-	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/query"
+	"golang.org/x/sync/semaphore"
 )
 
-const (
-	defaultLookbackWindow = 15 * time.Second // last seen
-	activeHostLookback    = 30 * time.Second // for determining online status
-)
+// ErrReaderBusy is returned when no query slot became free before the caller's deadline.
+var ErrReaderBusy = errors.New("influxdb reader busy: too many concurrent queries")
 
 type InfluxDBReader struct {
-	client   influxdb2.Client
-	queryAPI api.QueryAPI
-	org      string
-	bucket   string
+	client *Client
+	bucket string
+
+	coldBucket           string        // downsampled data, "" = none
+	coldBucketAfter      time.Duration // history windows longer than this use coldBucket
+	coldBucketResolution time.Duration
+
+	querySlots   *semaphore.Weighted // bounds in-flight dashboard reads
+	queueTimeout time.Duration       // max wait for a slot when ctx has no deadline
+
+	pointBudget      int64        // max estimated raw points per query, 0 = unlimited
+	rejectOverBudget bool         // false = only log queries over budget
+	historyMaxFields int          // distinct fields per GetHostMetricsHistory
+	knownHosts       atomic.Int64 // host count from the last overview, for fleet-wide estimates
+
+	overviewMaxAge time.Duration // hosts silent for longer are left out of the overview
+	onlineWithin   time.Duration // hosts seen within this are online, older ones offline
+	recentReboot   time.Duration // online hosts up for less than this are flagged, 0 = never
+
+	status atomic.Pointer[config.StatusThresholds] // replaced on SIGHUP
+
+	averageWindows      []time.Duration // rolling CPU/RAM averages for the overview, none = disabled
+	averages            rollingAverages
+	latest              latestTable   // each host's latest overview row, kept current by ingest
+	overviewCacheMaxAge time.Duration // re-seed latest from InfluxDB after this, 0 = always query
+	detailsLookback     time.Duration // host details use the latest point within this
+	displayLocation     *time.Location
+	processLookback     time.Duration // covers ProcessSampleEvery agent cycles
 }
 
-// NewInfluxDBReader creates a new InfluxDBReader.
-func NewInfluxDBReader(cfg config.InfluxDBConfig) (*InfluxDBReader, error) {
-	// Client setup is similar to InfluxDBWriter
-	// Consider sharing the client if both reader and writer are heavily used,
-	// but for now, separate clients are fine and simpler.
-	client := influxdb2.NewClient(cfg.URL, cfg.Token)
-	// Health check (optional but good)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	health, err := client.Health(ctx)
+// NewInfluxDBReader creates a new InfluxDBReader on the shared client.
+func NewInfluxDBReader(client *Client, cfg config.InfluxDBConfig) *InfluxDBReader {
+	r := &InfluxDBReader{
+		client: client,
+		bucket: cfg.Bucket,
+
+		coldBucket:           cfg.ColdBucket,
+		coldBucketAfter:      cfg.ColdBucketAfter,
+		coldBucketResolution: cfg.ColdBucketResolution,
+
+		querySlots:   semaphore.NewWeighted(int64(cfg.MaxConcurrentQueries)),
+		queueTimeout: cfg.QueryQueueTimeout,
+
+		pointBudget:      cfg.QueryPointBudget,
+		rejectOverBudget: cfg.RejectOverBudget,
+		historyMaxFields: cfg.HistoryMaxFields,
+
+		overviewMaxAge: max(cfg.OverviewMaxOfflineAge, cfg.OnlineWithin),
+		onlineWithin:   cfg.OnlineWithin,
+		recentReboot:   cfg.RecentRebootWithin,
+
+		averageWindows:      cfg.OverviewAverageWindows,
+		overviewCacheMaxAge: cfg.OverviewCacheMaxAge,
+		detailsLookback:     cfg.DetailsLookback,
+		displayLocation:     cfg.DisplayLocation,
+		processLookback:     processLookback(cfg.ProcessSampleEvery, cfg.DetailsLookback),
+	}
+	r.SetStatusThresholds(cfg.Status)
+	return r
+}
+
+// SetStatusThresholds replaces the usage percentages statuses are derived
+// from. Requests already computing a status finish with the old ones.
+func (r *InfluxDBReader) SetStatusThresholds(t config.StatusThresholds) {
+	r.status.Store(&t)
+}
+
+// processLookback is the process query window when agents send processes
+// every n-th cycle: n intervals plus the slack of the details lookback, which
+// it equals for n = 1.
+func processLookback(n int, detailsLookback time.Duration) time.Duration {
+	return time.Duration(max(n, 1))*rawSampleInterval + max(detailsLookback-rawSampleInterval, 0)
+}
+
+// query runs a Flux query on the shared client. Callers defer Close on the
+// result, on every path.
+func (r *InfluxDBReader) query(ctx context.Context, flux string) (*queryResult, error) {
+	result, err := r.client.Query(ctx, flux)
 	if err != nil {
-		return nil, fmt.Errorf("influxdb health check failed for reader: %w", err)
+		if result != nil {
+			_ = result.Closer.Close()
+		}
+		return nil, err
 	}
-	if health.Status != "pass" {
-		return nil, fmt.Errorf("influxdb not healthy for reader: status %s", health.Status)
+	return &queryResult{QueryTableResult: result, ctx: ctx}, nil
+}
+
+// acquireQuerySlot blocks until a query slot is free. A method holds one slot for
+// its whole lifetime (including result iteration), so GetHostDetails' sequential
+// queries count once. Returns ErrReaderBusy if the wait times out.
+func (r *InfluxDBReader) acquireQuerySlot(ctx context.Context) (release func(), err error) {
+	waitCtx := ctx
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && r.queueTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, r.queueTimeout)
+		defer cancel()
 	}
-	appLogger.Info("InfluxDBReader successfully connected to InfluxDB at %s", cfg.URL)
 
-	queryAPI := client.QueryAPI(cfg.Org)
-	return &InfluxDBReader{
-		client:   client,
-		queryAPI: queryAPI,
-		org:      cfg.Org,
-		bucket:   cfg.Bucket,
-	}, nil
+	if err := r.querySlots.Acquire(waitCtx, 1); err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, ctx.Err() // caller went away, not a load problem
+		}
+		appLogger.FromContext(ctx).Warn("InfluxDBReader query slot wait timed out: %v", err)
+		return nil, ErrReaderBusy
+	}
+	return func() { r.querySlots.Release(1) }, nil
 }
 
+// GetHostOverviewList returns the latest values of every host seen within
+// OVERVIEW_MAX_OFFLINE_AGE, sorted by hostname. While the latest table is
+// fresh it is served from there without a query; otherwise InfluxDB is
+// queried and the result re-seeds the table.
 func (r *InfluxDBReader) GetHostOverviewList(ctx context.Context) ([]models.HostOverviewData, error) {
+	now := time.Now()
+	if r.overviewCacheMaxAge > 0 {
+		if rows, fresh := r.latest.rows(now, r.overviewCacheMaxAge, r.overviewMaxAge); fresh {
+			return r.finishOverview(rows, now), nil
+		}
+	}
+	rows, err := r.queryHostOverviewList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.overviewCacheMaxAge > 0 {
+		rows = r.latest.seed(rows, now, r.overviewMaxAge)
+	}
+	return r.finishOverview(rows, now), nil
+}
+
+// queryHostOverviewList reads each host's latest system and root disk values
+// from InfluxDB, without status or averages.
+func (r *InfluxDBReader) queryHostOverviewList(ctx context.Context) ([]models.HostOverviewData, error) {
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	query := fmt.Sprintf(`
 		import "influxdata/influxdb/schema"
 		import "join"
@@ -69,7 +174,9 @@ func (r *InfluxDBReader) GetHostOverviewList(ctx context.Context) ([]models.Host
 					hostname: r.hostname,
 					cpu_usage_percent: if exists r.cpu_usage_percent then r.cpu_usage_percent else 0.0,
 					mem_usage_percent: if exists r.mem_usage_percent then r.mem_usage_percent else 0.0,
-					// uptime_seconds: REMOVED FOR TESTING
+					uptime_seconds: if exists r.system_uptime_seconds then r.system_uptime_seconds else 0,
+					lag_found: exists r.ingest_lag_seconds,
+					ingest_lag_seconds: if exists r.ingest_lag_seconds then r.ingest_lag_seconds else 0.0,
 					net_upload_bytes_sec: if exists r.net_upload_bytes_sec then r.net_upload_bytes_sec else 0.0,
 					net_download_bytes_sec: if exists r.net_download_bytes_sec then r.net_download_bytes_sec else 0.0
 				}
@@ -79,13 +186,14 @@ func (r *InfluxDBReader) GetHostOverviewList(ctx context.Context) ([]models.Host
 			|> range(start: -%s)
 			|> filter(fn: (r) => 
 				r._measurement == "disk_metrics" and 
-				r._field == "usage_percent" and 
+				(r._field == "usage_percent" or r._field == "inodes_usage_percent") and 
 				r.path == "/"
 			)
-			|> group(columns: ["host_id"])
+			|> group(columns: ["host_id", "_field"])
 			|> last()
-			|> rename(columns: {_value: "root_disk_usage_percent"})
-			|> keep(columns: ["host_id", "root_disk_usage_percent"])
+			|> group(columns: ["host_id"])
+			|> pivot(rowKey: ["host_id"], columnKey: ["_field"], valueColumn: "_value")
+			|> keep(columns: ["host_id", "usage_percent", "inodes_usage_percent"])
 
 		join.left(
 			left: systemData,
@@ -97,26 +205,30 @@ func (r *InfluxDBReader) GetHostOverviewList(ctx context.Context) ([]models.Host
 				hostname: l.hostname,
 				cpu_usage_percent: l.cpu_usage_percent,
 				mem_usage_percent: l.mem_usage_percent,
-				// uptime_seconds: REMOVED FOR TESTING
+				uptime_seconds: l.uptime_seconds,
+				lag_found: l.lag_found,
+				ingest_lag_seconds: l.ingest_lag_seconds,
 				net_upload_bytes_sec: l.net_upload_bytes_sec,
 				net_download_bytes_sec: l.net_download_bytes_sec,
-				disk_usage_percent: if exists r.root_disk_usage_percent then r.root_disk_usage_percent else 0.0
+				disk_found: exists r.usage_percent,
+				disk_usage_percent: if exists r.usage_percent then r.usage_percent else 0.0,
+				inodes_found: exists r.inodes_usage_percent,
+				inodes_usage_percent: if exists r.inodes_usage_percent then r.inodes_usage_percent else 0.0
 			})
 		)
 		|> yield(name: "overview")
-	`, r.bucket, activeHostLookback.String(), /* for systemData */
-		r.bucket, activeHostLookback.String() /* for rootDiskUsage */)
+	`, r.bucket, r.overviewMaxAge.String(), /* for systemData */
+		r.bucket, r.overviewMaxAge.String() /* for rootDiskUsage */)
 
-	appLogger.Debug("GetHostOverviewList Query:\n%s", query) // Log the query
-	results, err := r.queryAPI.Query(ctx, query)
+	appLogger.FromContext(ctx).Debug("GetHostOverviewList Query:\n%s", query) // Log the query
+	results, err := r.query(ctx, query)
 	if err != nil {
-		appLogger.Error("InfluxDB query failed for GetHostOverviewList: %v", err)
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostOverviewList: %v", err)
 		return nil, fmt.Errorf("query influxdb for host overview: %w", err)
 	}
+	defer results.Close()
 
 	var overviews []models.HostOverviewData
-	now := time.Now()
-
 	for results.Next() {
 		record := results.Record()
 		getFloat := func(field string) float64 {
@@ -127,21 +239,71 @@ func (r *InfluxDBReader) GetHostOverviewList(ctx context.Context) ([]models.Host
 			return val
 		}
 
+		// Tags should always be strings, but one malformed record mustn't panic the whole overview
+		getString := func(field string) string {
+			val, ok := record.ValueByKey(field).(string)
+			if !ok {
+				appLogger.FromContext(ctx).Warn("GetHostOverviewList: record without a string %s (got %T)", field, record.ValueByKey(field))
+			}
+			return val
+		}
+		hostID := getString("host_id")
+		if hostID == "" {
+			continue // nothing to show it under
+		}
+		hostname := getString("hostname")
+		if hostname == "" {
+			hostname = hostID
+		}
+
 		overview := models.HostOverviewData{
-			ID:              record.ValueByKey("host_id").(string),
-			Hostname:        record.ValueByKey("hostname").(string),
+			ID:              hostID,
+			Hostname:        hostname,
 			CPUUsage:        getFloat("cpu_usage_percent"),
 			RAMUsage:        getFloat("mem_usage_percent"),
-			DiskUsage:       getFloat("disk_usage_percent"), // This now directly comes from 'root_disk_usage_percent'
 			NetworkUpload:   getFloat("net_upload_bytes_sec"),
 			NetworkDownload: getFloat("net_download_bytes_sec"),
-			//UptimeSeconds:   record.ValueByKey("uptime_seconds").(string),
-			LastSeen: record.Time(),
+			LastSeen:        record.Time(),
+		}
+		overview.UptimeSeconds, _ = record.ValueByKey("uptime_seconds").(int64)
+		if found, _ := record.ValueByKey("lag_found").(bool); found {
+			lag := getFloat("ingest_lag_seconds")
+			overview.IngestLagSeconds = &lag
+		}
+		// Root disk from the rootDiskUsage join, left nil when the agent sent none
+		if found, _ := record.ValueByKey("disk_found").(bool); found {
+			diskUsage := getFloat("disk_usage_percent")
+			overview.DiskUsage = &diskUsage
 		}
+		if found, _ := record.ValueByKey("inodes_found").(bool); found {
+			inodeUsage := getFloat("inodes_usage_percent")
+			overview.InodeUsage = &inodeUsage
+		}
+		overviews = append(overviews, overview)
+	}
 
-		if now.Sub(overview.LastSeen) <= activeHostLookback+(5*time.Second) {
-			overview.Status = "online"
-			if overview.CPUUsage > 85 || overview.RAMUsage > 85 || overview.DiskUsage > 90 {
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetHostOverviewList: %v", results.Err())
+		return nil, fmt.Errorf("process query results for host overview: %w", results.Err())
+	}
+	return overviews, nil
+}
+
+// finishOverview drops hosts silent for longer than overviewMaxAge, adds the
+// rolling averages and status, and sorts by hostname.
+func (r *InfluxDBReader) finishOverview(rows []models.HostOverviewData, now time.Time) []models.HostOverviewData {
+	var overviews []models.HostOverviewData
+	for _, overview := range rows {
+		if now.Sub(overview.LastSeen) > r.overviewMaxAge {
+			continue // range() is relative to InfluxDB's clock, check against ours too
+		}
+		r.averages.apply(&overview)
+
+		// Status only depends on LastSeen, offline hosts keep their last-known values
+		if now.Sub(overview.LastSeen) <= r.onlineWithin {
+			overview.RecentlyRebooted = r.recentlyRebooted(overview.UptimeSeconds)
+			overview.Status = r.usageStatus(overview.CPUUsage, overview.RAMUsage, overview.DiskUsage, overview.InodeUsage)
+			if overview.Status == "online" && overview.RecentlyRebooted {
 				overview.Status = "warning"
 			}
 		} else {
@@ -150,26 +312,88 @@ func (r *InfluxDBReader) GetHostOverviewList(ctx context.Context) ([]models.Host
 		overviews = append(overviews, overview)
 	}
 
-	if results.Err() != nil {
-		appLogger.Error("Error processing results for GetHostOverviewList: %v", results.Err())
-		return nil, fmt.Errorf("process query results for host overview: %w", results.Err())
-	}
-
 	sort.Slice(overviews, func(i, j int) bool {
 		return overviews[i].Hostname < overviews[j].Hostname
 	})
+	r.knownHosts.Store(int64(len(overviews)))
+	return overviews
+}
 
-	return overviews, nil
+// recentlyRebooted reports whether an uptime is known and under
+// RECENT_REBOOT_THRESHOLD.
+func (r *InfluxDBReader) recentlyRebooted(uptimeSeconds int64) bool {
+	return r.recentReboot > 0 && uptimeSeconds > 0 && time.Duration(uptimeSeconds)*time.Second < r.recentReboot
+}
+
+// GetOverviewSparklines returns short trend lines for every host in one query:
+// host_id -> field -> mean per 'every' window over the last 'window', oldest first.
+// Only cpu_usage_percent and mem_usage_percent are included.
+func (r *InfluxDBReader) GetOverviewSparklines(ctx context.Context, window, every time.Duration) (map[string]map[string][]float64, error) {
+	estimate := queryEstimate{Hosts: int(r.knownHosts.Load()), Fields: 2, Span: window}
+	if err := r.checkQueryCost("GetOverviewSparklines", estimate); err != nil {
+		return nil, err
+	}
+
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and (r._field == "cpu_usage_percent" or r._field == "mem_usage_percent"))
+			|> group(columns: ["host_id", "_field"])
+			|> aggregateWindow(every: %s, fn: mean, createEmpty: false)
+	`, r.bucket, window.String(), every.String())
+
+	appLogger.FromContext(ctx).Debug("GetOverviewSparklines Query:\n%s", query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetOverviewSparklines: %v", err)
+		return nil, fmt.Errorf("query influxdb for overview sparklines: %w", err)
+	}
+	defer results.Close()
+
+	sparklines := make(map[string]map[string][]float64)
+	for results.Next() {
+		record := results.Record()
+		hostID, _ := record.ValueByKey("host_id").(string)
+		value, ok := record.Value().(float64)
+		if hostID == "" || !ok {
+			continue
+		}
+		if sparklines[hostID] == nil {
+			sparklines[hostID] = make(map[string][]float64)
+		}
+		sparklines[hostID][record.Field()] = append(sparklines[hostID][record.Field()], value)
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetOverviewSparklines: %v", results.Err())
+		return nil, fmt.Errorf("process query results for overview sparklines: %w", results.Err())
+	}
+	return sparklines, nil
 }
 
 // GetHostDetails fetches detailed information for a single host.
+// It takes three round trips: one for system + disk data (joined in Flux, one
+// row per mountpoint), one for the process list and one for the process
+// summary.
 func (r *InfluxDBReader) GetHostDetails(ctx context.Context, hostID string) (*models.HostDetailsData, error) {
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// --- Query for System Data joined with the Root Disk ---
+	hostQuery := fmt.Sprintf(`
+    import "join"
 
-	// --- Query for System Data ---
-	systemQuery := fmt.Sprintf(`
-    from(bucket: "%s")
+    systemData = from(bucket: "%s")
         |> range(start: -%s)
-        |> filter(fn: (r) => r._measurement == "system_metrics" and r.host_id == "%s")
+        |> filter(fn: (r) => r._measurement == "system_metrics" and r.host_id == %s)
         |> last()
         |> pivot(rowKey:["_time", "host_id"], columnKey: ["_field"], valueColumn: "_value")
         |> map(fn: (r) => ({
@@ -184,35 +408,73 @@ func (r *InfluxDBReader) GetHostDetails(ctx context.Context, hostID string) (*mo
             mem_total_gb: if exists r.mem_total_gb then r.mem_total_gb else 0.0,
             mem_used_gb: if exists r.mem_used_gb then r.mem_used_gb else 0.0,
             mem_usage_percent: if exists r.mem_usage_percent then r.mem_usage_percent else 0.0,
+            mem_total_bytes: if exists r.mem_total_bytes then r.mem_total_bytes else 0,
+            mem_available_bytes: if exists r.mem_available_bytes then r.mem_available_bytes else 0,
+            mem_used_bytes: if exists r.mem_used_bytes then r.mem_used_bytes else 0,
             net_download_bytes_sec: if exists r.net_download_bytes_sec then r.net_download_bytes_sec else 0.0,
             net_upload_bytes_sec: if exists r.net_upload_bytes_sec then r.net_upload_bytes_sec else 0.0,
+            net_bytes_sent_total: if exists r.net_bytes_sent_total then r.net_bytes_sent_total else uint(v: 0),
+            net_bytes_recv_total: if exists r.net_bytes_recv_total then r.net_bytes_recv_total else uint(v: 0),
+            logged_in_users: if exists r.logged_in_users then r.logged_in_users else 0,
+            process_cpu_convention: if exists r.process_cpu_convention then r.process_cpu_convention else "per_core",
             os: if exists r.os then r.os else "",
             os_version: if exists r.os_version then r.os_version else "",
 			kernel: if exists r.kernel then r.kernel else "",
             kernel_arch: if exists r.kernel_arch then r.kernel_arch else "",
-            // uptime_seconds: if exists r.uptime_seconds then uint(v: r.uptime_seconds) else uint(v: 0) // if you re-add it
-        })) // <<<< THIS IS THE END OF THE map() call.
-           // There is no findRecord after this.
-`, r.bucket, defaultLookbackWindow, hostID)
-
-	appLogger.Debug("GetHostDetails System Query for host %s:\n%s", hostID, systemQuery)
-	sysResults, err := r.queryAPI.Query(ctx, systemQuery)
+            uptime_seconds: if exists r.system_uptime_seconds then r.system_uptime_seconds else 0,
+            throttle_found: exists r.throttled,
+            throttled: if exists r.throttled then r.throttled else false,
+            throttle_count: if exists r.throttle_count then r.throttle_count else 0,
+            throttle_flags: if exists r.throttle_flags then r.throttle_flags else 0,
+        }))
+        |> group(columns: ["host_id"]) // same group key as hostDisks for the join
+
+    hostDisks = from(bucket: "%s")
+        |> range(start: -%s)
+        |> filter(fn: (r) => r._measurement == "disk_metrics" and r.host_id == %s)
+        |> last()
+        |> pivot(rowKey:["_time", "host_id", "path"], columnKey: ["_field"], valueColumn: "_value")
+        |> group(columns: ["host_id"])
+
+    join.left(
+        left: systemData,
+        right: hostDisks,
+        on: (l, r) => l.host_id == r.host_id,
+        as: (l, r) => ({l with
+            disk_found: exists r.path,
+            disk_time: if exists r._time then r._time else l._time,
+            disk_path: if exists r.path then r.path else "/",
+            disk_total_gb: if exists r.total_gb then r.total_gb else 0.0,
+            disk_used_gb: if exists r.used_gb then r.used_gb else 0.0,
+            disk_free_gb: if exists r.free_gb then r.free_gb else 0.0,
+            disk_usage_percent: if exists r.usage_percent then r.usage_percent else 0.0,
+            disk_inodes_total: if exists r.inodes_total then r.inodes_total else 0,
+            disk_inodes_used: if exists r.inodes_used then r.inodes_used else 0,
+            disk_inodes_usage_percent: if exists r.inodes_usage_percent then r.inodes_usage_percent else 0.0,
+        })
+    )
+`, r.bucket, r.detailsLookback, fluxString(hostID),
+		r.bucket, r.detailsLookback, fluxString(hostID))
+
+	appLogger.FromContext(ctx).Debug("GetHostDetails Host Query for host %s:\n%s", hostID, hostQuery)
+	sysResults, err := r.query(ctx, hostQuery)
 	if err != nil {
-		appLogger.Error("InfluxDB query failed for GetHostDetails (system) for host %s: %v", hostID, err)
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostDetails (system) for host %s: %v", hostID, err)
 		return nil, fmt.Errorf("query influxdb for host details (system): %w", err)
 	}
+	defer sysResults.Close()
 
 	if !sysResults.Next() {
 		if sysResults.Err() != nil {
-			appLogger.Error("Error processing system results for GetHostDetails host %s: %v", hostID, sysResults.Err())
+			appLogger.FromContext(ctx).Error("Error processing system results for GetHostDetails host %s: %v", hostID, sysResults.Err())
 			return nil, fmt.Errorf("no data found for host %s or query error: %w", hostID, sysResults.Err())
 		}
-		appLogger.Warn("No system data found for host_id: %s", hostID)
+		appLogger.FromContext(ctx).Warn("No system data found for host_id: %s", hostID)
 		return nil, fmt.Errorf("no system data found for host_id: %s", hostID) // Or return a specific "not found" error
 	}
 	record := sysResults.Record()
 	if sysResults.Err() != nil { // Check error after Next()
-		appLogger.Error("Error after Next() for system results, host %s: %v", hostID, sysResults.Err())
+		appLogger.FromContext(ctx).Error("Error after Next() for system results, host %s: %v", hostID, sysResults.Err())
 		return nil, fmt.Errorf("error processing system record for host %s: %w", hostID, sysResults.Err())
 	}
 
@@ -237,6 +499,22 @@ func (r *InfluxDBReader) GetHostDetails(ctx context.Context, hostID string) (*mo
 		}
 		return int32(val)
 	}
+	// Helper to get int64 (integer fields), defaulting to 0
+	getI64 := func(key string) int64 {
+		v, ok := record.ValueByKey(key).(int64)
+		if !ok {
+			return 0
+		}
+		return v
+	}
+	// Helper to get uint64 (unsigned counter fields), defaulting to 0
+	getU64 := func(key string) uint64 {
+		v, ok := record.ValueByKey(key).(uint64)
+		if !ok {
+			return 0
+		}
+		return v
+	}
 	// Helper to get string, defaulting to ""
 	getS := func(key string) string {
 		v, ok := record.ValueByKey(key).(string)
@@ -247,10 +525,10 @@ func (r *InfluxDBReader) GetHostDetails(ctx context.Context, hostID string) (*mo
 	}
 
 	details := &models.HostDetailsData{
-		ID:       hostID,
-		Hostname: getS("hostname"),
-		//UptimeSeconds: getS("uptime_seconds"),
-		LastSeen: record.Time(),
+		ID:            hostID,
+		Hostname:      getS("hostname"),
+		UptimeSeconds: getI64("uptime_seconds"),
+		LastSeen:      record.Time(),
 		CPU: models.CPUDetails{
 			Cores:     getI32("cpu_cores"),
 			ModelName: getS("cpu_model_name"),
@@ -258,7 +536,12 @@ func (r *InfluxDBReader) GetHostDetails(ctx context.Context, hostID string) (*mo
 		Memory: models.MemoryDetails{
 			TotalGB:      getF("mem_total_gb"),
 			AvailableGB:  getF("mem_available_gb"),
-			UsagePercent: getF("mem_used_gb"),
+			UsedGB:       getF("mem_used_gb"),
+			UsagePercent: getF("mem_usage_percent"),
+
+			TotalBytes:     getI64("mem_total_bytes"),
+			AvailableBytes: getI64("mem_available_bytes"),
+			UsedBytes:      getI64("mem_used_bytes"),
 		},
 		OS: models.OSLiteralDetails{
 			Name:       getS("os"), // Assuming 'os' field in system_metrics stores this
@@ -266,87 +549,135 @@ func (r *InfluxDBReader) GetHostDetails(ctx context.Context, hostID string) (*mo
 			Kernel:     getS("kernel"),
 			KernelArch: getS("kernel_arch"),
 		},
-		CPUUsage:        getF("cpu_usage_percent"),
-		RAMUsage:        getF("mem_usage_percent"),
-		NetworkUpload:   getF("net_upload_bytes_sec"),
-		NetworkDownload: getF("net_download_bytes_sec"),
+		CPUUsage:         getF("cpu_usage_percent"),
+		RAMUsage:         getF("mem_usage_percent"),
+		NetworkUpload:    getF("net_upload_bytes_sec"),
+		NetworkDownload:  getF("net_download_bytes_sec"),
+		NetworkSentTotal: getU64("net_bytes_sent_total"),
+		NetworkRecvTotal: getU64("net_bytes_recv_total"),
+		LoggedInUsers:    getI64("logged_in_users"),
+		ProcessCPU:       getS("process_cpu_convention"),
+	}
+	if found, _ := record.ValueByKey("throttle_found").(bool); found {
+		throttled, _ := record.ValueByKey("throttled").(bool)
+		details.Throttle = &models.ThrottleDetails{
+			Throttled:     throttled,
+			ThrottleCount: getI64("throttle_count"),
+			Flags:         getI64("throttle_flags"),
+		}
 	}
 
-	// --- Query for Root Disk Data ---
-	diskQuery := fmt.Sprintf(`
-    from(bucket: "%s")
-        |> range(start: -%s)
-        |> filter(fn: (r) => 
-            r._measurement == "disk_metrics" and 
-            r.host_id == "%s" and 
-            r.path == "/"
-        )
-        |> last()
-        |> pivot(rowKey:["_time", "host_id", "path"], columnKey: ["_field"], valueColumn: "_value")
-
-	`, r.bucket, defaultLookbackWindow, hostID)
-
-	appLogger.Debug("GetHostDetails Disk Query for host %s:\n%s", hostID, diskQuery)
-	diskResults, err := r.queryAPI.Query(ctx, diskQuery)
-	if err != nil {
-		appLogger.Error("InfluxDB query failed for GetHostDetails (root disk) for host %s: %v", hostID, err)
-		// Set default empty disk details or handle error as appropriate
-		details.Disk = models.RootDiskDetails{Path: "/"} // Indicate path even if data is missing
-	} else {
-		if diskResults.Next() {
-			dRec := diskResults.Record()
-			getDF := func(key string) float64 {
-				v, ok := dRec.ValueByKey(key).(float64)
-				if !ok {
-					return 0.0
+	// --- Disk Data (from the join), one row per mountpoint, none when the agent sent none ---
+	// A mountpoint whose tags changed within the lookback (e.g. labels) has
+	// a row per series, keep the newest
+	disks := map[string]models.DiskDetails{}
+	diskTimes := map[string]time.Time{}
+	for {
+		if found, _ := record.ValueByKey("disk_found").(bool); found {
+			path := getS("disk_path")
+			at, _ := record.ValueByKey("disk_time").(time.Time)
+			if _, seen := disks[path]; !seen || at.After(diskTimes[path]) {
+				disks[path] = models.DiskDetails{
+					Path:         path,
+					TotalGB:      getF("disk_total_gb"),
+					UsedGB:       getF("disk_used_gb"),
+					FreeGB:       getF("disk_free_gb"),
+					UsagePercent: getF("disk_usage_percent"),
+
+					InodesTotal:        getI64("disk_inodes_total"),
+					InodesUsed:         getI64("disk_inodes_used"),
+					InodesUsagePercent: getF("disk_inodes_usage_percent"),
 				}
-				return v
-			}
-
-			details.Disk = models.RootDiskDetails{
-				Path:         dRec.ValueByKey("path").(string), // Should be "/"
-				TotalGB:      getDF("total_gb"),
-				UsedGB:       getDF("used_gb"),
-				FreeGB:       getDF("free_gb"),
-				UsagePercent: getDF("usage_percent"),
+				diskTimes[path] = at
 			}
-		} else {
-			appLogger.Warn("No root disk data found for host_id: %s", hostID)
-			details.Disk = models.RootDiskDetails{Path: "/"} // Default if no record found
 		}
-		if diskResults.Err() != nil {
-			appLogger.Error("Error processing root disk results for host %s: %v", hostID, diskResults.Err())
-			// Disk details might be partially populated or default
+		if !sysResults.Next() {
+			break
 		}
+		record = sysResults.Record() // the get helpers read this record
+	}
+	if sysResults.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing disk rows for GetHostDetails host %s: %v", hostID, sysResults.Err())
+		return nil, fmt.Errorf("error processing disk records for host %s: %w", hostID, sysResults.Err())
+	}
+	details.Disks = make([]models.DiskDetails, 0, len(disks))
+	for _, disk := range disks {
+		details.Disks = append(details.Disks, disk)
+	}
+	sort.Slice(details.Disks, func(i, j int) bool {
+		if details.Disks[i].UsagePercent != details.Disks[j].UsagePercent {
+			return details.Disks[i].UsagePercent > details.Disks[j].UsagePercent
+		}
+		return details.Disks[i].Path < details.Disks[j].Path
+	})
+	if root, ok := disks["/"]; ok {
+		details.Disk = &root
+	} else {
+		appLogger.FromContext(ctx).Debug("No root disk data found for host_id: %s", hostID)
 	}
 
 	// --- Query for Process Metrics ---
-	// --- Query for Process Metrics (Username field excluded for testing) ---
-	processMap := make(map[string]*models.ProcessDetail) // Pointer to modify in place
-
-	// Query 1: Get mem_percent and base process info (pid, name)
-	processQuery_mem_and_tags := fmt.Sprintf(`
-		targetFields = ["mem_percent"] 
+	// All numeric process fields in one query. Each process (or process name,
+	// see processPoints) is one point per report, so its fields share _time and
+	// pivot to one row. The window covers processLookback (agents may report
+	// processes only every Nth cycle); only the newest report's processes are
+	// kept below. Points are tagged by pid (older servers), by name only
+	// (aggregated) or by name and instance; the missing tags are filled in so
+	// all three group and pivot alike. The fields mix floats, integers and
+	// unsigned integers, so they are pivoted straight from the per-field
+	// tables: pivot gives each field its own column, while grouping the
+	// fields into one table first would put their values in one _value
+	// column and fail with a schema collision.
+	processQuery := fmt.Sprintf(`
+		targetFields = ["cpu_percent", "mem_percent", "create_time", "age_seconds", "read_bytes", "write_bytes", "read_bytes_per_sec", "write_bytes_per_sec", "instance_count", "process_id"]
 		from(bucket: "%s")
 			|> range(start: -%s)
-			|> filter(fn: (r) => r._measurement == "process_metrics" and r.host_id == "%s" and contains(value: r._field, set: targetFields))
-			|> group(columns: ["host_id", "pid", "name"]) 
-			|> last() 
-			|> pivot(rowKey:["_time", "host_id", "pid", "name"], columnKey: ["_field"], valueColumn: "_value")
-	`, r.bucket, defaultLookbackWindow, hostID)
-
-	appLogger.Debug("GetHostDetails Process Query (Mem & Tags) for host %s:\n%s", hostID, processQuery_mem_and_tags)
-	memResults, memErr := r.queryAPI.Query(ctx, processQuery_mem_and_tags)
-	if memErr != nil {
-		appLogger.Error("InfluxDB query failed for GetHostDetails (processes mem_and_tags) for host %s: %v", hostID, memErr)
+			|> filter(fn: (r) => r._measurement == "process_metrics" and r.host_id == %s and contains(value: r._field, set: targetFields))
+			|> map(fn: (r) => ({r with pid: if exists r.pid then r.pid else "", instance: if exists r.instance then r.instance else ""}))
+			|> group(columns: ["host_id", "pid", "name", "instance", "_field"])
+			|> last()
+			|> pivot(rowKey:["_time", "host_id", "pid", "name", "instance"], columnKey: ["_field"], valueColumn: "_value")
+	`, r.bucket, r.processLookback, fluxString(hostID))
+
+	appLogger.FromContext(ctx).Debug("GetHostDetails Process Query for host %s:\n%s", hostID, processQuery)
+	finalProcesses := []models.ProcessDetail{}
+	var newestReport time.Time
+	aggregated := false // the newest report's processes are summed by name
+	procResults, procErr := r.query(ctx, processQuery)
+	if procErr != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostDetails (processes) for host %s: %v", hostID, procErr)
 	} else {
-		for memResults.Next() {
-			pRec := memResults.Record()
-			getPF := func(key string) float64 { /* ... same as before ... */
+		defer procResults.Close()
+		for procResults.Next() {
+			pRec := procResults.Record()
+			switch {
+			case pRec.Time().Before(newestReport):
+				continue // process from an older report, exited since
+			case pRec.Time().After(newestReport):
+				newestReport = pRec.Time()
+				finalProcesses = finalProcesses[:0]
+				aggregated = false
+			}
+			getPF := func(key string) float64 {
 				val, ok := pRec.ValueByKey(key).(float64)
 				if !ok {
-					appLogger.Warn("[MemQuery] Field '%s' expected float64, got %T for process PID '%s', Name '%s'", key, pRec.ValueByKey(key), pRec.ValueByKey("pid"), pRec.ValueByKey("name"))
-					return 0.0
+					return 0.0 // field not reported for this process in the window
+				}
+				return val
+			}
+			// create_time / age_seconds are integer fields; older points won't have them
+			getPI := func(key string) int64 {
+				val, ok := pRec.ValueByKey(key).(int64)
+				if !ok {
+					return 0
+				}
+				return val
+			}
+			// I/O counters are unsigned
+			getPU := func(key string) uint64 {
+				val, ok := pRec.ValueByKey(key).(uint64)
+				if !ok {
+					return 0
 				}
 				return val
 			}
@@ -354,96 +685,84 @@ func (r *InfluxDBReader) GetHostDetails(ctx context.Context, hostID string) (*mo
 			pidStr, _ := pRec.ValueByKey("pid").(string)
 			nameStr, _ := pRec.ValueByKey("name").(string)
 			var pidVal int32
-			_, scanErr := fmt.Sscan(pidStr, &pidVal)
-			if scanErr != nil { /* ... log error ... */
+			instances := 1
+			switch {
+			case pidStr != "":
+				if _, scanErr := fmt.Sscan(pidStr, &pidVal); scanErr != nil {
+					appLogger.FromContext(ctx).Warn("Unparseable pid tag %q for process %s on host %s", pidStr, nameStr, hostID)
+				}
+			case getPI("instance_count") > 0:
+				instances = int(getPI("instance_count")) // aggregated by name, no PID
+				aggregated = true
+			default:
+				pidVal = int32(getPI("process_id"))
 			}
 
-			processKey := fmt.Sprintf("%s_%s", pidStr, nameStr) // Unique key for the map
-			procDetail := &models.ProcessDetail{
+			procDetail := models.ProcessDetail{
 				PID:           pidVal,
 				Name:          nameStr,
+				Instances:     instances,
+				CPUPercent:    getPF("cpu_percent"),
 				MemoryPercent: float32(getPF("mem_percent")),
-				CPUPercent:    0, // Default, will be updated by CPU query
+				AgeSeconds:    getPI("age_seconds"),
 				// Username: "", // If you bring it back
-			}
-			processMap[processKey] = procDetail
-		}
-		if memResults.Err() != nil {
-			appLogger.Error("Error processing process mem_and_tags results for host %s: %v", hostID, memResults.Err())
-		}
-	}
-
-	// Query 2: Get cpu_percent
-	processQuery_cpu := fmt.Sprintf(`
-		targetFields = ["cpu_percent"]
-		from(bucket: "%s")
-			|> range(start: -%s)
-			|> filter(fn: (r) => r._measurement == "process_metrics" and r.host_id == "%s" and contains(value: r._field, set: targetFields))
-			|> group(columns: ["host_id", "pid", "name"])
-			|> last()
-			|> pivot(rowKey:["_time", "host_id", "pid", "name"], columnKey: ["_field"], valueColumn: "_value")
-	`, r.bucket, defaultLookbackWindow, hostID)
 
-	appLogger.Debug("GetHostDetails Process Query (CPU) for host %s:\n%s", hostID, processQuery_cpu)
-	cpuResults, cpuErr := r.queryAPI.Query(ctx, processQuery_cpu)
-	if cpuErr != nil {
-		appLogger.Error("InfluxDB query failed for GetHostDetails (processes cpu) for host %s: %v", hostID, cpuErr)
-	} else {
-		for cpuResults.Next() {
-			pRec := cpuResults.Record()
-			getPF := func(key string) float64 { /* ... same as before ... */
-				val, ok := pRec.ValueByKey(key).(float64)
-				if !ok {
-					appLogger.Warn("[CPUQuery] Field '%s' expected float64, got %T for process PID '%s', Name '%s'", key, pRec.ValueByKey(key), pRec.ValueByKey("pid"), pRec.ValueByKey("name"))
-					return 0.0
-				}
-				return val
+				ReadBytes:        getPU("read_bytes"),
+				WriteBytes:       getPU("write_bytes"),
+				ReadBytesPerSec:  getPF("read_bytes_per_sec"),
+				WriteBytesPerSec: getPF("write_bytes_per_sec"),
 			}
-
-			pidStr, _ := pRec.ValueByKey("pid").(string)
-			nameStr, _ := pRec.ValueByKey("name").(string)
-
-			processKey := fmt.Sprintf("%s_%s", pidStr, nameStr)
-			if procDetail, exists := processMap[processKey]; exists {
-				procDetail.CPUPercent = getPF("cpu_percent")
-			} else {
-				// This case means a process had CPU usage but no memory usage reported in the first query
-				// or there's a timing mismatch. You might want to create a new entry or log it.
-				appLogger.Warn("Found CPU data for process PID '%s', Name '%s' but no prior mem data. Creating new entry.", pidStr, nameStr)
-				var pidVal int32 // Need to parse pidStr again if creating new
-				_, scanErr := fmt.Sscan(pidStr, &pidVal)
-				if scanErr != nil { /* ... log error ... */
-				}
-
-				newProcDetail := &models.ProcessDetail{
-					PID:           pidVal,
-					Name:          nameStr,
-					CPUPercent:    getPF("cpu_percent"),
-					MemoryPercent: 0, // No memory data from first query
-				}
-				processMap[processKey] = newProcDetail
+			if createTimeMs := getPI("create_time"); createTimeMs > 0 {
+				procDetail.StartedAt = time.UnixMilli(createTimeMs).UTC()
+				// Age relative to now rather than to the (slightly older) collection time
+				procDetail.AgeSeconds = int64(time.Since(procDetail.StartedAt) / time.Second)
 			}
+			finalProcesses = append(finalProcesses, procDetail)
 		}
-		if cpuResults.Err() != nil {
-			appLogger.Error("Error processing process cpu results for host %s: %v", hostID, cpuResults.Err())
+		if procResults.Err() != nil {
+			appLogger.FromContext(ctx).Error("Error processing process results for host %s: %v", hostID, procResults.Err())
 		}
 	}
 
-	// Convert map to slice for the final details struct
-	var finalProcesses []models.ProcessDetail
-	for _, procDetail := range processMap {
-		finalProcesses = append(finalProcesses, *procDetail)
-	}
-	// Optionally sort finalProcesses, e.g., by PID or Name
 	sort.Slice(finalProcesses, func(i, j int) bool {
-		return finalProcesses[i].PID < finalProcesses[j].PID
+		if finalProcesses[i].PID != finalProcesses[j].PID {
+			return finalProcesses[i].PID < finalProcesses[j].PID
+		}
+		return finalProcesses[i].Name < finalProcesses[j].Name
 	})
 	details.Processes = finalProcesses
+	details.ProcessMode = "list"
+	if aggregated {
+		details.ProcessMode = "aggregated"
+	}
+
+	// Agents in summary mode send a process_summary point instead; whichever
+	// form came last is shown (an agent may have switched within the lookback)
+	summary, err := r.latestProcessSummary(ctx, hostID)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostDetails (process summary) for host %s: %v", hostID, err)
+	} else if summary != nil && summary.Time.After(newestReport) {
+		details.Processes = []models.ProcessDetail{}
+		details.ProcessMode = "summary"
+		details.ProcessSummary = summary
+	}
+
+	// systemd units of agents with MONITOR_SERVICES
+	details.Services, err = r.hostServices(ctx, hostID)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostDetails (services) for host %s: %v", hostID, err)
+		details.Services = []models.ServiceDetail{}
+	}
 
 	// Determine status
-	if time.Since(details.LastSeen) <= activeHostLookback+(5*time.Second) {
-		details.Status = "online"
-		if details.CPUUsage > 85 || details.RAMUsage > 85 { // Add disk warning later
+	if time.Since(details.LastSeen) <= r.onlineWithin {
+		var disk, inodes *float64
+		if details.Disk != nil {
+			disk, inodes = &details.Disk.UsagePercent, &details.Disk.InodesUsagePercent
+		}
+		details.RecentlyRebooted = r.recentlyRebooted(details.UptimeSeconds)
+		details.Status = r.usageStatus(details.CPUUsage, details.RAMUsage, disk, inodes)
+		if details.Status == "online" && details.RecentlyRebooted {
 			details.Status = "warning"
 		}
 	} else {
@@ -454,72 +773,251 @@ func (r *InfluxDBReader) GetHostDetails(ctx context.Context, hostID string) (*mo
 }
 
 // GetHostMetricHistory fetches time-series data for a specific metric of a host.
-func (r *InfluxDBReader) GetHostMetricHistory(ctx context.Context, hostID, metricField string, rangeStart time.Duration, aggregateInterval time.Duration) ([]models.MetricPoint, error) {
+// The window is absolute; relative ranges are resolved against now by the caller.
+func (r *InfluxDBReader) GetHostMetricHistory(ctx context.Context, hostID, metricField string, start, end time.Time, aggregateInterval time.Duration) ([]models.MetricPoint, error) {
+	var points []models.MetricPoint
+	err := r.StreamHostMetricHistory(ctx, hostID, metricField, start, end, aggregateInterval, func(p models.MetricPoint) error {
+		points = append(points, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// historyBucket returns the bucket a metric history window of span is read
+// from and the interval of its points: the cold bucket for windows longer
+// than coldBucketAfter, the raw bucket otherwise or when there is none.
+func (r *InfluxDBReader) historyBucket(span time.Duration) (string, time.Duration) {
+	if r.coldBucket != "" && span > r.coldBucketAfter {
+		return r.coldBucket, r.coldBucketResolution
+	}
+	return r.bucket, rawSampleInterval
+}
+
+// StreamHostMetricHistory is GetHostMetricHistory without buffering: emit is
+// called for every point as it is read from the result cursor. An error from
+// emit stops the stream and is returned.
+func (r *InfluxDBReader) StreamHostMetricHistory(ctx context.Context, hostID, metricField string, start, end time.Time, aggregateInterval time.Duration, emit func(models.MetricPoint) error) error {
 	// Validate metricField to prevent injection and ensure it's a known numeric field
-	validNumericFields := map[string]bool{
-		"cpu_usage_percent":      true,
-		"mem_usage_percent":      true,
-		"net_upload_bytes_sec":   true,
-		"net_download_bytes_sec": true,
-		// Add disk usage later if needed, requires specifying path
+	if !HistoryFields[metricField] {
+		return fmt.Errorf("invalid or non-numeric metric field for history: %s", metricField)
 	}
-	if !validNumericFields[metricField] {
-		return nil, fmt.Errorf("invalid or non-numeric metric field for history: %s", metricField)
+	bucket, resolution := r.historyBucket(end.Sub(start))
+	if err := r.checkQueryCost("GetHostMetricHistory", queryEstimate{Hosts: 1, Fields: 1, Span: end.Sub(start), Resolution: resolution}); err != nil {
+		return err
 	}
 
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	query := fmt.Sprintf(`
 		from(bucket: "%s")
-			|> range(start: -%s)
-			|> filter(fn: (r) => r._measurement == "system_metrics" and r.host_id == "%s" and r._field == "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and r.host_id == %s and r._field == "%s")
 			|> aggregateWindow(every: %s, fn: mean, createEmpty: false) // Use mean for aggregation
 			|> yield(name: "mean")
-	`, r.bucket, rangeStart.String(), hostID, metricField, aggregateInterval.String())
+	`, bucket, fluxTime(start), fluxTime(end), fluxString(hostID), metricField, aggregateInterval.String())
 
-	appLogger.Debug("GetHostMetricHistory Query for host %s, metric %s:\n%s", hostID, metricField, query)
-	results, err := r.queryAPI.Query(ctx, query)
+	appLogger.FromContext(ctx).Debug("GetHostMetricHistory Query for host %s, metric %s (bucket %s):\n%s", hostID, metricField, bucket, query)
+	results, err := r.query(ctx, query)
 	if err != nil {
-		appLogger.Error("InfluxDB query failed for GetHostMetricHistory (host %s, metric %s): %v", hostID, metricField, err)
-		return nil, fmt.Errorf("query influxdb for host metric history: %w", err)
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostMetricHistory (host %s, metric %s): %v", hostID, metricField, err)
+		return fmt.Errorf("query influxdb for host metric history: %w", err)
 	}
+	defer results.Close()
 
-	var points []models.MetricPoint
 	for results.Next() {
-		record := results.Record()
-		value, ok := record.Value().(float64) // Assuming aggregated values are float64
+		point, ok := r.metricPoint(results.Record())
 		if !ok {
-			// Try int64 then cast, sometimes it might be integer if original data was integer and aggregateWindow didn't change type
-			ival, iok := record.Value().(int64)
-			if iok {
-				value = float64(ival)
-				ok = true
-			} else {
-				appLogger.Warn("Unexpected value type for metric %s, host %s: %T, value: %v", metricField, hostID, record.Value(), record.Value())
-				continue // Skip if not a float or convertible int
-			}
+			appLogger.FromContext(ctx).Warn("Unexpected value type for metric %s, host %s: %T, value: %v", metricField, hostID, results.Record().Value(), results.Record().Value())
+			continue // Skip if not a float or convertible int
+		}
+		if err := emit(point); err != nil {
+			return err
 		}
-
-		points = append(points, models.MetricPoint{
-			// Format timestamp as "HH:MM" as in your mock data
-			Timestamp: record.Time().In(time.Local).Format("15:04"), // Use local time for display
-			Value:     value,
-		})
 	}
 
 	if results.Err() != nil {
-		appLogger.Error("Error processing results for GetHostMetricHistory (host %s, metric %s): %v", hostID, metricField, results.Err())
-		return nil, fmt.Errorf("process query results for host metric history: %w", results.Err())
+		appLogger.FromContext(ctx).Error("Error processing results for GetHostMetricHistory (host %s, metric %s): %v", hostID, metricField, results.Err())
+		return fmt.Errorf("process query results for host metric history: %w", results.Err())
 	}
 
 	// Data from InfluxDB is typically time-sorted, but ensure if needed
 	// sort.Slice(points, func(i, j int) bool { return points[i].Timestamp < points[j].Timestamp })
 
-	return points, nil
+	return nil
+}
+
+// metricPoint converts an aggregated history record. ok is false for values
+// that are neither float64 nor int64.
+func (r *InfluxDBReader) metricPoint(record *query.FluxRecord) (models.MetricPoint, bool) {
+	value, ok := record.Value().(float64) // Assuming aggregated values are float64
+	if !ok {
+		// Try int64 then cast, sometimes it might be integer if original data was integer and aggregateWindow didn't change type
+		ival, iok := record.Value().(int64)
+		if !iok {
+			return models.MetricPoint{}, false
+		}
+		value = float64(ival)
+	}
+	location := r.displayLocation
+	if location == nil {
+		location = time.Local
+	}
+	return models.MetricPoint{
+		// Format timestamp as "HH:MM" as in your mock data
+		Timestamp: record.Time().In(location).Format("15:04"),
+		Time:      record.Time().UTC(),
+		Value:     value,
+	}, true
+}
+
+// CountHostsByAgentVersion returns agent_version -> number of hosts, using each
+// host's latest system_metrics point in [start, end]. Agents without a version
+// are counted as "unknown".
+func (r *InfluxDBReader) CountHostsByAgentVersion(ctx context.Context, start, end time.Time) (map[string]int, error) {
+	estimate := queryEstimate{Hosts: int(r.knownHosts.Load()), Fields: 1, Span: end.Sub(start)}
+	if err := r.checkQueryCost("CountHostsByAgentVersion", estimate); err != nil {
+		return nil, err
+	}
+
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// One field is enough to see the tags of each host's latest point
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and r._field == "cpu_usage_percent")
+			|> group(columns: ["host_id"])
+			|> last()
+			|> group()
+			|> keep(columns: ["host_id", "agent_version"])
+	`, r.bucket, fluxTime(start), fluxTime(end))
+
+	appLogger.FromContext(ctx).Debug("CountHostsByAgentVersion Query:\n%s", query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for CountHostsByAgentVersion: %v", err)
+		return nil, fmt.Errorf("query influxdb for agent versions: %w", err)
+	}
+	defer results.Close()
+
+	counts := make(map[string]int)
+	for results.Next() {
+		version, _ := results.Record().ValueByKey("agent_version").(string)
+		if version == "" {
+			version = "unknown"
+		}
+		counts[version]++
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for CountHostsByAgentVersion: %v", results.Err())
+		return nil, fmt.Errorf("process query results for agent versions: %w", results.Err())
+	}
+	return counts, nil
 }
 
-// Close cleans up resources.
-func (r *InfluxDBReader) Close() {
-	if r.client != nil {
-		r.client.Close()
-		appLogger.Info("InfluxDBReader client closed.")
+// GetRootDiskUsageSeries returns every host's root disk used_gb over the last
+// lookback as means per 'every' window, for the alert engine's disk-full
+// forecasts. It isn't subject to the cost budget: the engine caches the
+// result and refreshes it every few minutes, not per request.
+func (r *InfluxDBReader) GetRootDiskUsageSeries(ctx context.Context, lookback, every time.Duration) ([]models.DiskUsageSeries, error) {
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
+
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "disk_metrics" and r.path == "/" and (r._field == "used_gb" or r._field == "total_gb"))
+			|> group(columns: ["host_id", "hostname", "_field"])
+			|> aggregateWindow(every: %s, fn: mean, createEmpty: false)
+			|> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+			|> group(columns: ["host_id"])
+			|> sort(columns: ["_time"])
+	`, r.bucket, lookback.String(), every.String())
+
+	appLogger.FromContext(ctx).Debug("GetRootDiskUsageSeries Query:\n%s", query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetRootDiskUsageSeries: %v", err)
+		return nil, fmt.Errorf("query influxdb for disk usage series: %w", err)
+	}
+	defer results.Close()
+
+	var order []string
+	byHost := make(map[string]*models.DiskUsageSeries)
+	for results.Next() {
+		record := results.Record()
+		hostID, _ := record.ValueByKey("host_id").(string)
+		used, usedOK := record.ValueByKey("used_gb").(float64)
+		if hostID == "" || !usedOK {
+			continue
+		}
+		series, ok := byHost[hostID]
+		if !ok {
+			hostname, _ := record.ValueByKey("hostname").(string)
+			series = &models.DiskUsageSeries{HostID: hostID, Hostname: hostname}
+			byHost[hostID] = series
+			order = append(order, hostID)
+		}
+		if total, ok := record.ValueByKey("total_gb").(float64); ok {
+			series.TotalGB = total
+		}
+		series.Points = append(series.Points, models.DiskUsagePoint{Time: record.Time(), UsedGB: used})
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetRootDiskUsageSeries: %v", results.Err())
+		return nil, fmt.Errorf("process query results for disk usage series: %w", results.Err())
+	}
+
+	all := make([]models.DiskUsageSeries, 0, len(order))
+	for _, hostID := range order {
+		all = append(all, *byHost[hostID])
+	}
+	return all, nil
+}
+
+// usageStatus is an online host's status from its usage: critical, warning
+// or online. disk and inodes are the root disk's, nil if not reported.
+func (r *InfluxDBReader) usageStatus(cpu, ram float64, disk, inodes *float64) string {
+	t := r.status.Load()
+	switch {
+	case cpu >= t.UsageCritical || ram >= t.UsageCritical || atLeast(disk, t.DiskCritical) || atLeast(inodes, t.DiskCritical):
+		return "critical"
+	case cpu >= t.UsageWarning || ram >= t.UsageWarning || atLeast(disk, t.DiskWarning) || atLeast(inodes, t.DiskWarning):
+		return "warning"
+	}
+	return "online"
+}
+
+// atLeast reports whether an optional percentage is reported and at limit
+// or over it.
+func atLeast(percent *float64, limit float64) bool {
+	return percent != nil && *percent >= limit
+}
+
+// fluxTime formats t as a Flux time literal.
+func fluxTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// fluxStringEscaper escapes what ends or interpolates a Flux string literal.
+var fluxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `${`, `\${`)
+
+// fluxString formats s as a Flux string literal. Host IDs come from agents
+// and request paths, so they are quoted here rather than trusted.
+func fluxString(s string) string {
+	return `"` + fluxStringEscaper.Replace(s) + `"`
 }
@@ -0,0 +1,286 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+// newTestReader returns a reader on a fake InfluxDB. configure, if not nil,
+// adjusts the settings first.
+func newTestReader(t *testing.T, configure func(*config.InfluxDBConfig)) (*InfluxDBReader, *influxtest.Server) {
+	t.Helper()
+	server := influxtest.NewServer(t)
+	cfg := server.Config()
+	if configure != nil {
+		configure(&cfg)
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return NewInfluxDBReader(client, cfg), server
+}
+
+func TestQuerySlotsBoundInFlightReads(t *testing.T) {
+	const limit = 3
+	reader, server := newTestReader(t, func(cfg *config.InfluxDBConfig) {
+		cfg.MaxConcurrentQueries = limit
+		cfg.OverviewCacheMaxAge = 0 // every call queries
+	})
+
+	var inFlight, peak atomic.Int32
+	server.RespondFunc(`yield(name: "overview")`, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := reader.GetHostOverviewList(context.Background()); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("GetHostOverviewList: %v", err)
+	}
+
+	if got := peak.Load(); got > limit {
+		t.Errorf("peak in-flight queries = %d, want at most %d", got, limit)
+	}
+	if got := len(server.Queries()); got != 20 {
+		t.Errorf("queries = %d, want 20", got)
+	}
+}
+
+func TestQuerySlotWaitTimesOut(t *testing.T) {
+	reader, server := newTestReader(t, func(cfg *config.InfluxDBConfig) {
+		cfg.MaxConcurrentQueries = 1
+		cfg.QueryQueueTimeout = 50 * time.Millisecond
+		cfg.OverviewCacheMaxAge = 0
+	})
+	unblock := make(chan struct{})
+	server.RespondFunc(`yield(name: "overview")`, func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := reader.GetHostOverviewList(context.Background())
+		done <- err
+	}()
+	// wait until the first call holds the only slot
+	for len(server.Queries()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := reader.GetHostOverviewList(context.Background())
+	if !errors.Is(err, ErrReaderBusy) {
+		t.Errorf("second call error = %v, want ErrReaderBusy", err)
+	}
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Errorf("first call: %v", err)
+	}
+}
+
+func TestProcessLookbackCoversEveryNthCycle(t *testing.T) {
+	const detailsLookback = 15 * time.Second
+	for _, tt := range []struct {
+		every int
+		want  time.Duration
+	}{
+		{0, detailsLookback}, // unset, agents send every cycle
+		{1, detailsLookback},
+		{6, 40 * time.Second}, // 6 intervals of 5s plus the 10s slack
+		{12, 70 * time.Second},
+	} {
+		got := processLookback(tt.every, detailsLookback)
+		if got != tt.want {
+			t.Errorf("processLookback(%d, %v) = %v, want %v", tt.every, detailsLookback, got, tt.want)
+		}
+		if tt.every > 0 && got < time.Duration(tt.every)*rawSampleInterval {
+			t.Errorf("processLookback(%d) = %v misses a process sample sent %v ago", tt.every, got, time.Duration(tt.every)*rawSampleInterval)
+		}
+	}
+
+	// the process query uses the widened window
+	reader, server := newTestReader(t, func(cfg *config.InfluxDBConfig) { cfg.ProcessSampleEvery = 12 })
+	respondHost(server, map[string]any{"host_id": "host-1", "hostname": "host-1"})
+	if _, err := reader.GetHostDetails(context.Background(), "host-1"); err != nil {
+		t.Fatalf("GetHostDetails: %v", err)
+	}
+	var processQuery string
+	for _, q := range server.Queries() {
+		if strings.Contains(q, `"process_metrics"`) {
+			processQuery = q
+		}
+	}
+	if !strings.Contains(processQuery, "range(start: -1m10s)") {
+		t.Errorf("process query doesn't look back 1m10s:\n%s", processQuery)
+	}
+}
+
+func TestMetricHistoryLabelsUseTheDisplayTimeZone(t *testing.T) {
+	kathmandu, err := time.LoadLocation("Asia/Kathmandu") // UTC+5:45, unlike any whole-hour local zone
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	at := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	history := influxtest.NewTable("_time:time", "_value:double").Row(at, 42.0).Row(at.Add(30*time.Minute), 43.0)
+
+	for _, tt := range []struct {
+		location *time.Location
+		want     []string
+	}{
+		{kathmandu, []string{"15:45", "16:15"}},
+		{time.UTC, []string{"10:00", "10:30"}},
+	} {
+		reader, server := newTestReader(t, func(cfg *config.InfluxDBConfig) { cfg.DisplayLocation = tt.location })
+		server.Respond(`yield(name: "mean")`, history)
+
+		points, err := reader.GetHostMetricHistory(context.Background(), "host-1", "cpu_usage_percent", at.Add(-time.Hour), at.Add(time.Hour), time.Minute)
+		if err != nil {
+			t.Fatalf("GetHostMetricHistory: %v", err)
+		}
+		if len(points) != len(tt.want) {
+			t.Fatalf("%s: %d points, want %d", tt.location, len(points), len(tt.want))
+		}
+		for i, point := range points {
+			if point.Timestamp != tt.want[i] {
+				t.Errorf("%s: label %q, want %q", tt.location, point.Timestamp, tt.want[i])
+			}
+			// the machine-readable time is UTC whatever the display zone
+			if point.Time.Location() != time.UTC || !point.Time.Equal(at.Add(time.Duration(i)*30*time.Minute)) {
+				t.Errorf("%s: time %v, want %v in UTC", tt.location, point.Time, at.Add(time.Duration(i)*30*time.Minute))
+			}
+		}
+	}
+}
+
+func TestLongHistoryTargetsTheColdBucket(t *testing.T) {
+	const cold = "system_stats_downsampled"
+	end := time.Now()
+	tests := []struct {
+		name       string
+		coldBucket string
+		span       time.Duration
+		want       string
+	}{
+		{"7 days", cold, 7 * 24 * time.Hour, cold},
+		{"just past the crossover", cold, 24*time.Hour + time.Minute, cold},
+		{"at the crossover", cold, 24 * time.Hour, influxtest.Bucket},
+		{"6 hours", cold, 6 * time.Hour, influxtest.Bucket},
+		{"7 days without a cold bucket", "", 7 * 24 * time.Hour, influxtest.Bucket},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, server := newTestReader(t, func(cfg *config.InfluxDBConfig) { cfg.ColdBucket = tt.coldBucket })
+			if _, err := reader.GetHostMetricHistory(context.Background(), "host-1", "cpu_usage_percent", end.Add(-tt.span), end, time.Hour); err != nil {
+				t.Fatalf("GetHostMetricHistory: %v", err)
+			}
+			queries := server.Queries()
+			if len(queries) != 1 || !strings.Contains(queries[0], `from(bucket: "`+tt.want+`")`) {
+				t.Errorf("queries = %q, want one from %s", queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestFluxString(t *testing.T) {
+	for in, want := range map[string]string{
+		"host-1":                `"host-1"`,
+		`x" or r.host_id != "`:  `"x\" or r.host_id != \""`,
+		`C:\`:                   `"C:\\"`,
+		"${r.host_id}":          `"\${r.host_id}"`,
+		`a\" or true or "`:      `"a\\\" or true or \""`,
+		"web 1 (rack $4) {old}": `"web 1 (rack $4) {old}"`,
+	} {
+		if got := fluxString(in); got != want {
+			t.Errorf("fluxString(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+// hostile host IDs, as they could arrive in a request path
+var hostileHostIDs = []string{`x" or r.host_id != "`, `x\`, "${r.host_id}"}
+
+func TestHostIDsAreQuotedInFlux(t *testing.T) {
+	end := time.Now()
+	start := end.Add(-time.Hour)
+	for name, read := range map[string]func(r *InfluxDBReader, hostID string) error{
+		"GetHostDetails": func(r *InfluxDBReader, hostID string) error {
+			_, err := r.GetHostDetails(context.Background(), hostID)
+			return err
+		},
+		"GetHostMetricHistory": func(r *InfluxDBReader, hostID string) error {
+			_, err := r.GetHostMetricHistory(context.Background(), hostID, "cpu_usage_percent", start, end, time.Minute)
+			return err
+		},
+		"GetDiskMetricHistory": func(r *InfluxDBReader, hostID string) error {
+			_, err := r.GetDiskMetricHistory(context.Background(), hostID, "/", "usage_percent", start, end, time.Minute)
+			return err
+		},
+		"GetHostLatestField": func(r *InfluxDBReader, hostID string) error {
+			_, err := r.GetHostLatestField(context.Background(), hostID, "cpu_usage_percent")
+			return err
+		},
+		"GetHostInterfaces": func(r *InfluxDBReader, hostID string) error {
+			_, err := r.GetHostInterfaces(context.Background(), hostID)
+			return err
+		},
+		"latestProcessSummary": func(r *InfluxDBReader, hostID string) error {
+			_, err := r.latestProcessSummary(context.Background(), hostID)
+			return err
+		},
+		"GetHostMetricsHistory": func(r *InfluxDBReader, hostID string) error {
+			_, err := r.GetHostMetricsHistory(context.Background(), hostID, []string{"cpu_usage_percent", "mem_usage_percent"}, start, end, time.Minute)
+			return err
+		},
+		"GetHostMetricDelta": func(r *InfluxDBReader, hostID string) error {
+			_, err := r.GetHostMetricDelta(context.Background(), hostID, "cpu_usage_percent", time.Hour)
+			return err
+		},
+		"hostServices": func(r *InfluxDBReader, hostID string) error {
+			_, err := r.hostServices(context.Background(), hostID)
+			return err
+		},
+	} {
+		for _, hostID := range hostileHostIDs {
+			reader, server := newTestReader(t, nil)
+			_ = read(reader, hostID) // the fake has no data, only the queries matter
+			queries := server.Queries()
+			if len(queries) == 0 {
+				t.Fatalf("%s(%q) sent no query", name, hostID)
+			}
+			for _, query := range queries {
+				if strings.Contains(query, "r.host_id ==") && !strings.Contains(query, "r.host_id == "+fluxString(hostID)) {
+					t.Errorf("%s(%q): host ID not quoted:\n%s", name, hostID, query)
+				}
+			}
+		}
+	}
+}
@@ -2,57 +2,57 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
 	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
-	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
-	"github.com/influxdata/influxdb-client-go/v2/api"
 	"github.com/influxdata/influxdb-client-go/v2/api/write"
 )
 
 // handles writing data to InfluxDB
 type InfluxDBWriter struct {
-	client   influxdb2.Client
-	writeAPI api.WriteAPIBlocking
-	org      string
-	bucket   string
+	client           *Client
+	bucket           string
+	writeConcurrency int    // points of one payload written at once, see writePoints
+	processSeries    string // PROCESS_SERIES, see processPoints
 }
 
-// Create a new InfluxDBWriter
-func NewInfluxDBWriter(cfg config.InfluxDBConfig) (*InfluxDBWriter, error) {
-	client := influxdb2.NewClient(cfg.URL, cfg.Token)
-
-	// Check connectivity (optional, but good for startup)
-	// Use a timeout for the health check
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	health, err := client.Health(ctx)
-	if err != nil {
-		appLogger.Error("InfluxDB health check failed: %v", err)
-		return nil, fmt.Errorf("influxdb health check failed: %w", err)
-	}
-	if health.Status != "pass" {
-		appLogger.Error("InfluxDB is not healthy: status %s, message %s", health.Status, *health.Message)
-		return nil, fmt.Errorf("influxdb not healthy: status %s", health.Status)
+// Create a new InfluxDBWriter on the shared client
+func NewInfluxDBWriter(client *Client, cfg config.InfluxDBConfig) *InfluxDBWriter {
+	return &InfluxDBWriter{
+		client:           client,
+		bucket:           cfg.Bucket,
+		writeConcurrency: max(cfg.WriteConcurrency, 1),
+		processSeries:    cfg.ProcessSeries,
 	}
-	appLogger.Info("Successfully connected to InfluxDB at %s", cfg.URL)
+}
 
-	writeAPI := client.WriteAPIBlocking(cfg.Org, cfg.Bucket)
+// writePoint writes one point to the writer's bucket.
+func (w *InfluxDBWriter) writePoint(ctx context.Context, p *write.Point) error {
+	return w.client.WritePoint(ctx, w.bucket, p)
+}
 
-	return &InfluxDBWriter{
-		client:   client,
-		writeAPI: writeAPI,
-		org:      cfg.Org,
-		bucket:   cfg.Bucket,
-	}, nil
+// readinessMeasurement only receives the startup test write; nothing reads it.
+const readinessMeasurement = "server_readiness_check"
+
+// CheckWrite writes a throwaway point to the bucket. Unlike the startup health
+// check this fails on a token without write access or a missing bucket.
+func (w *InfluxDBWriter) CheckWrite(ctx context.Context) error {
+	p := write.NewPoint(readinessMeasurement, nil, map[string]interface{}{"ok": true}, time.Now())
+	if err := w.writePoint(ctx, p); err != nil {
+		return fmt.Errorf("test write to bucket %s: %w", w.bucket, err)
+	}
+	return nil
 }
 
 // converts the client payload into InfluxDB points and writes them.
 func (w *InfluxDBWriter) WriteStats(ctx context.Context, payload *models.ClientPayload) error {
+	log := appLogger.FromContext(ctx) // carries request_id and host_id from the ingest handler
 
 	// --- Create common tags for all points from this payload ---
 	tags := map[string]string{
@@ -63,23 +63,77 @@ func (w *InfluxDBWriter) WriteStats(ctx context.Context, payload *models.ClientP
 	// --- Create point for general system, CPU, and Memory stats ---
 	measurement := "system_metrics"
 
+	// Only on system_metrics: one value per host at a time, so the extra
+	// series cardinality stays at hosts x versions seen during a rollout.
+	// Static labels (label_<name>) change as rarely.
+	systemTags := tags
+	labels := validLabels(log, payload.Labels)
+	if payload.AgentVersion != "" || len(labels) > 0 {
+		systemTags = make(map[string]string, len(tags)+len(labels)+1)
+		for k, v := range tags {
+			systemTags[k] = v
+		}
+		if payload.AgentVersion != "" {
+			systemTags["agent_version"] = payload.AgentVersion
+		}
+		for name, value := range labels {
+			systemTags[labelTagPrefix+name] = value
+		}
+	}
+
 	fields := map[string]interface{}{
-		"uptime_seconds":         payload.System.Uptime,
-		"os":                     payload.System.OS,
-		"os_version":             payload.System.OSVersion,
-		"kernel":                 payload.System.Kernel,
-		"kernel_arch":            payload.System.KernelVersion,
-		"cpu_model_name":         payload.CPU.ModelName, // String field
-		"cpu_cores":              payload.CPU.Cores,
-		"cpu_usage_percent":      payload.CPU.Usage,
-		"mem_total_gb":           payload.Memory.TotalGB,
-		"mem_used_gb":            payload.Memory.TotalGB - payload.Memory.FreeGB,
-		"mem_available_gb":       payload.Memory.FreeGB,
-		"mem_usage_percent":      payload.Memory.UsagePercent,
-		"net_bytes_sent_period":  payload.Network.BytesSentPeriod, // Assuming aggregate network stats
-		"net_bytes_recv_period":  payload.Network.BytesRecvPeriod,
-		"net_upload_bytes_sec":   payload.Network.UploadBytesPerSec,
-		"net_download_bytes_sec": payload.Network.DownloadBytesPerSec,
+		"uptime_seconds":       payload.System.Uptime, // the duration string (26h3m12s) despite the name, kept for existing queries
+		"os":                   payload.System.OS,
+		"os_version":           payload.System.OSVersion,
+		"kernel":               payload.System.Kernel,
+		"kernel_arch":          payload.System.KernelVersion,
+		"logged_in_users":      int64(payload.System.LoggedInUsers),
+		"cpu_model_name":       payload.CPU.ModelName, // String field
+		"cpu_cores":            payload.CPU.Cores,
+		"cpu_usage_percent":    payload.CPU.Usage,
+		"mem_total_gb":         payload.Memory.TotalGB,
+		"mem_used_gb":          payload.Memory.TotalGB - payload.Memory.FreeGB,
+		"mem_available_gb":     payload.Memory.FreeGB,
+		"mem_usage_percent":    payload.Memory.UsagePercent,
+		"net_bytes_sent_total": payload.Network.CumulativeBytesSent, // monotonic since boot
+		"net_bytes_recv_total": payload.Network.CumulativeBytesRecv,
+	}
+	// A new field name, uptime_seconds already holds strings and InfluxDB
+	// rejects a field changing type
+	if uptime, ok := payload.System.UptimeSecs(); ok {
+		fields["system_uptime_seconds"] = uptime
+	}
+	// received_at in epoch millis like create_time; the point's time stays collected_at
+	if lag, ok := payload.IngestLag(); ok {
+		fields["received_at"] = payload.ReceivedAt.UnixMilli()
+		fields["ingest_lag_seconds"] = lag.Seconds()
+	}
+	fields["process_cpu_convention"] = models.ProcessCPUPerCore
+	if payload.ProcessCPU != "" {
+		fields["process_cpu_convention"] = payload.ProcessCPU
+	}
+
+	// Agents skip rates after a counter reset or an implausible jump; leave a
+	// gap in the rate series rather than writing a fake 0
+	if !payload.Network.RatesSkipped {
+		fields["net_bytes_sent_period"] = payload.Network.BytesSentPeriod // Assuming aggregate network stats
+		fields["net_bytes_recv_period"] = payload.Network.BytesRecvPeriod
+		fields["net_upload_bytes_sec"] = payload.Network.UploadBytesPerSec
+		fields["net_download_bytes_sec"] = payload.Network.DownloadBytesPerSec
+	}
+
+	// Exact memory bytes as integer fields, only when the agent sends them
+	if payload.Memory.TotalBytes > 0 {
+		fields["mem_total_bytes"] = int64(payload.Memory.TotalBytes)
+		fields["mem_available_bytes"] = int64(payload.Memory.FreeBytes)
+		fields["mem_used_bytes"] = int64(payload.Memory.UsedBytes)
+	}
+
+	// CPU throttling, ARM Linux agents only
+	if throttle := payload.Throttle; throttle != nil {
+		fields["throttled"] = throttle.Throttled
+		fields["throttle_count"] = throttle.ThrottleCount
+		fields["throttle_flags"] = int64(throttle.Flags)
 	}
 
 	// Add network interface if available and not "all" or empty
@@ -88,14 +142,18 @@ func (w *InfluxDBWriter) WriteStats(ctx context.Context, payload *models.ClientP
 	}
 
 	// Create the point
-	p := write.NewPoint(measurement, tags, fields, payload.CollectedAt)
+	p := write.NewPoint(measurement, systemTags, fields, payload.CollectedAt)
 
 	// write the point
-	if err := w.writeAPI.WritePoint(ctx, p); err != nil {
-		appLogger.Error("Failed to write system_metrics point to InfluxDB for host %s: %v", payload.System.HostID, err)
+	if err := w.writePoint(ctx, p); err != nil {
+		log.Error("Failed to write system_metrics point to InfluxDB: %v", err)
 		return fmt.Errorf("influxdb write point error for system_metrics: %w", err)
 	}
-	appLogger.Debug("Successfully wrote system_metrics point for host %s at %s", payload.System.HostID, payload.CollectedAt)
+	log.Debug("Successfully wrote system_metrics point at %s", payload.CollectedAt)
+
+	// Disk, SMART, interface and process points don't depend on each other or on their
+	// order, they are collected here and written together by writePoints
+	var points []pointWrite
 
 	// --- Create separate points for each disk ---
 	diskMeasurement := "disk_metrics"
@@ -112,46 +170,159 @@ func (w *InfluxDBWriter) WriteStats(ctx context.Context, payload *models.ClientP
 			"free_gb":       disk.FreeGB,
 			"usage_percent": disk.UsagePercent,
 		}
+		if disk.InodesTotal > 0 { // not every filesystem has inodes
+			diskFields["inodes_total"] = int64(disk.InodesTotal)
+			diskFields["inodes_used"] = int64(disk.InodesUsed)
+			diskFields["inodes_usage_percent"] = disk.InodesUsagePercent
+		}
 		diskPoint := write.NewPoint(diskMeasurement, diskTags, diskFields, payload.CollectedAt)
-		if err := w.writeAPI.WritePoint(ctx, diskPoint); err != nil {
-			appLogger.Error("Failed to write disk_metrics point for host %s, disk %s: %v", payload.System.HostID, disk.Path, err)
-			// Continue to try writing other disk points
-		} else {
-			appLogger.Debug("Successfully wrote disk_metrics point for host %s, disk %s", payload.System.HostID, disk.Path)
+		points = append(points, pointWrite{diskPoint, "disk_metrics point for disk " + disk.Path})
+	}
+
+	// --- SMART health, one point per physical disk ---
+	for _, health := range payload.DiskHealth {
+		healthTags := make(map[string]string)
+		for k, v := range tags {
+			healthTags[k] = v
 		}
+		healthTags["device"] = health.Device
+		if health.Model != "" {
+			healthTags["model"] = health.Model
+		}
+
+		healthFields := map[string]interface{}{
+			"healthy":             health.Healthy,
+			"temperature_celsius": health.TemperatureCelsius,
+			"power_on_hours":      health.PowerOnHours,
+		}
+		healthPoint := write.NewPoint("disk_health_metrics", healthTags, healthFields, payload.CollectedAt)
+		points = append(points, pointWrite{healthPoint, "disk_health_metrics point for device " + health.Device})
 	}
 
-	// ----- HANDLING PROCESSES ------
-	processMeasurement := "process_metrics"
-	for _, proc := range payload.Processes {
-		processTags := make(map[string]string)
+	// --- systemd units, one point per unit ---
+	for _, service := range payload.Services {
+		serviceTags := make(map[string]string)
+		for k, v := range tags {
+			serviceTags[k] = v
+		}
+		serviceTags["unit"] = service.Unit
+
+		serviceFields := map[string]interface{}{
+			"load_state":   service.LoadState,
+			"active_state": service.ActiveState,
+			"sub_state":    service.SubState,
+			"active":       service.ActiveState == "active",
+		}
+		servicePoint := write.NewPoint("service_metrics", serviceTags, serviceFields, payload.CollectedAt)
+		points = append(points, pointWrite{servicePoint, "service_metrics point for unit " + service.Unit})
+	}
+
+	// --- Network interfaces, one point per interface ---
+	for _, iface := range payload.Interfaces {
+		ifaceTags := make(map[string]string)
 		for k, v := range tags {
-			processTags[k] = v
+			ifaceTags[k] = v
 		}
-		processTags["pid"] = strconv.Itoa(int(proc.PID))
-		processTags["name"] = proc.Name
+		ifaceTags["interface"] = iface.Name
+
+		ifaceFields := map[string]interface{}{
+			"up":         iface.Up,
+			"speed_mbps": iface.SpeedMbps,
+		}
+		if !iface.RatesSkipped {
+			ifaceFields["upload_bytes_sec"] = iface.UploadBytesPerSec
+			ifaceFields["download_bytes_sec"] = iface.DownloadBytesPerSec
+		}
+		ifacePoint := write.NewPoint("interface_metrics", ifaceTags, ifaceFields, payload.CollectedAt)
+		points = append(points, pointWrite{ifacePoint, "interface_metrics point for interface " + iface.Name})
+	}
 
-		processFields := map[string]interface{}{
-			"cpu_percent": proc.CPUPercent,
-			"mem_percent": proc.MemoryPercent,
-			"user":        proc.Username,
+	// ----- HANDLING PROCESSES ------
+	points = append(points, processPoints(tags, payload.Processes, w.processSeries, payload.CollectedAt)...)
+
+	// ----- PROCESS SUMMARY (agents in summary mode) ------
+	// One point per report without pid or name tags, so it adds one series
+	// per host however many processes come and go
+	if summary := payload.ProcessSummary; summary != nil {
+		summaryFields := map[string]interface{}{
+			"process_count":   summary.Count,
+			"top_cpu_percent": summary.TopCPUPercent,
 		}
-		processPoint := write.NewPoint(processMeasurement, processTags, processFields, payload.CollectedAt)
-		if err := w.writeAPI.WritePoint(ctx, processPoint); err != nil {
-			appLogger.Error("Failed to write process_metrics point for host %s, process %s (PID %d): %v", payload.System.HostID, proc.Name, proc.PID, err)
-			// Continue writing other processes
-		} else {
-			appLogger.Debug("Successfully wrote process_metrics point for host %s, process %s (PID %d)", payload.System.HostID, proc.Name, proc.PID)
+		for i, top := range summary.Top {
+			if i == ProcessSummaryMaxTop {
+				break
+			}
+			prefix := fmt.Sprintf("top_%d_", i+1)
+			summaryFields[prefix+"name"] = top.Name
+			summaryFields[prefix+"cpu_percent"] = top.CPUPercent
+			summaryFields[prefix+"mem_percent"] = top.MemoryPercent
 		}
+		summaryPoint := write.NewPoint("process_summary", tags, summaryFields, payload.CollectedAt)
+		points = append(points, pointWrite{summaryPoint, "process_summary point"})
 	}
 
+	// A failed point is logged and the others are still written; only the
+	// system_metrics point above fails the request
+	if failed, err := w.writePoints(ctx, points); err != nil {
+		log.Error("Failed to write %d of %d disk/process points: %v", failed, len(points), err)
+	} else if len(points) > 0 {
+		log.Debug("Successfully wrote %d disk/process points", len(points))
+	}
 	return nil
 }
 
-// Close ensures the InfluxDB client is closed gracefully.
-func (w *InfluxDBWriter) Close() {
-	if w.client != nil {
-		w.client.Close()
-		appLogger.Info("InfluxDB client closed.")
+// pointWrite is one independent point of a payload, described for logs.
+type pointWrite struct {
+	point *write.Point
+	what  string
+}
+
+// writePoints writes points with up to writeConcurrency writes in flight.
+// Every point is attempted; the failures are counted and the first few are
+// returned in one error.
+func (w *InfluxDBWriter) writePoints(ctx context.Context, points []pointWrite) (int, error) {
+	errs := make([]error, len(points))
+	workers := min(w.writeConcurrency, len(points))
+	if workers <= 1 {
+		for i, p := range points {
+			errs[i] = w.writePoint(ctx, p.point)
+		}
+	} else {
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					errs[i] = w.writePoint(ctx, points[i].point)
+				}
+			}()
+		}
+		for i := range points {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	}
+
+	// With InfluxDB down every point fails the same way, name only a few
+	const maxListed = 5
+	var failed []string
+	count := 0
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if count++; count <= maxListed {
+			failed = append(failed, fmt.Sprintf("%s: %v", points[i].what, err))
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	if count > maxListed {
+		failed = append(failed, fmt.Sprintf("and %d more", count-maxListed))
 	}
+	return count, errors.New(strings.Join(failed, "; "))
 }
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// newTestWriter returns a writer on a fake InfluxDB. configure, if not nil,
+// adjusts the settings first.
+func newTestWriter(t testing.TB, configure func(*config.InfluxDBConfig)) (*InfluxDBWriter, *influxtest.Server) {
+	t.Helper()
+	server := influxtest.NewServer(t)
+	cfg := server.Config()
+	if configure != nil {
+		configure(&cfg)
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return NewInfluxDBWriter(client, cfg), server
+}
+
+// testPayload is a minimal payload of one host with a root disk.
+func testPayload(hostID string, collectedAt time.Time) models.ClientPayload {
+	return models.ClientPayload{
+		CollectedAt: collectedAt,
+		System:      models.SystemInfoPayload{HostID: hostID, Hostname: hostID + ".example.com", OS: "linux"},
+		CPU:         models.CPUInfoPayload{ModelName: "Test CPU", Cores: 4, Usage: 12.5},
+		Memory:      models.MemInfoPayload{TotalGB: 16, FreeGB: 8, UsagePercent: 50},
+		Disks:       []models.DiskUsagePayload{{Path: "/", TotalGB: 100, UsedGB: 30, FreeGB: 70, UsagePercent: 30}},
+	}
+}
+
+// linesOf returns the written lines of measurement.
+func linesOf(server *influxtest.Server, measurement string) []string {
+	var lines []string
+	for _, line := range server.Lines() {
+		if strings.HasPrefix(line, measurement+",") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestWriteStatsTagsAgentVersion(t *testing.T) {
+	writer, server := newTestWriter(t, nil)
+
+	payload := testPayload("host-1", time.Now())
+	payload.AgentVersion = "1.4.0"
+	if err := writer.WriteStats(context.Background(), &payload); err != nil {
+		t.Fatalf("WriteStats: %v", err)
+	}
+	unversioned := testPayload("host-2", time.Now())
+	if err := writer.WriteStats(context.Background(), &unversioned); err != nil {
+		t.Fatalf("WriteStats: %v", err)
+	}
+
+	system := linesOf(server, "system_metrics")
+	if len(system) != 2 {
+		t.Fatalf("system_metrics lines = %d, want 2: %q", len(system), server.Lines())
+	}
+	if !strings.Contains(system[0], ",agent_version=1.4.0,") {
+		t.Errorf("host-1 line lacks agent_version=1.4.0: %s", system[0])
+	}
+	if strings.Contains(system[1], "agent_version=") {
+		t.Errorf("host-2 sent no version but its line has a tag: %s", system[1])
+	}
+	// only system_metrics carries the version, the other series stay as they are
+	for _, line := range server.Lines() {
+		if !strings.HasPrefix(line, "system_metrics,") && strings.Contains(line, "agent_version=") {
+			t.Errorf("agent_version tag outside system_metrics: %s", line)
+		}
+	}
+}
+
+func TestCountHostsByAgentVersion(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	server.Respond(`keep(columns: ["host_id", "agent_version"])`,
+		influxtest.NewTable("host_id", "agent_version").
+			Row("host-1", "1.4.0").
+			Row("host-2", "1.4.0").
+			Row("host-3", "1.3.2").
+			Row("host-4", nil))
+
+	end := time.Now()
+	counts, err := reader.CountHostsByAgentVersion(context.Background(), end.Add(-10*time.Minute), end)
+	if err != nil {
+		t.Fatalf("CountHostsByAgentVersion: %v", err)
+	}
+	want := map[string]int{"1.4.0": 2, "1.3.2": 1, "unknown": 1}
+	if len(counts) != len(want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	for version, n := range want {
+		if counts[version] != n {
+			t.Errorf("hosts on %s = %d, want %d (counts %v)", version, counts[version], n, counts)
+		}
+	}
+}
+
+// BenchmarkWriteStats100Disks compares writing a 100-disk payload one point
+// at a time with the worker pool, against an InfluxDB taking 1ms per write.
+func BenchmarkWriteStats100Disks(b *testing.B) {
+	payload := testPayload("host-1", time.Now())
+	payload.Disks = nil
+	for i := range 100 {
+		payload.Disks = append(payload.Disks, models.DiskUsagePayload{Path: fmt.Sprintf("/mnt/disk%02d", i), TotalGB: 1000, UsedGB: 400, FreeGB: 600, UsagePercent: 40})
+	}
+	for _, bench := range []struct {
+		name        string
+		concurrency int
+	}{
+		{"sequential", 1},
+		{"pooled-4", 4},
+		{"pooled-16", 16},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			writer, server := newTestWriter(b, func(cfg *config.InfluxDBConfig) { cfg.WriteConcurrency = bench.concurrency })
+			server.DelayWrites(time.Millisecond)
+			b.ResetTimer()
+			for range b.N {
+				if err := writer.WriteStats(context.Background(), &payload); err != nil {
+					b.Fatalf("WriteStats: %v", err)
+				}
+			}
+			b.StopTimer()
+			if disks := len(linesOf(server, "disk_metrics")); disks != 100*b.N {
+				b.Fatalf("%d disk points written, want %d", disks, 100*b.N)
+			}
+		})
+	}
+}
+
+func TestSummaryModeWritesNoPerPIDSeries(t *testing.T) {
+	writer, server := newTestWriter(t, nil)
+
+	payload := testPayload("host-1", time.Now())
+	payload.ProcessSummary = &models.ProcessSummaryPayload{
+		Count:         312,
+		TopCPUPercent: 61.5,
+		Top: []models.ProcessSummaryEntry{
+			{Name: "postgres", CPUPercent: 40, MemoryPercent: 12},
+			{Name: "nginx", CPUPercent: 21.5, MemoryPercent: 1.5},
+		},
+	}
+	if err := writer.WriteStats(context.Background(), &payload); err != nil {
+		t.Fatalf("WriteStats: %v", err)
+	}
+
+	if processes := linesOf(server, "process_metrics"); len(processes) != 0 {
+		t.Errorf("process_metrics lines = %q, want none in summary mode", processes)
+	}
+	summary := linesOf(server, "process_summary")
+	if len(summary) != 1 {
+		t.Fatalf("process_summary lines = %q, want 1", summary)
+	}
+	tagSet, _, _ := strings.Cut(summary[0], " ")
+	if strings.Contains(tagSet, ",pid=") || strings.Contains(tagSet, ",name=") {
+		t.Errorf("process_summary tags %s, want no pid or name tag", tagSet)
+	}
+	for _, field := range []string{"process_count=312i", "top_cpu_percent=61.5", `top_1_name="postgres"`, `top_2_name="nginx"`} {
+		if !strings.Contains(summary[0], field) {
+			t.Errorf("process_summary line lacks %s: %s", field, summary[0])
+		}
+	}
+	for _, line := range server.Lines() {
+		if strings.Contains(line, ",pid=") {
+			t.Errorf("per-PID series written: %s", line)
+		}
+	}
+}
+
+func TestWriteStatsRecordsServiceStates(t *testing.T) {
+	writer, server := newTestWriter(t, nil)
+
+	payload := testPayload("host-1", time.Now())
+	payload.Services = []models.ServicePayload{
+		{Unit: "nginx", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+		{Unit: "postgresql.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+	}
+	if err := writer.WriteStats(context.Background(), &payload); err != nil {
+		t.Fatalf("WriteStats: %v", err)
+	}
+
+	services := linesOf(server, "service_metrics")
+	if len(services) != 2 {
+		t.Fatalf("service_metrics lines = %q, want one per unit", services)
+	}
+	byUnit := make(map[string]string)
+	for _, line := range services {
+		for _, unit := range []string{"nginx", "postgresql.service"} {
+			if strings.Contains(line, ",unit="+unit+" ") {
+				byUnit[unit] = line
+			}
+		}
+	}
+	for unit, fields := range map[string][]string{
+		"nginx":              {`active_state="active"`, "active=true", `sub_state="running"`, `load_state="loaded"`},
+		"postgresql.service": {`active_state="failed"`, "active=false", `sub_state="failed"`},
+	} {
+		line, ok := byUnit[unit]
+		if !ok {
+			t.Errorf("no service_metrics line tagged unit=%s: %q", unit, services)
+			continue
+		}
+		for _, field := range fields {
+			if !strings.Contains(line, field) {
+				t.Errorf("%s line lacks %s: %s", unit, field, line)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package influxtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table is one table of a query result.
+type Table struct {
+	names []string
+	types []string
+	rows  [][]string
+}
+
+// NewTable starts a table with the given columns: "name" for a string
+// column, "name:type" for double, long, unsignedLong, boolean or time.
+func NewTable(columns ...string) *Table {
+	t := &Table{}
+	for _, column := range columns {
+		name, typ, found := strings.Cut(column, ":")
+		switch {
+		case !found:
+			typ = "string"
+		case typ == "time":
+			typ = "dateTime:RFC3339"
+		}
+		t.names = append(t.names, name)
+		t.types = append(t.types, typ)
+	}
+	return t
+}
+
+// Row appends a row with one value per column. nil is null; time.Time,
+// numbers and bools are formatted the way InfluxDB writes them.
+func (t *Table) Row(values ...any) *Table {
+	if len(values) != len(t.names) {
+		panic(fmt.Sprintf("influxtest: row has %d values for %d columns", len(values), len(t.names)))
+	}
+	row := make([]string, len(values))
+	for i, value := range values {
+		row[i] = formatValue(value)
+	}
+	t.rows = append(t.rows, row)
+	return t
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int:
+		return strconv.Itoa(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		panic(fmt.Sprintf("influxtest: unsupported value %v (%T)", value, value))
+	}
+}
+
+// CSV renders tables as a query response body in InfluxDB's annotated CSV,
+// each table with its own annotations so their columns may differ.
+func CSV(tables ...*Table) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	for i, t := range tables {
+		group := make([]string, len(t.names))
+		defaults := make([]string, len(t.names))
+		for j := range group {
+			group[j] = "false"
+		}
+		_ = w.Write(append([]string{"#datatype", "string", "long"}, t.types...))
+		_ = w.Write(append([]string{"#group", "false", "false"}, group...))
+		_ = w.Write(append([]string{"#default", "_result", ""}, defaults...))
+		_ = w.Write(append([]string{"", "result", "table"}, t.names...))
+		for _, row := range t.rows {
+			_ = w.Write(append([]string{"", "", strconv.Itoa(i)}, row...))
+		}
+		w.Flush()
+		b.WriteString("\r\n")
+	}
+	w.Flush()
+	return b.String()
+}
@@ -0,0 +1,222 @@
+// Package influxtest provides a fake InfluxDB 2.x HTTP API for tests of the
+// reader, the writer and the handlers built on them.
+//
+// The fake doesn't run Flux. Each query is answered with the tables of the
+// first response whose match is part of the query text, so a test states
+// what InfluxDB would return and checks what the reader makes of it. Queries
+// nothing matches get an empty result. Tests of the Flux itself run against
+// a real InfluxDB, see the integration tests in package database.
+package influxtest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+)
+
+// Org and Bucket are the names Config uses.
+const (
+	Org    = "test-org"
+	Bucket = "test-bucket"
+)
+
+// Server is a fake InfluxDB: /health passes, /api/v2/write records the line
+// protocol it receives and /api/v2/query answers from the registered
+// responses.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []response
+	queries   []string
+	lines     []string
+	drop      int           // connections to close without a response, see DropConnections
+	delay     time.Duration // before answering a write, see DelayWrites
+}
+
+type response struct {
+	match   string
+	handler http.HandlerFunc
+}
+
+// NewServer starts a fake InfluxDB, closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.health)
+	mux.HandleFunc("/api/v2/query", s.query)
+	mux.HandleFunc("/api/v2/write", s.write)
+	s.Server = httptest.NewServer(s.dropping(mux))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Config returns reader/writer settings for the fake with the defaults of
+// config.Load.
+func (s *Server) Config() config.InfluxDBConfig {
+	return config.InfluxDBConfig{
+		URLs:   []string{s.URL},
+		Token:  "test-token",
+		Org:    Org,
+		Bucket: Bucket,
+
+		ColdBucketAfter:      24 * time.Hour,
+		ColdBucketResolution: 5 * time.Minute,
+
+		MaxConcurrentQueries: 8,
+		QueryQueueTimeout:    5 * time.Second,
+		WriteConcurrency:     4,
+
+		QueryPointBudget: 1000000,
+		RejectOverBudget: true,
+		HistoryMaxFields: 6,
+
+		ReconnectAfterErrors:  3,
+		ReconnectCooldown:     30 * time.Second,
+		FailbackProbeInterval: 30 * time.Second,
+
+		OverviewMaxOfflineAge: 24 * time.Hour,
+		OnlineWithin:          35 * time.Second,
+		RecentRebootWithin:    12 * time.Hour,
+		Status: config.StatusThresholds{
+			UsageWarning:  85,
+			UsageCritical: 95,
+			DiskWarning:   90,
+			DiskCritical:  95,
+		},
+		DisplayLocation:    time.UTC,
+		ProcessSampleEvery: 1,
+		ProcessSeries:      "aggregated",
+
+		OverviewAverageRefresh: 30 * time.Second,
+		OverviewCacheMaxAge:    5 * time.Minute,
+
+		HealthCheckTimeout: 5 * time.Second,
+		DetailsLookback:    15 * time.Second,
+	}
+}
+
+// Respond answers queries containing match with tables.
+func (s *Server) Respond(match string, tables ...*Table) {
+	body := CSV(tables...)
+	s.RespondFunc(match, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_, _ = io.WriteString(w, body)
+	})
+}
+
+// RespondFunc answers queries containing match with handler, e.g. to stall
+// or to fail a query.
+func (s *Server) RespondFunc(match string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, response{match: match, handler: handler})
+}
+
+// DropConnections makes the next n requests (health checks included) fail
+// with a closed connection, as if InfluxDB went away.
+func (s *Server) DropConnections(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop = n
+}
+
+// DelayWrites makes every write wait d before it is answered, like a
+// loaded InfluxDB, e.g. to compare sequential and concurrent writes.
+func (s *Server) DelayWrites(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// Queries returns the Flux of every query received so far, in order.
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+// Lines returns every line of line protocol written so far, in order.
+func (s *Server) Lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lines...)
+}
+
+func (s *Server) dropping(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		drop := s.drop > 0
+		if drop {
+			s.drop--
+		}
+		s.mu.Unlock()
+		if !drop {
+			next.ServeHTTP(w, r)
+			return
+		}
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			panic(err)
+		}
+		_ = conn.Close()
+	})
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = io.WriteString(w, `{"name":"influxdb","message":"ready for queries and writes","status":"pass","checks":[],"version":"v2.7.0"}`)
+}
+
+func (s *Server) query(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"code":"invalid","message":"bad query body"}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.queries = append(s.queries, body.Query)
+	var handler http.HandlerFunc
+	for _, resp := range s.responses {
+		if strings.Contains(body.Query, resp.match) {
+			handler = resp.handler
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if handler == nil {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		return
+	}
+	handler(w, r)
+}
+
+func (s *Server) write(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			s.lines = append(s.lines, line)
+		}
+	}
+	delay := s.delay
+	s.mu.Unlock()
+	time.Sleep(delay)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,114 @@
+//go:build integration
+
+// The integration tests write synthetic payloads through InfluxDBWriter into
+// a real InfluxDB 2.x and check what InfluxDBReader returns for them, the
+// safety net for changes to the schema and the Flux queries. They read the
+// InfluxDB settings like the server (INFLUXDB_*) and write hosts named
+// it-<run>-*, so point them at a throwaway bucket:
+//
+//	docker run -d --name influx-check -p 8086:8086 \
+//	  -e DOCKER_INFLUXDB_INIT_MODE=setup -e DOCKER_INFLUXDB_INIT_USERNAME=check \
+//	  -e DOCKER_INFLUXDB_INIT_PASSWORD=check-password -e DOCKER_INFLUXDB_INIT_ORG=check \
+//	  -e DOCKER_INFLUXDB_INIT_BUCKET=check -e DOCKER_INFLUXDB_INIT_ADMIN_TOKEN=check-token influxdb:2.7
+//	INFLUXDB_URL=http://localhost:8086 INFLUXDB_TOKEN=check-token INFLUXDB_ORG=check \
+//	  INFLUXDB_BUCKET=check go test -tags integration ./internal/server/database/
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// integration is a reader and a writer on the InfluxDB under test.
+type integration struct {
+	cfg    config.InfluxDBConfig
+	reader *InfluxDBReader
+	writer *InfluxDBWriter
+	run    string // host ID prefix, so runs against one bucket don't see each other's hosts
+}
+
+// newIntegration connects to the InfluxDB of the INFLUXDB_* settings.
+// configure, if not nil, adjusts the settings first.
+func newIntegration(t *testing.T, configure func(*config.InfluxDBConfig)) *integration {
+	t.Helper()
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("invalid InfluxDB settings, see integration_test.go:\n%v", err)
+	}
+	influx := cfg.InfluxDB
+	influx.OverviewCacheMaxAge = 0 // always query, the cache is not what is tested
+	influx.OverviewAverageWindows = nil
+	if configure != nil {
+		configure(&influx)
+	}
+
+	client, err := NewClient(influx)
+	if err != nil {
+		t.Fatalf("connect to InfluxDB: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return &integration{
+		cfg:    influx,
+		reader: NewInfluxDBReader(client, influx),
+		writer: NewInfluxDBWriter(client, influx),
+		run:    fmt.Sprintf("it-%d", time.Now().UnixNano()),
+	}
+}
+
+// hostID returns the ID of this run's host called name.
+func (it *integration) hostID(name string) string {
+	return it.run + "-" + name
+}
+
+func (it *integration) write(t *testing.T, payloads ...*models.ClientPayload) {
+	t.Helper()
+	for _, p := range payloads {
+		if err := it.writer.WriteStats(context.Background(), p); err != nil {
+			t.Fatalf("write payload of %s: %v", p.System.HostID, err)
+		}
+	}
+}
+
+// lastValues runs a plain last() over one measurement of a host, without
+// any pivot or join, and returns the values by series (the tags in keyTags,
+// joined with "/") and field.
+func (it *integration) lastValues(t *testing.T, measurement, hostID string, keyTags ...string) map[string]map[string]any {
+	t.Helper()
+	ctx := context.Background()
+	results, err := it.reader.query(ctx, fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -1h)
+			|> filter(fn: (r) => r._measurement == "%s" and r.host_id == "%s")
+			|> last()
+	`, it.cfg.Bucket, measurement, hostID))
+	if err != nil {
+		t.Fatalf("query %s: %v", measurement, err)
+	}
+	defer results.Close()
+
+	values := make(map[string]map[string]any)
+	for results.Next() {
+		record := results.Record()
+		var key []string
+		for _, tag := range keyTags {
+			value, _ := record.ValueByKey(tag).(string)
+			key = append(key, value)
+		}
+		series := strings.Join(key, "/")
+		if values[series] == nil {
+			values[series] = make(map[string]any)
+		}
+		values[series][record.Field()] = record.Value()
+	}
+	if results.Err() != nil {
+		t.Fatalf("query %s: %v", measurement, results.Err())
+	}
+	return values
+}
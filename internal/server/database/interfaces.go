@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// GetHostInterfaces returns the latest interface_metrics point of every
+// interface of a host within the details lookback, with rates in bytes/sec.
+// Utilization is the busier direction against the link speed (links are
+// full duplex, so upload and download each have the whole speed).
+func (r *InfluxDBReader) GetHostInterfaces(ctx context.Context, hostID string) ([]models.InterfaceDetail, error) {
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Pivot before picking the latest row, so a point without rates (skipped
+	// by the agent) isn't mixed with the rates of an older one
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "interface_metrics" and r.host_id == %s)
+			|> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+			|> group(columns: ["interface"])
+			|> sort(columns: ["_time"])
+			|> last(column: "_time")
+	`, r.bucket, r.detailsLookback, fluxString(hostID))
+
+	appLogger.FromContext(ctx).Debug("GetHostInterfaces Query for host %s:\n%s", hostID, query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostInterfaces (host %s): %v", hostID, err)
+		return nil, fmt.Errorf("query influxdb for host interfaces: %w", err)
+	}
+	defer results.Close()
+
+	interfaces := []models.InterfaceDetail{}
+	for results.Next() {
+		record := results.Record()
+		name, _ := record.ValueByKey("interface").(string)
+		if name == "" {
+			continue
+		}
+		iface := models.InterfaceDetail{Name: name, LastSeen: record.Time().UTC()}
+		iface.Up, _ = record.ValueByKey("up").(bool)
+		iface.SpeedMbps, _ = record.ValueByKey("speed_mbps").(int64)
+		upload, hasUpload := record.ValueByKey("upload_bytes_sec").(float64)
+		download, hasDownload := record.ValueByKey("download_bytes_sec").(float64)
+		if hasUpload && hasDownload {
+			iface.NetworkUpload, iface.NetworkDownload = &upload, &download
+			if iface.SpeedMbps > 0 {
+				utilization := math.Max(upload, download) * 8 / (float64(iface.SpeedMbps) * 1e6) * 100
+				iface.Utilization = &utilization
+			}
+		}
+		interfaces = append(interfaces, iface)
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetHostInterfaces (host %s): %v", hostID, results.Err())
+		return nil, fmt.Errorf("process query results for host interfaces: %w", results.Err())
+	}
+	sort.Slice(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+	return interfaces, nil
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// Static agent labels (MONITOR_LABELS) are written as label_<name> tags on
+// system_metrics. Names and values are restricted so they are safe in Flux
+// column names and string literals.
+const (
+	labelTagPrefix = "label_"
+	maxLabels      = 10
+)
+
+var (
+	labelNamePattern  = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/-]{0,63}$`)
+)
+
+// ValidLabel reports whether name=value is a label that is stored and can be queried.
+func ValidLabel(name, value string) bool {
+	return labelNamePattern.MatchString(name) && labelValuePattern.MatchString(value)
+}
+
+// validLabels returns the payload's labels that can be stored, at most
+// maxLabels in name order; the rest are logged and dropped.
+func validLabels(log appLogger.Entry, labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	valid := make(map[string]string, len(labels))
+	for _, name := range names {
+		switch {
+		case !ValidLabel(name, labels[name]):
+			log.Warn("Dropping invalid label %q=%q (names: lowercase letters, digits, _; values: letters, digits, . _ : / -)", name, labels[name])
+		case len(valid) == maxLabels:
+			log.Warn("Dropping label %q, at most %d labels are stored", name, maxLabels)
+		default:
+			valid[name] = labels[name]
+		}
+	}
+	return valid
+}
+
+// GetLabelGroups lists every label value carried by hosts seen within the
+// overview's window, with the number of hosts, by label and value. A host
+// counts with the labels of its latest point.
+func (r *InfluxDBReader) GetLabelGroups(ctx context.Context) ([]models.LabelGroup, error) {
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and r._field == "cpu_usage_percent")
+			|> group(columns: ["host_id"])
+			|> last()
+			|> group()
+	`, r.bucket, r.overviewMaxAge.String())
+
+	appLogger.FromContext(ctx).Debug("GetLabelGroups Query:\n%s", query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetLabelGroups: %v", err)
+		return nil, fmt.Errorf("query influxdb for label groups: %w", err)
+	}
+	defer results.Close()
+
+	counts := make(map[models.LabelGroup]int)
+	for results.Next() {
+		for column, value := range results.Record().Values() {
+			name, isLabel := strings.CutPrefix(column, labelTagPrefix)
+			if text, ok := value.(string); isLabel && ok && text != "" {
+				counts[models.LabelGroup{Label: name, Value: text}]++
+			}
+		}
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetLabelGroups: %v", results.Err())
+		return nil, fmt.Errorf("process query results for label groups: %w", results.Err())
+	}
+
+	groups := make([]models.LabelGroup, 0, len(counts))
+	for group, hosts := range counts {
+		group.Hosts = hosts
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Label != groups[j].Label {
+			return groups[i].Label < groups[j].Label
+		}
+		return groups[i].Value < groups[j].Value
+	})
+	return groups, nil
+}
+
+// GetHostIDsByLabel returns the hosts seen within the overview's window whose
+// latest point carries label=value. Callers validate with ValidLabel first.
+func (r *InfluxDBReader) GetHostIDsByLabel(ctx context.Context, label, value string) ([]string, error) {
+	if !ValidLabel(label, value) {
+		return nil, fmt.Errorf("invalid label %q=%q", label, value)
+	}
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and r._field == "cpu_usage_percent")
+			|> group(columns: ["host_id"])
+			|> last()
+			|> filter(fn: (r) => r["%s%s"] == "%s")
+			|> keep(columns: ["host_id"])
+	`, r.bucket, r.overviewMaxAge.String(), labelTagPrefix, label, value)
+
+	appLogger.FromContext(ctx).Debug("GetHostIDsByLabel Query for %s=%s:\n%s", label, value, query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostIDsByLabel (%s=%s): %v", label, value, err)
+		return nil, fmt.Errorf("query influxdb for hosts by label: %w", err)
+	}
+	defer results.Close()
+
+	hostIDs := []string{}
+	for results.Next() {
+		if hostID, ok := results.Record().ValueByKey("host_id").(string); ok && hostID != "" {
+			hostIDs = append(hostIDs, hostID)
+		}
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetHostIDsByLabel (%s=%s): %v", label, value, results.Err())
+		return nil, fmt.Errorf("process query results for hosts by label: %w", results.Err())
+	}
+	return hostIDs, nil
+}
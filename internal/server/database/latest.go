@@ -0,0 +1,123 @@
+package database
+
+import (
+	"sync"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// latestTable is the overview's materialized "latest" table: host_id -> the
+// host's most recent overview row. RecordIngest updates it from every stored
+// payload, so the overview neither waits for nor runs the Flux join. The
+// query re-seeds it on cold start and every OVERVIEW_CACHE_MAX_AGE, which
+// also picks up hosts reporting to other server instances.
+type latestTable struct {
+	mu       sync.Mutex
+	hosts    map[string]models.HostOverviewData
+	seededAt time.Time // zero until the first query
+}
+
+// RecordIngest puts a stored payload into the latest table. Payloads older
+// than the host's current row (late or replayed sends) are ignored.
+func (r *InfluxDBReader) RecordIngest(payload *models.ClientPayload) {
+	if r.overviewCacheMaxAge <= 0 {
+		return
+	}
+	r.latest.record(payload)
+}
+
+func (t *latestTable) record(payload *models.ClientPayload) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hostID := payload.System.HostID
+	previous, known := t.hosts[hostID]
+	if known && previous.LastSeen.After(payload.CollectedAt) {
+		return
+	}
+	row := models.HostOverviewData{
+		ID:       hostID,
+		Hostname: payload.System.Hostname,
+		CPUUsage: payload.CPU.Usage,
+		RAMUsage: payload.Memory.UsagePercent,
+		LastSeen: payload.CollectedAt,
+		// Like the query's last(): values this payload doesn't carry stay
+		DiskUsage:       previous.DiskUsage,
+		InodeUsage:      previous.InodeUsage,
+		NetworkUpload:   previous.NetworkUpload,
+		NetworkDownload: previous.NetworkDownload,
+	}
+	if uptime, ok := payload.System.UptimeSecs(); ok {
+		row.UptimeSeconds = uptime
+	}
+	if lag, ok := payload.IngestLag(); ok {
+		seconds := lag.Seconds()
+		row.IngestLagSeconds = &seconds
+	}
+	if !payload.Network.RatesSkipped {
+		row.NetworkUpload = payload.Network.UploadBytesPerSec
+		row.NetworkDownload = payload.Network.DownloadBytesPerSec
+	}
+	for _, disk := range payload.Disks {
+		if disk.Path != "/" {
+			continue
+		}
+		usage := disk.UsagePercent
+		row.DiskUsage = &usage
+		if disk.InodesTotal > 0 {
+			inodes := disk.InodesUsagePercent
+			row.InodeUsage = &inodes
+		}
+	}
+	if t.hosts == nil {
+		t.hosts = make(map[string]models.HostOverviewData)
+	}
+	t.hosts[hostID] = row
+}
+
+// rows returns a copy of the table and whether it was seeded within maxAge.
+// Hosts silent for longer than expireAfter are dropped, as in seed, so they
+// don't wait for the next query to leave the table.
+func (t *latestTable) rows(now time.Time, maxAge, expireAfter time.Duration) ([]models.HostOverviewData, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.seededAt.IsZero() || now.Sub(t.seededAt) > maxAge {
+		return nil, false
+	}
+	rows := make([]models.HostOverviewData, 0, len(t.hosts))
+	for hostID, row := range t.hosts {
+		if now.Sub(row.LastSeen) > expireAfter {
+			delete(t.hosts, hostID)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, true
+}
+
+// seed merges queried rows into the table, keeping whichever row of a host is
+// newer (ingest may have raced the query), drops hosts silent for longer than
+// expireAfter and returns the merged table.
+func (t *latestTable) seed(queried []models.HostOverviewData, now time.Time, expireAfter time.Duration) []models.HostOverviewData {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.hosts == nil {
+		t.hosts = make(map[string]models.HostOverviewData, len(queried))
+	}
+	for _, row := range queried {
+		if current, ok := t.hosts[row.ID]; !ok || row.LastSeen.After(current.LastSeen) {
+			t.hosts[row.ID] = row
+		}
+	}
+	rows := make([]models.HostOverviewData, 0, len(t.hosts))
+	for hostID, row := range t.hosts {
+		if now.Sub(row.LastSeen) > expireAfter {
+			delete(t.hosts, hostID)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	t.seededAt = now
+	return rows
+}
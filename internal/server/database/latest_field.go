@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// LatestFields are the numeric system_metrics fields GetHostLatestField accepts.
+var LatestFields = map[string]bool{
+	"cpu_usage_percent":      true,
+	"mem_usage_percent":      true,
+	"mem_used_gb":            true,
+	"mem_available_gb":       true,
+	"net_upload_bytes_sec":   true,
+	"net_download_bytes_sec": true,
+	"logged_in_users":        true,
+	"ingest_lag_seconds":     true,
+}
+
+// GetHostLatestField returns the latest value of one system_metrics field of
+// a host within the details lookback, for single-value widgets. It is nil
+// when the host has no such point.
+func (r *InfluxDBReader) GetHostLatestField(ctx context.Context, hostID, field string) (*models.MetricPoint, error) {
+	if !LatestFields[field] {
+		return nil, fmt.Errorf("invalid metric field for latest value: %s", field)
+	}
+
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and r.host_id == %s and r._field == "%s")
+			|> last()
+	`, r.bucket, r.detailsLookback, fluxString(hostID), field)
+
+	appLogger.FromContext(ctx).Debug("GetHostLatestField Query for host %s, field %s:\n%s", hostID, field, query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostLatestField (host %s, field %s): %v", hostID, field, err)
+		return nil, fmt.Errorf("query influxdb for latest value: %w", err)
+	}
+	defer results.Close()
+
+	// last() leaves one row per series; a host that changed its tags (e.g.
+	// labels) within the lookback has several, keep the newest
+	var latest *models.MetricPoint
+	for results.Next() {
+		point, ok := r.metricPoint(results.Record())
+		if ok && (latest == nil || point.Time.After(latest.Time)) {
+			latest = &point
+		}
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetHostLatestField (host %s, field %s): %v", hostID, field, results.Err())
+		return nil, fmt.Errorf("process query results for latest value: %w", results.Err())
+	}
+	return latest, nil
+}
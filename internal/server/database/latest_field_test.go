@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+func TestGetHostLatestField(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	now := time.Now().Truncate(time.Second)
+	// two series: the host changed its labels within the lookback
+	server.Respond("|> last()", influxtest.NewTable("_time:time", "_value:double", "label_tier").
+		Row(now.Add(-10*time.Second), 41.0, "").
+		Row(now, 57.5, "web"))
+
+	point, err := reader.GetHostLatestField(context.Background(), "host-1", "cpu_usage_percent")
+	if err != nil {
+		t.Fatalf("GetHostLatestField: %v", err)
+	}
+	if point == nil || point.Value != 57.5 || !point.Time.Equal(now) {
+		t.Fatalf("point = %+v, want 57.5 at %v", point, now)
+	}
+	queries := server.Queries()
+	if len(queries) != 1 || !strings.Contains(queries[0], `r.host_id == "host-1" and r._field == "cpu_usage_percent"`) || !strings.Contains(queries[0], "range(start: -15s)") {
+		t.Errorf("queries = %q, want one for host-1's cpu_usage_percent over the details lookback", queries)
+	}
+
+	// no recent point
+	reader, _ = newTestReader(t, nil)
+	if point, err := reader.GetHostLatestField(context.Background(), "host-1", "mem_usage_percent"); err != nil || point != nil {
+		t.Errorf("without data: %+v, %v, want nil", point, err)
+	}
+
+	// fields outside LatestFields are rejected before querying
+	reader, server = newTestReader(t, nil)
+	if _, err := reader.GetHostLatestField(context.Background(), "host-1", `cpu") or true or (r._field == "`); err == nil {
+		t.Error("invalid field: no error")
+	}
+	if queries := server.Queries(); len(queries) != 0 {
+		t.Errorf("invalid field sent %d queries", len(queries))
+	}
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+func TestLatestTableExpiresSilentHosts(t *testing.T) {
+	const maxAge, expireAfter = 5 * time.Minute, 24 * time.Hour
+	now := time.Now()
+	var table latestTable
+	if _, fresh := table.rows(now, maxAge, expireAfter); fresh {
+		t.Fatal("rows are fresh before the first seed")
+	}
+	table.seed([]models.HostOverviewData{{ID: "queried", LastSeen: now.Add(-time.Hour)}}, now, expireAfter)
+
+	for hostID, collectedAt := range map[string]time.Time{"fresh": now, "stale": now.Add(-expireAfter + time.Minute)} {
+		payload := &models.ClientPayload{CollectedAt: collectedAt, System: models.SystemInfoPayload{HostID: hostID, Hostname: hostID}}
+		table.record(payload)
+	}
+	// two minutes later "stale" has been silent for longer than expireAfter
+	rows, fresh := table.rows(now.Add(2*time.Minute), maxAge, expireAfter)
+	if !fresh {
+		t.Fatal("rows are not fresh within maxAge of the seed")
+	}
+	got := map[string]bool{}
+	for _, row := range rows {
+		got[row.ID] = true
+	}
+	if len(got) != 2 || !got["fresh"] || !got["queried"] {
+		t.Errorf("rows = %v, want fresh and queried", got)
+	}
+	if _, ok := table.hosts["stale"]; ok {
+		t.Error("the expired host is still in the table")
+	}
+
+	// past maxAge the table needs a new query
+	if _, fresh := table.rows(now.Add(maxAge+time.Second), maxAge, expireAfter); fresh {
+		t.Error("rows are fresh past maxAge")
+	}
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// GetHostMetricDelta compares the mean of one history field over the last
+// window with its mean over the window before that, in one query with a range
+// per window. The windows end now; the percent change is relative to the
+// previous window's mean.
+func (r *InfluxDBReader) GetHostMetricDelta(ctx context.Context, hostID, field string, window time.Duration) (*models.MetricDelta, error) {
+	if !HistoryFields[field] {
+		return nil, fmt.Errorf("invalid or non-numeric metric field for delta: %s", field)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive, got %s", window)
+	}
+	bucket, resolution := r.historyBucket(2 * window)
+	if err := r.checkQueryCost("GetHostMetricDelta", queryEstimate{Hosts: 1, Fields: 1, Span: 2 * window, Resolution: resolution}); err != nil {
+		return nil, err
+	}
+
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	end := time.Now().UTC()
+	split := end.Add(-window)
+	// group() before mean(): a host whose tags changed within the windows has
+	// several series, each window gets one mean over all of them
+	query := fmt.Sprintf(`
+		data = (start, stop) => from(bucket: "%s")
+			|> range(start: start, stop: stop)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and r.host_id == %s and r._field == "%s")
+			|> group()
+			|> mean()
+		union(tables: [
+			data(start: %s, stop: %s) |> set(key: "window", value: "current"),
+			data(start: %s, stop: %s) |> set(key: "window", value: "previous")
+		])
+	`, bucket, fluxString(hostID), field,
+		fluxTime(split), fluxTime(end),
+		fluxTime(split.Add(-window)), fluxTime(split))
+
+	appLogger.FromContext(ctx).Debug("GetHostMetricDelta Query for host %s, field %s, window %s (bucket %s):\n%s", hostID, field, window, bucket, query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostMetricDelta (host %s, field %s): %v", hostID, field, err)
+		return nil, fmt.Errorf("query influxdb for metric delta: %w", err)
+	}
+	defer results.Close()
+
+	delta := &models.MetricDelta{ID: hostID, Metric: field, Window: window.String(), End: end}
+	for results.Next() {
+		record := results.Record()
+		mean, ok := record.Value().(float64)
+		if !ok {
+			continue // no points in the window
+		}
+		switch record.ValueByKey("window") {
+		case "current":
+			delta.Current = &mean
+		case "previous":
+			delta.Previous = &mean
+		}
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetHostMetricDelta (host %s, field %s): %v", hostID, field, results.Err())
+		return nil, fmt.Errorf("process query results for metric delta: %w", results.Err())
+	}
+	delta.PercentChange = percentChange(delta.Previous, delta.Current)
+	return delta, nil
+}
+
+// percentChange is (current - previous) / |previous| x 100, nil when either
+// is missing or previous is 0.
+func percentChange(previous, current *float64) *float64 {
+	if previous == nil || current == nil || *previous == 0 {
+		return nil
+	}
+	change := (*current - *previous) / math.Abs(*previous) * 100
+	return &change
+}
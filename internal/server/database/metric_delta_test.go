@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+func TestMetricDeltaOfARisingSeries(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	// the means InfluxDB computes for a CPU rising from 10% to 80% over the two windows
+	server.Respond(`set(key: "window", value: "current")`, influxtest.NewTable("window", "_value:double").
+		Row("current", 65.0).
+		Row("previous", 25.0))
+
+	delta, err := reader.GetHostMetricDelta(context.Background(), "host-1", "cpu_usage_percent", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("GetHostMetricDelta: %v", err)
+	}
+	if delta.Current == nil || *delta.Current != 65 || delta.Previous == nil || *delta.Previous != 25 {
+		t.Fatalf("current %v, previous %v, want 65 and 25", delta.Current, delta.Previous)
+	}
+	if delta.PercentChange == nil || *delta.PercentChange <= 0 || *delta.PercentChange != 160 {
+		t.Errorf("percentChange = %v, want +160", delta.PercentChange)
+	}
+	if delta.Window != "5m0s" || delta.Metric != "cpu_usage_percent" {
+		t.Errorf("delta = %+v, want cpu_usage_percent over 5m0s", delta)
+	}
+
+	// one query with both windows, back to back
+	queries := server.Queries()
+	if len(queries) != 1 {
+		t.Fatalf("%d queries, want 1", len(queries))
+	}
+	split, previousStart := fluxTime(delta.End.Add(-5*time.Minute)), fluxTime(delta.End.Add(-10*time.Minute))
+	for _, want := range []string{
+		"data(start: " + split + ", stop: " + fluxTime(delta.End) + `) |> set(key: "window", value: "current")`,
+		"data(start: " + previousStart + ", stop: " + split + `) |> set(key: "window", value: "previous")`,
+	} {
+		if !strings.Contains(queries[0], want) {
+			t.Errorf("query lacks %s:\n%s", want, queries[0])
+		}
+	}
+}
+
+func TestPercentChange(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name              string
+		previous, current *float64
+		want              *float64
+	}{
+		{"rising", value(40), value(50), value(25)},
+		{"falling", value(50), value(40), value(-20)},
+		{"unchanged", value(30), value(30), value(0)},
+		{"from a negative previous", value(-10), value(-5), value(50)},
+		{"previous 0", value(0), value(10), nil},
+		{"no current points", value(10), nil, nil},
+		{"no previous points", nil, value(10), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := percentChange(tt.previous, tt.current)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("percentChange = %v, want %v", deref(got), deref(tt.want))
+			}
+		})
+	}
+}
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
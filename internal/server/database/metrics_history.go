@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// HistoryFields are the system_metrics fields GetHostMetricHistory and
+// GetHostMetricsHistory accept.
+var HistoryFields = map[string]bool{
+	"cpu_usage_percent":      true,
+	"mem_usage_percent":      true,
+	"net_upload_bytes_sec":   true,
+	"net_download_bytes_sec": true,
+	"ingest_lag_seconds":     true,
+	// Add disk usage later if needed, requires specifying path
+}
+
+// GetHostMetricsHistory is GetHostMetricHistory for several fields in one
+// query, returning field -> points for every requested field (empty when the
+// host has none). Repeated names are queried once; more distinct fields than
+// INFLUXDB_HISTORY_MAX_FIELDS give ErrTooManyFields before anything is queried.
+func (r *InfluxDBReader) GetHostMetricsHistory(ctx context.Context, hostID string, fields []string, start, end time.Time, aggregateInterval time.Duration) (map[string][]models.MetricPoint, error) {
+	history := make(map[string][]models.MetricPoint)
+	var distinct []string
+	for _, field := range fields {
+		if !HistoryFields[field] {
+			return nil, fmt.Errorf("invalid or non-numeric metric field for history: %s", field)
+		}
+		if _, seen := history[field]; !seen {
+			history[field] = []models.MetricPoint{}
+			distinct = append(distinct, field)
+		}
+	}
+	if len(distinct) == 0 {
+		return nil, fmt.Errorf("no metric fields requested")
+	}
+	if r.historyMaxFields > 0 && len(distinct) > r.historyMaxFields {
+		return nil, fmt.Errorf("%w: %d fields, at most %d", ErrTooManyFields, len(distinct), r.historyMaxFields)
+	}
+	bucket, resolution := r.historyBucket(end.Sub(start))
+	if err := r.checkQueryCost("GetHostMetricsHistory", queryEstimate{Hosts: 1, Fields: len(distinct), Span: end.Sub(start), Resolution: resolution}); err != nil {
+		return nil, err
+	}
+
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Field names are from HistoryFields, safe to put in the query as they are
+	query := fmt.Sprintf(`
+		targetFields = ["%s"]
+		from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => r._measurement == "system_metrics" and r.host_id == %s and contains(value: r._field, set: targetFields))
+			|> aggregateWindow(every: %s, fn: mean, createEmpty: false)
+	`, strings.Join(distinct, `", "`), bucket, fluxTime(start), fluxTime(end), fluxString(hostID), aggregateInterval.String())
+
+	appLogger.FromContext(ctx).Debug("GetHostMetricsHistory Query for host %s, metrics %v (bucket %s):\n%s", hostID, distinct, bucket, query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		appLogger.FromContext(ctx).Error("InfluxDB query failed for GetHostMetricsHistory (host %s, metrics %v): %v", hostID, distinct, err)
+		return nil, fmt.Errorf("query influxdb for host metrics history: %w", err)
+	}
+	defer results.Close()
+
+	for results.Next() {
+		record := results.Record()
+		point, ok := r.metricPoint(record)
+		if !ok {
+			appLogger.FromContext(ctx).Warn("Unexpected value type for metric %s, host %s: %T, value: %v", record.Field(), hostID, record.Value(), record.Value())
+			continue
+		}
+		if points, requested := history[record.Field()]; requested {
+			history[record.Field()] = append(points, point)
+		}
+	}
+	if results.Err() != nil {
+		appLogger.FromContext(ctx).Error("Error processing results for GetHostMetricsHistory (host %s, metrics %v): %v", hostID, distinct, results.Err())
+		return nil, fmt.Errorf("process query results for host metrics history: %w", results.Err())
+	}
+	return history, nil
+}
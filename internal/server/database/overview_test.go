@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// overviewRow is one host of the overview query's result. disk and inodes
+// are the root disk's percentages, nil when the host reported none.
+type overviewRow struct {
+	hostID       string
+	cpu, mem     float64
+	disk, inodes *float64
+	uptime       int64
+	at           time.Time
+}
+
+func percent(v float64) *float64 { return &v }
+
+// overviewTable is the overview query's result for rows.
+func overviewTable(rows ...overviewRow) *influxtest.Table {
+	table := influxtest.NewTable("host_id", "hostname",
+		"cpu_usage_percent:double", "mem_usage_percent:double", "uptime_seconds:long",
+		"lag_found:boolean", "ingest_lag_seconds:double",
+		"net_upload_bytes_sec:double", "net_download_bytes_sec:double",
+		"disk_found:boolean", "disk_usage_percent:double",
+		"inodes_found:boolean", "inodes_usage_percent:double", "_time:time")
+	for _, row := range rows {
+		disk, inodes := 0.0, 0.0
+		if row.disk != nil {
+			disk = *row.disk
+		}
+		if row.inodes != nil {
+			inodes = *row.inodes
+		}
+		table.Row(row.hostID, row.hostID+".example.com", row.cpu, row.mem, row.uptime,
+			false, 0.0, 1000.0, 2000.0,
+			row.disk != nil, disk, row.inodes != nil, inodes, row.at)
+	}
+	return table
+}
+
+// respondOverview answers the overview query with rows.
+func respondOverview(server *influxtest.Server, rows ...overviewRow) {
+	server.Respond(`yield(name: "overview")`, overviewTable(rows...))
+}
+
+// overviewByID returns the overview's hosts by ID.
+func overviewByID(t *testing.T, reader *InfluxDBReader) map[string]models.HostOverviewData {
+	t.Helper()
+	hosts, err := reader.GetHostOverviewList(context.Background())
+	if err != nil {
+		t.Fatalf("GetHostOverviewList: %v", err)
+	}
+	byID := make(map[string]models.HostOverviewData, len(hosts))
+	for _, host := range hosts {
+		byID[host.ID] = host
+	}
+	return byID
+}
+
+func TestOverviewStatusCountsInodeUsage(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	now := time.Now()
+	respondOverview(server,
+		overviewRow{hostID: "healthy", cpu: 10, mem: 20, disk: percent(40), inodes: percent(30), at: now},
+		overviewRow{hostID: "inodes-warning", cpu: 10, mem: 20, disk: percent(40), inodes: percent(92), at: now},
+		overviewRow{hostID: "inodes-critical", cpu: 10, mem: 20, disk: percent(40), inodes: percent(97), at: now},
+		overviewRow{hostID: "no-inodes", cpu: 10, mem: 20, disk: percent(40), at: now},
+	)
+
+	hosts := overviewByID(t, reader)
+	for id, want := range map[string]string{
+		"healthy":         "online",
+		"inodes-warning":  "warning",
+		"inodes-critical": "critical",
+		"no-inodes":       "online",
+	} {
+		if got := hosts[id].Status; got != want {
+			t.Errorf("%s: status %q, want %q", id, got, want)
+		}
+	}
+	if inodes := hosts["inodes-warning"].InodeUsage; inodes == nil || *inodes != 92 {
+		t.Errorf("inodes-warning: inodeUsage = %v, want 92", inodes)
+	}
+	if inodes := hosts["no-inodes"].InodeUsage; inodes != nil {
+		t.Errorf("no-inodes: inodeUsage = %v, want null", *inodes)
+	}
+}
+
+func TestOverviewOmitsHostsPastMaxOfflineAge(t *testing.T) {
+	for _, cacheMaxAge := range []time.Duration{0, 5 * time.Minute} {
+		t.Run("cache "+cacheMaxAge.String(), func(t *testing.T) {
+			reader, server := newTestReader(t, func(cfg *config.InfluxDBConfig) {
+				cfg.OverviewMaxOfflineAge = time.Hour
+				cfg.OverviewCacheMaxAge = cacheMaxAge
+			})
+			now := time.Now()
+			// range() is relative to InfluxDB's clock, a skewed one can return older rows
+			respondOverview(server,
+				overviewRow{hostID: "online", cpu: 10, mem: 20, at: now},
+				overviewRow{hostID: "offline", cpu: 10, mem: 20, at: now.Add(-50 * time.Minute)},
+				overviewRow{hostID: "gone", cpu: 10, mem: 20, at: now.Add(-61 * time.Minute)},
+			)
+
+			hosts := overviewByID(t, reader)
+			if _, ok := hosts["gone"]; ok {
+				t.Error("host silent for 61m listed with a max offline age of 1h")
+			}
+			if got := hosts["offline"].Status; got != "offline" {
+				t.Errorf("host silent for 50m: status %q, want offline", got)
+			}
+			if got := hosts["online"].Status; got != "online" {
+				t.Errorf("reporting host: status %q, want online", got)
+			}
+			if queries := server.Queries(); len(queries) != 1 || !strings.Contains(queries[0], "range(start: -1h0m0s)") {
+				t.Errorf("overview query doesn't cover the max offline age of 1h: %q", queries)
+			}
+		})
+	}
+}
+
+func TestOfflineHostKeepsItsLastMetrics(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	now := time.Now()
+	lastSeen := now.Add(-10 * time.Minute) // well past ONLINE_WITHIN, within OVERVIEW_MAX_OFFLINE_AGE
+	respondOverview(server,
+		overviewRow{hostID: "online", cpu: 10, mem: 20, disk: percent(30), at: now},
+		// usage that would be critical if the host were online
+		overviewRow{hostID: "offline", cpu: 97, mem: 66, disk: percent(55), inodes: percent(12), uptime: 600, at: lastSeen},
+	)
+
+	hosts := overviewByID(t, reader)
+	offline, ok := hosts["offline"]
+	if !ok {
+		t.Fatal("host offline for 10m missing from the overview")
+	}
+	if offline.Status != "offline" {
+		t.Errorf("status %q, want offline", offline.Status)
+	}
+	if offline.CPUUsage != 97 || offline.RAMUsage != 66 {
+		t.Errorf("cpu %v / ram %v, want the last-known 97 / 66", offline.CPUUsage, offline.RAMUsage)
+	}
+	if offline.DiskUsage == nil || *offline.DiskUsage != 55 || offline.InodeUsage == nil || *offline.InodeUsage != 12 {
+		t.Errorf("disk %v / inodes %v, want the last-known 55 / 12", offline.DiskUsage, offline.InodeUsage)
+	}
+	if !offline.LastSeen.Equal(lastSeen) {
+		t.Errorf("lastSeen %v, want %v", offline.LastSeen, lastSeen)
+	}
+	if offline.RecentlyRebooted {
+		t.Error("offline host flagged as recently rebooted")
+	}
+	if got := hosts["online"].Status; got != "online" {
+		t.Errorf("reporting host: status %q, want online", got)
+	}
+}
+
+func TestOverviewToleratesMissingTags(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	now := time.Now()
+	table := overviewTable(overviewRow{hostID: "host-1", cpu: 10, mem: 20, at: now})
+	// the same columns as overviewTable: a record without a hostname, and one without a host_id
+	table.Row("no-hostname", nil, 30.0, 40.0, int64(60), false, 0.0, 1000.0, 2000.0, false, 0.0, false, 0.0, now)
+	table.Row(nil, "orphan.example.com", 50.0, 60.0, int64(60), false, 0.0, 1000.0, 2000.0, false, 0.0, false, 0.0, now)
+	server.Respond(`yield(name: "overview")`, table)
+
+	hosts := overviewByID(t, reader) // fails the test rather than panicking
+	if len(hosts) != 2 {
+		t.Errorf("hosts = %v, want host-1 and no-hostname, the record without a host_id skipped", hosts)
+	}
+	host, ok := hosts["no-hostname"]
+	if !ok {
+		t.Fatal("the record without a hostname is not in the overview")
+	}
+	if host.Hostname != "no-hostname" || host.CPUUsage != 30 || host.RAMUsage != 40 {
+		t.Errorf("no-hostname = hostname %q, CPU %v, RAM %v, want the host_id as hostname, 30, 40", host.Hostname, host.CPUUsage, host.RAMUsage)
+	}
+	if hosts["host-1"].Hostname != "host-1.example.com" {
+		t.Errorf("host-1 hostname = %q, want host-1.example.com", hosts["host-1"].Hostname)
+	}
+}
+
+func TestUsageStatusTiers(t *testing.T) {
+	reader, server := newTestReader(t, nil) // warning at 85%, critical at 95%
+	now := time.Now()
+	respondOverview(server,
+		overviewRow{hostID: "cpu-95", cpu: 95, mem: 20, at: now},
+		overviewRow{hostID: "cpu-88", cpu: 88, mem: 20, at: now},
+		overviewRow{hostID: "cpu-85", cpu: 85, mem: 20, at: now},
+		overviewRow{hostID: "cpu-84.9", cpu: 84.9, mem: 20, at: now},
+		overviewRow{hostID: "ram-97", cpu: 10, mem: 97, at: now},
+		overviewRow{hostID: "disk-95", cpu: 10, mem: 20, disk: percent(95), at: now},
+		overviewRow{hostID: "disk-90", cpu: 10, mem: 20, disk: percent(90), at: now},
+	)
+
+	hosts := overviewByID(t, reader)
+	for id, want := range map[string]string{
+		"cpu-95":   "critical",
+		"cpu-88":   "warning",
+		"cpu-85":   "warning", // at the threshold counts
+		"cpu-84.9": "online",
+		"ram-97":   "critical",
+		"disk-95":  "critical",
+		"disk-90":  "warning",
+	} {
+		if got := hosts[id].Status; got != want {
+			t.Errorf("%s: status %q, want %q", id, got, want)
+		}
+	}
+
+	// host details use the same tiers
+	for cpu, want := range map[float64]string{95: "critical", 88: "warning"} {
+		reader, server := newTestReader(t, nil)
+		respondHost(server, map[string]any{"host_id": "host-1", "hostname": "host-1", "cpu_usage_percent": cpu})
+		details, err := reader.GetHostDetails(context.Background(), "host-1")
+		if err != nil {
+			t.Fatalf("GetHostDetails: %v", err)
+		}
+		if details.Status != want {
+			t.Errorf("details at %v%% CPU: status %q, want %q", cpu, details.Status, want)
+		}
+	}
+}
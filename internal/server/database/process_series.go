@@ -0,0 +1,100 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// processPoints builds the process_metrics points of one report, in the
+// PROCESS_SERIES shape. Tagging by PID (as older servers did) made every
+// short-lived process a new series; both shapes here only add a series per
+// process name (and, in instance mode, per concurrent instance of it).
+//
+//   - "aggregated": one point per name, tagged name, with the instances'
+//     cpu_percent, mem_percent and I/O summed, instance_count, and the
+//     oldest instance's create_time and age_seconds.
+//   - "instance": one point per process, tagged name and instance (0, 1, ...
+//     among the processes of that name, by PID), with the PID in the
+//     process_id field.
+func processPoints(tags map[string]string, processes []models.ProcessPayload, mode string, at time.Time) []pointWrite {
+	byName := make(map[string][]models.ProcessPayload)
+	var names []string
+	for _, proc := range processes {
+		if _, seen := byName[proc.Name]; !seen {
+			names = append(names, proc.Name)
+		}
+		byName[proc.Name] = append(byName[proc.Name], proc)
+	}
+	sort.Strings(names)
+
+	var points []pointWrite
+	for _, name := range names {
+		instances := byName[name]
+		if mode == "instance" {
+			sort.Slice(instances, func(i, j int) bool { return instances[i].PID < instances[j].PID })
+			for i, proc := range instances {
+				processTags := withTags(tags, "name", name, "instance", strconv.Itoa(i))
+				fields := processFields(proc)
+				fields["process_id"] = int64(proc.PID)
+				points = append(points, pointWrite{
+					write.NewPoint("process_metrics", processTags, fields, at),
+					fmt.Sprintf("process_metrics point for process %s (PID %d)", name, proc.PID),
+				})
+			}
+			continue
+		}
+
+		var sum models.ProcessPayload
+		for _, proc := range instances {
+			sum.CPUPercent += proc.CPUPercent
+			sum.MemoryPercent += proc.MemoryPercent
+			sum.ReadBytes += proc.ReadBytes
+			sum.WriteBytes += proc.WriteBytes
+			sum.ReadBytesPerSec += proc.ReadBytesPerSec
+			sum.WriteBytesPerSec += proc.WriteBytesPerSec
+			if proc.CreateTime > 0 && (sum.CreateTime == 0 || proc.CreateTime < sum.CreateTime) {
+				sum.CreateTime = proc.CreateTime
+			}
+			sum.AgeSeconds = max(sum.AgeSeconds, proc.AgeSeconds)
+		}
+		fields := processFields(sum)
+		delete(fields, "user") // instances may run as different users
+		fields["instance_count"] = int64(len(instances))
+		points = append(points, pointWrite{
+			write.NewPoint("process_metrics", withTags(tags, "name", name), fields, at),
+			fmt.Sprintf("process_metrics point for %d process(es) named %s", len(instances), name),
+		})
+	}
+	return points
+}
+
+func processFields(proc models.ProcessPayload) map[string]interface{} {
+	return map[string]interface{}{
+		"cpu_percent":         proc.CPUPercent,
+		"mem_percent":         proc.MemoryPercent,
+		"user":                proc.Username,
+		"create_time":         proc.CreateTime, // epoch millis
+		"age_seconds":         proc.AgeSeconds,
+		"read_bytes":          proc.ReadBytes,
+		"write_bytes":         proc.WriteBytes,
+		"read_bytes_per_sec":  proc.ReadBytesPerSec,
+		"write_bytes_per_sec": proc.WriteBytesPerSec,
+	}
+}
+
+// withTags copies tags and adds the alternating keys and values of kv.
+func withTags(tags map[string]string, kv ...string) map[string]string {
+	copied := make(map[string]string, len(tags)+len(kv)/2)
+	for k, v := range tags {
+		copied[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		copied[kv[i]] = kv[i+1]
+	}
+	return copied
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// ProcessSummaryMaxTop is how many of a process summary's top processes are
+// stored, as top_<n>_name, top_<n>_cpu_percent and top_<n>_mem_percent.
+const ProcessSummaryMaxTop = 5
+
+// latestProcessSummary returns the newest process_summary point of a host
+// within the process lookback, nil if the host sent none. The caller holds
+// the query slot.
+func (r *InfluxDBReader) latestProcessSummary(ctx context.Context, hostID string) (*models.ProcessSummaryDetail, error) {
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "process_summary" and r.host_id == %s)
+			|> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+			|> group()
+			|> sort(columns: ["_time"])
+			|> last(column: "_time")
+	`, r.bucket, r.processLookback, fluxString(hostID))
+
+	appLogger.FromContext(ctx).Debug("GetHostDetails Process Summary Query for host %s:\n%s", hostID, query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query influxdb for process summary: %w", err)
+	}
+	defer results.Close()
+
+	if !results.Next() {
+		if results.Err() != nil {
+			return nil, fmt.Errorf("process query results for process summary: %w", results.Err())
+		}
+		return nil, nil
+	}
+	record := results.Record()
+	summary := &models.ProcessSummaryDetail{Time: record.Time().UTC(), Top: []models.ProcessSummaryEntry{}}
+	summary.Count, _ = record.ValueByKey("process_count").(int64)
+	summary.TopCPUPercent, _ = record.ValueByKey("top_cpu_percent").(float64)
+	for i := 1; i <= ProcessSummaryMaxTop; i++ {
+		name, ok := record.ValueByKey(fmt.Sprintf("top_%d_name", i)).(string)
+		if !ok {
+			break
+		}
+		top := models.ProcessSummaryEntry{Name: name}
+		top.CPUPercent, _ = record.ValueByKey(fmt.Sprintf("top_%d_cpu_percent", i)).(float64)
+		memPercent, _ := record.ValueByKey(fmt.Sprintf("top_%d_mem_percent", i)).(float64)
+		top.MemoryPercent = float32(memPercent)
+		summary.Top = append(summary.Top, top)
+	}
+	return summary, nil
+}
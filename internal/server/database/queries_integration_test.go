@@ -0,0 +1,395 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// queryFixtures are the hosts TestReaderQueries writes and the values the
+// reader should return for them.
+type queryFixtures struct {
+	now time.Time
+
+	online  string // latest point now, root disk, CPU history
+	noDisk  string // reports /data but no root disk, and processes
+	offline string // latest point older than OnlineWithin
+	summary string // agent in process summary mode
+
+	history []float64 // online's CPU once a minute, oldest first, ending with latest
+	latest  float64
+}
+
+// fixtureIngestLag is the time between collected_at and received_at.
+const fixtureIngestLag = 2 * time.Second
+
+var (
+	fixtureRootDisk = models.DiskUsagePayload{Path: "/", TotalGB: 100, UsedGB: 40, FreeGB: 60, UsagePercent: 40, InodesTotal: 1000, InodesUsed: 250, InodesUsagePercent: 25}
+	fixtureDataDisk = models.DiskUsagePayload{Path: "/data", TotalGB: 500, UsedGB: 350, FreeGB: 150, UsagePercent: 70}
+)
+
+func fixturePayload(hostID string, at time.Time, cpu float64, disks ...models.DiskUsagePayload) *models.ClientPayload {
+	return &models.ClientPayload{
+		CollectedAt:  at,
+		ReceivedAt:   at.Add(fixtureIngestLag),
+		AgentVersion: "integration",
+		System:       models.SystemInfoPayload{HostID: hostID, Hostname: hostID, OS: "linux", UptimeSeconds: 172800, LoggedInUsers: 2},
+		CPU:          models.CPUInfoPayload{ModelName: "Check CPU", Cores: 4, Usage: cpu},
+		Memory:       models.MemInfoPayload{TotalGB: 16, FreeGB: 10, UsagePercent: 37.5},
+		Network:      models.NetworkPayload{InterfaceName: "all", UploadBytesPerSec: 1000, DownloadBytesPerSec: 2000},
+		Interfaces: []models.InterfacePayload{
+			{Name: "eth0", Up: true, SpeedMbps: 100, UploadBytesPerSec: 1000, DownloadBytesPerSec: 2_500_000},
+			{Name: "wg0", Up: true, RatesSkipped: true},
+		},
+		Disks: disks,
+	}
+}
+
+// processFixture reports /data only, three processes and two services.
+func processFixture(hostID string, at time.Time) *models.ClientPayload {
+	p := fixturePayload(hostID, at, 5, fixtureDataDisk)
+	p.Processes = []models.ProcessPayload{
+		{PID: 300, Name: "nginx", CPUPercent: 2, MemoryPercent: 1},
+		{PID: 100, Name: "nginx", CPUPercent: 4, MemoryPercent: 3},
+		{PID: 200, Name: "postgres", CPUPercent: 10, MemoryPercent: 20},
+	}
+	p.Services = []models.ServicePayload{
+		{Unit: "nginx.service", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+		{Unit: "backup", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+	}
+	return p
+}
+
+// summaryFixture is an agent in process summary mode.
+func summaryFixture(hostID string, at time.Time) *models.ClientPayload {
+	p := fixturePayload(hostID, at, 30, fixtureRootDisk)
+	p.System.UptimeSeconds = 0
+	p.System.Uptime = "10m0s"  // an older agent without uptime_seconds, just rebooted
+	p.ReceivedAt = time.Time{} // and written before received_at was recorded
+	p.ProcessSummary = &models.ProcessSummaryPayload{
+		Count:         212,
+		TopCPUPercent: 45,
+		Top: []models.ProcessSummaryEntry{
+			{Name: "postgres", CPUPercent: 30, MemoryPercent: 12},
+			{Name: "nginx", CPUPercent: 15, MemoryPercent: 2},
+		},
+	}
+	return p
+}
+
+// writeQueryFixtures writes every fixture point through the writer, oldest first.
+func writeQueryFixtures(t *testing.T, it *integration) *queryFixtures {
+	t.Helper()
+	f := &queryFixtures{
+		now:     time.Now().UTC().Add(-time.Second), // not ahead of InfluxDB's clock
+		online:  it.hostID("online"),
+		noDisk:  it.hostID("nodisk"),
+		offline: it.hostID("offline"),
+		summary: it.hostID("summary"),
+		history: []float64{10, 20, 30, 40, 12.5},
+		latest:  12.5,
+	}
+	for i, cpu := range f.history {
+		at := f.now.Add(-time.Duration(len(f.history)-1-i) * time.Minute)
+		p := fixturePayload(f.online, at, cpu, fixtureRootDisk, fixtureDataDisk)
+		p.Throttle = &models.ThrottlePayload{Throttled: true, ThrottleCount: 7, Flags: 0x50005}
+		it.write(t, p)
+	}
+	it.write(t,
+		processFixture(f.noDisk, f.now),
+		fixturePayload(f.offline, f.now.Add(-it.cfg.OnlineWithin-time.Minute), 55, fixtureRootDisk),
+		summaryFixture(f.summary, f.now),
+	)
+	return f
+}
+
+func TestReaderQueries(t *testing.T) {
+	it := newIntegration(t, nil)
+	f := writeQueryFixtures(t, it)
+	reader := it.reader
+	ctx := context.Background()
+
+	overview := func(t *testing.T, hostID string) models.HostOverviewData {
+		t.Helper()
+		hosts, err := reader.GetHostOverviewList(ctx)
+		if err != nil {
+			t.Fatalf("GetHostOverviewList: %v", err)
+		}
+		for _, host := range hosts {
+			if host.ID == hostID {
+				return host
+			}
+		}
+		t.Fatalf("host %s not in the overview", hostID)
+		return models.HostOverviewData{}
+	}
+	details := func(t *testing.T, hostID string) *models.HostDetailsData {
+		t.Helper()
+		details, err := reader.GetHostDetails(ctx, hostID)
+		if err != nil {
+			t.Fatalf("GetHostDetails(%s): %v", hostID, err)
+		}
+		return details
+	}
+
+	t.Run("overview: online host with root disk", func(t *testing.T) {
+		host := overview(t, f.online)
+		expect(t, "status", host.Status, "online")
+		expectFloat(t, "cpuUsage", host.CPUUsage, f.latest)
+		expectFloat(t, "ramUsage", host.RAMUsage, 37.5)
+		expectFloatPtr(t, "diskUsage", host.DiskUsage, 40)
+		expectFloatPtr(t, "inodeUsage", host.InodeUsage, 25)
+		expectFloat(t, "networkDownload", host.NetworkDownload, 2000)
+		expectFloatPtr(t, "ingestLagSeconds", host.IngestLagSeconds, fixtureIngestLag.Seconds())
+	})
+	t.Run("overview: no ingest lag recorded", func(t *testing.T) {
+		if host := overview(t, f.summary); host.IngestLagSeconds != nil {
+			t.Errorf("ingestLagSeconds = %v, want null", *host.IngestLagSeconds)
+		}
+	})
+	t.Run("overview: host without root disk", func(t *testing.T) {
+		host := overview(t, f.noDisk)
+		if host.DiskUsage != nil || host.InodeUsage != nil {
+			t.Errorf("diskUsage/inodeUsage = %v/%v, want null", host.DiskUsage, host.InodeUsage)
+		}
+		expect(t, "status", host.Status, "online")
+	})
+	t.Run("overview: offline host keeps its last values", func(t *testing.T) {
+		host := overview(t, f.offline)
+		expect(t, "status", host.Status, "offline")
+		expectFloat(t, "cpuUsage", host.CPUUsage, 55)
+		expectFloatPtr(t, "diskUsage", host.DiskUsage, 40)
+	})
+
+	t.Run("details: online host", func(t *testing.T) {
+		details := details(t, f.online)
+		if details.Disk == nil {
+			t.Fatal("disk is null, want the root disk")
+		}
+		if len(details.Disks) != 2 || details.Disks[0].Path != "/data" || details.Disks[1].Path != "/" {
+			t.Errorf("disks %+v, want /data then /", details.Disks)
+		}
+		expect(t, "status", details.Status, "online")
+		expect(t, "hostname", details.Hostname, f.online)
+		expect(t, "processMode", details.ProcessMode, "list")
+		expect(t, "uptimeSeconds", details.UptimeSeconds, int64(172800))
+		expect(t, "recentlyRebooted", details.RecentlyRebooted, false)
+		expect(t, "cpu.modelName", details.CPU.ModelName, "Check CPU")
+		expectFloat(t, "cpuUsage", details.CPUUsage, f.latest)
+		expectFloat(t, "ramUsage", details.RAMUsage, 37.5)
+		expectFloat(t, "memory.usage_percent", details.Memory.UsagePercent, 37.5)
+		expectFloat(t, "memory.used_gb", details.Memory.UsedGB, 6)
+		expectFloat(t, "memory.total_gb", details.Memory.TotalGB, 16)
+		expect(t, "disk.path", details.Disk.Path, "/")
+		expectFloat(t, "disk.usage_percent", details.Disk.UsagePercent, 40)
+		expect(t, "loggedInUsers", details.LoggedInUsers, int64(2))
+		expect(t, "throttle", fmt.Sprint(details.Throttle), "&{true 7 327685}")
+	})
+	t.Run("details: v2 schema", func(t *testing.T) {
+		// Through JSON, so the keys are checked along with the values
+		data, err := json.Marshal(models.NewHostDetailsV2(details(t, f.online)))
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var v2 struct {
+			Memory map[string]float64 `json:"memory"`
+			CPU    struct {
+				UsagePercent float64 `json:"usage_percent"`
+			} `json:"cpu"`
+		}
+		if err := json.Unmarshal(data, &v2); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		expectFloat(t, "memory.used_gb", v2.Memory["used_gb"], 6)
+		expectFloat(t, "memory.usage_percent", v2.Memory["usage_percent"], 37.5)
+		expectFloat(t, "memory.available_gb", v2.Memory["available_gb"], 10)
+		expectFloat(t, "cpu.usage_percent", v2.CPU.UsagePercent, f.latest)
+	})
+	t.Run("details: process summary mode", func(t *testing.T) {
+		details := details(t, f.summary)
+		summary := details.ProcessSummary
+		if summary == nil {
+			t.Fatal("processSummary is null")
+		}
+		if len(summary.Top) != 2 || summary.Top[0].Name != "postgres" || summary.Top[1].Name != "nginx" {
+			t.Fatalf("top %+v, want postgres then nginx", summary.Top)
+		}
+		if len(details.Processes) != 0 {
+			t.Errorf("processes %+v, want none in summary mode", details.Processes)
+		}
+		expect(t, "processMode", details.ProcessMode, "summary")
+		expect(t, "uptimeSeconds", details.UptimeSeconds, int64(600))
+		expect(t, "recentlyRebooted", details.RecentlyRebooted, true)
+		expect(t, "status", details.Status, "warning")
+		expect(t, "processSummary.count", summary.Count, int64(212))
+		expectFloat(t, "processSummary.top_cpu_percent", summary.TopCPUPercent, 45)
+		expectFloat(t, "top[0].cpu_percent", summary.Top[0].CPUPercent, 30)
+	})
+	t.Run("details: host without root disk", func(t *testing.T) {
+		details := details(t, f.noDisk)
+		if details.Disk != nil {
+			t.Errorf("disk = %+v, want null", *details.Disk)
+		}
+		if len(details.Disks) != 1 || details.Disks[0].Path != "/data" {
+			t.Errorf("disks %+v, want only /data", details.Disks)
+		}
+		if details.Throttle != nil {
+			t.Errorf("throttle = %+v, want null", *details.Throttle)
+		}
+	})
+	t.Run("details: services", func(t *testing.T) {
+		var got []string
+		for _, s := range details(t, f.noDisk).Services {
+			got = append(got, fmt.Sprintf("%s %t %s/%s/%s", s.Unit, s.Active, s.LoadState, s.ActiveState, s.SubState))
+		}
+		expect(t, "services", fmt.Sprint(got), "[backup false loaded/failed/failed nginx.service true loaded/active/running]")
+		expect(t, "services without MONITOR_SERVICES", fmt.Sprint(details(t, f.online).Services), "[]")
+	})
+
+	t.Run("history: cpu_usage_percent per minute", func(t *testing.T) {
+		start := f.now.Add(-time.Duration(len(f.history)) * time.Minute)
+		points, err := reader.GetHostMetricHistory(ctx, f.online, "cpu_usage_percent", start, time.Now(), time.Minute)
+		if err != nil {
+			t.Fatalf("GetHostMetricHistory: %v", err)
+		}
+		var values []float64
+		for _, p := range points {
+			values = append(values, p.Value)
+		}
+		expect(t, "values", fmt.Sprint(values), fmt.Sprint(f.history))
+	})
+	t.Run("history: several metrics in one query", func(t *testing.T) {
+		start := f.now.Add(-time.Duration(len(f.history)) * time.Minute)
+		fields := []string{"cpu_usage_percent", "mem_usage_percent", "cpu_usage_percent"}
+		history, err := reader.GetHostMetricsHistory(ctx, f.online, fields, start, time.Now(), time.Minute)
+		if err != nil {
+			t.Fatalf("GetHostMetricsHistory: %v", err)
+		}
+		if len(history) != 2 {
+			t.Fatalf("%d metrics, want cpu_usage_percent and mem_usage_percent", len(history))
+		}
+		var cpu, mem []float64
+		for _, p := range history["cpu_usage_percent"] {
+			cpu = append(cpu, p.Value)
+		}
+		for _, p := range history["mem_usage_percent"] {
+			mem = append(mem, p.Value)
+		}
+		expect(t, "cpu_usage_percent", fmt.Sprint(cpu), fmt.Sprint(f.history))
+		expect(t, "mem_usage_percent", fmt.Sprint(mem), "[37.5 37.5 37.5 37.5 37.5]")
+	})
+	t.Run("delta: cpu_usage_percent over 2m", func(t *testing.T) {
+		// Current window: the last two points (40, 12.5), previous: the two before (20, 30)
+		delta, err := reader.GetHostMetricDelta(ctx, f.online, "cpu_usage_percent", 2*time.Minute)
+		if err != nil {
+			t.Fatalf("GetHostMetricDelta: %v", err)
+		}
+		expectFloatPtr(t, "current", delta.Current, 26.25)
+		expectFloatPtr(t, "previous", delta.Previous, 25)
+		expectFloatPtr(t, "percentChange", delta.PercentChange, 5)
+	})
+	t.Run("latest: cpu_usage_percent", func(t *testing.T) {
+		point, err := reader.GetHostLatestField(ctx, f.online, "cpu_usage_percent")
+		if err != nil {
+			t.Fatalf("GetHostLatestField: %v", err)
+		}
+		if point == nil {
+			t.Fatal("no point")
+		}
+		expectFloat(t, "value", point.Value, f.latest)
+	})
+	t.Run("hosts: lightweight list", func(t *testing.T) {
+		hosts, err := reader.ListHosts(ctx, f.now.Add(-time.Hour), f.now.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("ListHosts: %v", err)
+		}
+		// The bucket may hold other runs' hosts; ours sort by hostname (= host_id)
+		var got []string
+		var onlineSeen time.Time
+		for _, host := range hosts {
+			switch host.ID {
+			case f.online:
+				onlineSeen = host.LastSeen
+				fallthrough
+			case f.noDisk, f.offline, f.summary:
+				got = append(got, host.Hostname)
+			}
+		}
+		expect(t, "hosts", fmt.Sprint(got), fmt.Sprint([]string{f.noDisk, f.offline, f.online, f.summary}))
+		expect(t, "online lastSeen", onlineSeen.Equal(f.now), true)
+	})
+	t.Run("interfaces", func(t *testing.T) {
+		interfaces, err := reader.GetHostInterfaces(ctx, f.online)
+		if err != nil {
+			t.Fatalf("GetHostInterfaces: %v", err)
+		}
+		if len(interfaces) != 2 || interfaces[0].Name != "eth0" || interfaces[1].Name != "wg0" {
+			t.Fatalf("interfaces %+v, want eth0 and wg0", interfaces)
+		}
+		eth0, wg0 := interfaces[0], interfaces[1]
+		if wg0.NetworkUpload != nil || wg0.Utilization != nil {
+			t.Error("wg0 has rates without the agent reporting them")
+		}
+		// 2.5 MB/s = 20 Mbit/s of a 100 Mbit/s link
+		expectFloatPtr(t, "eth0 networkDownload", eth0.NetworkDownload, 2_500_000)
+		expectFloatPtr(t, "eth0 utilizationPercent", eth0.Utilization, 20)
+	})
+}
+
+// GetHostDetails' processes in both PROCESS_SERIES layouts.
+func TestReaderProcessSeries(t *testing.T) {
+	for _, tt := range []struct {
+		series, mode, want string
+	}{
+		{"instance", "list", "[100 nginx x1 4% 200 postgres x1 10% 300 nginx x1 2%]"},
+		{"aggregated", "aggregated", "[0 nginx x2 6% 0 postgres x1 10%]"},
+	} {
+		t.Run(tt.series, func(t *testing.T) {
+			it := newIntegration(t, func(cfg *config.InfluxDBConfig) { cfg.ProcessSeries = tt.series })
+			hostID := it.hostID("processes-" + tt.series)
+			it.write(t, processFixture(hostID, time.Now().UTC().Add(-time.Second)))
+
+			details, err := it.reader.GetHostDetails(context.Background(), hostID)
+			if err != nil {
+				t.Fatalf("GetHostDetails: %v", err)
+			}
+			var got []string
+			for _, p := range details.Processes {
+				got = append(got, fmt.Sprintf("%d %s x%d %g%%", p.PID, p.Name, p.Instances, p.CPUPercent))
+			}
+			expect(t, "processMode", details.ProcessMode, tt.mode)
+			expect(t, "processes", fmt.Sprint(got), tt.want)
+		})
+	}
+}
+
+func expect[T comparable](t *testing.T, name string, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func expectFloat(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func expectFloatPtr(t *testing.T, name string, got *float64, want float64) {
+	t.Helper()
+	if got == nil {
+		t.Errorf("%s = null, want %v", name, want)
+		return
+	}
+	expectFloat(t, name, *got, want)
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+)
+
+// ErrQueryTooExpensive is returned when a query's estimated cost exceeds the
+// configured point budget. The query is not sent to InfluxDB.
+var ErrQueryTooExpensive = errors.New("query too expensive")
+
+// ErrTooManyFields is returned when a multi-field history request asks for
+// more distinct fields than INFLUXDB_HISTORY_MAX_FIELDS.
+var ErrTooManyFields = errors.New("too many fields requested")
+
+// rawSampleInterval is the agent's collection interval: every host writes one
+// value per field this often, so it sets how many raw points a range scans.
+const rawSampleInterval = 5 * time.Second
+
+// queryEstimate describes the shape of a range query for cost estimation.
+// Aggregation doesn't help here, InfluxDB still reads every raw point in the range.
+type queryEstimate struct {
+	Hosts      int
+	Fields     int
+	Span       time.Duration
+	Resolution time.Duration // time between stored points, 0 = rawSampleInterval
+}
+
+// Points is the estimated number of raw points the query reads.
+func (q queryEstimate) Points() int64 {
+	hosts, fields := max(q.Hosts, 1), max(q.Fields, 1)
+	resolution := q.Resolution
+	if resolution <= 0 {
+		resolution = rawSampleInterval
+	}
+	perSeries := int64(q.Span/resolution) + 1
+	return int64(hosts) * int64(fields) * perSeries
+}
+
+// checkQueryCost rejects (or only logs, depending on config) queries estimated
+// over the point budget. Must be called before acquiring a query slot.
+func (r *InfluxDBReader) checkQueryCost(name string, est queryEstimate) error {
+	if r.pointBudget <= 0 {
+		return nil
+	}
+	points := est.Points()
+	if points <= r.pointBudget {
+		return nil
+	}
+	if !r.rejectOverBudget {
+		appLogger.Warn("%s query over budget: ~%d points (%d hosts x %d fields over %s), budget %d", name, points, est.Hosts, est.Fields, est.Span, r.pointBudget)
+		return nil
+	}
+	appLogger.Warn("Rejected %s query: ~%d points (%d hosts x %d fields over %s), budget %d", name, points, est.Hosts, est.Fields, est.Span, r.pointBudget)
+	return fmt.Errorf("%w: ~%d points estimated, budget is %d", ErrQueryTooExpensive, points, r.pointBudget)
+}
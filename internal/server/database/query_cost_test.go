@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+)
+
+func TestQueryEstimatePoints(t *testing.T) {
+	tests := []struct {
+		est  queryEstimate
+		want int64
+	}{
+		{queryEstimate{Hosts: 1, Fields: 1, Span: time.Hour}, 721},
+		{queryEstimate{Hosts: 10, Fields: 2, Span: time.Hour}, 14420},
+		{queryEstimate{Hosts: 1, Fields: 1, Span: 24 * time.Hour, Resolution: 5 * time.Minute}, 289},
+		{queryEstimate{Span: time.Minute}, 13}, // at least one host and field
+	}
+	for _, tt := range tests {
+		if got := tt.est.Points(); got != tt.want {
+			t.Errorf("%+v: Points() = %d, want %d", tt.est, got, tt.want)
+		}
+	}
+}
+
+func TestOverBudgetQueryIsRejectedBeforeExecution(t *testing.T) {
+	budget := func(reject bool) func(*config.InfluxDBConfig) {
+		return func(cfg *config.InfluxDBConfig) {
+			cfg.QueryPointBudget = 1000 // a bit over 80 minutes of one raw field
+			cfg.RejectOverBudget = reject
+		}
+	}
+	end := time.Now()
+
+	reader, server := newTestReader(t, budget(true))
+	_, err := reader.GetHostMetricHistory(context.Background(), "host-1", "cpu_usage_percent", end.Add(-2*time.Hour), end, time.Minute)
+	if !errors.Is(err, ErrQueryTooExpensive) {
+		t.Fatalf("2h of raw points: error = %v, want ErrQueryTooExpensive", err)
+	}
+	if queries := server.Queries(); len(queries) != 0 {
+		t.Errorf("%d queries sent for a rejected request, want none", len(queries))
+	}
+
+	if _, err := reader.GetHostMetricHistory(context.Background(), "host-1", "cpu_usage_percent", end.Add(-time.Hour), end, time.Minute); err != nil {
+		t.Fatalf("1h of raw points: %v", err)
+	}
+	if queries := server.Queries(); len(queries) != 1 {
+		t.Errorf("%d queries sent within budget, want 1", len(queries))
+	}
+
+	// with INFLUXDB_QUERY_REJECT_OVER_BUDGET=false the query only logs
+	reader, server = newTestReader(t, budget(false))
+	if _, err := reader.GetHostMetricHistory(context.Background(), "host-1", "cpu_usage_percent", end.Add(-2*time.Hour), end, time.Minute); err != nil {
+		t.Fatalf("over budget, not rejecting: %v", err)
+	}
+	if queries := server.Queries(); len(queries) != 1 {
+		t.Errorf("%d queries sent over budget without rejecting, want 1", len(queries))
+	}
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb-client-go/v2/api"
+)
+
+// queryResult is a query's result tied to the caller's context. Once ctx is
+// done (the dashboard client went away, or the route timed out) Next stops,
+// even for rows that are already buffered, and Err reports why. The HTTP
+// request carries ctx too, so the response stops streaming at the same time.
+type queryResult struct {
+	*api.QueryTableResult
+	ctx       context.Context
+	cancelled bool // Next stopped because ctx was done
+}
+
+func (q *queryResult) Next() bool {
+	if q.ctx.Err() != nil {
+		q.cancelled = true
+		return false
+	}
+	return q.QueryTableResult.Next()
+}
+
+func (q *queryResult) Err() error {
+	if err := q.QueryTableResult.Err(); err != nil {
+		return err
+	}
+	if q.cancelled {
+		return q.ctx.Err()
+	}
+	return nil
+}
+
+// Close closes the response body. QueryTableResult.Close reads the rest of
+// the response first, which after an early return would stream a query
+// nobody reads to the end; closing the body instead drops the connection and
+// InfluxDB abandons the query. A result read to the end is already closed.
+func (q *queryResult) Close() error {
+	return q.QueryTableResult.Closer.Close()
+}
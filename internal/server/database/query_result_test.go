@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+// stall answers queries containing match with table and then keeps the
+// response open, like a long-running query still streaming. streamed is
+// closed once the table is sent, disconnected once the reader dropped the
+// connection.
+func stall(server *influxtest.Server, match string, table *influxtest.Table) (streamed, disconnected <-chan struct{}) {
+	streamedC, disconnectedC := make(chan struct{}), make(chan struct{})
+	body := influxtest.CSV(table)
+	server.RespondFunc(match, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body) // the disconnect cancels r.Context() only after the body's EOF
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_, _ = io.WriteString(w, body)
+		w.(http.Flusher).Flush()
+		close(streamedC)
+		<-r.Context().Done()
+		close(disconnectedC)
+	})
+	return streamedC, disconnectedC
+}
+
+// pointsTable is n points of a system_metrics field, one a second.
+func pointsTable(field string, n int) *influxtest.Table {
+	table := influxtest.NewTable("_time:time", "_value:double", "_field", "host_id")
+	start := time.Now().Add(-time.Duration(n) * time.Second)
+	for i := 0; i < n; i++ {
+		table.Row(start.Add(time.Duration(i)*time.Second), float64(i), field, "host-1")
+	}
+	return table
+}
+
+func TestCancelledQueryStopsPromptly(t *testing.T) {
+	end := time.Now()
+	start := end.Add(-time.Hour)
+	tests := []struct {
+		name  string
+		match string
+		call  func(ctx context.Context, r *InfluxDBReader) error
+	}{
+		{"metric history", "aggregateWindow", func(ctx context.Context, r *InfluxDBReader) error {
+			_, err := r.GetHostMetricHistory(ctx, "host-1", "cpu_usage_percent", start, end, 30*time.Second)
+			return err
+		}},
+		{"metrics history", "targetFields = ", func(ctx context.Context, r *InfluxDBReader) error {
+			_, err := r.GetHostMetricsHistory(ctx, "host-1", []string{"cpu_usage_percent", "mem_usage_percent"}, start, end, 30*time.Second)
+			return err
+		}},
+		{"metric delta", `value: "current"`, func(ctx context.Context, r *InfluxDBReader) error {
+			_, err := r.GetHostMetricDelta(ctx, "host-1", "cpu_usage_percent", 5*time.Minute)
+			return err
+		}},
+		{"latest field", "last()", func(ctx context.Context, r *InfluxDBReader) error {
+			_, err := r.GetHostLatestField(ctx, "host-1", "cpu_usage_percent")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, server := newTestReader(t, nil)
+			streamed, disconnected := stall(server, tt.match, pointsTable("cpu_usage_percent", 500))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				<-streamed
+				cancel() // the dashboard client navigated away
+			}()
+			done := make(chan error, 1)
+			go func() { done <- tt.call(ctx, reader) }()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the reader is still reading 5s after the cancellation")
+			}
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("err = %v, want context.Canceled", err)
+			}
+			select {
+			case <-disconnected:
+			case <-time.After(5 * time.Second):
+				t.Error("the query's response is still open, want it closed")
+			}
+		})
+	}
+}
+
+func TestEarlyReturnClosesTheResult(t *testing.T) {
+	reader, server := newTestReader(t, nil)
+	// latestProcessSummary reads the first row and returns, with the query
+	// still streaming and ctx alive
+	_, disconnected := stall(server, `r._measurement == "process_summary"`, influxtest.NewTable("_time:time", "process_count:long", "top_cpu_percent:double", "top_1_name").
+		Row(time.Now(), int64(312), 40.0, "postgres").
+		Row(time.Now(), int64(311), 39.0, "postgres"))
+
+	summary, err := reader.latestProcessSummary(context.Background(), "host-1")
+	if err != nil {
+		t.Fatalf("latestProcessSummary: %v", err)
+	}
+	if summary == nil || summary.Count != 312 {
+		t.Errorf("summary = %+v, want the first row", summary)
+	}
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Error("the query's response is still open after the early return, want it closed")
+	}
+}
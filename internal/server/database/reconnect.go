@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+)
+
+// reconnectingClient owns an influxdb2.Client and replaces it after a run of
+// connection-level errors. A client that survived a network partition can keep
+// failing on stale keep-alive connections even after connectivity returns.
+//
+// With several endpoints (INFLUXDB_URL is a list) the replacement connects to
+// the next endpoint instead, and while a later endpoint is in use the earlier
+// ones are probed every probeEvery so the client fails back once they pass
+// their health check again.
+type reconnectingClient struct {
+	name  string   // for logs, "client"
+	urls  []string // endpoints in order of preference
+	token string
+
+	threshold     int           // consecutive connection errors before recreating
+	cooldown      time.Duration // min time between two recreates
+	probeEvery    time.Duration
+	healthTimeout time.Duration
+
+	mu            sync.RWMutex
+	client        influxdb2.Client
+	active        int // index of the client's endpoint in urls
+	failures      int
+	lastReconnect time.Time
+
+	reconnects atomic.Int64
+	stop       chan struct{}
+}
+
+func newReconnectingClient(name string, cfg config.InfluxDBConfig) *reconnectingClient {
+	c := &reconnectingClient{
+		name:          name,
+		urls:          cfg.URLs,
+		token:         cfg.Token,
+		threshold:     max(cfg.ReconnectAfterErrors, 1),
+		cooldown:      cfg.ReconnectCooldown,
+		probeEvery:    cfg.FailbackProbeInterval,
+		healthTimeout: cfg.HealthCheckTimeout,
+		client:        influxdb2.NewClient(cfg.URLs[0], cfg.Token),
+		stop:          make(chan struct{}),
+	}
+	if len(c.urls) > 1 && c.probeEvery > 0 {
+		go c.runFailbackProbes()
+	}
+	return c
+}
+
+// connect health-checks every endpoint and switches to the first healthy
+// one. It only fails if none is healthy, the client then stays on the first.
+func (c *reconnectingClient) connect() (string, error) {
+	var problems []error
+	healthy := -1
+	for i, url := range c.urls {
+		err := c.checkHealth(url)
+		if err != nil {
+			problems = append(problems, err)
+			if len(c.urls) > 1 {
+				appLogger.Warn("InfluxDB %s: endpoint %s is unavailable: %v", c.name, url, err)
+			}
+		} else if healthy < 0 {
+			healthy = i
+		}
+	}
+	if healthy < 0 {
+		return "", errors.Join(problems...)
+	}
+	if healthy > 0 {
+		c.switchTo(healthy)
+	}
+	return c.urls[healthy], nil
+}
+
+// checkHealth runs InfluxDB's health check against url with a throwaway client.
+func (c *reconnectingClient) checkHealth(url string) error {
+	client := influxdb2.NewClient(url, c.token)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), c.healthTimeout)
+	defer cancel()
+	health, err := client.Health(ctx)
+	if err != nil {
+		return fmt.Errorf("influxdb health check failed for %s: %w", url, err)
+	}
+	if health.Status != "pass" {
+		return fmt.Errorf("influxdb at %s not healthy: status %s", url, health.Status)
+	}
+	return nil
+}
+
+// switchTo replaces the client with one for urls[i].
+func (c *reconnectingClient) switchTo(i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replaceLocked(i)
+}
+
+func (c *reconnectingClient) replaceLocked(i int) {
+	old := c.client
+	c.client = influxdb2.NewClient(c.urls[i], c.token)
+	c.active = i
+	c.failures = 0
+	go old.Close() // in-flight calls on the old client finish or fail on their own
+}
+
+// runFailbackProbes checks the endpoints preferred over the active one every
+// probeEvery and switches back to the first that is healthy again.
+func (c *reconnectingClient) runFailbackProbes() {
+	ticker := time.NewTicker(c.probeEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		c.mu.RLock()
+		active := c.active
+		c.mu.RUnlock()
+		for i := 0; i < active; i++ {
+			if c.checkHealth(c.urls[i]) != nil {
+				continue
+			}
+			c.mu.Lock()
+			if i < c.active {
+				from := c.urls[c.active]
+				c.replaceLocked(i)
+				c.lastReconnect = time.Now()
+				appLogger.Info("InfluxDB %s: endpoint %s is healthy again, failing back from %s", c.name, c.urls[i], from)
+			}
+			c.mu.Unlock()
+			break
+		}
+	}
+}
+
+// get returns the current client. Don't keep it across calls.
+func (c *reconnectingClient) get() influxdb2.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
+// observe records the outcome of an operation and reports whether the client
+// was just recreated (so the caller may retry once).
+func (c *reconnectingClient) observe(err error) bool {
+	connErr := isConnectionError(err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !connErr {
+		if err == nil {
+			c.failures = 0
+		}
+		return false
+	}
+	c.failures++
+	if c.failures < c.threshold || time.Since(c.lastReconnect) < c.cooldown {
+		return false
+	}
+
+	from := c.urls[c.active]
+	c.replaceLocked((c.active + 1) % len(c.urls))
+	c.lastReconnect = time.Now()
+	count := c.reconnects.Add(1)
+
+	if len(c.urls) > 1 {
+		appLogger.Warn("InfluxDB %s: failing over from %s to %s after repeated connection errors (reconnect #%d): %v", c.name, from, c.urls[c.active], count, err)
+	} else {
+		appLogger.Warn("InfluxDB %s: recreated client after repeated connection errors (reconnect #%d): %v", c.name, count, err)
+	}
+	return true
+}
+
+// Reconnects is how many times the client has been recreated, failovers
+// included (failbacks are not counted).
+func (c *reconnectingClient) Reconnects() int64 {
+	return c.reconnects.Load()
+}
+
+func (c *reconnectingClient) close() {
+	close(c.stop)
+	c.get().Close()
+}
+
+// isConnectionError reports transport failures (refused, reset, timeouts, EOF),
+// as opposed to InfluxDB answering with an error status.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
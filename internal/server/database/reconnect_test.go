@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+func TestClientIsRecreatedOnceAfterConnectionErrors(t *testing.T) {
+	server := influxtest.NewServer(t)
+	cfg := server.Config()
+	cfg.ReconnectAfterErrors = 3
+	cfg.ReconnectCooldown = time.Minute
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+	query := func() error {
+		result, err := client.Query(context.Background(), `from(bucket: "test-bucket") |> range(start: -1m)`)
+		if err == nil {
+			result.Close()
+		}
+		return err
+	}
+
+	// a network blip: the third failure recreates the client, its retry and
+	// the next query fail too, then the connection is back
+	server.DropConnections(5)
+	for i := 1; i <= 4; i++ {
+		if err := query(); err == nil {
+			t.Fatalf("query %d succeeded during the blip", i)
+		}
+	}
+	if err := query(); err != nil {
+		t.Fatalf("query after the blip: %v", err)
+	}
+	if got := client.Reconnects(); got != 1 {
+		t.Fatalf("reconnects after the blip = %d, want 1", got)
+	}
+
+	// another run of errors within the cooldown doesn't recreate it again
+	server.DropConnections(3)
+	for i := 1; i <= 3; i++ {
+		if err := query(); err == nil {
+			t.Fatalf("query %d of the second blip succeeded", i)
+		}
+	}
+	if err := query(); err != nil {
+		t.Fatalf("query after the second blip: %v", err)
+	}
+	if got := client.Reconnects(); got != 1 {
+		t.Errorf("reconnects within the cooldown = %d, want still 1", got)
+	}
+}
+
+func TestNonConnectionErrorsDoNotRecreateTheClient(t *testing.T) {
+	reader, server := newTestReader(t, func(cfg *config.InfluxDBConfig) {
+		cfg.ReconnectAfterErrors = 1
+		cfg.ReconnectCooldown = 0
+	})
+	server.RespondFunc("", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code":"invalid","message":"compilation failed"}`, http.StatusBadRequest)
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := reader.GetHostDetails(context.Background(), "host-1"); err == nil {
+			t.Fatal("GetHostDetails succeeded with InfluxDB rejecting the query")
+		}
+	}
+	if got := reader.client.Reconnects(); got != 0 {
+		t.Errorf("reconnects after error responses = %d, want 0", got)
+	}
+}
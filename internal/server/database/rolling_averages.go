@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// rollingAverages caches CPU/RAM means per host and window for the overview
+// gauges. It is refreshed in the background (RunRollingAverages) so the
+// aggregation stays off the request path.
+type rollingAverages struct {
+	mu    sync.RWMutex
+	hosts map[string]hostAverages
+}
+
+type hostAverages struct {
+	cpu map[string]float64 // window label -> mean
+	ram map[string]float64
+}
+
+// apply copies the cached averages of the overview's host into it.
+func (a *rollingAverages) apply(overview *models.HostOverviewData) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if h, ok := a.hosts[overview.ID]; ok {
+		overview.CPUAverages = h.cpu
+		overview.RAMAverages = h.ram
+	}
+}
+
+// RunRollingAverages refreshes the overview's rolling averages right away and
+// then every interval until ctx is done. A failed refresh keeps the previous values.
+func (r *InfluxDBReader) RunRollingAverages(ctx context.Context, interval time.Duration) {
+	if len(r.averageWindows) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.refreshRollingAverages(ctx); err != nil && ctx.Err() == nil {
+			appLogger.Error("Failed to refresh overview rolling averages: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *InfluxDBReader) refreshRollingAverages(ctx context.Context) error {
+	release, err := r.acquireQuerySlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	hosts := make(map[string]hostAverages)
+	for _, window := range r.averageWindows {
+		query := fmt.Sprintf(`
+			from(bucket: "%s")
+				|> range(start: -%s)
+				|> filter(fn: (r) => r._measurement == "system_metrics" and (r._field == "cpu_usage_percent" or r._field == "mem_usage_percent"))
+				|> group(columns: ["host_id", "_field"])
+				|> mean()
+		`, r.bucket, window.String())
+
+		results, err := r.query(ctx, query)
+		if err != nil {
+			return fmt.Errorf("query influxdb for %s averages: %w", window, err)
+		}
+		label := windowLabel(window)
+		for results.Next() {
+			record := results.Record()
+			hostID, _ := record.ValueByKey("host_id").(string)
+			value, ok := record.Value().(float64)
+			if hostID == "" || !ok {
+				continue
+			}
+			h, exists := hosts[hostID]
+			if !exists {
+				h = hostAverages{cpu: make(map[string]float64), ram: make(map[string]float64)}
+				hosts[hostID] = h
+			}
+			switch record.Field() {
+			case "cpu_usage_percent":
+				h.cpu[label] = value
+			case "mem_usage_percent":
+				h.ram[label] = value
+			}
+		}
+		err = results.Err()
+		results.Close()
+		if err != nil {
+			return fmt.Errorf("process %s averages: %w", window, err)
+		}
+	}
+
+	r.averages.mu.Lock()
+	r.averages.hosts = hosts
+	r.averages.mu.Unlock()
+	appLogger.Debug("Refreshed overview rolling averages for %d hosts", len(hosts))
+	return nil
+}
+
+// windowLabel formats a window the way it is configured: "1m", "15m", "1h", "90s".
+func windowLabel(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/database/influxtest"
+)
+
+// averagesTable is a rolling averages result: cpu and ram means per host.
+func averagesTable(means map[string][2]float64) *influxtest.Table {
+	table := influxtest.NewTable("host_id", "_field", "_value:double")
+	for hostID, mean := range means {
+		table.Row(hostID, "cpu_usage_percent", mean[0])
+		table.Row(hostID, "mem_usage_percent", mean[1])
+	}
+	return table
+}
+
+func TestRollingAveragesAfterOneRefresh(t *testing.T) {
+	reader, server := newTestReader(t, func(cfg *config.InfluxDBConfig) {
+		cfg.OverviewAverageWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+		cfg.OverviewCacheMaxAge = 0
+	})
+	respondOverview(server,
+		overviewRow{hostID: "host-1", cpu: 90, mem: 50, at: time.Now()},
+		overviewRow{hostID: "host-2", cpu: 10, mem: 20, at: time.Now()},
+	)
+	server.Respond("range(start: -1m0s)", averagesTable(map[string][2]float64{"host-1": {80, 45}}))
+	server.Respond("range(start: -5m0s)", averagesTable(map[string][2]float64{"host-1": {60, 40}}))
+	server.Respond("range(start: -15m0s)", averagesTable(map[string][2]float64{"host-1": {30, 35}}))
+
+	// before the first refresh there is nothing to show
+	if hosts := overviewByID(t, reader); hosts["host-1"].CPUAverages != nil {
+		t.Errorf("averages before any refresh: %v", hosts["host-1"].CPUAverages)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reader.RunRollingAverages(ctx, time.Hour) // refreshes once right away
+		close(done)
+	}()
+	t.Cleanup(func() { cancel(); <-done })
+
+	deadline := time.Now().Add(5 * time.Second)
+	var averaged bool
+	for !averaged {
+		if time.Now().After(deadline) {
+			t.Fatal("no averages 5s after the first refresh started")
+		}
+		time.Sleep(5 * time.Millisecond)
+		reader.averages.mu.RLock()
+		averaged = reader.averages.hosts != nil
+		reader.averages.mu.RUnlock()
+	}
+
+	hosts := overviewByID(t, reader)
+	host := hosts["host-1"]
+	wantCPU := map[string]float64{"1m": 80, "5m": 60, "15m": 30}
+	wantRAM := map[string]float64{"1m": 45, "5m": 40, "15m": 35}
+	for window, want := range wantCPU {
+		if got, ok := host.CPUAverages[window]; !ok || got != want {
+			t.Errorf("host-1 cpu %s average = %v (set %t), want %v", window, got, ok, want)
+		}
+		if got := host.RAMAverages[window]; got != wantRAM[window] {
+			t.Errorf("host-1 ram %s average = %v, want %v", window, got, wantRAM[window])
+		}
+	}
+	if host.CPUUsage != 90 {
+		t.Errorf("host-1 cpu = %v, want the latest value 90 next to the averages", host.CPUUsage)
+	}
+	if hosts["host-2"].CPUAverages != nil {
+		t.Errorf("host-2 without data in any window: averages %v, want none", hosts["host-2"].CPUAverages)
+	}
+}
+
+func TestWindowLabel(t *testing.T) {
+	for window, want := range map[time.Duration]string{
+		time.Minute:      "1m",
+		15 * time.Minute: "15m",
+		time.Hour:        "1h",
+		90 * time.Second: "1m30s",
+		30 * time.Second: "30s",
+	} {
+		if got := windowLabel(window); got != want {
+			t.Errorf("windowLabel(%v) = %q, want %q", window, got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/models"
+)
+
+// hostServices returns the latest service_metrics point of every unit of a
+// host within the details lookback, by unit. Units dropped from the agent's
+// MONITOR_SERVICES stay listed until they fall out of the lookback. The
+// caller holds the query slot.
+func (r *InfluxDBReader) hostServices(ctx context.Context, hostID string) ([]models.ServiceDetail, error) {
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "service_metrics" and r.host_id == %s)
+			|> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+			|> group(columns: ["unit"])
+			|> sort(columns: ["_time"])
+			|> last(column: "_time")
+	`, r.bucket, r.detailsLookback, fluxString(hostID))
+
+	appLogger.FromContext(ctx).Debug("GetHostDetails Services Query for host %s:\n%s", hostID, query)
+	results, err := r.query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query influxdb for host services: %w", err)
+	}
+	defer results.Close()
+
+	services := []models.ServiceDetail{}
+	for results.Next() {
+		record := results.Record()
+		unit, _ := record.ValueByKey("unit").(string)
+		if unit == "" {
+			continue
+		}
+		service := models.ServiceDetail{Unit: unit, LastSeen: record.Time().UTC()}
+		service.Active, _ = record.ValueByKey("active").(bool)
+		service.LoadState, _ = record.ValueByKey("load_state").(string)
+		service.ActiveState, _ = record.ValueByKey("active_state").(string)
+		service.SubState, _ = record.ValueByKey("sub_state").(string)
+		services = append(services, service)
+	}
+	if results.Err() != nil {
+		return nil, fmt.Errorf("process query results for host services: %w", results.Err())
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Unit < services[j].Unit })
+	return services, nil
+}
@@ -0,0 +1,86 @@
+// Package hostrole infers a host's role (db, web, cache, ...) from the names
+// of the processes it reports, using the HOST_ROLE_RULES from the config.
+package hostrole
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+)
+
+// Classify returns the role of the first rule that matches one of the
+// process names, and that name. ok is false if no rule matches.
+func Classify(rules []config.RoleRule, processNames []string) (role, process string, ok bool) {
+	for _, rule := range rules {
+		part := strings.ToLower(rule.Contains)
+		for _, name := range processNames {
+			if strings.Contains(strings.ToLower(name), part) {
+				return rule.Role, name, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Classifier evaluates the rules on ingest and keeps each host's role in the
+// metadata store. Agents only report processes above their usage threshold,
+// so a payload without a match leaves the role as it is: an idle nginx
+// doesn't make a web server lose its role.
+type Classifier struct {
+	rules []config.RoleRule
+	store *metadata.Store
+
+	mu    sync.Mutex
+	roles map[string]string // host ID -> stored role, to skip unchanged writes
+}
+
+// NewClassifier returns a classifier, or nil when there are no rules.
+func NewClassifier(rules []config.RoleRule, store *metadata.Store) *Classifier {
+	if len(rules) == 0 {
+		return nil
+	}
+	roles := make(map[string]string)
+	stored, err := store.HostRoles()
+	if err != nil {
+		appLogger.Error("Failed to load host roles from metadata store: %v", err)
+	}
+	for hostID, role := range stored {
+		roles[hostID] = role.Role
+	}
+	return &Classifier{rules: rules, store: store, roles: roles}
+}
+
+// Observe classifies one ingested payload's process names (see
+// ClientPayload.ProcessNames) and stores the role when it changed. Safe to
+// call on a nil Classifier (role inference off).
+func (c *Classifier) Observe(hostID string, names []string, at time.Time) {
+	if c == nil || len(names) == 0 {
+		return
+	}
+	role, process, ok := Classify(c.rules, names)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, known := c.roles[hostID]
+	if known && previous == role {
+		return
+	}
+	err := c.store.SetHostRole(metadata.HostRole{HostID: hostID, Role: role, Process: process, UpdatedAt: at})
+	if err != nil {
+		appLogger.Error("Failed to store role %s for host %s: %v", role, hostID, err)
+		return
+	}
+	c.roles[hostID] = role
+	if known {
+		appLogger.Info("Host %s changed role from %s to %s (process %s)", hostID, previous, role, process)
+	} else {
+		appLogger.Info("Host %s classified as %s (process %s)", hostID, role, process)
+	}
+}
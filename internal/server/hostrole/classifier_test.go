@@ -0,0 +1,76 @@
+package hostrole
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/config"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+)
+
+var testRules = []config.RoleRule{
+	{Contains: "postgres", Role: "db"},
+	{Contains: "mysqld", Role: "db"},
+	{Contains: "nginx", Role: "web"},
+	{Contains: "redis", Role: "cache"},
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		processes []string
+		role      string
+		process   string
+	}{
+		{"nginx is web", []string{"sshd", "nginx"}, "web", "nginx"},
+		{"name part, any case", []string{"NGINX: worker process"}, "web", "NGINX: worker process"},
+		{"the first matching rule wins", []string{"nginx", "postgres"}, "db", "postgres"},
+		{"no match", []string{"sshd", "bash"}, "", ""},
+		{"no processes", nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, process, ok := Classify(testRules, tt.processes)
+			if role != tt.role || process != tt.process || ok != (tt.role != "") {
+				t.Errorf("Classify(%q) = %q, %q, %t, want %q, %q", tt.processes, role, process, ok, tt.role, tt.process)
+			}
+		})
+	}
+}
+
+func TestClassifierStoresTheRole(t *testing.T) {
+	store, err := metadata.Open(filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	classifier := NewClassifier(testRules, store)
+	now := time.Now()
+
+	classifier.Observe("host-1", []string{"sshd", "nginx"}, now)
+	roles, err := store.HostRoles()
+	if err != nil {
+		t.Fatalf("HostRoles: %v", err)
+	}
+	if role := roles["host-1"]; role.Role != "web" || role.Process != "nginx" {
+		t.Fatalf("host-1 role = %+v, want web from nginx", role)
+	}
+
+	// an idle nginx drops below the agent's threshold: the role stays
+	classifier.Observe("host-1", []string{"sshd"}, now.Add(time.Minute))
+	if roles, _ := store.HostRoles(); roles["host-1"].Role != "web" {
+		t.Errorf("host-1 role after a payload without nginx = %+v, want web", roles["host-1"])
+	}
+
+	// a restarted server picks up the stored roles
+	if reloaded := NewClassifier(testRules, store); reloaded.roles["host-1"] != "web" {
+		t.Errorf("reloaded roles = %v, want host-1 web", reloaded.roles)
+	}
+
+	if NewClassifier(nil, store) != nil {
+		t.Error("NewClassifier without rules is not nil")
+	}
+	var off *Classifier
+	off.Observe("host-2", []string{"nginx"}, now) // role inference off, must not panic
+}
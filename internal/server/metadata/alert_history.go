@@ -0,0 +1,95 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// AlertTransition is one entry of the alert history: an alert event that
+// started firing or resolved. Pending alerts that never fired are not recorded.
+type AlertTransition struct {
+	ID        uint64    `json:"id"`
+	EventID   uint64    `json:"event_id"`
+	RuleID    string    `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	HostID    string    `json:"host_id"`
+	Hostname  string    `json:"hostname"`
+	Metric    string    `json:"metric"`
+	Operator  string    `json:"operator"`
+	Threshold float64   `json:"threshold"`
+	Value     float64   `json:"value"`    // value at the transition
+	State     string    `json:"state"`    // firing or resolved
+	Silenced  bool      `json:"silenced"` // host was silenced, no notification sent
+	Time      time.Time `json:"time"`
+}
+
+// AddAlertTransition appends a transition to the history.
+func (s *Store) AddAlertTransition(t AlertTransition) error {
+	t.Time = t.Time.UTC()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(alertHistoryBucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return fmt.Errorf("next alert history id: %w", err)
+		}
+		t.ID = id
+		data, err := json.Marshal(&t)
+		if err != nil {
+			return fmt.Errorf("marshal alert transition: %w", err)
+		}
+		return b.Put(timeIDKey(t.Time, id), data)
+	})
+}
+
+// AlertHistoryQuery selects a page of the alert history.
+type AlertHistoryQuery struct {
+	Start, End time.Time
+	HostID     string // empty = every host
+	State      string // empty = firing and resolved
+	Offset     int
+	Limit      int
+}
+
+// AlertHistory returns transitions with Start <= Time <= End matching the
+// query, newest first. The first Offset matches are skipped and at most Limit
+// returned; total is the number of matches.
+func (s *Store) AlertHistory(q AlertHistoryQuery) (page []AlertTransition, total int, err error) {
+	page = []AlertTransition{}
+	start, end := q.Start, q.End
+	startNanos := uint64(start.UnixNano())
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(alertHistoryBucket).Cursor()
+		k, v := c.Seek(timeIDKey(end, ^uint64(0)))
+		if k == nil {
+			k, v = c.Last()
+		} else if binary.BigEndian.Uint64(k[:8]) > uint64(end.UnixNano()) {
+			k, v = c.Prev()
+		}
+		for ; k != nil; k, v = c.Prev() {
+			if binary.BigEndian.Uint64(k[:8]) < startNanos {
+				break
+			}
+			var t AlertTransition
+			if err := json.Unmarshal(v, &t); err != nil {
+				return fmt.Errorf("decode alert transition: %w", err)
+			}
+			if (q.HostID != "" && t.HostID != q.HostID) || (q.State != "" && t.State != q.State) {
+				continue
+			}
+			if total >= q.Offset && len(page) < q.Limit {
+				page = append(page, t)
+			}
+			total++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, total, nil
+}
@@ -0,0 +1,243 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// AlertRule is a threshold condition evaluated for every matching host.
+type AlertRule struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Metric    string        `json:"metric"`   // cpu_usage, mem_usage, disk_usage, host_offline
+	Operator  string        `json:"operator"` // >, >=, <, <=
+	Threshold float64       `json:"threshold"`
+	For       time.Duration `json:"for_ns"`             // condition must hold this long before firing
+	HostIDs   []string      `json:"host_ids,omitempty"` // empty = every host
+	Group     string        `json:"group,omitempty"`    // only hosts in this group
+	// Firing alerts resolve only once the value is past this (hysteresis), nil = Threshold
+	ClearThreshold *float64      `json:"clear_threshold,omitempty"`
+	Cooldown       time.Duration `json:"cooldown_ns,omitempty"` // min time between notifications per host
+	// disk_full_days rules: trend window and minimum fit quality
+	Lookback time.Duration `json:"lookback_ns,omitempty"`
+	MinR2    float64       `json:"min_r2,omitempty"`
+	// Replaces ALERT_WEBHOOK_URLS for this rule's notifications
+	WebhookURLs  []string `json:"webhook_urls,omitempty"`
+	SlackChannel string   `json:"slack_channel,omitempty"` // ALERT_SLACK_CHANNELS name
+	EmailTo      []string `json:"email_to,omitempty"`      // replaces ALERT_SMTP_TO
+	// Per-host and per-group exceptions, see RuleOverride
+	Overrides []RuleOverride `json:"overrides,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// RuleOverride changes a rule for one host or the members of one group.
+// Host overrides win over group overrides, which win over the rule itself.
+// Unset fields keep the rule's value.
+type RuleOverride struct {
+	HostID string `json:"host_id,omitempty"` // exactly one of HostID and Group
+	Group  string `json:"group,omitempty"`
+
+	Disabled       bool     `json:"disabled,omitempty"` // don't evaluate the rule for these hosts
+	Threshold      *float64 `json:"threshold,omitempty"`
+	ClearThreshold *float64 `json:"clear_threshold,omitempty"`
+
+	WebhookURLs  []string `json:"webhook_urls,omitempty"`
+	SlackChannel string   `json:"slack_channel,omitempty"`
+	EmailTo      []string `json:"email_to,omitempty"`
+}
+
+// Alert event states
+const (
+	AlertPending  = "pending"
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// AlertEvent is one rule matching one host, from the moment the condition
+// became true until it resolved.
+type AlertEvent struct {
+	ID        uint64    `json:"id"`
+	RuleID    string    `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	HostID    string    `json:"host_id"`
+	Hostname  string    `json:"hostname"`
+	Metric    string    `json:"metric"`
+	Operator  string    `json:"operator"`
+	Threshold float64   `json:"threshold"`
+	Value     float64   `json:"value"` // latest offending value (last value before resolving)
+	State     string    `json:"state"`
+	StartsAt  time.Time `json:"starts_at"` // condition first true
+	FiredAt   time.Time `json:"fired_at,omitempty"`
+	EndsAt    time.Time `json:"ends_at,omitempty"`
+	LastSeen  time.Time `json:"last_seen,omitempty"` // host-down alerts: when the host last reported
+	// disk_full_days alerts: when the root disk is projected to be full
+	ProjectedFullAt time.Time `json:"projected_full_at,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// CreateAlertRule stores a new rule and returns it with ID and CreatedAt set.
+func (s *Store) CreateAlertRule(rule AlertRule) (*AlertRule, error) {
+	rule.CreatedAt = time.Now().UTC()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(alertRulesBucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return fmt.Errorf("next alert rule id: %w", err)
+		}
+		rule.ID = strconv.FormatUint(id, 10)
+		return putJSON(b, rule.ID, &rule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListAlertRules returns all rules ordered by creation time.
+func (s *Store) ListAlertRules() ([]AlertRule, error) {
+	rules := []AlertRule{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(alertRulesBucket).ForEach(func(k, v []byte) error {
+			var rule AlertRule
+			if err := json.Unmarshal(v, &rule); err != nil {
+				return fmt.Errorf("decode alert rule %s: %w", k, err)
+			}
+			rules = append(rules, rule)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+	return rules, nil
+}
+
+// GetAlertRule returns one rule or ErrNotFound.
+func (s *Store) GetAlertRule(id string) (*AlertRule, error) {
+	var rule AlertRule
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(alertRulesBucket), id, &rule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SetAlertRuleOverrides replaces a rule's overrides and returns the updated rule.
+func (s *Store) SetAlertRuleOverrides(id string, overrides []RuleOverride) (*AlertRule, error) {
+	var rule AlertRule
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(alertRulesBucket)
+		if err := getJSON(b, id, &rule); err != nil {
+			return err
+		}
+		rule.Overrides = overrides
+		return putJSON(b, id, &rule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteAlertRule removes a rule. Its open alerts are resolved by the evaluator.
+func (s *Store) DeleteAlertRule(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(alertRulesBucket)
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// AddAlertEvent stores a new alert event and returns it with its ID set.
+func (s *Store) AddAlertEvent(e AlertEvent) (*AlertEvent, error) {
+	e.StartsAt = e.StartsAt.UTC()
+	e.UpdatedAt = time.Now().UTC()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(alertsBucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return fmt.Errorf("next alert id: %w", err)
+		}
+		e.ID = id
+		data, err := json.Marshal(&e)
+		if err != nil {
+			return fmt.Errorf("marshal alert event: %w", err)
+		}
+		return b.Put(timeIDKey(e.StartsAt, id), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// UpdateAlertEvent overwrites an existing event (state, value, timestamps).
+func (s *Store) UpdateAlertEvent(e *AlertEvent) error {
+	e.UpdatedAt = time.Now().UTC()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(alertsBucket)
+		key := timeIDKey(e.StartsAt, e.ID)
+		if b.Get(key) == nil {
+			return ErrNotFound
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("marshal alert event: %w", err)
+		}
+		return b.Put(key, data)
+	})
+}
+
+// DeleteAlertEvent removes an event, used for pending alerts that cleared before firing.
+func (s *Store) DeleteAlertEvent(e *AlertEvent) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(alertsBucket).Delete(timeIDKey(e.StartsAt, e.ID))
+	})
+}
+
+// ListAlertEvents returns open (pending/firing) events plus events resolved
+// at or after resolvedSince, newest first.
+func (s *Store) ListAlertEvents(resolvedSince time.Time) ([]AlertEvent, error) {
+	return s.scanAlertEvents(func(e *AlertEvent) bool {
+		return e.State != AlertResolved || !e.EndsAt.Before(resolvedSince)
+	})
+}
+
+// OpenAlertEvents returns every pending or firing event.
+func (s *Store) OpenAlertEvents() ([]AlertEvent, error) {
+	return s.scanAlertEvents(func(e *AlertEvent) bool {
+		return e.State != AlertResolved
+	})
+}
+
+// scanAlertEvents walks the alerts bucket newest first and keeps events matching keep.
+func (s *Store) scanAlertEvents(keep func(*AlertEvent) bool) ([]AlertEvent, error) {
+	events := []AlertEvent{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(alertsBucket).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var e AlertEvent
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("decode alert event: %w", err)
+			}
+			if keep(&e) {
+				events = append(events, e)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
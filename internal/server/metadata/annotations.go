@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Annotation is an event marker the frontend overlays on charts
+// (deploys, incidents, host registered / went offline, ...).
+type Annotation struct {
+	ID        uint64    `json:"id"`
+	Time      time.Time `json:"time"`
+	HostID    string    `json:"host_id,omitempty"` // empty = applies to every host
+	Title     string    `json:"title"`
+	Text      string    `json:"text,omitempty"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Keys are <unix nanos><id>, both big-endian, so a cursor walks records in time order.
+// Used for annotations and alert events.
+func timeIDKey(t time.Time, id uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], id)
+	return key
+}
+
+// AddAnnotation stores a new annotation and returns it with its ID set.
+// A zero Time defaults to now.
+func (s *Store) AddAnnotation(a Annotation) (*Annotation, error) {
+	now := time.Now().UTC()
+	if a.Time.IsZero() {
+		a.Time = now
+	}
+	a.Time = a.Time.UTC()
+	a.CreatedAt = now
+	if a.Tags == nil {
+		a.Tags = []string{}
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(annotationsBucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return fmt.Errorf("next annotation id: %w", err)
+		}
+		a.ID = id
+		data, err := json.Marshal(&a)
+		if err != nil {
+			return fmt.Errorf("marshal annotation: %w", err)
+		}
+		return b.Put(timeIDKey(a.Time, id), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListAnnotations returns annotations with start <= Time <= end in time order.
+// If hostID is set, only that host's annotations plus global (host-less) ones are returned.
+func (s *Store) ListAnnotations(start, end time.Time, hostID string) ([]Annotation, error) {
+	annotations := []Annotation{}
+	endNanos := uint64(end.UnixNano())
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(annotationsBucket).Cursor()
+		for k, v := c.Seek(timeIDKey(start, 0)); k != nil; k, v = c.Next() {
+			if binary.BigEndian.Uint64(k[:8]) > endNanos {
+				break
+			}
+			var a Annotation
+			if err := json.Unmarshal(v, &a); err != nil {
+				return fmt.Errorf("decode annotation: %w", err)
+			}
+			if hostID != "" && a.HostID != "" && a.HostID != hostID {
+				continue
+			}
+			annotations = append(annotations, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
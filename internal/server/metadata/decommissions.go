@@ -0,0 +1,77 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Decommission marks a host as retired: its history is kept, but it no
+// longer counts as offline or raises host-down alerts.
+type Decommission struct {
+	HostID           string    `json:"host_id"`
+	Reason           string    `json:"reason"`
+	DecommissionedBy string    `json:"decommissioned_by"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Decommission marks a host as decommissioned. Returns ErrAlreadyExists if it is.
+func (s *Store) Decommission(decommission Decommission) (*Decommission, error) {
+	decommission.CreatedAt = time.Now().UTC()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(decommissionsBucket)
+		if b.Get([]byte(decommission.HostID)) != nil {
+			return ErrAlreadyExists
+		}
+		return putJSON(b, decommission.HostID, &decommission)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &decommission, nil
+}
+
+// Recommission clears a host's decommission. Returns ErrNotFound if there is none.
+func (s *Store) Recommission(hostID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(decommissionsBucket)
+		if b.Get([]byte(hostID)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(hostID))
+	})
+}
+
+// GetDecommission returns the host's decommission or ErrNotFound. It only
+// reads, so the ingest path can call it for every payload.
+func (s *Store) GetDecommission(hostID string) (*Decommission, error) {
+	var decommission Decommission
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(decommissionsBucket), hostID, &decommission)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &decommission, nil
+}
+
+// DecommissionedHosts returns host_id -> decommission for every decommissioned host.
+func (s *Store) DecommissionedHosts() (map[string]Decommission, error) {
+	decommissioned := make(map[string]Decommission)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(decommissionsBucket).ForEach(func(k, v []byte) error {
+			var decommission Decommission
+			if err := json.Unmarshal(v, &decommission); err != nil {
+				return fmt.Errorf("decode decommission %s: %w", k, err)
+			}
+			decommissioned[decommission.HostID] = decommission
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decommissioned, nil
+}
@@ -0,0 +1,140 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Group is a named set of hosts, e.g. "prod-eu-web".
+type Group struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Hosts       []string  `json:"hosts"` // host_ids, kept sorted
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// CreateGroup creates a new empty group. Returns ErrAlreadyExists if the name is taken.
+func (s *Store) CreateGroup(name, description string) (*Group, error) {
+	group := &Group{
+		Name:        name,
+		Description: description,
+		Hosts:       []string{},
+		CreatedAt:   time.Now().UTC(),
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(groupsBucket)
+		if b.Get([]byte(name)) != nil {
+			return ErrAlreadyExists
+		}
+		return putJSON(b, name, group)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetGroup returns a single group or ErrNotFound.
+func (s *Store) GetGroup(name string) (*Group, error) {
+	var group Group
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(groupsBucket), name, &group)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListGroups returns all groups sorted by name.
+func (s *Store) ListGroups() ([]Group, error) {
+	groups := []Group{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(groupsBucket).ForEach(func(k, v []byte) error {
+			var group Group
+			if err := json.Unmarshal(v, &group); err != nil {
+				return fmt.Errorf("decode group %s: %w", k, err)
+			}
+			groups = append(groups, group)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	// bbolt iterates in key order already, this just makes it explicit
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+// DeleteGroup removes a group. Hosts themselves are not affected.
+func (s *Store) DeleteGroup(name string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(groupsBucket)
+		if b.Get([]byte(name)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(name))
+	})
+}
+
+// AddHostToGroup assigns hostID to the group. Adding an existing member is a no-op.
+func (s *Store) AddHostToGroup(name, hostID string) (*Group, error) {
+	return s.updateGroup(name, func(group *Group) {
+		for _, h := range group.Hosts {
+			if h == hostID {
+				return
+			}
+		}
+		group.Hosts = append(group.Hosts, hostID)
+		sort.Strings(group.Hosts)
+	})
+}
+
+// RemoveHostFromGroup removes hostID from the group. Removing a non-member is a no-op.
+func (s *Store) RemoveHostFromGroup(name, hostID string) (*Group, error) {
+	return s.updateGroup(name, func(group *Group) {
+		hosts := group.Hosts[:0]
+		for _, h := range group.Hosts {
+			if h != hostID {
+				hosts = append(hosts, h)
+			}
+		}
+		group.Hosts = hosts
+	})
+}
+
+// GroupsByHost returns host_id -> sorted group names for every grouped host.
+func (s *Store) GroupsByHost() (map[string][]string, error) {
+	groups, err := s.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+	byHost := make(map[string][]string)
+	for _, group := range groups { // groups are sorted, so each host's list is too
+		for _, hostID := range group.Hosts {
+			byHost[hostID] = append(byHost[hostID], group.Name)
+		}
+	}
+	return byHost, nil
+}
+
+func (s *Store) updateGroup(name string, fn func(group *Group)) (*Group, error) {
+	var group Group
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(groupsBucket)
+		if err := getJSON(b, name, &group); err != nil {
+			return err
+		}
+		fn(&group)
+		return putJSON(b, name, &group)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
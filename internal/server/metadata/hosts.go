@@ -0,0 +1,49 @@
+package metadata
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// HostRecord is what the metadata store remembers about every host that has ever reported.
+type HostRecord struct {
+	HostID    string    `json:"host_id"`
+	Hostname  string    `json:"hostname"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// RegisterHost records hostID the first time it is seen.
+// Returns created=true only when the host was not known before.
+func (s *Store) RegisterHost(hostID, hostname string, seenAt time.Time) (record *HostRecord, created bool, err error) {
+	record = &HostRecord{}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(hostsBucket)
+		getErr := getJSON(b, hostID, record)
+		if getErr == nil {
+			return nil // already known
+		}
+		if getErr != ErrNotFound {
+			return getErr
+		}
+		*record = HostRecord{HostID: hostID, Hostname: hostname, FirstSeen: seenAt.UTC()}
+		created = true
+		return putJSON(b, hostID, record)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return record, created, nil
+}
+
+// GetHost returns the stored record for hostID or ErrNotFound.
+func (s *Store) GetHost(hostID string) (*HostRecord, error) {
+	var record HostRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(hostsBucket), hostID, &record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
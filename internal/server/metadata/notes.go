@@ -0,0 +1,61 @@
+package metadata
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// HostNotes is free-form operator text attached to a host_id. Notes are keyed by
+// host_id only, so they outlive deleted InfluxDB data and reappear when a host
+// re-registers with the same host_id.
+type HostNotes struct {
+	HostID    string    `json:"host_id"`
+	Text      string    `json:"text"`
+	Revision  uint64    `json:"revision"` // 0 = never written
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// GetHostNotes returns the notes for hostID. A host without notes gets an empty
+// value with Revision 0 rather than ErrNotFound.
+func (s *Store) GetHostNotes(hostID string) (*HostNotes, error) {
+	notes := &HostNotes{HostID: hostID}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		err := getJSON(tx.Bucket(notesBucket), hostID, notes)
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// PutHostNotes replaces the notes for hostID if expectedRevision matches the stored
+// revision (0 for a first write). On mismatch it returns the current notes together
+// with ErrRevisionConflict so the caller can show what changed.
+func (s *Store) PutHostNotes(hostID, text string, expectedRevision uint64) (*HostNotes, error) {
+	notes := &HostNotes{HostID: hostID}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(notesBucket)
+		if err := getJSON(b, hostID, notes); err != nil && err != ErrNotFound {
+			return err
+		}
+		if notes.Revision != expectedRevision {
+			return ErrRevisionConflict
+		}
+		notes.Text = text
+		notes.Revision++
+		notes.UpdatedAt = time.Now().UTC()
+		return putJSON(b, hostID, notes)
+	})
+	if err == ErrRevisionConflict {
+		return notes, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
@@ -0,0 +1,44 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// HostRole is the role inferred for a host from its processes (HOST_ROLE_RULES).
+type HostRole struct {
+	HostID    string    `json:"host_id"`
+	Role      string    `json:"role"`
+	Process   string    `json:"process"` // the process name that matched
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetHostRole stores the role of a host, replacing the previous one.
+func (s *Store) SetHostRole(role HostRole) error {
+	role.UpdatedAt = role.UpdatedAt.UTC()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(rolesBucket), role.HostID, &role)
+	})
+}
+
+// HostRoles returns every stored role, keyed by host ID.
+func (s *Store) HostRoles() (map[string]HostRole, error) {
+	roles := make(map[string]HostRole)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(rolesBucket).ForEach(func(k, v []byte) error {
+			var role HostRole
+			if err := json.Unmarshal(v, &role); err != nil {
+				return fmt.Errorf("decode host role %s: %w", k, err)
+			}
+			roles[role.HostID] = role
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Silence acknowledges a host's warning state until a point in time.
+type Silence struct {
+	HostID     string    `json:"host_id"`
+	Until      time.Time `json:"until"`
+	Reason     string    `json:"reason"`
+	SilencedBy string    `json:"silenced_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Active reports whether the silence is still in effect at now.
+func (s *Silence) Active(now time.Time) bool {
+	return now.Before(s.Until)
+}
+
+// SetSilence creates or replaces the silence for a host.
+func (s *Store) SetSilence(silence Silence) (*Silence, error) {
+	silence.Until = silence.Until.UTC()
+	silence.CreatedAt = time.Now().UTC()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(silencesBucket), silence.HostID, &silence)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// ClearSilence removes a host's silence. Returns ErrNotFound if there is none.
+func (s *Store) ClearSilence(hostID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(silencesBucket)
+		if b.Get([]byte(hostID)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(hostID))
+	})
+}
+
+// ActiveSilences returns host_id -> silence for every silence still in effect at now.
+// Expired silences are skipped. They are keyed by host, so at most one stale
+// record per host is ever kept and no cleanup job is needed.
+func (s *Store) ActiveSilences(now time.Time) (map[string]Silence, error) {
+	active := make(map[string]Silence)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(silencesBucket).ForEach(func(k, v []byte) error {
+			var silence Silence
+			if err := json.Unmarshal(v, &silence); err != nil {
+				return fmt.Errorf("decode silence %s: %w", k, err)
+			}
+			if silence.Active(now) {
+				active[silence.HostID] = silence
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+// GetActiveSilence returns the host's silence if it is in effect at now, else ErrNotFound.
+func (s *Store) GetActiveSilence(hostID string, now time.Time) (*Silence, error) {
+	var silence Silence
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(silencesBucket), hostID, &silence)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !silence.Active(now) {
+		return nil, ErrNotFound
+	}
+	return &silence, nil
+}
@@ -0,0 +1,106 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The metadata store holds small, mutable, relational data (groups, notes, ...)
+// that doesn't fit InfluxDB's append-only time-series model.
+
+var (
+	ErrNotFound      = errors.New("metadata: not found")
+	ErrAlreadyExists = errors.New("metadata: already exists")
+	// ErrRevisionConflict means the record changed since the caller read it.
+	ErrRevisionConflict = errors.New("metadata: revision conflict")
+)
+
+// Store wraps a bbolt database file.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the metadata database at path and makes sure all buckets exist.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open metadata store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range allBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("create bucket %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	appLogger.Info("Metadata store opened at %s", path)
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database file.
+func (s *Store) Close() {
+	if s.db != nil {
+		if err := s.db.Close(); err != nil {
+			appLogger.Error("Error closing metadata store: %v", err)
+			return
+		}
+		appLogger.Info("Metadata store closed.")
+	}
+}
+
+// bucket names
+var (
+	groupsBucket        = []byte("groups")
+	annotationsBucket   = []byte("annotations")
+	hostsBucket         = []byte("hosts")
+	notesBucket         = []byte("notes")
+	silencesBucket      = []byte("silences")
+	alertRulesBucket    = []byte("alert_rules")
+	alertsBucket        = []byte("alerts")
+	alertHistoryBucket  = []byte("alert_history")
+	rolesBucket         = []byte("roles")
+	decommissionsBucket = []byte("decommissions")
+)
+
+var allBuckets = [][]byte{
+	groupsBucket,
+	annotationsBucket,
+	hostsBucket,
+	notesBucket,
+	silencesBucket,
+	alertRulesBucket,
+	alertsBucket,
+	alertHistoryBucket,
+	rolesBucket,
+	decommissionsBucket,
+}
+
+// helpers for JSON encoded values
+
+func getJSON(b *bolt.Bucket, key string, v interface{}) error {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return ErrNotFound
+	}
+	return json.Unmarshal(data, v)
+}
+
+func putJSON(b *bolt.Bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", key, err)
+	}
+	return b.Put([]byte(key), data)
+}
@@ -3,22 +3,78 @@ package models
 import "time"
 
 type HostOverviewData struct {
-	ID              string  `json:"id"` //HostID
-	Hostname        string  `json:"hostname"`
-	Status          string  `json:"status"` // online, offline, warning
-	CPUUsage        float64 `json:"cpuUsage"`
-	RAMUsage        float64 `json:"ramUsage"`
-	DiskUsage       float64 `json:"diskUsage"`
-	NetworkUpload   float64 `json:"networkUpload"`   // Bytes/sec
-	NetworkDownload float64 `json:"networkDownload"` // Bytes/sec
-	// UptimeSeconds   string    `json:"uptimeSeconds"`   // Client send seconds
+	ID               string            `json:"id"` //HostID
+	Hostname         string            `json:"hostname"`
+	Status           string            `json:"status"` // online, offline, warning, critical, silenced, decommissioned
+	CPUUsage         float64           `json:"cpuUsage"`
+	RAMUsage         float64           `json:"ramUsage"`
+	DiskUsage        *float64          `json:"diskUsage"`        // Root disk percent, null if the host reported no root disk
+	InodeUsage       *float64          `json:"inodeUsage"`       // Root filesystem inode usage percent, null if not reported
+	NetworkUpload    float64           `json:"networkUpload"`    // In NetworkUnit
+	NetworkDownload  float64           `json:"networkDownload"`  // In NetworkUnit
+	NetworkUnit      string            `json:"networkUnit"`      // Bps (bytes/sec, default), bps, Mbps or MBps (?netUnit)
+	UptimeSeconds    int64             `json:"uptimeSeconds"`    // As of LastSeen, 0 if unknown
+	RecentlyRebooted bool              `json:"recentlyRebooted"` // Online and up for less than RECENT_REBOOT_THRESHOLD
+	IngestLagSeconds *float64          `json:"ingestLagSeconds"` // Receive time - collected_at of the latest point, null if not recorded
+	LastSeen         time.Time         `json:"lastSeen"`
+	Groups           []string          `json:"groups"`                   // From the metadata store
+	Role             string            `json:"role,omitempty"`           // Inferred from processes (HOST_ROLE_RULES)
+	Silence          *SilenceInfo      `json:"silence,omitempty"`        // Set while the host is silenced
+	Decommissioned   *DecommissionInfo `json:"decommissioned,omitempty"` // Set for decommissioned hosts (?include_decommissioned=true)
+	// Only with ?sparklines=true: 1m means over the last 15m, oldest first
+	CPUSparkline []float64 `json:"cpuSparkline,omitempty"`
+	RAMSparkline []float64 `json:"ramSparkline,omitempty"`
+	// Only with OVERVIEW_AVERAGE_WINDOWS: window ("1m", "5m", ...) -> mean, refreshed in the background
+	CPUAverages map[string]float64 `json:"cpuAverages,omitempty"`
+	RAMAverages map[string]float64 `json:"ramAverages,omitempty"`
+}
+
+// HostListEntry is one host of the lightweight host list (host pickers).
+type HostListEntry struct {
+	ID       string    `json:"id"`
+	Hostname string    `json:"hostname"`
 	LastSeen time.Time `json:"lastSeen"`
 }
 
+// Who silenced a host, why and until when.
+type SilenceInfo struct {
+	Until      time.Time `json:"until"`
+	Reason     string    `json:"reason"`
+	SilencedBy string    `json:"silencedBy"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Who decommissioned a host, why and when.
+type DecommissionInfo struct {
+	Reason           string    `json:"reason"`
+	DecommissionedBy string    `json:"decommissionedBy"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
 // For timeseries chart data
 type MetricPoint struct {
-	Timestamp string  `json:"timestamp"`
-	Value     float64 `json:"value"`
+	Timestamp string    `json:"timestamp"` // "HH:MM" in the server's display time zone (SERVER_DISPLAY_TZ)
+	Time      time.Time `json:"time"`      // UTC, for clients that localize themselves
+	Value     float64   `json:"value"`
+}
+
+// HostMetricsHistoryData is the history of several metrics of one host,
+// metric name -> points; every requested metric is present.
+type HostMetricsHistoryData struct {
+	ID      string                   `json:"id"`
+	Metrics map[string][]MetricPoint `json:"metrics"`
+}
+
+// MetricDelta compares the mean of a metric over the last window with its
+// mean over the window before it.
+type MetricDelta struct {
+	ID            string    `json:"id"`
+	Metric        string    `json:"metric"`
+	Window        string    `json:"window"`
+	End           time.Time `json:"end"`           // current window is [end-window, end), previous [end-2 x window, end-window)
+	Current       *float64  `json:"current"`       // null without points in the window
+	Previous      *float64  `json:"previous"`      // null without points in the window
+	PercentChange *float64  `json:"percentChange"` // (current - previous) / |previous| x 100, null if either is null or previous is 0
 }
 
 type CPUDetails struct {
@@ -29,15 +85,42 @@ type CPUDetails struct {
 type MemoryDetails struct {
 	TotalGB      float64 `json:"total_gb"`      // Total memory in GB
 	AvailableGB  float64 `json:"free_gb"`       // Available memory in GB (maps to 'free' in mock)
-	UsagePercent float64 `json:"usage_percent"` // not Used GB, Percent of Usage
+	UsedGB       float64 `json:"used_gb"`       // Total minus free memory in GB
+	UsagePercent float64 `json:"usage_percent"` // Percent of Usage, from mem_usage_percent
+	// Exact values for alerting on precise free bytes, 0 if the agent didn't send them
+	TotalBytes     int64 `json:"total_bytes"`
+	AvailableBytes int64 `json:"free_bytes"`
+	UsedBytes      int64 `json:"used_bytes"`
 }
 
-type RootDiskDetails struct {
+// DiskDetails is the latest usage of one mountpoint.
+type DiskDetails struct {
 	Path         string  `json:"path"`
 	TotalGB      float64 `json:"total_gb"`
 	UsedGB       float64 `json:"used_gb"`
 	FreeGB       float64 `json:"free_gb"`
 	UsagePercent float64 `json:"usage_percent"`
+
+	InodesTotal        int64   `json:"inodes_total"`
+	InodesUsed         int64   `json:"inodes_used"`
+	InodesUsagePercent float64 `json:"inodes_usage_percent"`
+}
+
+// ThrottleDetails is the latest CPU throttling state of an ARM Linux host.
+type ThrottleDetails struct {
+	Throttled     bool  `json:"throttled"`      // frequency capped, throttled or at the soft temperature limit
+	ThrottleCount int64 `json:"throttle_count"` // cooling state changes since boot, 0 if the kernel doesn't count them
+	Flags         int64 `json:"flags"`          // Raspberry Pi get_throttled bits, 0 on other boards
+}
+
+// ServiceDetail is the latest state of one systemd unit of a host.
+type ServiceDetail struct {
+	Unit        string    `json:"unit"`
+	Active      bool      `json:"active"`       // active_state is active
+	LoadState   string    `json:"load_state"`   // not-found for units the host doesn't have
+	ActiveState string    `json:"active_state"` // active, inactive, failed, activating, deactivating, reloading
+	SubState    string    `json:"sub_state"`
+	LastSeen    time.Time `json:"last_seen"`
 }
 
 type OSLiteralDetails struct {
@@ -48,26 +131,136 @@ type OSLiteralDetails struct {
 }
 
 type ProcessDetail struct {
-	PID           int32   `json:"pid"`
-	Name          string  `json:"name"`
-	CPUPercent    float64 `json:"cpu_percent"`
-	MemoryPercent float32 `json:"memory_percent"`
-	Username      string  `json:"username"`
+	PID           int32     `json:"pid"` // 0 in aggregated mode
+	Name          string    `json:"name"`
+	Instances     int       `json:"instances"` // processes of this name summed into the row, 1 per process in list mode
+	CPUPercent    float64   `json:"cpu_percent"`
+	MemoryPercent float32   `json:"memory_percent"`
+	Username      string    `json:"username"`
+	StartedAt     time.Time `json:"started_at"` // From create_time (epoch millis)
+	AgeSeconds    int64     `json:"age_seconds"`
+	// Disk I/O, 0 when the agent couldn't read it
+	ReadBytes        uint64  `json:"read_bytes"`
+	WriteBytes       uint64  `json:"write_bytes"`
+	ReadBytesPerSec  float64 `json:"read_bytes_per_sec"`
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec"`
 }
 
 type HostDetailsData struct {
-	ID       string `json:"id"` // HostID
-	Hostname string `json:"hostname"`
-	Status   string `json:"status"` // online, offline, warning
-	//	UptimeSeconds   string           `json:"uptimeSeconds"`
-	LastSeen        time.Time        `json:"lastSeen"`
-	CPU             CPUDetails       `json:"cpu"`
-	Memory          MemoryDetails    `json:"memory"`
-	Disk            RootDiskDetails  `json:"disk"`
-	OS              OSLiteralDetails `json:"os"`
-	Processes       []ProcessDetail  `json:"processes,omitempty"`
-	CPUUsage        float64          `json:"cpuUsage"`
-	RAMUsage        float64          `json:"ramUsage"`      // Memory usage percent
-	NetworkUpload   float64          `json:"networkUpload"` // Bytes/sec
-	NetworkDownload float64          `json:"networkDownload"`
+	ID               string                `json:"id"` // HostID
+	Hostname         string                `json:"hostname"`
+	Status           string                `json:"status"`           // online, offline, warning, critical, silenced, decommissioned
+	UptimeSeconds    int64                 `json:"uptimeSeconds"`    // As of LastSeen, 0 if unknown
+	RecentlyRebooted bool                  `json:"recentlyRebooted"` // Online and up for less than RECENT_REBOOT_THRESHOLD
+	LastSeen         time.Time             `json:"lastSeen"`
+	CPU              CPUDetails            `json:"cpu"`
+	Memory           MemoryDetails         `json:"memory"`
+	Disks            []DiskDetails         `json:"disks"` // every reported mountpoint, fullest first; empty if none
+	Disk             *DiskDetails          `json:"disk"`  // Deprecated: the "/" entry of Disks (null without one), kept for one release
+	OS               OSLiteralDetails      `json:"os"`
+	Processes        []ProcessDetail       `json:"processes"`            // empty if none were reported (all under the agent's threshold) or in summary mode
+	ProcessMode      string                `json:"processMode"`          // list, aggregated (PROCESS_SERIES=aggregated, one row per name), or summary for agents sending ProcessSummary instead
+	ProcessSummary   *ProcessSummaryDetail `json:"processSummary"`       // null in list mode
+	ProcessCPU       string                `json:"processCpuConvention"` // processes' cpu_percent: per_core (of one core) or total (of all cores)
+	CPUUsage         float64               `json:"cpuUsage"`
+	RAMUsage         float64               `json:"ramUsage"`      // Memory usage percent
+	NetworkUpload    float64               `json:"networkUpload"` // In NetworkUnit
+	NetworkDownload  float64               `json:"networkDownload"`
+	NetworkUnit      string                `json:"networkUnit"`      // Bps (bytes/sec, default), bps, Mbps or MBps (?netUnit)
+	NetworkSentTotal uint64                `json:"networkSentTotal"` // Bytes since boot
+	NetworkRecvTotal uint64                `json:"networkRecvTotal"`
+	LoggedInUsers    int64                 `json:"loggedInUsers"` // Login sessions, 0 if unsupported
+	Throttle         *ThrottleDetails      `json:"throttle"`      // CPU throttling, null unless an ARM Linux agent reports it
+	Services         []ServiceDetail       `json:"services"`      // MONITOR_SERVICES units by name, empty if none are reported
+	Notes            HostNotes             `json:"notes"`         // From the metadata store
+	Silence          *SilenceInfo          `json:"silence,omitempty"`
+	Decommissioned   *DecommissionInfo     `json:"decommissioned,omitempty"`
+}
+
+// ProcessSummaryDetail is the latest process summary of a host whose agent
+// runs in summary mode (MONITOR_PROCESS_MODE=summary).
+type ProcessSummaryDetail struct {
+	Count         int64                 `json:"count"`
+	TopCPUPercent float64               `json:"top_cpu_percent"`
+	Top           []ProcessSummaryEntry `json:"top"` // highest CPU first
+	Time          time.Time             `json:"time"`
+}
+
+// Operator notes for a host. Revision must be sent back on update.
+type HostNotes struct {
+	Text      string    `json:"text"`
+	Revision  uint64    `json:"revision"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DiskUsageSeries is a host's root disk usage over time, used for disk-full forecasts.
+type DiskUsageSeries struct {
+	HostID   string           `json:"host_id"`
+	Hostname string           `json:"hostname"`
+	TotalGB  float64          `json:"total_gb"` // latest
+	Points   []DiskUsagePoint `json:"points"`   // oldest first
+}
+
+type DiskUsagePoint struct {
+	Time   time.Time `json:"time"`
+	UsedGB float64   `json:"used_gb"`
+}
+
+// FlappingHost is a host whose samples arrive irregularly or whose usage keeps
+// crossing the warning threshold within the inspected window.
+type FlappingHost struct {
+	HostID             string    `json:"host_id"`
+	Hostname           string    `json:"hostname"`
+	Samples            int       `json:"samples"`
+	IntervalSeconds    float64   `json:"interval_seconds"` // median time between samples
+	Gaps               int       `json:"gaps"`             // times between samples far above the interval
+	LongestGapSeconds  float64   `json:"longest_gap_seconds"`
+	ThresholdCrossings int       `json:"threshold_crossings"` // CPU/RAM going over or back under 85%
+	Score              float64   `json:"score"`
+	LastSeen           time.Time `json:"lastSeen"`
+}
+
+// HostConflict is a host_id that several machines report at the same time,
+// so their metrics are mixed into one host.
+type HostConflict struct {
+	HostID    string             `json:"hostId"`
+	Since     time.Time          `json:"since"`
+	Hostnames []ConflictHostname `json:"hostnames"` // most recently seen first
+}
+
+// ConflictHostname is one of the machines reporting a conflicting host_id.
+type ConflictHostname struct {
+	Hostname string    `json:"hostname"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// HostConflictsData answers GET /api/dashboard/conflicts.
+type HostConflictsData struct {
+	Conflicts       []HostConflict `json:"conflicts"`
+	CollisionsTotal int64          `json:"collisionsTotal"` // collisions detected since the server started
+}
+
+// LabelGroup is one value of a static agent label and how many hosts carry it.
+type LabelGroup struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+	Hosts int    `json:"hosts"`
+}
+
+// InterfaceDetail is the latest state of one network interface of a host.
+type InterfaceDetail struct {
+	Name            string    `json:"name"`
+	Up              bool      `json:"up"`
+	SpeedMbps       int64     `json:"speedMbps"`          // 0 if the agent couldn't read the link speed
+	NetworkUpload   *float64  `json:"networkUpload"`      // In NetworkUnit, null if the agent skipped the interval's rates
+	NetworkDownload *float64  `json:"networkDownload"`    // In NetworkUnit
+	Utilization     *float64  `json:"utilizationPercent"` // busier direction as percent of the link speed, null if either is unknown
+	LastSeen        time.Time `json:"lastSeen"`
+}
+
+// HostInterfacesData is the per-interface network view of a host.
+type HostInterfacesData struct {
+	ID          string            `json:"id"`          // HostID
+	NetworkUnit string            `json:"networkUnit"` // Bps (bytes/sec, default), bps, Mbps or MBps (?netUnit)
+	Interfaces  []InterfaceDetail `json:"interfaces"`  // by name, empty if the agent reports none
 }
@@ -0,0 +1,170 @@
+package models
+
+import "time"
+
+// HostDetailsV2 is the v2 schema of host details, served by
+// /api/v2/dashboard/host/:hostID/details. HostDetailsData's field names were
+// chosen to match the frontend's early mock data; here every key is
+// snake_case and named after what it holds, and related values are grouped:
+//
+//	cpuUsage                       -> cpu.usage_percent
+//	ramUsage, memory.usage_percent -> memory.usage_percent (mem_usage_percent, as reported by the agent)
+//	memory.used_gb                 -> memory.used_gb (mem_used_gb, total minus available)
+//	memory.free_gb, free_bytes     -> memory.available_gb, available_bytes (mem_available_gb/_bytes)
+//	disk                           -> dropped, use the "/" entry of disks
+//	networkUpload/Download/Unit    -> network.upload, network.download, network.unit
+//	networkSentTotal/RecvTotal     -> network.sent_total_bytes, network.recv_total_bytes
+//	processes, processMode         -> processes.list, processes.mode
+//	processSummary                 -> processes.summary
+//	processCpuConvention           -> processes.cpu_convention
+//	os.kernelArch                  -> os.kernel_arch
+//	other camelCase keys           -> snake_case (uptime_seconds, last_seen, silence.silenced_by, ...)
+type HostDetailsV2 struct {
+	ID               string           `json:"id"`
+	Hostname         string           `json:"hostname"`
+	Status           string           `json:"status"` // online, offline, warning, critical, silenced, decommissioned
+	UptimeSeconds    int64            `json:"uptime_seconds"`
+	RecentlyRebooted bool             `json:"recently_rebooted"`
+	LastSeen         time.Time        `json:"last_seen"`
+	CPU              CPUDetailsV2     `json:"cpu"`
+	Memory           MemoryDetailsV2  `json:"memory"`
+	Disks            []DiskDetails    `json:"disks"` // fullest first
+	OS               OSDetailsV2      `json:"os"`
+	Network          NetworkDetailsV2 `json:"network"`
+	Processes        ProcessesV2      `json:"processes"`
+	LoggedInUsers    int64            `json:"logged_in_users"`
+	Throttle         *ThrottleDetails `json:"throttle"` // null unless an ARM Linux agent reports it
+	Services         []ServiceDetail  `json:"services"` // by unit
+	Notes            HostNotes        `json:"notes"`
+	Silence          *SilenceV2       `json:"silence,omitempty"`
+	Decommissioned   *DecommissionV2  `json:"decommissioned,omitempty"`
+}
+
+type CPUDetailsV2 struct {
+	Cores        int32   `json:"cores"`
+	ModelName    string  `json:"model_name"`
+	UsagePercent float64 `json:"usage_percent"`
+}
+
+// MemoryDetailsV2 keeps used_gb and usage_percent apart: the first is an
+// amount (total minus available), the second the agent's usage percent.
+type MemoryDetailsV2 struct {
+	TotalGB        float64 `json:"total_gb"`
+	UsedGB         float64 `json:"used_gb"`
+	AvailableGB    float64 `json:"available_gb"`
+	UsagePercent   float64 `json:"usage_percent"`
+	TotalBytes     int64   `json:"total_bytes"` // 0 if the agent didn't send exact values
+	UsedBytes      int64   `json:"used_bytes"`
+	AvailableBytes int64   `json:"available_bytes"`
+}
+
+type OSDetailsV2 struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Kernel     string `json:"kernel"`
+	KernelArch string `json:"kernel_arch"`
+}
+
+type NetworkDetailsV2 struct {
+	Unit           string  `json:"unit"`     // of upload and download, see ?netUnit
+	Upload         float64 `json:"upload"`   // current rate
+	Download       float64 `json:"download"` // current rate
+	SentTotalBytes uint64  `json:"sent_total_bytes"`
+	RecvTotalBytes uint64  `json:"recv_total_bytes"`
+}
+
+type ProcessesV2 struct {
+	Mode          string                `json:"mode"`           // list, aggregated or summary
+	CPUConvention string                `json:"cpu_convention"` // per_core or total
+	List          []ProcessDetail       `json:"list"`           // empty in summary mode
+	Summary       *ProcessSummaryDetail `json:"summary"`        // null in list mode
+}
+
+type SilenceV2 struct {
+	Until      time.Time `json:"until"`
+	Reason     string    `json:"reason"`
+	SilencedBy string    `json:"silenced_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type DecommissionV2 struct {
+	Reason           string    `json:"reason"`
+	DecommissionedBy string    `json:"decommissioned_by"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// NewHostDetailsV2 converts details to the v2 schema. Only the shape
+// changes, every value comes from the same field of details.
+func NewHostDetailsV2(details *HostDetailsData) HostDetailsV2 {
+	v2 := HostDetailsV2{
+		ID:               details.ID,
+		Hostname:         details.Hostname,
+		Status:           details.Status,
+		UptimeSeconds:    details.UptimeSeconds,
+		RecentlyRebooted: details.RecentlyRebooted,
+		LastSeen:         details.LastSeen,
+		CPU: CPUDetailsV2{
+			Cores:        details.CPU.Cores,
+			ModelName:    details.CPU.ModelName,
+			UsagePercent: details.CPUUsage,
+		},
+		Memory: MemoryDetailsV2{
+			TotalGB:        details.Memory.TotalGB,
+			UsedGB:         details.Memory.UsedGB,
+			AvailableGB:    details.Memory.AvailableGB,
+			UsagePercent:   details.Memory.UsagePercent,
+			TotalBytes:     details.Memory.TotalBytes,
+			UsedBytes:      details.Memory.UsedBytes,
+			AvailableBytes: details.Memory.AvailableBytes,
+		},
+		Disks: details.Disks,
+		OS: OSDetailsV2{
+			Name:       details.OS.Name,
+			Version:    details.OS.Version,
+			Kernel:     details.OS.Kernel,
+			KernelArch: details.OS.KernelArch,
+		},
+		Network: NetworkDetailsV2{
+			Unit:           details.NetworkUnit,
+			Upload:         details.NetworkUpload,
+			Download:       details.NetworkDownload,
+			SentTotalBytes: details.NetworkSentTotal,
+			RecvTotalBytes: details.NetworkRecvTotal,
+		},
+		Processes: ProcessesV2{
+			Mode:          details.ProcessMode,
+			CPUConvention: details.ProcessCPU,
+			List:          details.Processes,
+			Summary:       details.ProcessSummary,
+		},
+		LoggedInUsers: details.LoggedInUsers,
+		Throttle:      details.Throttle,
+		Services:      details.Services,
+		Notes:         details.Notes,
+	}
+	if v2.Disks == nil {
+		v2.Disks = []DiskDetails{}
+	}
+	if v2.Processes.List == nil {
+		v2.Processes.List = []ProcessDetail{}
+	}
+	if v2.Services == nil {
+		v2.Services = []ServiceDetail{}
+	}
+	if details.Silence != nil {
+		v2.Silence = &SilenceV2{
+			Until:      details.Silence.Until,
+			Reason:     details.Silence.Reason,
+			SilencedBy: details.Silence.SilencedBy,
+			CreatedAt:  details.Silence.CreatedAt,
+		}
+	}
+	if details.Decommissioned != nil {
+		v2.Decommissioned = &DecommissionV2{
+			Reason:           details.Decommissioned.Reason,
+			DecommissionedBy: details.Decommissioned.DecommissionedBy,
+			CreatedAt:        details.Decommissioned.CreatedAt,
+		}
+	}
+	return v2
+}
@@ -11,7 +11,11 @@ type SystemInfoPayload struct {
 	OSVersion     string `json:"os_version"`
 	Kernel        string `json:"kernel"`
 	KernelVersion string `json:"kernel_version"`
-	Uptime        string `json:"uptime"`
+	Uptime        string `json:"uptime"`                   // duration string, e.g. 26h3m12s
+	UptimeSeconds uint64 `json:"uptime_seconds,omitempty"` // not sent by older agents, see UptimeSecs
+	// Login sessions on the host, 0 when the agent can't read them
+	LoggedInUsers     int      `json:"logged_in_users"`
+	LoggedInUsernames []string `json:"logged_in_usernames,omitempty"`
 }
 
 type CPUInfoPayload struct {
@@ -24,6 +28,9 @@ type MemInfoPayload struct {
 	TotalGB      float64 `json:"total_gb"`
 	FreeGB       float64 `json:"free_gb"` // From memoryInfo.Available
 	UsagePercent float64 `json:"usage_percent"`
+	TotalBytes   uint64  `json:"total_bytes"` // Exact byte counts, 0 from older agents
+	FreeBytes    uint64  `json:"free_bytes"`
+	UsedBytes    uint64  `json:"used_bytes"`
 }
 
 type NetworkPayload struct {
@@ -34,6 +41,9 @@ type NetworkPayload struct {
 	PacketsRecvPeriod   uint64  `json:"packets_recv_period"`
 	UploadBytesPerSec   float64 `json:"upload_bytes_per_sec"`
 	DownloadBytesPerSec float64 `json:"download_bytes_per_sec"`
+	CumulativeBytesSent uint64  `json:"cumulative_bytes_sent"` // Counter total since boot
+	CumulativeBytesRecv uint64  `json:"cumulative_bytes_recv"`
+	RatesSkipped        bool    `json:"rates_skipped,omitempty"` // period/rate fields are not meaningful, not written
 }
 type ProcessPayload struct {
 	PID           int32   `json:"pid"`
@@ -41,6 +51,14 @@ type ProcessPayload struct {
 	CPUPercent    float64 `json:"cpu_percent"`
 	MemoryPercent float32 `json:"memory_percent"`
 	Username      string  `json:"username"`
+	CreateTime    int64   `json:"create_time"` // Unix epoch milliseconds
+	AgeSeconds    int64   `json:"age_seconds"`
+	// Disk I/O since process start and rates since the previous report, 0
+	// when unknown (no permission, first report, older agents)
+	ReadBytes        uint64  `json:"read_bytes"`
+	WriteBytes       uint64  `json:"write_bytes"`
+	ReadBytesPerSec  float64 `json:"read_bytes_per_sec"`
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec"`
 	// Add more fields as needed, e.g., status, command line
 }
 
@@ -50,16 +68,122 @@ type DiskUsagePayload struct {
 	UsedGB       float64 `json:"used_gb"`
 	FreeGB       float64 `json:"free_gb"`
 	UsagePercent float64 `json:"usage_percent"`
+	// 0 when the filesystem doesn't report inodes
+	InodesTotal        uint64  `json:"inodes_total"`
+	InodesUsed         uint64  `json:"inodes_used"`
+	InodesUsagePercent float64 `json:"inodes_usage_percent"`
+}
+
+// InterfacePayload is the link state and rates of one network interface.
+type InterfacePayload struct {
+	Name                string  `json:"name"`
+	Up                  bool    `json:"up"`
+	SpeedMbps           int64   `json:"speed_mbps"` // 0 = unknown
+	UploadBytesPerSec   float64 `json:"upload_bytes_per_sec"`
+	DownloadBytesPerSec float64 `json:"download_bytes_per_sec"`
+	RatesSkipped        bool    `json:"rates_skipped,omitempty"`
+}
+
+// DiskHealthPayload is the SMART summary of one physical disk (MONITOR_SMART agents only).
+type DiskHealthPayload struct {
+	Device             string  `json:"device"`
+	Model              string  `json:"model,omitempty"`
+	Healthy            bool    `json:"healthy"`
+	TemperatureCelsius float64 `json:"temperature_celsius"`
+	PowerOnHours       int64   `json:"power_on_hours"`
+}
+
+// ProcessSummaryPayload is sent instead of the process list by agents with
+// MONITOR_PROCESS_MODE=summary: a count and the top processes by CPU, by name.
+type ProcessSummaryPayload struct {
+	Count         int                   `json:"count"`
+	TopCPUPercent float64               `json:"top_cpu_percent"` // sum over Top
+	Top           []ProcessSummaryEntry `json:"top"`             // highest CPU first
+}
+
+type ProcessSummaryEntry struct {
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryPercent float32 `json:"memory_percent"`
+}
+
+// How a payload's process cpu_percent is scaled.
+const (
+	ProcessCPUPerCore = "per_core" // percent of one core, up to 100 x cores (older agents)
+	ProcessCPUTotal   = "total"    // percent of all logical cores, up to 100
+)
+
+// ThrottlePayload is the CPU throttling state of ARM Linux boards.
+type ThrottlePayload struct {
+	Throttled     bool   `json:"throttled"`       // capped, throttled or at the soft temperature limit
+	ThrottleCount int64  `json:"throttle_count"`  // cooling state changes since boot, 0 if not counted
+	Flags         uint32 `json:"flags,omitempty"` // Raspberry Pi get_throttled bits, 0 elsewhere
+}
+
+// ServicePayload is the state of one systemd unit (MONITOR_SERVICES agents on systemd hosts only).
+type ServicePayload struct {
+	Unit        string `json:"unit"`
+	LoadState   string `json:"load_state"`   // loaded, not-found, masked, ...
+	ActiveState string `json:"active_state"` // active, inactive, failed, activating, deactivating, reloading
+	SubState    string `json:"sub_state"`    // running, exited, dead, ...
 }
 
 // ClientPayload is the top-level struct expected from the client.
 // This must match the AllHostStats struct sent by your client.
 type ClientPayload struct {
-	CollectedAt time.Time          `json:"collected_at"` // Crucial for InfluxDB timestamp
-	System      SystemInfoPayload  `json:"system_info"`
-	CPU         CPUInfoPayload     `json:"cpu_info"`
-	Memory      MemInfoPayload     `json:"memory_info"`
-	Network     NetworkPayload     `json:"network_info"`
-	Processes   []ProcessPayload   `json:"processes,omitempty"`
-	Disks       []DiskUsagePayload `json:"disk_usage,omitempty"`
+	CollectedAt    time.Time              `json:"collected_at"`                     // Crucial for InfluxDB timestamp
+	AgentVersion   string                 `json:"agent_version,omitempty"`          // build version of the agent, empty for old agents
+	Labels         map[string]string      `json:"labels,omitempty"`                 // static labels from MONITOR_LABELS, e.g. tier=web
+	ProcessCPU     string                 `json:"process_cpu_convention,omitempty"` // ProcessCPUPerCore (also when empty) or ProcessCPUTotal
+	System         SystemInfoPayload      `json:"system_info"`
+	CPU            CPUInfoPayload         `json:"cpu_info"`
+	Memory         MemInfoPayload         `json:"memory_info"`
+	Network        NetworkPayload         `json:"network_info"`
+	Interfaces     []InterfacePayload     `json:"interface_info,omitempty"`
+	Processes      []ProcessPayload       `json:"processes,omitempty"`
+	ProcessSummary *ProcessSummaryPayload `json:"process_summary,omitempty"` // instead of Processes from agents in summary mode
+	Disks          []DiskUsagePayload     `json:"disk_usage,omitempty"`
+	DiskHealth     []DiskHealthPayload    `json:"disk_health,omitempty"`
+	Throttle       *ThrottlePayload       `json:"throttle,omitempty"` // ARM Linux agents only
+	Services       []ServicePayload       `json:"services,omitempty"` // MONITOR_SERVICES agents on systemd hosts only
+
+	ReceivedAt time.Time `json:"-"` // set by the server on ingest, zero for payloads from elsewhere
+}
+
+// IngestLag is how long after collection the server received the payload:
+// normally the send time, large for replayed sends, negative when the
+// agent's clock is ahead. ok is false if ReceivedAt isn't set.
+func (p *ClientPayload) IngestLag() (lag time.Duration, ok bool) {
+	if p.ReceivedAt.IsZero() {
+		return 0, false
+	}
+	return p.ReceivedAt.Sub(p.CollectedAt), true
+}
+
+// ProcessNames returns the names of the reported processes, from the list or
+// the summary's top processes.
+func (p *ClientPayload) ProcessNames() []string {
+	var names []string
+	for _, proc := range p.Processes {
+		names = append(names, proc.Name)
+	}
+	if p.ProcessSummary != nil {
+		for _, top := range p.ProcessSummary.Top {
+			names = append(names, top.Name)
+		}
+	}
+	return names
+}
+
+// UptimeSecs returns the uptime in seconds, from Uptime for older agents that
+// only send the duration string. ok is false if neither is usable.
+func (s SystemInfoPayload) UptimeSecs() (seconds int64, ok bool) {
+	if s.UptimeSeconds > 0 {
+		return int64(s.UptimeSeconds), true
+	}
+	uptime, err := time.ParseDuration(s.Uptime)
+	if err != nil || uptime <= 0 {
+		return 0, false
+	}
+	return int64(uptime / time.Second), true
 }
@@ -0,0 +1,290 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	appLogger "github.com/4Noyis/system-stats-monitoring/internal/logger"
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+)
+
+// HostTracker follows host presence from the ingest path so that otherwise
+// invisible events (new host, host went offline / came back) get annotated.
+type HostTracker struct {
+	store        *metadata.Store
+	offlineAfter time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostPresence
+
+	collisions atomic.Int64 // host_id collisions detected since start
+}
+
+type hostPresence struct {
+	hostname string
+	lastSeen time.Time
+	online   bool
+	interval time.Duration // smoothed gap between reports, 0 until the second report
+
+	// hostnames is when each hostname reporting this host_id was last seen,
+	// within offlineAfter. More than one means different machines share the
+	// host_id (e.g. cloned VMs with the same /etc/machine-id).
+	hostnames     map[string]time.Time
+	conflictSince time.Time // zero while only one hostname is active
+}
+
+// NewHostTracker creates a tracker. A host is considered offline once nothing
+// has been received from it for offlineAfter.
+func NewHostTracker(store *metadata.Store, offlineAfter time.Duration) *HostTracker {
+	return &HostTracker{
+		store:        store,
+		offlineAfter: offlineAfter,
+		hosts:        make(map[string]*hostPresence),
+	}
+}
+
+// Seen is called for every successfully ingested payload.
+func (t *HostTracker) Seen(hostID, hostname string, at time.Time) {
+	t.mu.Lock()
+	presence, known := t.hosts[hostID]
+	if !known {
+		presence = &hostPresence{}
+		t.hosts[hostID] = presence
+	}
+	cameBack := known && !presence.online
+	// Interleaved reports of colliding machines aren't one agent's interval
+	if known && !cameBack && presence.conflictSince.IsZero() {
+		presence.interval = smoothInterval(presence.interval, at.Sub(presence.lastSeen))
+	}
+	presence.hostname = hostname
+	presence.lastSeen = at
+	presence.online = true
+	collidingWith := t.observeHostname(presence, hostname, at)
+	t.mu.Unlock()
+
+	if len(collidingWith) > 0 {
+		count := t.collisions.Add(1)
+		appLogger.WarnKV("HOST ID COLLISION: different machines are reporting the same host_id, their metrics are mixed into one host",
+			"host_id", hostID, "hostname", hostname, "also_reported_by", strings.Join(collidingWith, ","), "collisions_total", count)
+		t.annotate(hostID, at, "Host ID collision", fmt.Sprintf("%s reports the host_id already used by %s", hostname, strings.Join(collidingWith, ", ")), "host-id-collision")
+	}
+
+	t.recommission(hostID, hostname, at)
+
+	if !known {
+		// First time since server start, check whether it's a brand new host
+		_, created, err := t.store.RegisterHost(hostID, hostname, at)
+		if err != nil {
+			appLogger.Error("Failed to register host %s in metadata store: %v", hostID, err)
+			return
+		}
+		if created {
+			appLogger.Info("New host registered: %s (%s)", hostID, hostname)
+			t.annotate(hostID, at, "Host registered", fmt.Sprintf("%s reported for the first time", hostname), "host-registered")
+		}
+		return
+	}
+
+	if cameBack {
+		appLogger.Info("Host %s (%s) is back online", hostID, hostname)
+		t.annotate(hostID, at, "Host online", fmt.Sprintf("%s started reporting again", hostname), "host-online")
+	}
+}
+
+// recommission brings a decommissioned host back when it reports again, so
+// a reinstalled or re-enabled machine doesn't stay hidden. Reports within
+// offlineAfter of decommissioning don't count: they may have been in flight
+// while the agent was being stopped. The check is a read; the store is only
+// written when the host is recommissioned.
+func (t *HostTracker) recommission(hostID, hostname string, at time.Time) {
+	decommission, err := t.store.GetDecommission(hostID)
+	if err != nil {
+		if !errors.Is(err, metadata.ErrNotFound) {
+			appLogger.Error("Failed to check whether host %s is decommissioned: %v", hostID, err)
+		}
+		return
+	}
+	if at.Sub(decommission.CreatedAt) <= t.offlineAfter {
+		return
+	}
+	if err := t.store.Recommission(hostID); err != nil {
+		if !errors.Is(err, metadata.ErrNotFound) { // already recommissioned through the API
+			appLogger.Error("Failed to recommission host %s: %v", hostID, err)
+		}
+		return
+	}
+	appLogger.Warn("Decommissioned host %s (%s) is reporting again, recommissioned it", hostID, hostname)
+	t.annotate(hostID, at, "Host recommissioned", fmt.Sprintf("%s reported again after being decommissioned on %s", hostname, decommission.CreatedAt.Format(time.RFC3339)), "host-recommissioned")
+}
+
+// observeHostname records hostname for presence and forgets hostnames not
+// seen for offlineAfter. It returns the other active hostnames when hostname
+// newly joins them, i.e. when a collision starts or grows. Called with t.mu held.
+func (t *HostTracker) observeHostname(presence *hostPresence, hostname string, at time.Time) []string {
+	if presence.hostnames == nil {
+		presence.hostnames = make(map[string]time.Time)
+	}
+	for name, lastSeen := range presence.hostnames {
+		if at.Sub(lastSeen) > t.offlineAfter {
+			delete(presence.hostnames, name)
+		}
+	}
+	_, active := presence.hostnames[hostname]
+	presence.hostnames[hostname] = at
+	if len(presence.hostnames) < 2 {
+		presence.conflictSince = time.Time{}
+		return nil
+	}
+	if presence.conflictSince.IsZero() {
+		presence.conflictSince = at
+	}
+	if active {
+		return nil
+	}
+	others := make([]string, 0, len(presence.hostnames)-1)
+	for name := range presence.hostnames {
+		if name != hostname {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return others
+}
+
+// smoothInterval folds a new gap into the running estimate (EWMA, 1/8 weight).
+// Gaps over 3x the estimate are outages, not the agent's interval, and are ignored.
+func smoothInterval(current, gap time.Duration) time.Duration {
+	if gap <= 0 {
+		return current
+	}
+	if current == 0 {
+		return gap
+	}
+	if gap > 3*current {
+		return current
+	}
+	return (current*7 + gap) / 8
+}
+
+// HostStatus is a point-in-time view of a tracked host.
+type HostStatus struct {
+	HostID   string
+	Hostname string
+	LastSeen time.Time
+	Online   bool
+	// ExpectedInterval is the host's observed reporting interval, 0 if not known yet.
+	ExpectedInterval time.Duration
+}
+
+// Snapshot returns every host seen since the server started.
+func (t *HostTracker) Snapshot() []HostStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hosts := make([]HostStatus, 0, len(t.hosts))
+	for hostID, presence := range t.hosts {
+		hosts = append(hosts, HostStatus{
+			HostID:   hostID,
+			Hostname: presence.hostname,
+			LastSeen: presence.lastSeen,
+			Online:   presence.online,
+
+			ExpectedInterval: presence.interval,
+		})
+	}
+	return hosts
+}
+
+// HostConflict is a host_id reported by more than one hostname within the
+// offline threshold.
+type HostConflict struct {
+	HostID    string
+	Since     time.Time // when the second hostname appeared
+	Hostnames map[string]time.Time
+}
+
+// Conflicts returns the host_ids currently reported by several hostnames,
+// sorted by host_id. Hostnames not seen for the offline threshold no longer
+// count.
+func (t *HostTracker) Conflicts(now time.Time) []HostConflict {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conflicts := []HostConflict{}
+	for hostID, presence := range t.hosts {
+		if presence.conflictSince.IsZero() {
+			continue
+		}
+		hostnames := make(map[string]time.Time, len(presence.hostnames))
+		for name, lastSeen := range presence.hostnames {
+			if now.Sub(lastSeen) <= t.offlineAfter {
+				hostnames[name] = lastSeen
+			}
+		}
+		if len(hostnames) > 1 {
+			conflicts = append(conflicts, HostConflict{HostID: hostID, Since: presence.conflictSince, Hostnames: hostnames})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].HostID < conflicts[j].HostID })
+	return conflicts
+}
+
+// Collisions is how many host_id collisions were detected since start: a
+// hostname joining the ones already reporting a host_id counts once.
+func (t *HostTracker) Collisions() int64 {
+	return t.collisions.Load()
+}
+
+// Run checks for hosts that stopped reporting every interval until ctx is cancelled.
+func (t *HostTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.checkOffline(now)
+		}
+	}
+}
+
+func (t *HostTracker) checkOffline(now time.Time) {
+	type wentOffline struct {
+		hostID, hostname string
+		lastSeen         time.Time
+	}
+	var offline []wentOffline
+
+	t.mu.Lock()
+	for hostID, presence := range t.hosts {
+		if presence.online && now.Sub(presence.lastSeen) > t.offlineAfter {
+			presence.online = false
+			offline = append(offline, wentOffline{hostID, presence.hostname, presence.lastSeen})
+		}
+	}
+	t.mu.Unlock()
+
+	// annotate outside the lock, it hits the metadata store
+	for _, h := range offline {
+		appLogger.Warn("Host %s (%s) went offline, last seen %s", h.hostID, h.hostname, h.lastSeen.Format(time.RFC3339))
+		t.annotate(h.hostID, h.lastSeen, "Host offline", fmt.Sprintf("%s stopped reporting", h.hostname), "host-offline")
+	}
+}
+
+func (t *HostTracker) annotate(hostID string, at time.Time, title, text, tag string) {
+	_, err := t.store.AddAnnotation(metadata.Annotation{
+		Time:   at,
+		HostID: hostID,
+		Title:  title,
+		Text:   text,
+		Tags:   []string{"system", tag},
+	})
+	if err != nil {
+		appLogger.Error("Failed to store %q annotation for host %s: %v", title, hostID, err)
+	}
+}
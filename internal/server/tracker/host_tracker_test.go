@@ -0,0 +1,115 @@
+package tracker
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/4Noyis/system-stats-monitoring/internal/server/metadata"
+)
+
+func newTestStore(t *testing.T) *metadata.Store {
+	t.Helper()
+	store, err := metadata.Open(filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatalf("metadata.Open: %v", err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+// annotationTags lists the system tag of every annotation for hostID, in time order.
+func annotationTags(t *testing.T, store *metadata.Store, hostID string) []string {
+	t.Helper()
+	annotations, err := store.ListAnnotations(time.Now().Add(-24*time.Hour), time.Now().Add(time.Hour), hostID)
+	if err != nil {
+		t.Fatalf("ListAnnotations: %v", err)
+	}
+	tags := []string{}
+	for _, a := range annotations {
+		if len(a.Tags) != 2 || a.Tags[0] != "system" || a.HostID != hostID {
+			t.Errorf("annotation %+v: want host %s tagged system plus the event", a, hostID)
+			continue
+		}
+		tags = append(tags, a.Tags[1])
+	}
+	return tags
+}
+
+func TestPresenceAnnotations(t *testing.T) {
+	store := newTestStore(t)
+	tracker := NewHostTracker(store, time.Minute)
+	start := time.Now().Add(-30 * time.Minute).UTC()
+
+	tracker.Seen("host-1", "web-1", start)
+	tracker.Seen("host-1", "web-1", start.Add(10*time.Second))
+	tracker.checkOffline(start.Add(30 * time.Second)) // still within a minute
+	if got := fmt.Sprint(annotationTags(t, store, "host-1")); got != "[host-registered]" {
+		t.Fatalf("after the first reports: %s, want [host-registered]", got)
+	}
+
+	tracker.checkOffline(start.Add(2 * time.Minute))
+	tracker.checkOffline(start.Add(3 * time.Minute)) // already offline, no second annotation
+	tracker.Seen("host-1", "web-1", start.Add(5*time.Minute))
+	want := "[host-registered host-offline host-online]"
+	if got := fmt.Sprint(annotationTags(t, store, "host-1")); got != want {
+		t.Errorf("after going offline and back: %s, want %s", got, want)
+	}
+
+	annotations, err := store.ListAnnotations(start, start.Add(time.Hour), "host-1")
+	if err != nil {
+		t.Fatalf("ListAnnotations: %v", err)
+	}
+	// the offline annotation sits at the last report, not at the check that noticed
+	if len(annotations) == 3 && !annotations[1].Time.Equal(start.Add(10*time.Second)) {
+		t.Errorf("offline annotation at %s, want the last report %s", annotations[1].Time, start.Add(10*time.Second))
+	}
+
+	// a server restart forgets presence, but the store remembers the host
+	restarted := NewHostTracker(store, time.Minute)
+	restarted.Seen("host-1", "web-1", start.Add(6*time.Minute))
+	if got := fmt.Sprint(annotationTags(t, store, "host-1")); got != want {
+		t.Errorf("after a restart: %s, want no new registration (%s)", got, want)
+	}
+}
+
+func TestListAnnotationsTimeRange(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, a := range []metadata.Annotation{
+		{Time: base.Add(2 * time.Hour), HostID: "host-1", Title: "late"},
+		{Time: base, HostID: "host-1", Title: "start"},
+		{Time: base.Add(time.Hour), Title: "global"},
+		{Time: base.Add(time.Hour), HostID: "host-2", Title: "other host"},
+		{Time: base.Add(-time.Hour), HostID: "host-1", Title: "before"},
+	} {
+		if _, err := store.AddAnnotation(a); err != nil {
+			t.Fatalf("AddAnnotation: %v", err)
+		}
+	}
+
+	titles := func(start, end time.Time, hostID string) string {
+		t.Helper()
+		annotations, err := store.ListAnnotations(start, end, hostID)
+		if err != nil {
+			t.Fatalf("ListAnnotations: %v", err)
+		}
+		var titles []string
+		for _, a := range annotations {
+			titles = append(titles, a.Title)
+		}
+		return fmt.Sprint(titles)
+	}
+
+	// both ends inclusive, in time order whatever the insertion order
+	if got := titles(base, base.Add(2*time.Hour), ""); got != "[start global other host late]" {
+		t.Errorf("all hosts = %s", got)
+	}
+	if got := titles(base, base.Add(2*time.Hour), "host-1"); got != "[start global late]" {
+		t.Errorf("host-1 = %s, want its own and the global ones", got)
+	}
+	if got := titles(base.Add(time.Minute), base.Add(90*time.Minute), "host-1"); got != "[global]" {
+		t.Errorf("narrow range = %s, want [global]", got)
+	}
+}
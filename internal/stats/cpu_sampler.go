@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// CPUSampler computes CPU usage from the difference between the current
+// cpu.Times snapshot and the one taken on the previous call, so it returns
+// immediately instead of blocking for cpu.Percent's sampling second.
+//
+// The result is the average since the previous call (the whole collection
+// interval) rather than over one second, which smooths short spikes. The
+// first call has no previous snapshot and reports the average since boot.
+// With a state file the snapshot survives between runs, for oneshot/cron use.
+type CPUSampler struct {
+	statePath string // "" = keep the snapshot in memory only
+	prev      *cpu.TimesStat
+}
+
+// NewCPUSampler returns a sampler keeping its snapshot in statePath, or only
+// in memory when statePath is empty.
+func NewCPUSampler(statePath string) *CPUSampler {
+	return &CPUSampler{statePath: statePath}
+}
+
+// Usage returns the overall CPU usage percent since the previous call.
+func (s *CPUSampler) Usage() (float64, error) {
+	times, err := cpu.Times(false) // false -> one entry for all CPUs
+	if err != nil {
+		return 0, fmt.Errorf("error getting CPU times: %w", err)
+	}
+	if len(times) == 0 {
+		return 0, errors.New("no CPU times reported")
+	}
+	current := times[0]
+
+	prev := s.prev
+	if prev == nil && s.statePath != "" {
+		prev = s.load()
+	}
+	s.prev = &current
+	if s.statePath != "" {
+		if err := s.save(current); err != nil {
+			return 0, err
+		}
+	}
+
+	busy, total := cpuBusy(current)
+	if prev != nil {
+		prevBusy, prevTotal := cpuBusy(*prev)
+		if total > prevTotal && busy >= prevBusy { // not after a reboot
+			busy, total = busy-prevBusy, total-prevTotal
+		}
+	}
+	if total <= 0 {
+		return 0, errors.New("CPU times did not advance")
+	}
+	return math.Min(100, math.Max(0, busy/total*100)), nil
+}
+
+// cpuBusy returns busy and total CPU seconds the way cpu.Percent counts them.
+func cpuBusy(t cpu.TimesStat) (busy, total float64) {
+	total = t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+	return total - t.Idle - t.Iowait, total
+}
+
+func (s *CPUSampler) load() *cpu.TimesStat {
+	data, err := os.ReadFile(s.statePath)
+	if err != nil {
+		return nil // first run
+	}
+	var prev cpu.TimesStat
+	if json.Unmarshal(data, &prev) != nil {
+		return nil
+	}
+	return &prev
+}
+
+func (s *CPUSampler) save(t cpu.TimesStat) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.statePath), 0o755); err != nil {
+		return fmt.Errorf("error saving CPU times: %w", err)
+	}
+	// Write and rename so an interrupted run doesn't leave half a file
+	tmp := s.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error saving CPU times: %w", err)
+	}
+	if err := os.Rename(tmp, s.statePath); err != nil {
+		return fmt.Errorf("error saving CPU times: %w", err)
+	}
+	return nil
+}
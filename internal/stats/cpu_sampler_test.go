@@ -0,0 +1,57 @@
+package stats
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+func TestCPUSamplerReturnsPromptly(t *testing.T) {
+	sampler := NewCPUSampler("")
+	for cycle := 1; cycle <= 2; cycle++ {
+		start := time.Now()
+		usage, err := sampler.Usage()
+		if err != nil {
+			t.Fatalf("cycle %d: Usage: %v", cycle, err)
+		}
+		// cpu.Percent blocks for its one-second sample, the sampler only reads /proc/stat
+		if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+			t.Errorf("cycle %d: Usage took %v, want no sampling wait", cycle, elapsed)
+		}
+		if usage < 0 || usage > 100 {
+			t.Errorf("cycle %d: usage %v%%, want 0-100", cycle, usage)
+		}
+		if cycle == 1 {
+			// keep a core busy so the second cycle has something to measure
+			for deadline := time.Now().Add(300 * time.Millisecond); time.Now().Before(deadline); {
+			}
+		}
+		if cycle == 2 && usage == 0 {
+			t.Errorf("cycle 2: usage 0%% after 300ms of busy loop")
+		}
+	}
+}
+
+func TestCPUSamplerStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "cpu.json")
+	if _, err := NewCPUSampler(path).Usage(); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	// the next run (a new process in oneshot mode) continues from the saved snapshot
+	next := NewCPUSampler(path)
+	if next.load() == nil {
+		t.Fatal("no snapshot saved for the next run")
+	}
+	if usage, err := next.Usage(); err != nil || usage < 0 || usage > 100 {
+		t.Errorf("second run: %v%%, %v, want a percent", usage, err)
+	}
+}
+
+func TestCPUBusy(t *testing.T) {
+	busy, total := cpuBusy(cpu.TimesStat{User: 30, System: 10, Idle: 50, Iowait: 5, Nice: 2, Irq: 1, Softirq: 1, Steal: 1})
+	if busy != 45 || total != 100 {
+		t.Errorf("cpuBusy = %v busy / %v total, want 45 / 100 (idle and iowait are not busy)", busy, total)
+	}
+}
@@ -0,0 +1,106 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// InterfaceInfoData is the link state of one network interface and its
+// rates since the previous collection.
+type InterfaceInfoData struct {
+	Name                string  `json:"name"`
+	Up                  bool    `json:"up"`         // administratively up (IFF_UP)
+	SpeedMbps           int64   `json:"speed_mbps"` // 0 = unknown: virtual interface, no link, or not Linux (see linkSpeedMbps)
+	UploadBytesPerSec   float64 `json:"upload_bytes_per_sec"`
+	DownloadBytesPerSec float64 `json:"download_bytes_per_sec"`
+	RatesSkipped        bool    `json:"rates_skipped,omitempty"` // first collection or counter reset, rates are not meaningful
+}
+
+// InterfaceCollector keeps the per-interface counters of the previous
+// collection, for the rates. Loopback interfaces are left out.
+type InterfaceCollector struct {
+	previous map[string]net.IOCountersStat
+	at       time.Time
+}
+
+// NewInterfaceCollector returns a collector without a baseline; its first
+// Collect reports no rates.
+func NewInterfaceCollector() *InterfaceCollector {
+	return &InterfaceCollector{}
+}
+
+// Collect returns every non-loopback interface, sorted by name. Rates are
+// checked like CalculateNetworkRates does for the aggregate, and against the
+// interface's link speed when it is known (see linkRateLimit).
+func (c *InterfaceCollector) Collect(maxBytesPerSec float64) ([]InterfaceInfoData, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get per-interface I/O counters: %w", err)
+	}
+	now := time.Now()
+	current := make(map[string]net.IOCountersStat, len(counters))
+	for _, counter := range counters {
+		current[counter.Name] = counter
+	}
+
+	var data []InterfaceInfoData
+	for _, iface := range interfaces {
+		if hasFlag(iface.Flags, "loopback") {
+			continue
+		}
+		info := InterfaceInfoData{
+			Name:         iface.Name,
+			Up:           hasFlag(iface.Flags, "up"),
+			SpeedMbps:    linkSpeedMbps(iface.Name),
+			RatesSkipped: true,
+		}
+		counter, ok := current[iface.Name]
+		previous, hadBaseline := c.previous[iface.Name]
+		if ok && hadBaseline {
+			rates, err := CalculateNetworkRates(counter, previous, now.Sub(c.at), linkRateLimit(info.SpeedMbps, maxBytesPerSec))
+			if err == nil && !rates.RatesSkipped {
+				info.UploadBytesPerSec = rates.UploadBytesPerSec
+				info.DownloadBytesPerSec = rates.DownloadBytesPerSec
+				info.RatesSkipped = false
+			}
+		}
+		data = append(data, info)
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].Name < data[j].Name })
+
+	c.previous, c.at = current, now
+	return data, nil
+}
+
+// linkSpeedSlack allows for counters sampled slightly off the interval, so a
+// saturated link isn't mistaken for a bogus jump.
+const linkSpeedSlack = 1.25
+
+// linkRateLimit is the highest plausible rate of an interface, bytes/sec: its
+// link speed (with some slack) when known, capped at maxBytesPerSec.
+func linkRateLimit(speedMbps int64, maxBytesPerSec float64) float64 {
+	if speedMbps <= 0 {
+		return maxBytesPerSec
+	}
+	limit := float64(speedMbps) * 1e6 / 8 * linkSpeedSlack
+	if maxBytesPerSec > 0 && maxBytesPerSec < limit {
+		return maxBytesPerSec
+	}
+	return limit
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+func TestProcessAgeSeconds(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		createTime int64
+		want       int64
+	}{
+		{"started 90s ago", now.Add(-90 * time.Second).UnixMilli(), 90},
+		{"partial seconds round down", now.Add(-1500 * time.Millisecond).UnixMilli(), 1},
+		{"unknown create time", 0, 0},
+		{"create time in the future", now.Add(time.Minute).UnixMilli(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProcessAgeSeconds(tt.createTime, now); got != tt.want {
+				t.Errorf("ProcessAgeSeconds() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProcessAgeOfChild starts a child process and checks the age derived
+// from gopsutil's create time against the time it was started.
+func TestProcessAgeOfChild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("sleeps for two seconds")
+	}
+	started := time.Now()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperSleep$")
+	cmd.Env = append(os.Environ(), "STATS_TEST_HELPER_SLEEP=1")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start child: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	time.Sleep(2 * time.Second)
+	proc, err := process.NewProcess(int32(cmd.Process.Pid))
+	if err != nil {
+		t.Fatalf("NewProcess: %v", err)
+	}
+	createTime, err := proc.CreateTime()
+	if err != nil {
+		t.Fatalf("CreateTime: %v", err)
+	}
+
+	now := time.Now()
+	age := ProcessAgeSeconds(createTime, now)
+	if age <= 0 {
+		t.Fatalf("age = %d, want > 0", age)
+	}
+	// on Linux the create time is boot time (whole seconds) plus clock ticks
+	want := int64(now.Sub(started) / time.Second)
+	if age < want-2 || age > want+2 {
+		t.Errorf("age = %ds, want %d±2s (child started %v ago)", age, want, now.Sub(started))
+	}
+}
+
+// TestHelperSleep is the child process of TestProcessAgeOfChild.
+func TestHelperSleep(t *testing.T) {
+	if os.Getenv("STATS_TEST_HELPER_SLEEP") != "1" {
+		t.Skip("helper process")
+	}
+	time.Sleep(time.Minute)
+}
@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// firstSampleWindow is how long the first GetProcessList call of a cache
+// waits between its two CPU samples, as there is no previous cycle yet.
+const firstSampleWindow = 500 * time.Millisecond
+
+// ProcessCache keeps a gopsutil handle per running process across
+// collection cycles. A handle remembers the CPU times of its last sample, so
+// its Percent(0) is the usage since the previous cycle, like top shows it,
+// instead of CPUPercent's average since the process started. Entries are
+// keyed by PID and create time, so a reused PID gets a fresh handle, and
+// processes that exited are evicted on every call.
+type ProcessCache struct {
+	handles map[int32]*cachedProcess
+	io      ProcessIOCache // last I/O counters of the reported processes
+}
+
+type cachedProcess struct {
+	proc       *process.Process
+	createTime int64
+}
+
+// NewProcessCache returns an empty cache.
+func NewProcessCache() *ProcessCache {
+	return &ProcessCache{handles: make(map[int32]*cachedProcess), io: ProcessIOCache{}}
+}
+
+// refresh returns a handle for every pid, reusing the cached ones. New
+// handles take their first CPU sample here; their usage is known from the
+// next call on, so fresh reports which handles have no usage yet.
+func (c *ProcessCache) refresh(pids []int32) (handles []*process.Process, fresh map[int32]bool) {
+	fresh = make(map[int32]bool)
+	alive := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		proc, err := process.NewProcess(pid)
+		if err != nil {
+			continue // exited meanwhile
+		}
+		createTime, err := proc.CreateTime()
+		if err != nil {
+			createTime = 0
+		}
+		alive[pid] = true
+		if cached, ok := c.handles[pid]; ok && cached.createTime == createTime {
+			handles = append(handles, cached.proc)
+			continue
+		}
+		_, _ = proc.Percent(0) // first sample, always 0
+		c.handles[pid] = &cachedProcess{proc: proc, createTime: createTime}
+		fresh[pid] = true
+		handles = append(handles, proc)
+	}
+	for pid := range c.handles {
+		if !alive[pid] {
+			delete(c.handles, pid)
+		}
+	}
+	return handles, fresh
+}
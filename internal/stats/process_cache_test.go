@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestProcessCPUSinceThePreviousCollection starts a child spinning one core
+// and checks the CPU percent a cache reports for it: 0 while the child only
+// has its baseline sample, then close to 100 (of one core) on the next
+// collection, not an average since the child started.
+func TestProcessCPUSinceThePreviousCollection(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spins a core for about two seconds")
+	}
+	cache := NewProcessCache()
+	if _, err := GetProcessList(-1, false, cache); err != nil {
+		t.Fatalf("GetProcessList: %v", err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperBusyLoop$")
+	cmd.Env = append(os.Environ(), "STATS_TEST_HELPER_BUSY=1")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start child: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	time.Sleep(500 * time.Millisecond) // let the child spin up
+	pid := int32(cmd.Process.Pid)
+	childCPU := func() (float64, bool) {
+		t.Helper()
+		processes, err := GetProcessList(-1, false, cache) // -1: every process
+		if err != nil {
+			t.Fatalf("GetProcessList: %v", err)
+		}
+		for _, p := range processes {
+			if p.PID == pid {
+				return p.CPUPercent, true
+			}
+		}
+		return 0, false
+	}
+
+	// first seen: the baseline sample only
+	if cpu, ok := childCPU(); !ok || cpu != 0 {
+		t.Fatalf("first sample: child CPU %v (listed %t), want 0", cpu, ok)
+	}
+
+	time.Sleep(time.Second)
+	cpu, ok := childCPU()
+	if !ok {
+		t.Fatal("second sample: child not listed")
+	}
+	// busy on one core; leave room for a loaded test machine
+	if cpu < 50 || cpu > 110 {
+		t.Errorf("second sample: child CPU %.1f%%, want about 100%% of one core", cpu)
+	}
+}
+
+// TestHelperBusyLoop is the child process of
+// TestProcessCPUSinceThePreviousCollection.
+func TestHelperBusyLoop(t *testing.T) {
+	if os.Getenv("STATS_TEST_HELPER_BUSY") != "1" {
+		t.Skip("helper process")
+	}
+	for deadline := time.Now().Add(time.Minute); time.Now().Before(deadline); {
+	}
+}
@@ -0,0 +1,114 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// TestProcessIOOfAWritingProcess writes to disk from the test process and
+// checks its reported write bytes and, on the next call, its write rate.
+func TestProcessIOOfAWritingProcess(t *testing.T) {
+	self, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		t.Fatalf("NewProcess: %v", err)
+	}
+	if _, err := self.IOCounters(); err != nil {
+		t.Skipf("no process I/O counters on this platform: %v", err)
+	}
+
+	const chunk = 4 << 20
+	file, err := os.Create(filepath.Join(t.TempDir(), "io.bin"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer file.Close()
+	write := func() {
+		t.Helper()
+		if _, err := file.Write(make([]byte, chunk)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := file.Sync(); err != nil { // write_bytes counts what reaches the disk
+			t.Fatalf("sync: %v", err)
+		}
+	}
+	find := func(processes []ProcessData) ProcessData {
+		t.Helper()
+		for _, p := range processes {
+			if p.PID == self.Pid {
+				return p
+			}
+		}
+		t.Fatalf("the test process (%d) is not listed", self.Pid)
+		return ProcessData{}
+	}
+
+	cache := NewProcessCache()
+	write()
+	processes, err := GetProcessList(-1, false, cache) // every process
+	if err != nil {
+		t.Fatalf("GetProcessList: %v", err)
+	}
+	first := find(processes)
+	if first.WriteBytes < chunk {
+		t.Skipf("write_bytes = %d after a %d byte synced write, the filesystem doesn't account writes (tmpfs?)", first.WriteBytes, chunk)
+	}
+	if first.WriteBytesPerSec != 0 || first.ReadBytesPerSec != 0 {
+		t.Errorf("first call: rates %v / %v B/s, want none without a previous sample", first.ReadBytesPerSec, first.WriteBytesPerSec)
+	}
+
+	write()
+	time.Sleep(100 * time.Millisecond)
+	processes, err = GetProcessList(-1, false, cache)
+	if err != nil {
+		t.Fatalf("GetProcessList: %v", err)
+	}
+	second := find(processes)
+	if second.WriteBytes < first.WriteBytes+chunk {
+		t.Errorf("write_bytes = %d, want at least %d + %d", second.WriteBytes, first.WriteBytes, chunk)
+	}
+	if second.WriteBytesPerSec <= 0 {
+		t.Errorf("write rate = %v B/s after writing %d bytes, want > 0", second.WriteBytesPerSec, chunk)
+	}
+}
+
+func TestUpdateProcessIO(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := ProcessIOCache{}
+	updateProcessIO([]ProcessData{
+		{PID: 10, CreateTime: 1000, ReadBytes: 1000, WriteBytes: 5000},
+		{PID: 20, CreateTime: 2000, ReadBytes: 100},
+		{PID: 30, CreateTime: 3000},
+	}, cache, start)
+
+	processes := []ProcessData{
+		{PID: 10, CreateTime: 1000, ReadBytes: 11_000, WriteBytes: 55_000}, // 10 s later
+		{PID: 20, CreateTime: 2500, ReadBytes: 900},                        // a new process reusing the PID
+		{PID: 40, CreateTime: 4000, WriteBytes: 700},                       // newly reported
+	}
+	updateProcessIO(processes, cache, start.Add(10*time.Second))
+	if p := processes[0]; p.ReadBytesPerSec != 1000 || p.WriteBytesPerSec != 5000 {
+		t.Errorf("PID 10: %v / %v B/s, want 1000 / 5000", p.ReadBytesPerSec, p.WriteBytesPerSec)
+	}
+	for _, p := range processes[1:] {
+		if p.ReadBytesPerSec != 0 || p.WriteBytesPerSec != 0 {
+			t.Errorf("PID %d without a previous sample: %v / %v B/s, want none", p.PID, p.ReadBytesPerSec, p.WriteBytesPerSec)
+		}
+	}
+	if _, ok := cache[30]; ok {
+		t.Error("PID 30, no longer reported, is still cached")
+	}
+	if cache[20].createTime != 2500 {
+		t.Errorf("PID 20 cached with create time %d, want the new process's 2500", cache[20].createTime)
+	}
+
+	// counters going backwards (permission lost, left at 0) give no rate
+	processes = []ProcessData{{PID: 10, CreateTime: 1000}}
+	updateProcessIO(processes, cache, start.Add(20*time.Second))
+	if p := processes[0]; p.ReadBytesPerSec != 0 || p.WriteBytesPerSec != 0 {
+		t.Errorf("PID 10 after its counters dropped: %v / %v B/s, want none", p.ReadBytesPerSec, p.WriteBytesPerSec)
+	}
+}
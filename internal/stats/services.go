@@ -0,0 +1,54 @@
+package stats
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ServiceStatusData is the state of one systemd unit, as `systemctl show`
+// reports it.
+type ServiceStatusData struct {
+	Unit        string `json:"unit"`         // as configured, e.g. nginx or nginx.service
+	LoadState   string `json:"load_state"`   // loaded, not-found, masked, ...
+	ActiveState string `json:"active_state"` // active, inactive, failed, activating, deactivating, reloading
+	SubState    string `json:"sub_state"`    // unit type specific: running, exited, dead, ...
+}
+
+// Unit names systemd accepts: letters, digits and ":-_.\@" (escaped
+// characters and instances included). Anything else is rejected before it
+// reaches systemctl.
+var unitNamePattern = regexp.MustCompile(`^[A-Za-z0-9:_.\\@-]{1,255}$`)
+
+// ValidUnitName reports whether name can be passed to systemctl as a unit.
+func ValidUnitName(name string) bool {
+	return unitNamePattern.MatchString(name) && !strings.HasPrefix(name, "-")
+}
+
+// parseSystemctlShow parses `systemctl show --property=LoadState,ActiveState,SubState`
+// output for units, in the order they were passed: one block of key=value
+// lines per unit, separated by blank lines.
+func parseSystemctlShow(output string, units []string) []ServiceStatusData {
+	var services []ServiceStatusData
+	for i, block := range strings.Split(strings.TrimSpace(output), "\n\n") {
+		if i >= len(units) {
+			break
+		}
+		service := ServiceStatusData{Unit: units[i]}
+		for _, line := range strings.Split(block, "\n") {
+			key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "LoadState":
+				service.LoadState = value
+			case "ActiveState":
+				service.ActiveState = value
+			case "SubState":
+				service.SubState = value
+			}
+		}
+		services = append(services, service)
+	}
+	return services
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+package stats
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// systemdRuntimeDir exists only while systemd is PID 1 (sd_booted).
+const systemdRuntimeDir = "/run/systemd/system"
+
+const systemctlTimeout = 5 * time.Second
+
+// GetServiceStatus returns the state of each unit, in order, from one
+// `systemctl show` call. Hosts not booted with systemd (containers, other
+// init systems) and hosts without systemctl return nil without an error.
+// Unknown units are reported with load_state not-found.
+func GetServiceStatus(ctx context.Context, units []string) ([]ServiceStatusData, error) {
+	if len(units) == 0 {
+		return nil, nil
+	}
+	if _, err := os.Stat(systemdRuntimeDir); err != nil {
+		return nil, nil
+	}
+	systemctl, err := exec.LookPath("systemctl")
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, systemctlTimeout)
+	defer cancel()
+
+	args := append([]string{"show", "--property=LoadState,ActiveState,SubState", "--"}, units...)
+	out, err := exec.CommandContext(ctx, systemctl, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show failed: %w", err)
+	}
+	return parseSystemctlShow(string(out), units), nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package stats
+
+import "context"
+
+// GetServiceStatus only reads systemd units, on Linux; elsewhere it returns nil.
+func GetServiceStatus(ctx context.Context, units []string) ([]ServiceStatusData, error) {
+	return nil, nil
+}
@@ -0,0 +1,66 @@
+package stats
+
+import "testing"
+
+func TestParseSystemctlShow(t *testing.T) {
+	// `systemctl show --property=LoadState,ActiveState,SubState -- nginx postgresql.service backup.service nope`
+	const output = `LoadState=loaded
+ActiveState=active
+SubState=running
+
+LoadState=loaded
+ActiveState=failed
+SubState=failed
+
+LoadState=loaded
+ActiveState=inactive
+SubState=dead
+
+LoadState=not-found
+ActiveState=inactive
+SubState=dead
+`
+	units := []string{"nginx", "postgresql.service", "backup.service", "nope"}
+	want := []ServiceStatusData{
+		{Unit: "nginx", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+		{Unit: "postgresql.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+		{Unit: "backup.service", LoadState: "loaded", ActiveState: "inactive", SubState: "dead"},
+		{Unit: "nope", LoadState: "not-found", ActiveState: "inactive", SubState: "dead"},
+	}
+	got := parseSystemctlShow(output, units)
+	if len(got) != len(want) {
+		t.Fatalf("parseSystemctlShow = %+v, want %d units", got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("unit %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// properties in another order, and more blocks than units asked for
+	got = parseSystemctlShow("SubState=running\nActiveState=active\nLoadState=loaded\n\nActiveState=failed\n", []string{"nginx"})
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("reordered = %+v, want only %+v", got, want[0])
+	}
+	if got := parseSystemctlShow("", []string{"nginx"}); len(got) != 1 || got[0].ActiveState != "" {
+		t.Errorf("no output = %+v, want nginx without a state", got)
+	}
+}
+
+func TestValidUnitName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"nginx":              true,
+		"postgresql@14-main": true,
+		"systemd-fsck@dev-disk-by\\x2duuid-1234.service": true,
+		"getty@tty1.service":                             true,
+		"":                                               false,
+		"-H":                                             false, // an option, not a unit
+		"--host=evil":                                    false,
+		"nginx; rm -rf /":                                false,
+		"nginx service":                                  false,
+	} {
+		if got := ValidUnitName(name); got != valid {
+			t.Errorf("ValidUnitName(%q) = %t, want %t", name, got, valid)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// DiskHealthData is the SMART summary of one physical disk.
+type DiskHealthData struct {
+	Device             string  `json:"device"` // e.g. /dev/sda
+	Model              string  `json:"model,omitempty"`
+	Healthy            bool    `json:"healthy"` // SMART overall-health self-assessment passed
+	TemperatureCelsius float64 `json:"temperature_celsius"`
+	PowerOnHours       int64   `json:"power_on_hours"`
+}
+
+const smartctlTimeout = 10 * time.Second
+
+// smartctl exit status bits 0-2: bad command line, device open failed, SMART
+// command failed. The higher bits report disk problems and still come with a
+// usable JSON report.
+const smartctlFatalBits = 0x07
+
+// GetDiskHealth reads SMART health for every disk smartctl (smartmontools 7+)
+// can see. Reading SMART usually needs root; when smartctl is missing or no
+// disk is readable it returns nil without an error.
+func GetDiskHealth(ctx context.Context) ([]DiskHealthData, error) {
+	smartctl, err := exec.LookPath("smartctl")
+	if err != nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, smartctlTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, smartctl, "--scan", "--json").Output()
+	if err != nil {
+		return nil, nil
+	}
+	var scan struct {
+		Devices []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"devices"`
+	}
+	if err := json.Unmarshal(out, &scan); err != nil {
+		return nil, fmt.Errorf("failed to parse smartctl scan: %w", err)
+	}
+
+	var disks []DiskHealthData
+	for _, dev := range scan.Devices {
+		out, err := exec.CommandContext(ctx, smartctl, "--json", "-i", "-H", "-A", "-d", dev.Type, dev.Name).Output()
+		var exitErr *exec.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode()&smartctlFatalBits == 0) {
+			continue // no access to this device
+		}
+		health, err := parseSmartctlJSON(out)
+		if err != nil {
+			continue
+		}
+		if health.Device == "" {
+			health.Device = dev.Name
+		}
+		disks = append(disks, health)
+	}
+	return disks, nil
+}
+
+// parseSmartctlJSON extracts the health summary from `smartctl --json -i -H -A` output.
+func parseSmartctlJSON(data []byte) (DiskHealthData, error) {
+	var report struct {
+		Device struct {
+			Name string `json:"name"`
+		} `json:"device"`
+		ModelName   string `json:"model_name"`
+		SmartStatus *struct {
+			Passed bool `json:"passed"`
+		} `json:"smart_status"`
+		Temperature struct {
+			Current float64 `json:"current"`
+		} `json:"temperature"`
+		PowerOnTime struct {
+			Hours int64 `json:"hours"`
+		} `json:"power_on_time"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return DiskHealthData{}, fmt.Errorf("failed to parse smartctl output: %w", err)
+	}
+	if report.SmartStatus == nil {
+		return DiskHealthData{}, fmt.Errorf("smartctl output has no smart_status (SMART unsupported or disabled)")
+	}
+	return DiskHealthData{
+		Device:             report.Device.Name,
+		Model:              report.ModelName,
+		Healthy:            report.SmartStatus.Passed,
+		TemperatureCelsius: report.Temperature.Current,
+		PowerOnHours:       report.PowerOnTime.Hours,
+	}, nil
+}
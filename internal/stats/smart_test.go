@@ -0,0 +1,78 @@
+package stats
+
+import "testing"
+
+// Trimmed `smartctl --json -i -H -A` output of smartmontools 7.3.
+const (
+	smartctlSATA = `{
+  "json_format_version": [1, 0],
+  "smartctl": {"version": [7, 3], "exit_status": 0},
+  "device": {"name": "/dev/sda", "info_name": "/dev/sda [SAT]", "type": "sat", "protocol": "ATA"},
+  "model_family": "Samsung based SSDs",
+  "model_name": "Samsung SSD 860 EVO 500GB",
+  "serial_number": "S3Z1NB0K123456",
+  "smart_status": {"passed": true},
+  "ata_smart_attributes": {
+    "revision": 1,
+    "table": [
+      {"id": 9, "name": "Power_On_Hours", "value": 95, "raw": {"value": 21034, "string": "21034"}},
+      {"id": 190, "name": "Airflow_Temperature_Cel", "value": 66, "raw": {"value": 34, "string": "34"}}
+    ]
+  },
+  "power_on_time": {"hours": 21034},
+  "temperature": {"current": 34}
+}`
+	// exit status 8 (disk failing) still comes with a full report
+	smartctlNVMeFailing = `{
+  "smartctl": {"version": [7, 3], "exit_status": 8},
+  "device": {"name": "/dev/nvme0", "info_name": "/dev/nvme0", "type": "nvme", "protocol": "NVMe"},
+  "model_name": "WDC WDS100T2B0C-00PXH0",
+  "smart_status": {"passed": false, "nvme": {"value": 4}},
+  "nvme_smart_health_information_log": {"critical_warning": 4, "temperature": 71, "power_on_hours": 40211},
+  "temperature": {"current": 71},
+  "power_on_time": {"hours": 40211}
+}`
+	// SMART disabled or unsupported: info but no health section
+	smartctlNoSmart = `{
+  "smartctl": {"version": [7, 3], "exit_status": 4},
+  "device": {"name": "/dev/sdb", "type": "sat", "protocol": "ATA"},
+  "model_name": "VBOX HARDDISK",
+  "smart_support": {"available": false}
+}`
+)
+
+func TestParseSmartctlJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want DiskHealthData
+	}{
+		{"healthy SATA", smartctlSATA, DiskHealthData{
+			Device: "/dev/sda", Model: "Samsung SSD 860 EVO 500GB", Healthy: true, TemperatureCelsius: 34, PowerOnHours: 21034,
+		}},
+		{"failing NVMe", smartctlNVMeFailing, DiskHealthData{
+			Device: "/dev/nvme0", Model: "WDC WDS100T2B0C-00PXH0", Healthy: false, TemperatureCelsius: 71, PowerOnHours: 40211,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSmartctlJSON([]byte(tt.out))
+			if err != nil {
+				t.Fatalf("parseSmartctlJSON: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseSmartctlJSON = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for name, out := range map[string]string{
+		"no smart_status": smartctlNoSmart,
+		"not JSON":        "smartctl 6.6: unrecognized option '--json'",
+		"empty":           "",
+	} {
+		if got, err := parseSmartctlJSON([]byte(out)); err == nil {
+			t.Errorf("%s: parseSmartctlJSON = %+v, want an error", name, got)
+		}
+	}
+}
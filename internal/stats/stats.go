@@ -1,8 +1,11 @@
 package stats
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"runtime"
+	"sort"
 	"time"
 
 	"github.com/shirou/gopsutil/host"
@@ -20,7 +23,11 @@ type SystemInfoData struct {
 	OSVersion     string `json:"os_version"`
 	Kernel        string `json:"kernel"`
 	KernelVersion string `json:"kernel_version"`
-	Uptime        string `json:"uptime"`
+	Uptime        string `json:"uptime"`         // e.g. 26h3m12s
+	UptimeSeconds uint64 `json:"uptime_seconds"` // same as Uptime
+	// Login sessions (utmp), 0 where the platform doesn't expose them
+	LoggedInUsers     int      `json:"logged_in_users"`
+	LoggedInUsernames []string `json:"logged_in_usernames,omitempty"` // distinct names, sorted
 }
 
 type CPUInfoData struct {
@@ -33,6 +40,10 @@ type MemInfoData struct {
 	TotalGB      float64 `json:"total_gb"`
 	FreeGB       float64 `json:"free_gb"` // From memoryInfo.Available
 	UsagePercent float64 `json:"usage_percent"`
+	// Exact values, the GB fields above are rounded by the float conversion
+	TotalBytes uint64 `json:"total_bytes"`
+	FreeBytes  uint64 `json:"free_bytes"` // memoryInfo.Available, same as FreeGB
+	UsedBytes  uint64 `json:"used_bytes"` // TotalBytes - FreeBytes, same as the server's mem_used_gb
 }
 
 type NetworkData struct {
@@ -43,6 +54,9 @@ type NetworkData struct {
 	PacketsRecvPeriod   uint64  `json:"packets_recv_period"`
 	UploadBytesPerSec   float64 `json:"upload_bytes_per_sec"`
 	DownloadBytesPerSec float64 `json:"download_bytes_per_sec"`
+	CumulativeBytesSent uint64  `json:"cumulative_bytes_sent"` // Raw counter, total since boot
+	CumulativeBytesRecv uint64  `json:"cumulative_bytes_recv"`
+	RatesSkipped        bool    `json:"rates_skipped,omitempty"` // counter reset or implausible rate, period/rate fields are not meaningful
 }
 type ProcessData struct {
 	PID           int32   `json:"pid"`
@@ -50,17 +64,65 @@ type ProcessData struct {
 	CPUPercent    float64 `json:"cpu_percent"`
 	MemoryPercent float32 `json:"memory_percent"`
 	Username      string  `json:"username"`
+	CreateTime    int64   `json:"create_time"` // Unix epoch milliseconds, as returned by gopsutil
+	AgeSeconds    int64   `json:"age_seconds"` // Seconds since CreateTime at collection time
+	// Disk I/O since the process started, 0 when it can't be read (other
+	// users' processes without root, macOS)
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+	// Rates since the process was last reported, 0 the first time it is
+	ReadBytesPerSec  float64 `json:"read_bytes_per_sec"`
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec"`
 	// Add more fields as needed, e.g., status, command line
 }
 
+// ProcessSummaryData is sent instead of the process list in summary mode
+// (MONITOR_PROCESS_MODE=summary): no PIDs, users or start times.
+type ProcessSummaryData struct {
+	Count         int                   `json:"count"`           // all running processes
+	TopCPUPercent float64               `json:"top_cpu_percent"` // sum over Top
+	Top           []ProcessSummaryEntry `json:"top"`             // highest CPU first
+}
+
+type ProcessSummaryEntry struct {
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryPercent float32 `json:"memory_percent"`
+}
+
+// Conventions for ProcessData.CPUPercent, sent as process_cpu_convention.
+const (
+	ProcessCPUPerCore = "per_core" // percent of one core, up to 100 x cores (like top)
+	ProcessCPUTotal   = "total"    // percent of all logical cores, up to 100
+)
+
+// ProcessIOCache keeps the last I/O counters of the reported processes, so
+// GetProcessList can turn them into rates on the next call.
+type ProcessIOCache map[int32]processIOSample
+
+type processIOSample struct {
+	createTime            int64 // tells a reused PID from the same process
+	readBytes, writeBytes uint64
+	at                    time.Time
+}
+
 type DiskUsageData struct {
 	Path         string  `json:"path"`
 	TotalGB      float64 `json:"total_gb"`
 	UsedGB       float64 `json:"used_gb"`
 	FreeGB       float64 `json:"free_gb"`
 	UsagePercent float64 `json:"usage_percent"`
+	// Inode counts, 0 on filesystems without fixed inode tables (and on Windows)
+	InodesTotal        uint64  `json:"inodes_total"`
+	InodesUsed         uint64  `json:"inodes_used"`
+	InodesUsagePercent float64 `json:"inodes_usage_percent"`
 }
 
+// ErrUnsupported is returned by collectors that have no implementation on
+// the platform the agent was built for. Platform-specific collection lives
+// in stats_<goos>.go; stats_other.go covers everything else.
+var ErrUnsupported = errors.New("not supported on this platform")
+
 // Converts bytes to gigabytes
 func BytesToGB(bytes uint64) float64 {
 	return float64(bytes) / (1024 * 1024 * 1024)
@@ -71,6 +133,20 @@ func BytesToMB(bytes uint64) float64 {
 	return float64(bytes) / (1024 * 1024)
 }
 
+// ProcessAgeSeconds returns how long a process has been running at 'now'.
+// createTimeMs is in epoch milliseconds (gopsutil's CreateTime unit).
+// Returns 0 for unknown or future create times.
+func ProcessAgeSeconds(createTimeMs int64, now time.Time) int64 {
+	if createTimeMs <= 0 {
+		return 0
+	}
+	age := now.Sub(time.UnixMilli(createTimeMs))
+	if age < 0 {
+		return 0
+	}
+	return int64(age / time.Second)
+}
+
 /* <---------------- SYSTEM INFO -----------------> */
 
 func GetSystemInfo() (SystemInfoData, error) {
@@ -92,13 +168,38 @@ func GetSystemInfo() (SystemInfoData, error) {
 	uptime := time.Duration(SystemInfo.Uptime) * time.Second
 	uptime = uptime.Round(time.Second)
 	data.Uptime = uptime.String()
+	data.UptimeSeconds = SystemInfo.Uptime
+
+	// Not supported everywhere (e.g. Windows, containers without utmp), report 0 then
+	if users, err := loggedInUsers(); err == nil {
+		data.LoggedInUsers, data.LoggedInUsernames = CountLoggedInUsers(users)
+	}
 
 	return data, nil
 }
 
+// CountLoggedInUsers returns the number of login sessions and the distinct,
+// sorted user names behind them.
+func CountLoggedInUsers(users []host.UserStat) (int, []string) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, u := range users {
+		if u.User == "" || seen[u.User] {
+			continue
+		}
+		seen[u.User] = true
+		names = append(names, u.User)
+	}
+	sort.Strings(names)
+	return len(users), names
+}
+
 /* <---------------- CPU INFO -----------------> */
 
-func GetCPUInfo() (CPUInfoData, error) {
+// GetCPUInfo reports the CPU model and usage. With a sampler, usage is the
+// non-blocking average since its previous call; without one cpu.Percent
+// samples for one second.
+func GetCPUInfo(sampler *CPUSampler) (CPUInfoData, error) {
 
 	var data CPUInfoData
 
@@ -114,6 +215,14 @@ func GetCPUInfo() (CPUInfoData, error) {
 	}
 
 	// Get CPU Usage
+	if sampler != nil {
+		usage, err := sampler.Usage()
+		if err != nil {
+			return data, fmt.Errorf("error getting CPU usage %w", err)
+		}
+		data.Usage = math.Round(usage*100) / 100
+		return data, nil
+	}
 	percent, err := cpu.Percent(time.Second, false) // false -> overall percentage
 	if err != nil {
 		return data, fmt.Errorf("error getting CPU usage %w", err)
@@ -136,19 +245,28 @@ func GetMemInfo() (MemInfoData, error) {
 	if err != nil {
 		return data, fmt.Errorf("error getting Memory info: %w", err)
 	}
-	if memoryInfo != nil {
-		data.TotalGB = BytesToGB(memoryInfo.Total)
-		data.FreeGB = BytesToGB(memoryInfo.Available)
-	} else {
+	if memoryInfo == nil {
 		return data, fmt.Errorf("no Memory info found")
 	}
+	return memInfoFrom(memoryInfo), nil
+}
 
-	// Get memory usage Percent
-	memoryPercent := math.Round(memoryInfo.UsedPercent*100) / 100
-	data.UsagePercent = memoryPercent
-
-	return data, nil
+// memInfoFrom converts gopsutil's memory stats, the GB fields from the same
+// byte counts as the exact ones.
+func memInfoFrom(memoryInfo *mem.VirtualMemoryStat) MemInfoData {
+	data := MemInfoData{
+		TotalGB:    BytesToGB(memoryInfo.Total),
+		FreeGB:     BytesToGB(memoryInfo.Available),
+		TotalBytes: memoryInfo.Total,
+		FreeBytes:  memoryInfo.Available,
+	}
+	if memoryInfo.Total >= memoryInfo.Available {
+		data.UsedBytes = memoryInfo.Total - memoryInfo.Available
+	}
 
+	// Get memory usage Percent
+	data.UsagePercent = math.Round(memoryInfo.UsedPercent*100) / 100
+	return data
 }
 
 /* <---------------- NETWORK INFO -----------------> */
@@ -164,72 +282,95 @@ func GetCurrentIOCounters() (net.IOCountersStat, error) {
 	return ioCounters[0], nil // Return the first (and only) element for aggregate stats
 }
 
-func CalculateNetworkRates(current, previous net.IOCountersStat, duration time.Duration) (NetworkData, error) {
+// DefaultMaxNetworkBytesPerSec is the plausibility limit for network rates, 100 Gbit/s.
+const DefaultMaxNetworkBytesPerSec = 12_500_000_000
+
+// MinRateInterval is the shortest time between two counter samples that
+// rates are computed for.
+const MinRateInterval = time.Second
+
+// CalculateNetworkRates computes the period deltas and per-second rates between
+// two counter samples. Rates are skipped (zero, RatesSkipped set) instead of
+// reported when they can't be trusted:
+//   - a counter went backwards: it was reset, wrapped, or an interface left the
+//     aggregate. The current value is then a lifetime total of unknown age, not
+//     one interval's worth, and would show up as a huge spike.
+//   - a rate exceeds maxBytesPerSec (<= 0 disables the check), e.g. a bogus
+//     counter jump.
+//   - the samples are less than MinRateInterval apart: a few milliseconds of
+//     traffic divided by a few milliseconds isn't a rate anyone can chart.
+//   - previous is the zero value: there is no baseline yet (the agent just
+//     started), and the whole lifetime total would count as one interval.
+//
+// Lifetime totals are always reported.
+func CalculateNetworkRates(current, previous net.IOCountersStat, duration time.Duration, maxBytesPerSec float64) (NetworkData, error) {
 	var data NetworkData
 	data.InterfaceName = "all"
 
+	// Lifetime totals come straight from the counter, no delta math needed
+	data.CumulativeBytesSent = current.BytesSent
+	data.CumulativeBytesRecv = current.BytesRecv
+
 	if duration.Seconds() <= 0 {
 		return data, fmt.Errorf("duration must be positive, got %v", duration)
 	}
-
-	// Handle counter resets/overflows by checking if current < previous
-	if current.BytesSent < previous.BytesSent {
-		// Counter reset detected, use current values as the period
-		data.BytesSentPeriod = current.BytesSent
-	} else {
-		data.BytesSentPeriod = current.BytesSent - previous.BytesSent
+	if duration < MinRateInterval || previous == (net.IOCountersStat{}) {
+		data.RatesSkipped = true
+		return data, nil
 	}
 
-	if current.BytesRecv < previous.BytesRecv {
-		// Counter reset detected, use current values as the period
-		data.BytesRecvPeriod = current.BytesRecv
-	} else {
-		data.BytesRecvPeriod = current.BytesRecv - previous.BytesRecv
+	if current.BytesSent < previous.BytesSent || current.BytesRecv < previous.BytesRecv ||
+		current.PacketsSent < previous.PacketsSent || current.PacketsRecv < previous.PacketsRecv {
+		data.RatesSkipped = true
+		return data, nil
 	}
 
-	if current.PacketsSent < previous.PacketsSent {
-		data.PacketsSentPeriod = current.PacketsSent
-	} else {
-		data.PacketsSentPeriod = current.PacketsSent - previous.PacketsSent
-	}
-
-	if current.PacketsRecv < previous.PacketsRecv {
-		data.PacketsRecvPeriod = current.PacketsRecv
-	} else {
-		data.PacketsRecvPeriod = current.PacketsRecv - previous.PacketsRecv
-	}
+	data.BytesSentPeriod = current.BytesSent - previous.BytesSent
+	data.BytesRecvPeriod = current.BytesRecv - previous.BytesRecv
+	data.PacketsSentPeriod = current.PacketsSent - previous.PacketsSent
+	data.PacketsRecvPeriod = current.PacketsRecv - previous.PacketsRecv
 
 	// Calculate rates per second
 	durationSeconds := duration.Seconds()
-	data.UploadBytesPerSec = float64(data.BytesSentPeriod) / durationSeconds
-	data.DownloadBytesPerSec = float64(data.BytesRecvPeriod) / durationSeconds
+	upload := float64(data.BytesSentPeriod) / durationSeconds
+	download := float64(data.BytesRecvPeriod) / durationSeconds
+	if maxBytesPerSec > 0 && (upload > maxBytesPerSec || download > maxBytesPerSec) {
+		return NetworkData{
+			InterfaceName:       data.InterfaceName,
+			CumulativeBytesSent: data.CumulativeBytesSent,
+			CumulativeBytesRecv: data.CumulativeBytesRecv,
+			RatesSkipped:        true,
+		}, nil
+	}
+	data.UploadBytesPerSec = upload
+	data.DownloadBytesPerSec = download
 
 	return data, nil
 }
 
 /* <----------------  PROCESSES INFO -----------------> */
-func GetProcessList(count float64) ([]ProcessData, error) {
-	pids, err := process.Pids()
+// GetProcessList returns the processes above count percent CPU or memory.
+// CPU percent is the usage since the previous call with the same cache (of
+// one core, so up to 100 x cores), and I/O rates are since the process was
+// last reported. With normalizeCPU it is divided by the number of logical
+// cores (ProcessCPUTotal), and the count threshold applies to that value.
+// With a new or nil cache the call samples CPU twice,
+// firstSampleWindow apart; processes started since the previous call report
+// 0% CPU until the next one.
+func GetProcessList(count float64, normalizeCPU bool, cache *ProcessCache) ([]ProcessData, error) {
+	if cache == nil {
+		cache = NewProcessCache()
+	}
+	samples, now, err := sampleProcesses(normalizeCPU, cache)
 	if err != nil {
 		return nil, err
 	}
 
 	var processes []ProcessData
 
-	for _, pid := range pids {
-		proc, err := process.NewProcess(pid)
-		if err != nil {
-			continue
-		}
-		cpuPercent, err := proc.CPUPercent()
-		if err != nil {
-			continue // Skip process if CPU percent cannot be retrieved
-		}
-
-		memPercent, err := proc.MemoryPercent()
-		if err != nil {
-			continue // Skip process if memory percent cannot be retrieved
-		}
+	for _, sample := range samples {
+		proc := sample.proc
+		cpuPercent, memPercent := sample.cpuPercent, sample.memPercent
 
 		if cpuPercent > count || memPercent > float32(count) {
 			name, err := proc.Name()
@@ -242,21 +383,139 @@ func GetProcessList(count float64) ([]ProcessData, error) {
 				username = "unknown" // Use fallback username if retrieval fails
 			}
 
-			processes = append(processes, ProcessData{
-				PID:           pid,
+			createTime, err := proc.CreateTime() // epoch millis
+			if err != nil {
+				createTime = 0 // Age will be reported as 0 if unknown
+			}
+
+			data := ProcessData{
+				PID:           proc.Pid,
 				Name:          name,
 				CPUPercent:    cpuPercent,
 				MemoryPercent: memPercent,
 				Username:      username,
-			})
+				CreateTime:    createTime,
+				AgeSeconds:    ProcessAgeSeconds(createTime, now),
+			}
+			if io, err := proc.IOCounters(); err == nil && io != nil {
+				data.ReadBytes = io.ReadBytes
+				data.WriteBytes = io.WriteBytes
+			} // permission denied: left at 0
+			processes = append(processes, data)
 
 		}
 
 	}
+	updateProcessIO(processes, cache.io, now)
 	return processes, nil
 }
 
+// GetProcessSummary is the compact alternative to GetProcessList: the number
+// of processes and the top processes by CPU, by name only. CPU is measured
+// the same way.
+func GetProcessSummary(top int, normalizeCPU bool, cache *ProcessCache) (ProcessSummaryData, error) {
+	if cache == nil {
+		cache = NewProcessCache()
+	}
+	samples, _, err := sampleProcesses(normalizeCPU, cache)
+	if err != nil {
+		return ProcessSummaryData{}, err
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].cpuPercent != samples[j].cpuPercent {
+			return samples[i].cpuPercent > samples[j].cpuPercent
+		}
+		return samples[i].memPercent > samples[j].memPercent // idle hosts: biggest first
+	})
+	summary := ProcessSummaryData{Count: len(samples), Top: []ProcessSummaryEntry{}}
+	for _, sample := range samples {
+		if len(summary.Top) == top {
+			break
+		}
+		name, err := sample.proc.Name()
+		if err != nil {
+			name = "unknown"
+		}
+		summary.Top = append(summary.Top, ProcessSummaryEntry{
+			Name:          name,
+			CPUPercent:    sample.cpuPercent,
+			MemoryPercent: sample.memPercent,
+		})
+		summary.TopCPUPercent += sample.cpuPercent
+	}
+	return summary, nil
+}
+
+type processSample struct {
+	proc       *process.Process
+	cpuPercent float64
+	memPercent float32
+}
+
+// sampleProcesses reads CPU (see GetProcessList) and memory percent of every
+// running process. Processes that exit meanwhile are left out.
+func sampleProcesses(normalizeCPU bool, cache *ProcessCache) ([]processSample, time.Time, error) {
+	pids, err := process.Pids()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	first := len(cache.handles) == 0
+	handles, fresh := cache.refresh(pids)
+	if first {
+		time.Sleep(firstSampleWindow)
+	}
+	now := time.Now()
+
+	samples := make([]processSample, 0, len(handles))
+	for _, proc := range handles {
+		cpuPercent, err := proc.Percent(0) // since this handle's previous sample
+		if err != nil {
+			continue // Skip process if CPU percent cannot be retrieved (exited meanwhile)
+		}
+		if fresh[proc.Pid] && !first {
+			cpuPercent = 0 // only the baseline so far
+		}
+		if normalizeCPU {
+			cpuPercent /= float64(runtime.NumCPU())
+		}
+
+		memPercent, err := proc.MemoryPercent()
+		if err != nil {
+			continue // Skip process if memory percent cannot be retrieved
+		}
+		samples = append(samples, processSample{proc: proc, cpuPercent: cpuPercent, memPercent: memPercent})
+	}
+	return samples, now, nil
+}
+
+// updateProcessIO fills in the I/O rates from the previous samples in cache
+// and replaces them with this call's.
+func updateProcessIO(processes []ProcessData, cache ProcessIOCache, now time.Time) {
+	seen := make(map[int32]bool, len(processes))
+	for i := range processes {
+		p := &processes[i]
+		seen[p.PID] = true
+		prev, ok := cache[p.PID]
+		elapsed := now.Sub(prev.at).Seconds()
+		if ok && prev.createTime == p.CreateTime && elapsed > 0 &&
+			p.ReadBytes >= prev.readBytes && p.WriteBytes >= prev.writeBytes {
+			p.ReadBytesPerSec = float64(p.ReadBytes-prev.readBytes) / elapsed
+			p.WriteBytesPerSec = float64(p.WriteBytes-prev.writeBytes) / elapsed
+		}
+		cache[p.PID] = processIOSample{createTime: p.CreateTime, readBytes: p.ReadBytes, writeBytes: p.WriteBytes, at: now}
+	}
+	for pid := range cache {
+		if !seen[pid] {
+			delete(cache, pid)
+		}
+	}
+}
+
 /* <----------------  DISK INFO -----------------> */
+
+// GetDiskUsageInfo reports the primary disk: "/", or the system drive
+// (usually C:\) on Windows.
 func GetDiskUsageInfo() ([]DiskUsageData, error) {
 	// partitions, err := disk.Partitions(false) // false for physical devices only
 	// if err != nil {
@@ -265,19 +524,29 @@ func GetDiskUsageInfo() ([]DiskUsageData, error) {
 
 	var usages []DiskUsageData
 
-	usage, err := disk.Usage("/")
+	path := primaryDiskPath()
+	usage, err := disk.Usage(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get disk usage for '/': %w", err)
+		return nil, fmt.Errorf("failed to get disk usage for '%s': %w", path, err)
 	}
 
-	usages = append(usages, DiskUsageData{
+	usages = append(usages, diskUsageFrom(usage))
+
+	return usages, nil
+
+}
+
+// diskUsageFrom converts gopsutil's usage of one filesystem.
+func diskUsageFrom(usage *disk.UsageStat) DiskUsageData {
+	return DiskUsageData{
 		Path:         usage.Path,
 		TotalGB:      BytesToGB(usage.Total),
 		UsedGB:       BytesToGB(usage.Used),
 		FreeGB:       BytesToGB(usage.Free),
 		UsagePercent: usage.UsedPercent,
-	})
-
-	return usages, nil
 
+		InodesTotal:        usage.InodesTotal,
+		InodesUsed:         usage.InodesUsed,
+		InodesUsagePercent: usage.InodesUsedPercent,
+	}
 }
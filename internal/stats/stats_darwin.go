@@ -0,0 +1,20 @@
+//go:build darwin
+
+package stats
+
+import "github.com/shirou/gopsutil/host"
+
+func primaryDiskPath() string {
+	return "/"
+}
+
+// loggedInUsers reads utmpx.
+func loggedInUsers() ([]host.UserStat, error) {
+	return host.Users()
+}
+
+// linkSpeedMbps is unknown (0): macOS only exposes it through ioctls
+// gopsutil doesn't wrap.
+func linkSpeedMbps(name string) int64 {
+	return 0
+}
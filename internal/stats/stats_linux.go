@@ -0,0 +1,45 @@
+//go:build linux
+
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/host"
+)
+
+// sysClassNet is where Linux exposes per-interface attributes.
+const sysClassNet = "/sys/class/net"
+
+func primaryDiskPath() string {
+	return "/"
+}
+
+// loggedInUsers reads utmp; containers often have none and report 0 users.
+func loggedInUsers() ([]host.UserStat, error) {
+	return host.Users()
+}
+
+// linkSpeedMbps reads /sys/class/net/<name>/speed. The file is missing for
+// some virtual interfaces and can't be read while the link is down; both
+// give 0.
+func linkSpeedMbps(name string) int64 {
+	data, err := os.ReadFile(filepath.Join(sysClassNet, name, "speed"))
+	if err != nil {
+		return 0
+	}
+	return parseLinkSpeed(string(data))
+}
+
+// parseLinkSpeed parses the speed file's contents, in Mbit/s. The kernel
+// reports -1 (or 4294967295 on older kernels) when the speed is unknown.
+func parseLinkSpeed(value string) int64 {
+	speed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || speed <= 0 || speed == 4294967295 {
+		return 0
+	}
+	return speed
+}
@@ -0,0 +1,39 @@
+//go:build linux
+
+package stats
+
+import "testing"
+
+func TestParseLinkSpeed(t *testing.T) {
+	tests := []struct {
+		name, value string
+		want        int64
+	}{
+		{"gigabit", "1000\n", 1000},
+		{"10 gigabit", "10000\n", 10000},
+		{"no trailing newline", "100", 100},
+		{"unknown", "-1\n", 0},
+		{"unknown, older kernels", "4294967295\n", 0},
+		{"zero", "0\n", 0},
+		{"empty", "", 0},
+		{"garbage", "fast\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLinkSpeed(tt.value); got != tt.want {
+				t.Errorf("parseLinkSpeed(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkSpeedOfMissingInterface(t *testing.T) {
+	// no speed file at all
+	if got := linkSpeedMbps("no-such-interface0"); got != 0 {
+		t.Errorf("linkSpeedMbps(no-such-interface0) = %d, want 0", got)
+	}
+	// the loopback's speed file can't be read (EINVAL)
+	if got := linkSpeedMbps("lo"); got != 0 {
+		t.Errorf("linkSpeedMbps(lo) = %d, want 0", got)
+	}
+}
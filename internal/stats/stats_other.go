@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !windows
+
+package stats
+
+import "github.com/shirou/gopsutil/host"
+
+// Other Unix-like platforms (the BSDs, ...): the Unix root, and whatever
+// gopsutil supports for users.
+
+func primaryDiskPath() string {
+	return "/"
+}
+
+func loggedInUsers() ([]host.UserStat, error) {
+	return host.Users()
+}
+
+func linkSpeedMbps(name string) int64 {
+	return 0
+}
@@ -0,0 +1,331 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/host"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+func TestCalculateNetworkRatesReportsLifetimeTotals(t *testing.T) {
+	previous := net.IOCountersStat{BytesSent: 1_000_000, BytesRecv: 5_000_000, PacketsSent: 100, PacketsRecv: 400}
+	current := net.IOCountersStat{BytesSent: 1_500_000, BytesRecv: 6_000_000, PacketsSent: 150, PacketsRecv: 500}
+
+	data, err := CalculateNetworkRates(current, previous, 5*time.Second, DefaultMaxNetworkBytesPerSec)
+	if err != nil {
+		t.Fatalf("CalculateNetworkRates: %v", err)
+	}
+	if data.CumulativeBytesSent != current.BytesSent || data.CumulativeBytesRecv != current.BytesRecv {
+		t.Errorf("totals = %d sent / %d recv, want the counters %d / %d",
+			data.CumulativeBytesSent, data.CumulativeBytesRecv, current.BytesSent, current.BytesRecv)
+	}
+	if data.BytesSentPeriod != 500_000 || data.BytesRecvPeriod != 1_000_000 {
+		t.Errorf("periods = %d sent / %d recv, want 500000 / 1000000", data.BytesSentPeriod, data.BytesRecvPeriod)
+	}
+
+	// the totals are reported even when the rates can't be
+	data, err = CalculateNetworkRates(current, previous, 0, DefaultMaxNetworkBytesPerSec)
+	if err == nil {
+		t.Fatal("zero duration: want an error")
+	}
+	if data.CumulativeBytesSent != current.BytesSent || data.CumulativeBytesRecv != current.BytesRecv {
+		t.Errorf("zero duration: totals = %d / %d, want %d / %d",
+			data.CumulativeBytesSent, data.CumulativeBytesRecv, current.BytesSent, current.BytesRecv)
+	}
+}
+
+func TestCalculateNetworkRatesSkipsResetSpikes(t *testing.T) {
+	const interval = 5 * time.Second
+	baseline := net.IOCountersStat{BytesSent: 80_000_000_000, BytesRecv: 120_000_000_000, PacketsSent: 9_000_000, PacketsRecv: 11_000_000}
+	// the interface was reset: counters restart near zero
+	reset := net.IOCountersStat{BytesSent: 4_000_000, BytesRecv: 9_000_000, PacketsSent: 3_000, PacketsRecv: 7_000}
+
+	data, err := CalculateNetworkRates(reset, baseline, interval, DefaultMaxNetworkBytesPerSec)
+	if err != nil {
+		t.Fatalf("CalculateNetworkRates: %v", err)
+	}
+	if !data.RatesSkipped || data.UploadBytesPerSec != 0 || data.DownloadBytesPerSec != 0 {
+		t.Errorf("after a reset: skipped %t, %v B/s up / %v B/s down, want the rates skipped",
+			data.RatesSkipped, data.UploadBytesPerSec, data.DownloadBytesPerSec)
+	}
+	if data.BytesSentPeriod != 0 || data.BytesRecvPeriod != 0 {
+		t.Errorf("after a reset: periods %d / %d, want none rather than the whole counter", data.BytesSentPeriod, data.BytesRecvPeriod)
+	}
+	if data.CumulativeBytesSent != reset.BytesSent {
+		t.Errorf("after a reset: total sent %d, want the counter %d", data.CumulativeBytesSent, reset.BytesSent)
+	}
+
+	// the sample after the reset is a normal one again
+	next := net.IOCountersStat{BytesSent: 4_500_000, BytesRecv: 10_000_000, PacketsSent: 3_500, PacketsRecv: 8_000}
+	data, err = CalculateNetworkRates(next, reset, interval, DefaultMaxNetworkBytesPerSec)
+	if err != nil {
+		t.Fatalf("CalculateNetworkRates: %v", err)
+	}
+	if data.RatesSkipped || data.UploadBytesPerSec != 100_000 || data.DownloadBytesPerSec != 200_000 {
+		t.Errorf("after the reset: skipped %t, %v / %v B/s, want 100000 / 200000", data.RatesSkipped, data.UploadBytesPerSec, data.DownloadBytesPerSec)
+	}
+
+	// a counter jump past the maximum plausible rate is no rate either
+	jump := next
+	jump.BytesRecv += uint64(DefaultMaxNetworkBytesPerSec*interval.Seconds()) + 1
+	data, err = CalculateNetworkRates(jump, next, interval, DefaultMaxNetworkBytesPerSec)
+	if err != nil {
+		t.Fatalf("CalculateNetworkRates: %v", err)
+	}
+	if !data.RatesSkipped || data.DownloadBytesPerSec != 0 || data.UploadBytesPerSec != 0 {
+		t.Errorf("over the maximum: skipped %t, %v / %v B/s, want the rates skipped", data.RatesSkipped, data.UploadBytesPerSec, data.DownloadBytesPerSec)
+	}
+	if data, _ := CalculateNetworkRates(jump, next, interval, 0); data.RatesSkipped {
+		t.Error("maximum 0 (disabled): rates skipped")
+	}
+}
+
+func TestMemInfoBytesAgreeWithGB(t *testing.T) {
+	check := func(t *testing.T, data MemInfoData) {
+		t.Helper()
+		const gb = 1 << 30
+		// the GB values are within float rounding of the exact bytes
+		if diff := math.Abs(data.TotalGB*gb - float64(data.TotalBytes)); diff > 1 {
+			t.Errorf("total_gb %v is %v bytes off total_bytes %d", data.TotalGB, diff, data.TotalBytes)
+		}
+		if diff := math.Abs(data.FreeGB*gb - float64(data.FreeBytes)); diff > 1 {
+			t.Errorf("free_gb %v is %v bytes off free_bytes %d", data.FreeGB, diff, data.FreeBytes)
+		}
+		// the server derives mem_used_gb as total_gb - free_gb
+		if diff := math.Abs((data.TotalGB-data.FreeGB)*gb - float64(data.UsedBytes)); diff > 1 {
+			t.Errorf("total_gb - free_gb is %v bytes off used_bytes %d", diff, data.UsedBytes)
+		}
+		if data.UsedBytes != data.TotalBytes-data.FreeBytes {
+			t.Errorf("used_bytes %d, want total_bytes - free_bytes = %d", data.UsedBytes, data.TotalBytes-data.FreeBytes)
+		}
+	}
+
+	t.Run("fixture", func(t *testing.T) {
+		// odd byte counts that no GB value represents exactly
+		data := memInfoFrom(&mem.VirtualMemoryStat{Total: 17_179_869_183, Available: 5_368_709_121, UsedPercent: 68.751})
+		if data.TotalBytes != 17_179_869_183 || data.FreeBytes != 5_368_709_121 || data.UsedBytes != 11_811_160_062 {
+			t.Errorf("bytes = %d total / %d free / %d used, want the counts unchanged", data.TotalBytes, data.FreeBytes, data.UsedBytes)
+		}
+		if data.UsagePercent != 68.75 {
+			t.Errorf("usage_percent = %v, want 68.75", data.UsagePercent)
+		}
+		check(t, data)
+	})
+	t.Run("this host", func(t *testing.T) {
+		data, err := GetMemInfo()
+		if err != nil {
+			t.Skipf("GetMemInfo: %v", err)
+		}
+		if data.TotalBytes == 0 {
+			t.Fatal("total_bytes = 0")
+		}
+		check(t, data)
+	})
+}
+
+func TestCountLoggedInUsers(t *testing.T) {
+	tests := []struct {
+		name      string
+		users     []host.UserStat
+		count     int
+		usernames []string
+	}{
+		{"none", nil, 0, nil},
+		{"one session", []host.UserStat{{User: "alice", Terminal: "pts/0"}}, 1, []string{"alice"}},
+		{
+			"several sessions of one user count each",
+			[]host.UserStat{
+				{User: "bob", Terminal: "pts/1"},
+				{User: "alice", Terminal: "tty1"},
+				{User: "bob", Terminal: "pts/2"},
+			},
+			3, []string{"alice", "bob"},
+		},
+		{"session without a name", []host.UserStat{{Terminal: "pts/3"}, {User: "carol"}}, 2, []string{"carol"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, names := CountLoggedInUsers(tt.users)
+			if count != tt.count || fmt.Sprint(names) != fmt.Sprint(tt.usernames) {
+				t.Errorf("CountLoggedInUsers = %d %v, want %d %v", count, names, tt.count, tt.usernames)
+			}
+		})
+	}
+
+	// whatever the platform reports, never a negative count
+	data, err := GetSystemInfo()
+	if err != nil {
+		t.Skipf("GetSystemInfo: %v", err)
+	}
+	if data.LoggedInUsers < 0 || len(data.LoggedInUsernames) > data.LoggedInUsers {
+		t.Errorf("this host: %d users named %v", data.LoggedInUsers, data.LoggedInUsernames)
+	}
+}
+
+func TestDiskUsageReportsInodes(t *testing.T) {
+	data := diskUsageFrom(&disk.UsageStat{
+		Path: "/", Total: 100 << 30, Used: 40 << 30, Free: 60 << 30, UsedPercent: 40,
+		InodesTotal: 6_553_600, InodesUsed: 6_225_920, InodesFree: 327_680, InodesUsedPercent: 95,
+	})
+	if data.InodesTotal != 6_553_600 || data.InodesUsed != 6_225_920 || data.InodesUsagePercent != 95 {
+		t.Errorf("inodes = %d total / %d used / %v%%, want 6553600 / 6225920 / 95%%", data.InodesTotal, data.InodesUsed, data.InodesUsagePercent)
+	}
+	if data.TotalGB != 100 || data.UsedGB != 40 || data.UsagePercent != 40 {
+		t.Errorf("space = %v GB total / %v GB used / %v%%, want 100 / 40 / 40%%", data.TotalGB, data.UsedGB, data.UsagePercent)
+	}
+
+	disks, err := GetDiskUsageInfo()
+	if err != nil {
+		t.Skipf("GetDiskUsageInfo: %v", err)
+	}
+	// some filesystems (btrfs) report no inodes at all, none report more used than total
+	if disks[0].InodesUsed > disks[0].InodesTotal {
+		t.Errorf("this host's %s: %d of %d inodes used", disks[0].Path, disks[0].InodesUsed, disks[0].InodesTotal)
+	}
+}
+
+func TestLinkRateLimit(t *testing.T) {
+	if got := linkRateLimit(0, DefaultMaxNetworkBytesPerSec); got != DefaultMaxNetworkBytesPerSec {
+		t.Errorf("unknown speed: limit %v, want the maximum %v", got, DefaultMaxNetworkBytesPerSec)
+	}
+	// 1000 Mbit/s is 125,000,000 B/s, with 25% slack
+	if got := linkRateLimit(1000, 0); got != 156_250_000 {
+		t.Errorf("gigabit: limit %v, want 156250000", got)
+	}
+	if got := linkRateLimit(100_000, 1e9); got != 1e9 {
+		t.Errorf("100 Gbit/s under a 1 GB/s maximum: limit %v, want 1e9", got)
+	}
+}
+
+func TestByteConversions(t *testing.T) {
+	tests := []struct {
+		bytes  uint64
+		gb, mb float64
+	}{
+		{0, 0, 0},
+		{1 << 20, 1.0 / 1024, 1},
+		{1 << 30, 1, 1024},
+		{16 << 30, 16, 16384},
+		{1_500_000_000, 1_500_000_000.0 / (1 << 30), 1_500_000_000.0 / (1 << 20)},
+	}
+	for _, tt := range tests {
+		if got := BytesToGB(tt.bytes); got != tt.gb {
+			t.Errorf("BytesToGB(%d) = %v, want %v", tt.bytes, got, tt.gb)
+		}
+		if got := BytesToMB(tt.bytes); got != tt.mb {
+			t.Errorf("BytesToMB(%d) = %v, want %v", tt.bytes, got, tt.mb)
+		}
+	}
+}
+
+func TestCalculateNetworkRates(t *testing.T) {
+	previous := net.IOCountersStat{BytesSent: 10_000, BytesRecv: 50_000, PacketsSent: 10, PacketsRecv: 40}
+	tests := []struct {
+		name             string
+		current          net.IOCountersStat
+		duration         time.Duration
+		upload, download float64
+		packetsSent      uint64
+	}{
+		{"idle", previous, 5 * time.Second, 0, 0, 0},
+		{"one second", net.IOCountersStat{BytesSent: 11_000, BytesRecv: 52_000, PacketsSent: 12, PacketsRecv: 45}, time.Second, 1000, 2000, 2},
+		{"per second over the interval", net.IOCountersStat{BytesSent: 20_000, BytesRecv: 100_000, PacketsSent: 30, PacketsRecv: 90}, 10 * time.Second, 1000, 5000, 20},
+		{"partial seconds", net.IOCountersStat{BytesSent: 13_000, BytesRecv: 50_000, PacketsSent: 11, PacketsRecv: 40}, 1500 * time.Millisecond, 2000, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := CalculateNetworkRates(tt.current, previous, tt.duration, DefaultMaxNetworkBytesPerSec)
+			if err != nil {
+				t.Fatalf("CalculateNetworkRates: %v", err)
+			}
+			if data.RatesSkipped || data.UploadBytesPerSec != tt.upload || data.DownloadBytesPerSec != tt.download {
+				t.Errorf("rates = %v up / %v down B/s (skipped %t), want %v / %v", data.UploadBytesPerSec, data.DownloadBytesPerSec, data.RatesSkipped, tt.upload, tt.download)
+			}
+			if data.PacketsSentPeriod != tt.packetsSent || data.BytesSentPeriod != tt.current.BytesSent-previous.BytesSent {
+				t.Errorf("periods = %d packets / %d bytes sent, want %d / %d", data.PacketsSentPeriod, data.BytesSentPeriod, tt.packetsSent, tt.current.BytesSent-previous.BytesSent)
+			}
+			if data.InterfaceName != "all" {
+				t.Errorf("interface = %q, want all", data.InterfaceName)
+			}
+		})
+	}
+
+	if _, err := CalculateNetworkRates(previous, previous, -time.Second, DefaultMaxNetworkBytesPerSec); err == nil {
+		t.Error("negative duration: want an error")
+	}
+}
+
+func TestCalculateNetworkRatesEdgeCases(t *testing.T) {
+	const interval = 5 * time.Second
+	// a host up for weeks: lifetime totals far larger than one interval's traffic
+	running := net.IOCountersStat{Name: "all", BytesSent: 2_000_000_000, BytesRecv: 9_000_000_000, PacketsSent: 4_000_000, PacketsRecv: 7_000_000}
+	check := func(t *testing.T, data NetworkData, err error, skipped bool) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("CalculateNetworkRates: %v", err)
+		}
+		if data.RatesSkipped != skipped {
+			t.Errorf("rates skipped = %t, want %t (%v up / %v down B/s)", data.RatesSkipped, skipped, data.UploadBytesPerSec, data.DownloadBytesPerSec)
+		}
+		if skipped && (data.UploadBytesPerSec != 0 || data.DownloadBytesPerSec != 0 || data.BytesSentPeriod != 0 || data.BytesRecvPeriod != 0) {
+			t.Errorf("skipped rates are %v / %v B/s, periods %d / %d, want all 0",
+				data.UploadBytesPerSec, data.DownloadBytesPerSec, data.BytesSentPeriod, data.BytesRecvPeriod)
+		}
+	}
+
+	t.Run("agent restart", func(t *testing.T) {
+		// no previous sample: the lifetime totals are no interval's traffic
+		data, err := CalculateNetworkRates(running, net.IOCountersStat{}, interval, DefaultMaxNetworkBytesPerSec)
+		check(t, data, err, true)
+		if data.CumulativeBytesSent != running.BytesSent || data.CumulativeBytesRecv != running.BytesRecv {
+			t.Errorf("totals = %d / %d, want the counters", data.CumulativeBytesSent, data.CumulativeBytesRecv)
+		}
+		// the next sample has a baseline
+		next := running
+		next.BytesSent += 5_000
+		next.PacketsSent += 5
+		data, err = CalculateNetworkRates(next, running, interval, DefaultMaxNetworkBytesPerSec)
+		check(t, data, err, false)
+		if data.UploadBytesPerSec != 1000 {
+			t.Errorf("second sample: upload %v B/s, want 1000", data.UploadBytesPerSec)
+		}
+	})
+
+	t.Run("counter reset", func(t *testing.T) {
+		for name, current := range map[string]net.IOCountersStat{
+			"host reboot": {Name: "all", BytesSent: 10_000, BytesRecv: 20_000, PacketsSent: 10, PacketsRecv: 20},
+			// an interface left the aggregate: only some counters drop
+			"received bytes only": {Name: "all", BytesSent: running.BytesSent + 1000, BytesRecv: 1_000_000, PacketsSent: running.PacketsSent + 1, PacketsRecv: running.PacketsRecv + 1},
+			"packets only":        {Name: "all", BytesSent: running.BytesSent, BytesRecv: running.BytesRecv, PacketsSent: 3, PacketsRecv: running.PacketsRecv},
+		} {
+			t.Run(name, func(t *testing.T) {
+				data, err := CalculateNetworkRates(current, running, interval, DefaultMaxNetworkBytesPerSec)
+				check(t, data, err, true)
+				if data.CumulativeBytesRecv != current.BytesRecv {
+					t.Errorf("total received %d, want the counter %d", data.CumulativeBytesRecv, current.BytesRecv)
+				}
+			})
+		}
+	})
+
+	t.Run("tiny interval", func(t *testing.T) {
+		// 1 MB in 2ms would chart as 500 MB/s
+		current := running
+		current.BytesRecv += 1_000_000
+		current.PacketsRecv += 700
+		for _, duration := range []time.Duration{time.Nanosecond, 2 * time.Millisecond, MinRateInterval - time.Nanosecond} {
+			data, err := CalculateNetworkRates(current, running, duration, DefaultMaxNetworkBytesPerSec)
+			check(t, data, err, true)
+		}
+		data, err := CalculateNetworkRates(current, running, MinRateInterval, DefaultMaxNetworkBytesPerSec)
+		check(t, data, err, false)
+		if data.DownloadBytesPerSec != 1_000_000 {
+			t.Errorf("at MinRateInterval: download %v B/s, want 1000000", data.DownloadBytesPerSec)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package stats
+
+import (
+	"os"
+
+	"github.com/shirou/gopsutil/host"
+)
+
+// primaryDiskPath is the system drive, e.g. C:\.
+func primaryDiskPath() string {
+	if drive := os.Getenv("SystemDrive"); drive != "" {
+		return drive + `\`
+	}
+	return `C:\`
+}
+
+// loggedInUsers is unsupported, Windows has no utmp.
+func loggedInUsers() ([]host.UserStat, error) {
+	return nil, ErrUnsupported
+}
+
+// linkSpeedMbps is unknown (0), there is no /sys/class/net.
+func linkSpeedMbps(name string) int64 {
+	return 0
+}
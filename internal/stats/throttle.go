@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ThrottleData tells whether the CPU is throttled for temperature (or power),
+// from the Raspberry Pi firmware's get_throttled flags where available, and
+// the kernel's thermal cooling statistics otherwise.
+type ThrottleData struct {
+	Throttled     bool   `json:"throttled"`       // frequency capped, throttled or at the soft temperature limit right now
+	ThrottleCount int64  `json:"throttle_count"`  // cooling state changes since boot, 0 if the kernel doesn't count them
+	Flags         uint32 `json:"flags,omitempty"` // raw get_throttled bits, 0 without the Pi firmware
+}
+
+// Raspberry Pi get_throttled bits of the current state. The same bits
+// shifted by 16 record whether it happened since boot.
+const (
+	throttleUnderVoltage  = 1 << 0
+	throttleFreqCapped    = 1 << 1
+	throttleThrottled     = 1 << 2
+	throttleSoftTempLimit = 1 << 3
+)
+
+// throttledNow is the current-state bits that count as throttled. Under-voltage
+// alone doesn't slow the CPU down; the firmware caps the frequency for it.
+const throttledNow = throttleFreqCapped | throttleThrottled | throttleSoftTempLimit
+
+// parseThrottledFlags parses `vcgencmd get_throttled` output ("throttled=0x50005")
+// or the firmware's sysfs get_throttled file (hex, with or without 0x).
+func parseThrottledFlags(value string) (uint32, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "throttled=")
+	value = strings.TrimPrefix(value, "0x")
+	flags, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid get_throttled value %q", value)
+	}
+	return uint32(flags), nil
+}
+
+// throttleFromFlags builds the throttle state from get_throttled flags.
+func throttleFromFlags(flags uint32) ThrottleData {
+	return ThrottleData{Throttled: flags&throttledNow != 0, Flags: flags}
+}
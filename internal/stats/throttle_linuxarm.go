@@ -0,0 +1,92 @@
+//go:build linux && (arm || arm64)
+
+package stats
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Where the Raspberry Pi firmware driver exposes get_throttled (hex flags)
+// on recent kernels; older ones only have vcgencmd.
+const firmwareThrottledPath = "/sys/devices/platform/soc/soc:firmware/get_throttled"
+
+// sysClassThermal holds the kernel's cooling devices, with per-device
+// statistics when the kernel has CONFIG_THERMAL_STATISTICS.
+const sysClassThermal = "/sys/class/thermal"
+
+const vcgencmdTimeout = 2 * time.Second
+
+// GetThrottleInfo reads the Pi firmware's throttling flags, falling back to
+// the CPU frequency cooling devices on other ARM boards. It returns nil
+// without an error when neither is available.
+func GetThrottleInfo(ctx context.Context) (*ThrottleData, error) {
+	count, cooling, coolingFound := coolingStats()
+
+	if flags, ok := firmwareThrottled(ctx); ok {
+		data := throttleFromFlags(flags)
+		data.ThrottleCount = count
+		return &data, nil
+	}
+	if !coolingFound {
+		return nil, nil
+	}
+	return &ThrottleData{Throttled: cooling, ThrottleCount: count}, nil
+}
+
+// firmwareThrottled reads the get_throttled flags from sysfs, or from
+// vcgencmd when the file isn't there.
+func firmwareThrottled(ctx context.Context) (uint32, bool) {
+	if data, err := os.ReadFile(firmwareThrottledPath); err == nil {
+		if flags, err := parseThrottledFlags(string(data)); err == nil {
+			return flags, true
+		}
+	}
+	vcgencmd, err := exec.LookPath("vcgencmd")
+	if err != nil {
+		return 0, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, vcgencmdTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, vcgencmd, "get_throttled").Output()
+	if err != nil {
+		return 0, false
+	}
+	flags, err := parseThrottledFlags(string(out))
+	return flags, err == nil
+}
+
+// coolingStats sums the state changes of the CPU frequency cooling devices
+// (cpufreq-cpu0, ...) and reports whether any of them is cooling right now.
+// found is false when the board has none.
+func coolingStats() (transitions int64, cooling, found bool) {
+	devices, _ := filepath.Glob(filepath.Join(sysClassThermal, "cooling_device*"))
+	for _, dir := range devices {
+		kind, err := os.ReadFile(filepath.Join(dir, "type"))
+		if err != nil || !strings.HasPrefix(strings.TrimSpace(string(kind)), "cpufreq") {
+			continue
+		}
+		found = true
+		if state, ok := readSysInt(filepath.Join(dir, "cur_state")); ok && state > 0 {
+			cooling = true
+		}
+		if n, ok := readSysInt(filepath.Join(dir, "stats", "total_trans")); ok {
+			transitions += n
+		}
+	}
+	return transitions, cooling, found
+}
+
+func readSysInt(path string) (int64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux || !(arm || arm64)
+
+package stats
+
+import "context"
+
+// GetThrottleInfo only reads throttling on ARM Linux (Raspberry Pi and other
+// single-board computers); elsewhere it returns nil.
+func GetThrottleInfo(ctx context.Context) (*ThrottleData, error) {
+	return nil, nil
+}
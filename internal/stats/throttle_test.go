@@ -0,0 +1,41 @@
+package stats
+
+import "testing"
+
+func TestParseThrottledFlags(t *testing.T) {
+	tests := []struct {
+		name, value string
+		flags       uint32
+		throttled   bool
+	}{
+		{"vcgencmd, never throttled", "throttled=0x0\n", 0, false},
+		// under-voltage and throttled now, both also since boot
+		{"vcgencmd, throttled now", "throttled=0x50005\n", 0x50005, true},
+		{"vcgencmd, only since boot", "throttled=0x50000\n", 0x50000, false},
+		{"under-voltage alone", "throttled=0x1", 0x1, false},
+		{"frequency capped", "0x2", 0x2, true},
+		{"soft temperature limit", "0x8", 0x8, true},
+		{"sysfs file without 0x", "50005\n", 0x50005, true},
+		{"upper case", "THROTTLED=0X4", 0x4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, err := parseThrottledFlags(tt.value)
+			if err != nil {
+				t.Fatalf("parseThrottledFlags(%q): %v", tt.value, err)
+			}
+			if flags != tt.flags {
+				t.Errorf("parseThrottledFlags(%q) = %#x, want %#x", tt.value, flags, tt.flags)
+			}
+			if data := throttleFromFlags(flags); data.Throttled != tt.throttled || data.Flags != flags {
+				t.Errorf("throttleFromFlags(%#x) = %+v, want throttled %t", flags, data, tt.throttled)
+			}
+		})
+	}
+
+	for _, value := range []string{"", "throttled=", "throttled=0xzz", "0x100000000", "error=1 error_msg=\"Command not registered\""} {
+		if flags, err := parseThrottledFlags(value); err == nil {
+			t.Errorf("parseThrottledFlags(%q) = %#x, want an error", value, flags)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// SendStatsJSON's failures, to be told apart with errors.As. Timeouts,
+// connection errors and some rejections (see Retryable) may succeed when the
+// stats are sent again; a PayloadError or any other rejection won't.
+
+// TimeoutError means the server didn't answer within the send timeout.
+type TimeoutError struct {
+	URL string
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("http request to %s timed out: %v", e.URL, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// ConnectionError means the request didn't reach the server or got no
+// response: refused connection, DNS failure, reset, ...
+type ConnectionError struct {
+	URL string
+	Err error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("error sending stats to server %s: %v", e.URL, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error { return e.Err }
+
+// RejectedError means the server answered with a non-2xx status. Body is the
+// start of the response (at most maxErrorBodyBytes).
+type RejectedError struct {
+	URL        string
+	StatusCode int
+	Status     string // e.g. "400 Bad Request"
+	Body       string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("server at %s responded with %s: %s", e.URL, e.Status, e.Body)
+}
+
+// Retryable reports whether the server may accept the same payload later:
+// 5xx (including 503 not ready), 429 and 408. Other 4xx are about the
+// payload or the request itself.
+func (e *RejectedError) Retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout
+}
+
+// PayloadError means the stats couldn't be turned into a request (marshaling
+// or an invalid server URL); sending them again fails the same way.
+type PayloadError struct {
+	Err error
+}
+
+func (e *PayloadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PayloadError) Unwrap() error { return e.Err }
+
+// Retryable reports whether sending the same stats again may succeed.
+// Cancellation by the caller's context is not retryable.
+func Retryable(err error) bool {
+	var timeout *TimeoutError
+	var conn *ConnectionError
+	var rejected *RejectedError
+	switch {
+	case errors.As(err, &timeout), errors.As(err, &conn):
+		return true
+	case errors.As(err, &rejected):
+		return rejected.Retryable()
+	}
+	return false
+}
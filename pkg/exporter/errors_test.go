@@ -0,0 +1,132 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendStatsJSONErrorTypes(t *testing.T) {
+	stats := map[string]int{"cpu": 1}
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := SendStatsJSON(ctx, server.URL, stats)
+		var timeout *TimeoutError
+		if !errors.As(err, &timeout) || timeout.URL != server.URL {
+			t.Fatalf("SendStatsJSON = %v, want a *TimeoutError for %s", err, server.URL)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%v does not wrap context.DeadlineExceeded", err)
+		}
+		if !Retryable(err) {
+			t.Error("timeout not retryable")
+		}
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL
+		server.Close() // nothing listens there any more
+
+		err := SendStatsJSON(context.Background(), url, stats)
+		var conn *ConnectionError
+		if !errors.As(err, &conn) || conn.URL != url || conn.Err == nil {
+			t.Fatalf("SendStatsJSON = %v, want a *ConnectionError for %s", err, url)
+		}
+		if !Retryable(err) {
+			t.Error("connection error not retryable")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		err := SendStatsJSON(ctx, server.URL, stats)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("SendStatsJSON = %v, want context.Canceled", err)
+		}
+		var timeout *TimeoutError
+		var conn *ConnectionError
+		if errors.As(err, &timeout) || errors.As(err, &conn) || Retryable(err) {
+			t.Errorf("cancellation %T %v reported as a retryable send failure", err, err)
+		}
+	})
+
+	t.Run("payload", func(t *testing.T) {
+		for name, send := range map[string]func() error{
+			"unmarshalable stats": func() error {
+				return SendStatsJSON(context.Background(), "http://127.0.0.1:1", map[string]any{"ch": make(chan int)})
+			},
+			"invalid URL": func() error {
+				return SendStatsJSON(context.Background(), "http://bad host:80/api", stats)
+			},
+		} {
+			err := send()
+			var payload *PayloadError
+			if !errors.As(err, &payload) || payload.Err == nil {
+				t.Errorf("%s: SendStatsJSON = %v, want a *PayloadError", name, err)
+			}
+			if Retryable(err) {
+				t.Errorf("%s: retryable", name)
+			}
+		}
+	})
+}
+
+func TestRejectedErrorRetryable(t *testing.T) {
+	for _, tt := range []struct {
+		status    int
+		retryable bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusRequestEntityTooLarge, false},
+		{http.StatusRequestTimeout, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusServiceUnavailable, true},
+	} {
+		t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"error":"nope"}`, tt.status)
+			}))
+			defer server.Close()
+
+			err := SendStatsJSON(context.Background(), server.URL, map[string]int{"cpu": 1})
+			var rejected *RejectedError
+			if !errors.As(err, &rejected) {
+				t.Fatalf("SendStatsJSON = %v, want a *RejectedError", err)
+			}
+			if rejected.StatusCode != tt.status || rejected.Body != "{\"error\":\"nope\"}\n" {
+				t.Errorf("rejected = %d %q, want %d with the response body", rejected.StatusCode, rejected.Body, tt.status)
+			}
+			if rejected.Retryable() != tt.retryable || Retryable(err) != tt.retryable {
+				t.Errorf("Retryable() = %t, Retryable(err) = %t, want %t", rejected.Retryable(), Retryable(err), tt.retryable)
+			}
+			// wrapped by the agent's send loop, still classified
+			if wrapped := fmt.Errorf("send: %w", err); Retryable(wrapped) != tt.retryable {
+				t.Errorf("Retryable(wrapped) = %t, want %t", Retryable(wrapped), tt.retryable)
+			}
+		})
+	}
+}
@@ -14,6 +14,8 @@ import (
 )
 
 // SendStatsJSON marshals the provided data to JSON and sends it via HTTP POST to the specified serverURL.
+// Failures are a *TimeoutError, *ConnectionError, *RejectedError or *PayloadError
+// (see Retryable), or the context's error when ctx is cancelled.
 
 // The 'data' parameter is an interface{} to allow sending various data structures.
 func SendStatsJSON(ctx context.Context, serverURL string, data interface{}) error {
@@ -22,7 +24,7 @@ func SendStatsJSON(ctx context.Context, serverURL string, data interface{}) erro
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		appLogger.Error("Error marshaling stats to JSON: %v", err)
-		return fmt.Errorf("error marshaling data to JSON: %w", err)
+		return &PayloadError{Err: fmt.Errorf("error marshaling data to JSON: %w", err)}
 	}
 
 	// 2. Log for debugging (optional, can be removed or made conditional)
@@ -35,7 +37,7 @@ func SendStatsJSON(ctx context.Context, serverURL string, data interface{}) erro
 	req, err := http.NewRequestWithContext(reqCtx, "POST", serverURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		appLogger.Error("Error creating HTTP request: %v", err)
-		return fmt.Errorf("error creating HTTP request to %s: %w", serverURL, err)
+		return &PayloadError{Err: fmt.Errorf("error creating HTTP request to %s: %w", serverURL, err)}
 	}
 	req.Header.Set("Content-Type", "application/json")
 
@@ -46,29 +48,68 @@ func SendStatsJSON(ctx context.Context, serverURL string, data interface{}) erro
 		// Check for context errors (timeout or cancellation)
 		if reqCtx.Err() == context.DeadlineExceeded {
 			appLogger.Error("HTTP request to %s timed out.", serverURL)
-			return fmt.Errorf("http request to %s timed out: %w", serverURL, err)
+			return &TimeoutError{URL: serverURL, Err: err}
 		} else if ctx.Err() != nil { // Check original context passed to SendStatsJSON
 			appLogger.Error("HTTP request to %s cancelled by parent context: %v", serverURL, ctx.Err())
 			return fmt.Errorf("http request to %s cancelled by parent context: %w", serverURL, ctx.Err())
 		}
 		appLogger.Error("Error sending stats to server %s: %v", serverURL, err)
-		return fmt.Errorf("error sending stats to server %s: %w", serverURL, err)
+		return &ConnectionError{URL: serverURL, Err: err}
 	}
 	defer resp.Body.Close()
 
 	// 5. Process the response
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		appLogger.Info("Stats sent successfully to %s. Server responded with %s", serverURL, resp.Status)
+		logClockDrift(resp.Body, serverURL)
 	} else {
 		appLogger.Warn("Server at %s responded with non-OK status: %s", serverURL, resp.Status)
-		responseBody, readErr := io.ReadAll(resp.Body)
+		rejected := &RejectedError{URL: serverURL, StatusCode: resp.StatusCode, Status: resp.Status}
+		responseBody, readErr := readErrorBody(resp.Body)
 		if readErr != nil {
 			appLogger.Error("Error reading error response body from %s: %v", serverURL, readErr)
-			return fmt.Errorf("server at %s responded with %s (and error reading response body: %v)", serverURL, resp.Status, readErr)
+			rejected.Body = fmt.Sprintf("(error reading response body: %v)", readErr)
+			return rejected
 		}
-		appLogger.Error("Server error response from %s: %s", serverURL, string(responseBody))
-		return fmt.Errorf("server at %s responded with %s: %s", serverURL, resp.Status, string(responseBody))
+		appLogger.Error("Server error response from %s: %s", serverURL, responseBody)
+		rejected.Body = responseBody
+		return rejected
 	}
 
 	return nil // Success
 }
+
+// maxErrorBodyBytes caps how much of an error response is read and quoted, so
+// a misbehaving server can't make the agent buffer an arbitrarily large body.
+const maxErrorBodyBytes = 4096
+
+// readErrorBody reads at most maxErrorBodyBytes of an error response, marking
+// a longer body as truncated. The rest is not read.
+func readErrorBody(body io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxErrorBodyBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxErrorBodyBytes {
+		return string(data[:maxErrorBodyBytes]) + fmt.Sprintf("... (truncated, more than %d bytes)", maxErrorBodyBytes), nil
+	}
+	return string(data), nil
+}
+
+// the part of the server's success response we care about
+type statsResponse struct {
+	ClockDriftSeconds *float64 `json:"clockDriftSeconds"`
+}
+
+// logClockDrift warns when the server reports that our clock is off. The server
+// only includes the field when the drift exceeds its threshold.
+func logClockDrift(body io.Reader, serverURL string) {
+	var parsed statsResponse
+	if err := json.NewDecoder(io.LimitReader(body, 64*1024)).Decode(&parsed); err != nil {
+		return // not JSON or empty, nothing to report
+	}
+	if parsed.ClockDriftSeconds != nil {
+		drift := time.Duration(*parsed.ClockDriftSeconds * float64(time.Second)).Round(time.Millisecond)
+		appLogger.Warn("Server %s reports clock drift of %s for this host (positive = our clock is behind). Check NTP.", serverURL, drift)
+	}
+}
@@ -0,0 +1,53 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRejectedErrorBodyIsTruncated(t *testing.T) {
+	const bodySize = 8 << 20
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(strings.Repeat("x", bodySize)))
+	}))
+	defer server.Close()
+
+	err := SendStatsJSON(context.Background(), server.URL, map[string]int{"cpu": 1})
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("SendStatsJSON = %v, want a *RejectedError", err)
+	}
+	if rejected.StatusCode != http.StatusBadGateway {
+		t.Errorf("status %d, want 502", rejected.StatusCode)
+	}
+	if !strings.HasPrefix(rejected.Body, strings.Repeat("x", maxErrorBodyBytes)) || !strings.HasSuffix(rejected.Body, "(truncated, more than 4096 bytes)") {
+		t.Errorf("body (%d bytes) = %.40q...%q, want %d bytes of the response marked truncated",
+			len(rejected.Body), rejected.Body, rejected.Body[max(0, len(rejected.Body)-40):], maxErrorBodyBytes)
+	}
+	if len(rejected.Body) > maxErrorBodyBytes+100 || len(err.Error()) > maxErrorBodyBytes+200 {
+		t.Errorf("body %d bytes, error %d bytes, want about %d of the %d byte response", len(rejected.Body), len(err.Error()), maxErrorBodyBytes, bodySize)
+	}
+}
+
+func TestReadErrorBody(t *testing.T) {
+	for _, tt := range []struct {
+		name, body, want string
+	}{
+		{"short", `{"error":"invalid payload"}`, `{"error":"invalid payload"}`},
+		{"exactly the limit", strings.Repeat("a", maxErrorBodyBytes), strings.Repeat("a", maxErrorBodyBytes)},
+		{"one byte over", strings.Repeat("a", maxErrorBodyBytes+1), strings.Repeat("a", maxErrorBodyBytes) + "... (truncated, more than 4096 bytes)"},
+		{"empty", "", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readErrorBody(strings.NewReader(tt.body))
+			if err != nil || got != tt.want {
+				t.Errorf("readErrorBody = %.60q (%d bytes), %v, want %.60q (%d bytes)", got, len(got), err, tt.want, len(tt.want))
+			}
+		})
+	}
+}